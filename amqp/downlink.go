@@ -14,6 +14,9 @@ import (
 // DownlinkHandler is called for downlink messages
 type DownlinkHandler func(subscriber Subscriber, appID string, devID string, req types.DownlinkMessage)
 
+// CheckedDownlinkHandler is called for downlink messages. If it returns an error, the message is dead-lettered.
+type CheckedDownlinkHandler func(subscriber Subscriber, appID string, devID string, req types.DownlinkMessage) error
+
 // PublishDownlink publishes a downlink message to the AMQP broker
 func (c *DefaultPublisher) PublishDownlink(dataDown types.DownlinkMessage) error {
 	key := DeviceKey{dataDown.AppID, dataDown.DevID, DeviceDownlink, ""}
@@ -26,6 +29,25 @@ func (c *DefaultPublisher) PublishDownlink(dataDown types.DownlinkMessage) error
 
 // SubscribeDeviceDownlink subscribes to all downlink messages for the given application and device
 func (s *DefaultSubscriber) SubscribeDeviceDownlink(appID, devID string, handler DownlinkHandler) error {
+	return s.SubscribeDeviceDownlinkChecked(appID, devID, func(subscriber Subscriber, appID string, devID string, req types.DownlinkMessage) error {
+		handler(subscriber, appID, devID, req)
+		return nil
+	})
+}
+
+// SubscribeAppDownlink subscribes to all downlink messages for the given application
+func (s *DefaultSubscriber) SubscribeAppDownlink(appID string, handler DownlinkHandler) error {
+	return s.SubscribeDeviceDownlink(appID, "", handler)
+}
+
+// SubscribeDownlink subscribes to all downlink messages that the current user has access to
+func (s *DefaultSubscriber) SubscribeDownlink(handler DownlinkHandler) error {
+	return s.SubscribeDeviceDownlink("", "", handler)
+}
+
+// SubscribeDeviceDownlinkChecked subscribes to all downlink messages for the given application and device. Messages
+// that can not be unmarshaled or for which the handler returns an error are dead-lettered.
+func (s *DefaultSubscriber) SubscribeDeviceDownlinkChecked(appID, devID string, handler CheckedDownlinkHandler) error {
 	key := DeviceKey{appID, devID, DeviceDownlink, ""}
 	messages, err := s.subscribe(key.String())
 	if err != nil {
@@ -38,9 +60,13 @@ func (s *DefaultSubscriber) SubscribeDeviceDownlink(appID, devID string, handler
 			err := json.Unmarshal(delivery.Body, dataDown)
 			if err != nil {
 				s.ctx.Warnf("Could not unmarshal downlink %v (%s)", delivery, err)
+				s.deadLetter(delivery, err)
+				continue
+			}
+			if err := handler(s, dataDown.AppID, dataDown.DevID, *dataDown); err != nil {
+				s.deadLetter(delivery, err)
 				continue
 			}
-			handler(s, dataDown.AppID, dataDown.DevID, *dataDown)
 			delivery.Ack(false)
 		}
 	}()
@@ -48,12 +74,12 @@ func (s *DefaultSubscriber) SubscribeDeviceDownlink(appID, devID string, handler
 	return nil
 }
 
-// SubscribeAppDownlink subscribes to all downlink messages for the given application
-func (s *DefaultSubscriber) SubscribeAppDownlink(appID string, handler DownlinkHandler) error {
-	return s.SubscribeDeviceDownlink(appID, "", handler)
+// SubscribeAppDownlinkChecked subscribes to all downlink messages for the given application
+func (s *DefaultSubscriber) SubscribeAppDownlinkChecked(appID string, handler CheckedDownlinkHandler) error {
+	return s.SubscribeDeviceDownlinkChecked(appID, "", handler)
 }
 
-// SubscribeDownlink subscribes to all downlink messages that the current user has access to
-func (s *DefaultSubscriber) SubscribeDownlink(handler DownlinkHandler) error {
-	return s.SubscribeDeviceDownlink("", "", handler)
+// SubscribeDownlinkChecked subscribes to all downlink messages that the current user has access to
+func (s *DefaultSubscriber) SubscribeDownlinkChecked(handler CheckedDownlinkHandler) error {
+	return s.SubscribeDeviceDownlinkChecked("", "", handler)
 }
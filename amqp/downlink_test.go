@@ -4,8 +4,10 @@
 package amqp
 
 import (
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/TheThingsNetwork/ttn/core/types"
 	. "github.com/smartystreets/assertions"
@@ -67,3 +69,50 @@ func TestSubscribeDownlink(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestDeadLetterDownlink(t *testing.T) {
+	a := New(t)
+	c := NewClient(getLogger(t, "TestDeadLetterDownlink"), "guest", "guest", host)
+	err := c.Connect()
+	a.So(err, ShouldBeNil)
+	defer c.Disconnect()
+
+	p := c.NewPublisher("amq.topic")
+	err = p.Open()
+	a.So(err, ShouldBeNil)
+	defer p.Close()
+
+	s := c.NewSubscriber("amq.topic", "", false, true)
+	err = s.Open()
+	a.So(err, ShouldBeNil)
+	defer s.Close()
+	s.SetDeadLetterExchange("amq.direct")
+
+	dlq := c.NewSubscriber("amq.direct", "", false, true).(*DefaultSubscriber)
+	err = dlq.Open()
+	a.So(err, ShouldBeNil)
+	defer dlq.Close()
+	deadLetters, err := dlq.subscribe("app.devices.test.down")
+	a.So(err, ShouldBeNil)
+
+	err = s.SubscribeDownlinkChecked(func(_ Subscriber, _, _ string, _ types.DownlinkMessage) error {
+		return errors.New("Device not found")
+	})
+	a.So(err, ShouldBeNil)
+
+	err = p.PublishDownlink(types.DownlinkMessage{
+		AppID:      "app",
+		DevID:      "test",
+		PayloadRaw: []byte{0x01, 0x08},
+	})
+	a.So(err, ShouldBeNil)
+
+	select {
+	case delivery := <-deadLetters:
+		a.So(delivery.Headers["x-ttn-error"], ShouldEqual, "Device not found")
+		a.So(delivery.Headers["x-ttn-exchange"], ShouldEqual, "amq.topic")
+		delivery.Ack(false)
+	case <-time.After(time.Second):
+		t.Fatal("Downlink was not dead-lettered in time")
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package amqp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var unconfirmedPublishCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "ttn",
+		Subsystem: "amqp",
+		Name:      "unconfirmed_publishes_total",
+		Help:      "Total number of publish attempts that were not confirmed by the AMQP server.",
+	},
+)
+
+var failedPublishCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "ttn",
+		Subsystem: "amqp",
+		Name:      "failed_publishes_total",
+		Help:      "Total number of messages that could not be published after all retries.",
+	},
+)
+
+var deadLetteredDownlinkCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "ttn",
+		Subsystem: "amqp",
+		Name:      "dead_lettered_downlinks_total",
+		Help:      "Total number of downlinks that were dead-lettered or dropped.",
+	}, []string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(unconfirmedPublishCounter)
+	prometheus.MustRegister(failedPublishCounter)
+	prometheus.MustRegister(deadLetteredDownlinkCounter)
+}
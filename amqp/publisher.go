@@ -4,12 +4,31 @@
 package amqp
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/TheThingsNetwork/ttn/core/types"
 	AMQP "github.com/streadway/amqp"
 )
 
+var (
+	// PublishBufferSize indicates how many messages the publisher buffers for retrying
+	PublishBufferSize = 100
+	// PublishRetries says how many times the publisher should retry a message that was not confirmed
+	PublishRetries = 3
+	// PublishRetryDelay says how long the publisher should wait before retrying a message
+	PublishRetryDelay = 100 * time.Millisecond
+)
+
+var (
+	errPublishNacked        = errors.New("Message was rejected by the AMQP server")
+	errPublishChannelClosed = errors.New("Channel was closed before the message was confirmed")
+	errPublishBufferFull    = errors.New("Retry buffer of the publisher is full")
+)
+
 // Publisher represents a publisher for uplink messages
 type Publisher interface {
 	ChannelClient
@@ -20,9 +39,24 @@ type Publisher interface {
 	PublishAppEvent(appID string, eventType types.EventType, payload interface{}) error
 }
 
-// DefaultPublisher represents the default AMQP publisher
+// DefaultPublisher represents the default AMQP publisher. The channel of the publisher is in confirm mode. Messages
+// are published without waiting for their confirmation; messages that are rejected by the AMQP server or that are
+// unconfirmed when the channel closes are retried from a bounded buffer.
 type DefaultPublisher struct {
 	DefaultChannelClient
+
+	mutex       sync.Mutex
+	deliveryTag uint64
+	pending     map[uint64]*publishing
+	retries     chan *publishing
+	done        chan struct{}
+}
+
+type publishing struct {
+	key      string
+	msg      AMQP.Publishing
+	attempts int
+	retryAt  time.Time
 }
 
 // NewPublisher returns a new topic publisher on the specified exchange
@@ -34,14 +68,145 @@ func (c *DefaultClient) NewPublisher(exchange string) Publisher {
 			exchange: exchange,
 			name:     "Publisher",
 		},
+		retries: make(chan *publishing, PublishBufferSize),
 	}
 }
 
+// Open opens a new channel and puts it in confirm mode
+func (p *DefaultPublisher) Open() error {
+	if err := p.DefaultChannelClient.Open(); err != nil {
+		return err
+	}
+	if err := p.use(p.channel); err != nil {
+		p.DefaultChannelClient.Close()
+		return err
+	}
+	p.addUser(p)
+	p.mutex.Lock()
+	p.done = make(chan struct{})
+	go p.retryPublishings(p.done)
+	p.mutex.Unlock()
+	return nil
+}
+
+func (p *DefaultPublisher) use(channel *AMQP.Channel) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if err := channel.Confirm(false); err != nil {
+		return fmt.Errorf("Could not put channel in confirm mode (%s)", err)
+	}
+	p.pending = make(map[uint64]*publishing)
+	p.deliveryTag = 0
+	go p.handleConfirms(channel.NotifyPublish(make(chan AMQP.Confirmation, PublishBufferSize)), p.pending)
+	return nil
+}
+
+func (p *DefaultPublisher) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.done != nil {
+		close(p.done)
+		p.done = nil
+	}
+}
+
+// handleConfirms handles the confirmations of the messages that were published on a channel. When the channel is
+// closed, the messages that were not confirmed are retried.
+func (p *DefaultPublisher) handleConfirms(confirms chan AMQP.Confirmation, pending map[uint64]*publishing) {
+	for confirmation := range confirms {
+		p.mutex.Lock()
+		pub, ok := pending[confirmation.DeliveryTag]
+		delete(pending, confirmation.DeliveryTag)
+		p.mutex.Unlock()
+		if !ok || confirmation.Ack {
+			continue
+		}
+		unconfirmedPublishCounter.Inc()
+		p.retry(pub, errPublishNacked)
+	}
+
+	p.mutex.Lock()
+	tags := make([]uint64, 0, len(pending))
+	for tag := range pending {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+	unconfirmed := make([]*publishing, len(tags))
+	for i, tag := range tags {
+		unconfirmed[i] = pending[tag]
+		delete(pending, tag)
+	}
+	p.mutex.Unlock()
+	for _, pub := range unconfirmed {
+		unconfirmedPublishCounter.Inc()
+		p.retry(pub, errPublishChannelClosed)
+	}
+}
+
+// retryPublishings publishes the messages from the retry buffer until the publisher is closed
+func (p *DefaultPublisher) retryPublishings(done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case pub := <-p.retries:
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Until(pub.retryAt)):
+			}
+			p.ctx.Debugf("Retrying publish to %s (attempt %d)", pub.key, pub.attempts)
+			if err := p.send(pub); err != nil {
+				p.retry(pub, err)
+			}
+		}
+	}
+}
+
+// retry adds the message to the retry buffer. It returns an error if the message is dropped, because it was retried
+// too often or because the buffer is full.
+func (p *DefaultPublisher) retry(pub *publishing, err error) error {
+	pub.attempts++
+	if pub.attempts > PublishRetries {
+		failedPublishCounter.Inc()
+		p.ctx.WithError(err).Warnf("Could not publish to %s after %d retries", pub.key, PublishRetries)
+		return err
+	}
+	pub.retryAt = time.Now().Add(PublishRetryDelay)
+	select {
+	case p.retries <- pub:
+		return nil
+	default:
+		failedPublishCounter.Inc()
+		p.ctx.WithError(err).Warnf("Could not retry publish to %s", pub.key)
+		return errPublishBufferFull
+	}
+}
+
+// send publishes the message and keeps track of it until it is confirmed
+func (p *DefaultPublisher) send(pub *publishing) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if err := p.channel.Publish(p.exchange, pub.key, false, false, pub.msg); err != nil {
+		return err
+	}
+	p.deliveryTag++
+	p.pending[p.deliveryTag] = pub
+	return nil
+}
+
 func (p *DefaultPublisher) publish(key string, msg []byte, timestamp time.Time) error {
-	return p.channel.Publish(p.exchange, key, false, false, AMQP.Publishing{
-		ContentType:  "application/json",
-		DeliveryMode: AMQP.Persistent,
-		Timestamp:    timestamp,
-		Body:         msg,
-	})
+	pub := &publishing{
+		key: key,
+		msg: AMQP.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: AMQP.Persistent,
+			Timestamp:    timestamp,
+			Body:         msg,
+		},
+	}
+	if err := p.send(pub); err != nil {
+		return p.retry(pub, err)
+	}
+	return nil
 }
@@ -4,7 +4,10 @@
 package amqp
 
 import (
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/TheThingsNetwork/ttn/core/types"
 	AMQP "github.com/streadway/amqp"
@@ -15,8 +18,12 @@ var (
 	PrefetchCount = 3
 	// PrefetchSize represents the number of bytes to prefetch before the AMQP server requires acknowledgment
 	PrefetchSize = 0
+	// DeadLetterConfirmTimeout is the time that the AMQP server gets to confirm a dead-lettered message
+	DeadLetterConfirmTimeout = 5 * time.Second
 )
 
+var errDeadLetterNotConfirmed = errors.New("Dead-lettered message was not confirmed by the AMQP server")
+
 // Subscriber represents a subscriber for uplink messages
 type Subscriber interface {
 	ChannelClient
@@ -25,6 +32,8 @@ type Subscriber interface {
 	QueueBind(name, key string) error
 	QueueUnbind(name, key string) error
 
+	SetDeadLetterExchange(exchange string)
+
 	SubscribeDeviceUplink(appID, devID string, handler UplinkHandler) error
 	SubscribeAppUplink(appID string, handler UplinkHandler) error
 	SubscribeUplink(handler UplinkHandler) error
@@ -33,6 +42,9 @@ type Subscriber interface {
 	SubscribeDeviceDownlink(appID, devID string, handler DownlinkHandler) error
 	SubscribeAppDownlink(appID string, handler DownlinkHandler) error
 	SubscribeDownlink(handler DownlinkHandler) error
+	SubscribeDeviceDownlinkChecked(appID, devID string, handler CheckedDownlinkHandler) error
+	SubscribeAppDownlinkChecked(appID string, handler CheckedDownlinkHandler) error
+	SubscribeDownlinkChecked(handler CheckedDownlinkHandler) error
 
 	SubscribeDeviceEvents(appID string, devID string, eventType types.EventType, handler DeviceEventHandler) error
 	SubscribeAppEvents(appID string, eventType types.EventType, handler AppEventHandler) error
@@ -45,6 +57,15 @@ type DefaultSubscriber struct {
 	name       string
 	durable    bool
 	autoDelete bool
+
+	deadLetterExchange string
+
+	// The channel is put in confirm mode when the first message is dead-lettered, so that the delivery is only
+	// acknowledged after the AMQP server confirmed the dead-lettered message
+	deadLetterMutex       sync.Mutex
+	deadLetterChannel     *AMQP.Channel
+	deadLetterConfirms    chan AMQP.Confirmation
+	deadLetterDeliveryTag uint64
 }
 
 // NewSubscriber returns a new topic subscriber on the specified exchange
@@ -89,6 +110,85 @@ func (s *DefaultSubscriber) QueueUnbind(name, key string) error {
 	return nil
 }
 
+// SetDeadLetterExchange sets the exchange to which messages that could not be handled are published. If no dead-letter
+// exchange is set, these messages are rejected.
+func (s *DefaultSubscriber) SetDeadLetterExchange(exchange string) {
+	s.deadLetterExchange = exchange
+}
+
+// deadLetter publishes the delivery to the dead-letter exchange, with the reason in the x-ttn-error header. The
+// delivery is acknowledged after the AMQP server confirmed the dead-lettered message. Otherwise, it is requeued once,
+// and rejected if it was already redelivered.
+func (s *DefaultSubscriber) deadLetter(delivery AMQP.Delivery, reason error) {
+	if s.deadLetterExchange == "" {
+		deadLetteredDownlinkCounter.WithLabelValues("rejected").Inc()
+		delivery.Nack(false, false)
+		return
+	}
+	err := s.publishDeadLetter(AMQP.Publishing{
+		Headers: AMQP.Table{
+			"x-ttn-error":    reason.Error(),
+			"x-ttn-exchange": delivery.Exchange,
+		},
+		ContentType:  delivery.ContentType,
+		DeliveryMode: AMQP.Persistent,
+		Timestamp:    delivery.Timestamp,
+		Body:         delivery.Body,
+	}, delivery.RoutingKey)
+	if err != nil {
+		s.ctx.WithError(err).Warnf("Could not publish message to dead-letter exchange %s", s.deadLetterExchange)
+		if !delivery.Redelivered {
+			// The message is handled once more, instead of being lost
+			deadLetteredDownlinkCounter.WithLabelValues("requeued").Inc()
+			delivery.Nack(false, true)
+			return
+		}
+		deadLetteredDownlinkCounter.WithLabelValues("rejected").Inc()
+		delivery.Nack(false, false)
+		return
+	}
+	deadLetteredDownlinkCounter.WithLabelValues("dead_lettered").Inc()
+	delivery.Ack(false)
+}
+
+// publishDeadLetter publishes the message to the dead-letter exchange and waits for its confirmation
+func (s *DefaultSubscriber) publishDeadLetter(msg AMQP.Publishing, key string) error {
+	s.deadLetterMutex.Lock()
+	defer s.deadLetterMutex.Unlock()
+	if s.deadLetterChannel != s.channel {
+		// The channel is new, or it was reopened after a reconnect
+		if err := s.channel.Confirm(false); err != nil {
+			return fmt.Errorf("Could not put channel in confirm mode (%s)", err)
+		}
+		s.deadLetterChannel = s.channel
+		s.deadLetterConfirms = s.channel.NotifyPublish(make(chan AMQP.Confirmation, PrefetchCount))
+		s.deadLetterDeliveryTag = 0
+	}
+	if err := s.channel.Publish(s.deadLetterExchange, key, false, false, msg); err != nil {
+		return err
+	}
+	s.deadLetterDeliveryTag++
+	timeout := time.NewTimer(DeadLetterConfirmTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case confirmation, ok := <-s.deadLetterConfirms:
+			if !ok {
+				return errDeadLetterNotConfirmed
+			}
+			if confirmation.DeliveryTag < s.deadLetterDeliveryTag {
+				continue // Late confirmation of a message that timed out
+			}
+			if !confirmation.Ack {
+				return errDeadLetterNotConfirmed
+			}
+			return nil
+		case <-timeout.C:
+			return errDeadLetterNotConfirmed
+		}
+	}
+}
+
 type consumer struct {
 	queue      string
 	deliveries chan AMQP.Delivery
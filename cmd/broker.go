@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
 	"syscall"
 	"time"
 
@@ -16,9 +18,12 @@ import (
 
 	ttnlog "github.com/TheThingsNetwork/go-utils/log"
 	"github.com/TheThingsNetwork/ttn/core/broker"
+	"github.com/TheThingsNetwork/ttn/core/broker/usage"
 	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/redis.v5"
 )
 
 // brokerCmd represents the broker command
@@ -32,6 +37,7 @@ var brokerCmd = &cobra.Command{
 			"Announce":           fmt.Sprintf("%s:%d", viper.GetString("broker.server-address-announce"), viper.GetInt("broker.server-port")),
 			"NetworkServer":      viper.GetString("broker.networkserver-address"),
 			"DeduplicationDelay": viper.GetString("broker.deduplication-delay"),
+			"HandlerSelection":   viper.GetString("broker.handler-selection"),
 		}).Info("Initializing Broker")
 	},
 	Run: func(cmd *cobra.Command, args []string) {
@@ -52,11 +58,54 @@ var brokerCmd = &cobra.Command{
 			nsCert = string(contents)
 		}
 
+		handlerSelection, err := broker.ParseHandlerSelection(viper.GetString("broker.handler-selection"))
+		if err != nil {
+			ctx.WithError(err).Fatal("Invalid handler selection")
+		}
+
+		var roamingNetID types.NetID
+		if err := roamingNetID.UnmarshalText([]byte(viper.GetString("broker.roaming-net-id"))); err != nil {
+			ctx.WithError(err).Fatal("Invalid roaming NetID")
+		}
+		var roamingPartners []broker.RoamingPartner
+		for _, str := range viper.GetStringSlice("broker.roaming-partners") {
+			partner, err := broker.ParseRoamingPartner(str)
+			if err != nil {
+				ctx.WithError(err).Fatal("Invalid roaming partner")
+			}
+			roamingPartners = append(roamingPartners, partner)
+		}
+
+		var usageStore usage.Store
+		var redisClient *redis.Client
+		if redisAddress := viper.GetString("broker.redis-address"); redisAddress != "" {
+			client := redis.NewClient(&redis.Options{
+				Addr:     redisAddress,
+				Password: viper.GetString("broker.redis-password"),
+				DB:       viper.GetInt("broker.redis-db"),
+				PoolSize: 10 * runtime.NumCPU(),
+			})
+			if err := connectRedis(client); err != nil {
+				ctx.WithError(err).Fatal("Could not initialize database connection")
+			}
+			usageStore = usage.NewRedisStore(client, "broker:usage")
+			redisClient = client
+		}
+
 		// Broker
 		broker := broker.NewBroker(
 			time.Duration(viper.GetInt("broker.deduplication-delay")) * time.Millisecond,
 		)
 		broker.SetNetworkServer(viper.GetString("broker.networkserver-address"), nsCert, viper.GetString("broker.networkserver-token"))
+		broker.SetHandlerSelection(handlerSelection)
+		if len(roamingPartners) != 0 {
+			broker.SetRoaming(roamingNetID, viper.GetString("broker.roaming-token"), roamingPartners...)
+		}
+		if usageStore != nil {
+			broker.SetUsageStore(usageStore)
+			broker.SetGatewayRouterStore(redisClient)
+			broker.SetDownlinkFallbackStore(redisClient)
+		}
 		err = broker.Init(component)
 		if err != nil {
 			ctx.WithError(err).Fatal("Could not initialize broker")
@@ -76,6 +125,22 @@ var brokerCmd = &cobra.Command{
 
 		go grpc.Serve(lis)
 
+		if len(roamingPartners) != 0 && viper.GetInt("broker.roaming-port") != 0 {
+			srv := &http.Server{
+				Addr:              fmt.Sprintf("%s:%d", viper.GetString("broker.roaming-address"), viper.GetInt("broker.roaming-port")),
+				Handler:           broker.RoamingHandler(),
+				ReadHeaderTimeout: 5 * time.Second,
+				ReadTimeout:       10 * time.Second,
+				WriteTimeout:      10 * time.Second,
+			}
+			go func() {
+				err := srv.ListenAndServe()
+				if err != nil {
+					ctx.WithError(err).Fatal("Error in roaming server")
+				}
+			}()
+		}
+
 		sigChan := make(chan os.Signal)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 		ctx.WithField("signal", <-sigChan).Info("signal received")
@@ -98,6 +163,27 @@ func init() {
 	brokerCmd.Flags().Int("deduplication-delay", 200, "Deduplication delay (in ms)")
 	viper.BindPFlag("broker.deduplication-delay", brokerCmd.Flags().Lookup("deduplication-delay"))
 
+	brokerCmd.Flags().String("handler-selection", "single", "Handler selection if multiple Handlers serve an AppID (single, primary or hash)")
+	viper.BindPFlag("broker.handler-selection", brokerCmd.Flags().Lookup("handler-selection"))
+
+	brokerCmd.Flags().String("roaming-net-id", "000013", "NetID of this network for passive roaming")
+	brokerCmd.Flags().StringSlice("roaming-partners", []string{}, "Passive roaming partners (NetID=URL)")
+	brokerCmd.Flags().String("roaming-token", "", "Token for authentication with roaming partners")
+	brokerCmd.Flags().String("roaming-address", "0.0.0.0", "The IP address to listen for roaming partners")
+	brokerCmd.Flags().Int("roaming-port", 0, "The port to listen for roaming partners")
+	viper.BindPFlag("broker.roaming-net-id", brokerCmd.Flags().Lookup("roaming-net-id"))
+	viper.BindPFlag("broker.roaming-partners", brokerCmd.Flags().Lookup("roaming-partners"))
+	viper.BindPFlag("broker.roaming-token", brokerCmd.Flags().Lookup("roaming-token"))
+	viper.BindPFlag("broker.roaming-address", brokerCmd.Flags().Lookup("roaming-address"))
+	viper.BindPFlag("broker.roaming-port", brokerCmd.Flags().Lookup("roaming-port"))
+
+	brokerCmd.Flags().String("redis-address", "", "Redis host and port for traffic accounting, the Routers of Gateways and alternative downlink options. Leave empty to disable accounting")
+	viper.BindPFlag("broker.redis-address", brokerCmd.Flags().Lookup("redis-address"))
+	brokerCmd.Flags().String("redis-password", "", "Redis password")
+	viper.BindPFlag("broker.redis-password", brokerCmd.Flags().Lookup("redis-password"))
+	brokerCmd.Flags().Int("redis-db", 0, "Redis database")
+	viper.BindPFlag("broker.redis-db", brokerCmd.Flags().Lookup("redis-db"))
+
 	brokerCmd.Flags().String("server-address", "0.0.0.0", "The IP address to listen for communication")
 	brokerCmd.Flags().String("server-address-announce", "localhost", "The public IP address to announce")
 	brokerCmd.Flags().Int("server-port", 1902, "The port for communication")
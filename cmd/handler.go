@@ -4,12 +4,14 @@
 package cmd
 
 import (
+	"encoding/hex"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	goruntime "runtime"
+	"strings"
 	"syscall"
 
 	pb "github.com/TheThingsNetwork/api/handler"
@@ -17,8 +19,11 @@ import (
 	"github.com/TheThingsNetwork/ttn/api/pool"
 	"github.com/TheThingsNetwork/ttn/core/component"
 	"github.com/TheThingsNetwork/ttn/core/handler"
+	"github.com/TheThingsNetwork/ttn/core/handler/functions"
+	"github.com/TheThingsNetwork/ttn/core/handler/timeseries"
 	"github.com/TheThingsNetwork/ttn/core/proxy"
 	"github.com/TheThingsNetwork/ttn/core/proxy/jsonpb"
+	"github.com/TheThingsNetwork/ttn/kafka"
 	"github.com/TheThingsNetwork/ttn/utils/parse"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/spf13/cobra"
@@ -70,6 +75,28 @@ var handlerCmd = &cobra.Command{
 			component.Identity.ApiAddress = fmt.Sprintf("http://%s:%d", viper.GetString("handler.server-address-announce"), viper.GetInt("handler.http-port"))
 		}
 
+		joinServerKEKs := make(map[string][]byte)
+		for _, str := range viper.GetStringSlice("handler.join-server-keks") {
+			parts := strings.SplitN(str, "=", 2)
+			if len(parts) != 2 {
+				ctx.WithField("KEK", parts[0]).Fatal("Invalid Join Server KEK, expected format label=key")
+			}
+			kek, err := hex.DecodeString(parts[1])
+			if err != nil {
+				ctx.WithError(err).WithField("KEK", parts[0]).Fatal("Invalid Join Server KEK")
+			}
+			joinServerKEKs[parts[0]] = kek
+		}
+		var joinServers []handler.JoinServer
+		for _, str := range viper.GetStringSlice("handler.join-servers") {
+			joinServer, err := handler.ParseJoinServer(str)
+			if err != nil {
+				ctx.WithError(err).Fatal("Invalid Join Server")
+			}
+			joinServer.Token = viper.GetString("handler.join-server-token")
+			joinServers = append(joinServers, joinServer)
+		}
+
 		// Handler
 		handler := handler.NewRedisHandler(
 			client,
@@ -101,6 +128,9 @@ var handlerCmd = &cobra.Command{
 				viper.GetString("handler.amqp-address"),
 				viper.GetString("handler.amqp-exchange"),
 			)
+			if deadLetterExchange := viper.GetString("handler.amqp-dead-letter-exchange"); deadLetterExchange != "" {
+				handler = handler.WithAMQPDeadLetterExchange(deadLetterExchange)
+			}
 
 			amqpPort, err := parse.Port(viper.GetString("handler.amqp-address"))
 			if err != nil {
@@ -114,6 +144,17 @@ var handlerCmd = &cobra.Command{
 		} else {
 			ctx.Warn("AMQP is not enabled in your configuration")
 		}
+		if kafkaBrokers := viper.GetStringSlice("handler.kafka-brokers"); len(kafkaBrokers) != 0 {
+			handler = handler.WithKafka(kafkaBrokers, kafka.Topics{
+				Uplink:        viper.GetString("handler.kafka-uplink-topic"),
+				Activations:   viper.GetString("handler.kafka-activations-topic"),
+				Events:        viper.GetString("handler.kafka-events-topic"),
+				Downlink:      viper.GetString("handler.kafka-downlink-topic"),
+				DownlinkGroup: viper.GetString("handler.kafka-downlink-group"),
+			})
+		} else {
+			ctx.Debug("Kafka is not enabled in your configuration")
+		}
 
 		if extraDeviceAttributes := viper.GetStringSlice("handler.extra-device-attributes"); len(extraDeviceAttributes) != 0 {
 			handler = handler.WithDeviceAttributes(extraDeviceAttributes...)
@@ -121,6 +162,33 @@ var handlerCmd = &cobra.Command{
 			ctx.Debug("No extra device attribute set in your configuration")
 		}
 
+		if len(joinServers) != 0 {
+			handler = handler.WithJoinServers(joinServerKEKs, joinServers...)
+		}
+
+		handler = handler.WithConfirmedDownlinkRetries(viper.GetInt("handler.confirmed-downlink-retries"))
+
+		functions.ApplicationQuota = functions.Quota{
+			ExecutionTime: viper.GetDuration("handler.payload-functions-execution-time"),
+			Window:        viper.GetDuration("handler.payload-functions-window"),
+			Disable:       viper.GetDuration("handler.payload-functions-disable"),
+			ResultSize:    viper.GetInt("handler.payload-functions-result-size"),
+		}
+
+		if viper.GetBool("handler.webhooks") {
+			handler = handler.WithWebhooks()
+			if !httpActive {
+				ctx.Warn("Webhooks are enabled, but the HTTP server is not: webhooks can not be managed and can not send downlink")
+			}
+		}
+
+		if viper.GetBool("handler.uplink-storage") {
+			handler = handler.WithUplinkStorage(timeseries.NewRedisStore(client, "handler:uplinks", viper.GetDuration("handler.uplink-storage-retention")))
+			if !httpActive {
+				ctx.Warn("Uplink storage is enabled, but the HTTP server is not: stored uplinks can only be queried over gRPC")
+			}
+		}
+
 		err = handler.Init(component)
 		if err != nil {
 			ctx.WithError(err).Fatal("Could not initialize handler")
@@ -156,6 +224,12 @@ var handlerCmd = &cobra.Command{
 
 			prxy := proxy.WithToken(mux)
 			prxy = proxy.WithPagination(prxy)
+			if viper.GetBool("handler.webhooks") {
+				prxy = proxy.WithRoute(prxy, "/webhooks/", handler.WebhookHandler())
+			}
+			if viper.GetBool("handler.uplink-storage") {
+				prxy = proxy.WithRoute(prxy, "/uplinks/", handler.UplinkStorageHandler())
+			}
 			prxy = proxy.WithLogger(prxy, ctx)
 
 			go func() {
@@ -205,11 +279,26 @@ func init() {
 	handlerCmd.Flags().String("amqp-username", "guest", "AMQP username")
 	handlerCmd.Flags().String("amqp-password", "guest", "AMQP password")
 	handlerCmd.Flags().String("amqp-exchange", "ttn.handler", "AMQP exchange")
+	handlerCmd.Flags().String("amqp-dead-letter-exchange", "", "AMQP exchange for downlinks that could not be handled. Leave empty to reject these downlinks")
 	viper.BindPFlag("handler.amqp-address", handlerCmd.Flags().Lookup("amqp-address"))
 	viper.BindPFlag("handler.amqp-address-announce", handlerCmd.Flags().Lookup("amqp-address-announce"))
 	viper.BindPFlag("handler.amqp-username", handlerCmd.Flags().Lookup("amqp-username"))
 	viper.BindPFlag("handler.amqp-password", handlerCmd.Flags().Lookup("amqp-password"))
 	viper.BindPFlag("handler.amqp-exchange", handlerCmd.Flags().Lookup("amqp-exchange"))
+	viper.BindPFlag("handler.amqp-dead-letter-exchange", handlerCmd.Flags().Lookup("amqp-dead-letter-exchange"))
+
+	handlerCmd.Flags().StringSlice("kafka-brokers", nil, "Kafka broker addresses. Leave empty to disable Kafka")
+	handlerCmd.Flags().String("kafka-uplink-topic", kafka.DefaultTopics.Uplink, "Kafka topic for uplink messages")
+	handlerCmd.Flags().String("kafka-activations-topic", kafka.DefaultTopics.Activations, "Kafka topic for activations")
+	handlerCmd.Flags().String("kafka-events-topic", kafka.DefaultTopics.Events, "Kafka topic for device and application events")
+	handlerCmd.Flags().String("kafka-downlink-topic", kafka.DefaultTopics.Downlink, "Kafka topic to consume downlink messages from. Leave empty to disable")
+	handlerCmd.Flags().String("kafka-downlink-group", kafka.DefaultTopics.DownlinkGroup, "Kafka consumer group of the downlink topic. Handlers in the same group share the downlink messages")
+	viper.BindPFlag("handler.kafka-brokers", handlerCmd.Flags().Lookup("kafka-brokers"))
+	viper.BindPFlag("handler.kafka-uplink-topic", handlerCmd.Flags().Lookup("kafka-uplink-topic"))
+	viper.BindPFlag("handler.kafka-activations-topic", handlerCmd.Flags().Lookup("kafka-activations-topic"))
+	viper.BindPFlag("handler.kafka-events-topic", handlerCmd.Flags().Lookup("kafka-events-topic"))
+	viper.BindPFlag("handler.kafka-downlink-topic", handlerCmd.Flags().Lookup("kafka-downlink-topic"))
+	viper.BindPFlag("handler.kafka-downlink-group", handlerCmd.Flags().Lookup("kafka-downlink-group"))
 
 	handlerCmd.Flags().String("server-address", "0.0.0.0", "The IP address to listen for communication")
 	handlerCmd.Flags().String("server-address-announce", "localhost", "The public IP address to announce")
@@ -223,6 +312,33 @@ func init() {
 	viper.BindPFlag("handler.http-address", handlerCmd.Flags().Lookup("http-address"))
 	viper.BindPFlag("handler.http-port", handlerCmd.Flags().Lookup("http-port"))
 
+	handlerCmd.Flags().Bool("webhooks", false, "Enable webhook integrations of applications (managed through the HTTP server)")
+	viper.BindPFlag("handler.webhooks", handlerCmd.Flags().Lookup("webhooks"))
+
+	handlerCmd.Flags().Bool("uplink-storage", false, "Store uplink messages in Redis so that they can be queried")
+	handlerCmd.Flags().Duration("uplink-storage-retention", timeseries.DefaultRetention, "Retention period of stored uplink messages")
+	viper.BindPFlag("handler.uplink-storage", handlerCmd.Flags().Lookup("uplink-storage"))
+	viper.BindPFlag("handler.uplink-storage-retention", handlerCmd.Flags().Lookup("uplink-storage-retention"))
+
+	handlerCmd.Flags().StringSlice("join-servers", nil, "External Join Servers (AppEUI=URL or FromAppEUI-ToAppEUI=URL)")
+	handlerCmd.Flags().String("join-server-token", "", "Token for authentication with external Join Servers")
+	handlerCmd.Flags().StringSlice("join-server-keks", nil, "Key encryption keys for session keys from external Join Servers (label=key)")
+	viper.BindPFlag("handler.join-servers", handlerCmd.Flags().Lookup("join-servers"))
+	viper.BindPFlag("handler.join-server-token", handlerCmd.Flags().Lookup("join-server-token"))
+	viper.BindPFlag("handler.join-server-keks", handlerCmd.Flags().Lookup("join-server-keks"))
+
 	handlerCmd.Flags().StringSlice("extra-device-attributes", nil, "Extra device attributes to be whitelisted")
 	viper.BindPFlag("handler.extra-device-attributes", handlerCmd.Flags().Lookup("extra-device-attributes"))
+
+	handlerCmd.Flags().Int("confirmed-downlink-retries", handler.DefaultConfirmedDownlinkRetries, "Number of retransmissions of a confirmed downlink before it is dropped")
+	viper.BindPFlag("handler.confirmed-downlink-retries", handlerCmd.Flags().Lookup("confirmed-downlink-retries"))
+
+	handlerCmd.Flags().Duration("payload-functions-execution-time", functions.DefaultQuota.ExecutionTime, "Execution (wall clock) time that the payload functions of an application can use per window. Set to 0 for no limit")
+	handlerCmd.Flags().Duration("payload-functions-window", functions.DefaultQuota.Window, "Window of the execution time of payload functions")
+	handlerCmd.Flags().Duration("payload-functions-disable", functions.DefaultQuota.Disable, "Time for which the payload functions of an application are disabled after exceeding their quota")
+	handlerCmd.Flags().Int("payload-functions-result-size", functions.DefaultQuota.ResultSize, "Maximum size in bytes of the value that a payload function returns. Set to 0 for no limit")
+	viper.BindPFlag("handler.payload-functions-execution-time", handlerCmd.Flags().Lookup("payload-functions-execution-time"))
+	viper.BindPFlag("handler.payload-functions-window", handlerCmd.Flags().Lookup("payload-functions-window"))
+	viper.BindPFlag("handler.payload-functions-disable", handlerCmd.Flags().Lookup("payload-functions-disable"))
+	viper.BindPFlag("handler.payload-functions-result-size", handlerCmd.Flags().Lookup("payload-functions-result-size"))
 }
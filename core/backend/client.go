@@ -0,0 +1,114 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// DefaultTimeout for requests to other Backend Interfaces servers
+var DefaultTimeout = 5 * time.Second
+
+var transactionID uint32
+
+// NextTransactionID returns a new TransactionID
+func NextTransactionID() uint32 {
+	return atomic.AddUint32(&transactionID, 1)
+}
+
+// Client sends Backend Interfaces messages to a remote server
+type Client struct {
+	SenderID   string
+	ReceiverID string
+	URL        string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// Do fills the header of req, sends it to the remote server and decodes the answer into ans
+func (c *Client) Do(messageType MessageType, req Message, ans Message) error {
+	header := req.GetHeader()
+	header.ProtocolVersion = ProtocolVersion
+	header.SenderID = c.SenderID
+	header.ReceiverID = c.ReceiverID
+	header.MessageType = messageType
+	if header.TransactionID == 0 {
+		header.TransactionID = NextTransactionID()
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	res, err := httpClient.Do(httpReq)
+	if err != nil {
+		return errors.NewErrUnavailable(err.Error())
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.NewErrUnavailable(fmt.Sprintf("%s returned %s", c.URL, res.Status))
+	}
+	if err := json.NewDecoder(res.Body).Decode(ans); err != nil {
+		return errors.Wrap(err, "Could not decode answer")
+	}
+	if ans.GetHeader().TransactionID != header.TransactionID {
+		return errors.NewErrInvalidArgument("TransactionID", "does not match request")
+	}
+	return nil
+}
+
+// CheckResult returns an error if the result is not successful
+func CheckResult(result Result) error {
+	switch result.ResultCode {
+	case ResultSuccess:
+		return nil
+	case ResultUnknownDevAddr:
+		return errors.NewErrNotFound(fmt.Sprintf("DevAddr (%s)", result.Description))
+	case ResultUnknownDevEUI:
+		return errors.NewErrNotFound(fmt.Sprintf("DevEUI (%s)", result.Description))
+	case ResultUnknownSender, ResultRoamingNotAllowed:
+		return errors.NewErrPermissionDenied(fmt.Sprintf("%s: %s", result.ResultCode, result.Description))
+	case ResultMalformedRequest, ResultFrameSizeError, ResultMICFailed, ResultJoinReqFailed:
+		return errors.NewErrInvalidArgument(string(result.ResultCode), result.Description)
+	default:
+		return errors.NewErrInternal(fmt.Sprintf("%s: %s", result.ResultCode, result.Description))
+	}
+}
+
+// ResultFor returns the result for the given error
+func ResultFor(err error) Result {
+	if err == nil {
+		return Result{ResultCode: ResultSuccess}
+	}
+	switch errors.GetErrType(err) {
+	case errors.NotFound:
+		return Result{ResultCode: ResultUnknownDevAddr, Description: err.Error()}
+	case errors.InvalidArgument:
+		return Result{ResultCode: ResultMalformedRequest, Description: err.Error()}
+	case errors.PermissionDenied:
+		return Result{ResultCode: ResultUnknownSender, Description: err.Error()}
+	default:
+		return Result{ResultCode: ResultOther, Description: err.Error()}
+	}
+}
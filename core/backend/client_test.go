@@ -0,0 +1,74 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestClient(t *testing.T) {
+	a := New(t)
+
+	var received PRStartReqMessage
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(PRStartAnsMessage{
+			Header: Header{
+				ProtocolVersion: ProtocolVersion,
+				SenderID:        received.ReceiverID,
+				ReceiverID:      received.SenderID,
+				TransactionID:   received.TransactionID,
+				MessageType:     PRStartAns,
+			},
+			Result: Result{ResultCode: ResultUnknownDevAddr},
+		})
+	}))
+	defer server.Close()
+
+	client := &Client{SenderID: "000013", ReceiverID: "000042", URL: server.URL, Token: "secret"}
+
+	ans := new(PRStartAnsMessage)
+	err := client.Do(PRStartReq, &PRStartReqMessage{PHYPayload: HEXBytes{0x40, 0x01}}, ans)
+	a.So(err, ShouldBeNil)
+	a.So(authorization, ShouldEqual, "Bearer secret")
+	a.So(received.MessageType, ShouldEqual, PRStartReq)
+	a.So(received.SenderID, ShouldEqual, "000013")
+	a.So(received.ReceiverID, ShouldEqual, "000042")
+	a.So(received.PHYPayload, ShouldResemble, HEXBytes{0x40, 0x01})
+	a.So(received.TransactionID, ShouldNotEqual, 0)
+	a.So(CheckResult(ans.Result), ShouldHaveSameTypeAs, &errors.ErrNotFound{})
+
+	client.URL = server.URL + "/invalid\x00"
+	a.So(client.Do(PRStartReq, &PRStartReqMessage{}, new(PRStartAnsMessage)), ShouldNotBeNil)
+}
+
+func TestHEXBytes(t *testing.T) {
+	a := New(t)
+
+	data, err := json.Marshal(HEXBytes{0xAB, 0xCD})
+	a.So(err, ShouldBeNil)
+	a.So(string(data), ShouldEqual, `"ABCD"`)
+
+	var parsed HEXBytes
+	a.So(json.Unmarshal([]byte(`"0xabcd"`), &parsed), ShouldBeNil)
+	a.So(parsed, ShouldResemble, HEXBytes{0xAB, 0xCD})
+}
+
+func TestResult(t *testing.T) {
+	a := New(t)
+
+	a.So(CheckResult(Result{ResultCode: ResultSuccess}), ShouldBeNil)
+	a.So(ResultFor(nil).ResultCode, ShouldEqual, ResultSuccess)
+	a.So(ResultFor(errors.NewErrNotFound("Device")).ResultCode, ShouldEqual, ResultUnknownDevAddr)
+	a.So(ResultFor(errors.NewErrInvalidArgument("Uplink", "invalid")).ResultCode, ShouldEqual, ResultMalformedRequest)
+	a.So(ResultFor(errors.New("other")).ResultCode, ShouldEqual, ResultOther)
+}
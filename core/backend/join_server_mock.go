@@ -0,0 +1,166 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/TheThingsNetwork/go-utils/random"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/TheThingsNetwork/ttn/utils/otaa"
+	"github.com/brocaar/lorawan"
+)
+
+// MockJoinServer is a minimal Join Server that can be used for testing the external Join Server flow. It holds the
+// AppKeys of devices and handles JoinReq messages.
+type MockJoinServer struct {
+	KEKLabel string
+	KEK      []byte
+
+	mu      sync.Mutex
+	appKeys map[types.DevEUI]types.AppKey
+	joins   int
+}
+
+// NewMockJoinServer returns a new MockJoinServer
+func NewMockJoinServer() *MockJoinServer {
+	return &MockJoinServer{
+		appKeys: make(map[types.DevEUI]types.AppKey),
+	}
+}
+
+// SetAppKey sets the AppKey of a device
+func (s *MockJoinServer) SetAppKey(devEUI types.DevEUI, appKey types.AppKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appKeys[devEUI] = appKey
+}
+
+// Joins returns the number of accepted joins
+func (s *MockJoinServer) Joins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.joins
+}
+
+// ServeHTTP implements http.Handler
+func (s *MockJoinServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req JoinReqMessage
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ans := &JoinAnsMessage{
+		Header: Header{
+			ProtocolVersion: ProtocolVersion,
+			SenderID:        req.ReceiverID,
+			ReceiverID:      req.SenderID,
+			TransactionID:   req.TransactionID,
+			MessageType:     JoinAns,
+		},
+	}
+	err := s.handleJoinReq(&req, ans)
+	ans.Result = ResultFor(err)
+	switch {
+	case err == errMICFailed:
+		ans.Result.ResultCode = ResultMICFailed
+	case errors.GetErrType(err) == errors.NotFound:
+		ans.Result.ResultCode = ResultUnknownDevEUI
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ans)
+}
+
+var errMICFailed = errors.NewErrInvalidArgument("MIC", "invalid")
+
+func (s *MockJoinServer) handleJoinReq(req *JoinReqMessage, ans *JoinAnsMessage) error {
+	if req.MessageType != JoinReq {
+		return errors.NewErrInvalidArgument("MessageType", "must be JoinReq")
+	}
+	s.mu.Lock()
+	appKey, ok := s.appKeys[req.DevEUI]
+	s.mu.Unlock()
+	if !ok {
+		return errors.NewErrNotFound(req.DevEUI.String())
+	}
+
+	var reqPHY lorawan.PHYPayload
+	if err := reqPHY.UnmarshalBinary(req.PHYPayload); err != nil {
+		return errors.NewErrInvalidArgument("PHYPayload", err.Error())
+	}
+	reqMAC, ok := reqPHY.MACPayload.(*lorawan.JoinRequestPayload)
+	if !ok {
+		return errors.NewErrInvalidArgument("PHYPayload", "does not contain a JoinRequestPayload")
+	}
+	if ok, err := reqPHY.ValidateMIC(lorawan.AES128Key(appKey)); err != nil || !ok {
+		return errMICFailed
+	}
+
+	netID, err := types.ParseHEX(req.SenderID, 3)
+	if err != nil {
+		return errors.NewErrInvalidArgument("SenderID", err.Error())
+	}
+
+	joinAccept := &lorawan.JoinAcceptPayload{
+		DevAddr: lorawan.DevAddr(req.DevAddr),
+		RXDelay: uint8(req.RxDelay),
+	}
+	copy(joinAccept.NetID[:], netID)
+	random.FillBytes(joinAccept.AppNonce[:])
+	if len(req.DLSettings) == 1 {
+		joinAccept.DLSettings.RX1DROffset = (req.DLSettings[0] >> 4) & 0x07
+		joinAccept.DLSettings.RX2DataRate = req.DLSettings[0] & 0x0F
+	}
+	if len(req.CFList) != 0 {
+		joinAccept.CFList = new(lorawan.CFList)
+		if err := joinAccept.CFList.UnmarshalBinary(req.CFList); err != nil {
+			return errors.NewErrInvalidArgument("CFList", err.Error())
+		}
+	}
+
+	appSKey, nwkSKey, err := otaa.CalculateSessionKeys(appKey, joinAccept.AppNonce, joinAccept.NetID, reqMAC.DevNonce)
+	if err != nil {
+		return err
+	}
+
+	resPHY := lorawan.PHYPayload{
+		MHDR:       lorawan.MHDR{MType: lorawan.JoinAccept, Major: lorawan.LoRaWANR1},
+		MACPayload: joinAccept,
+	}
+	if err := resPHY.SetMIC(lorawan.AES128Key(appKey)); err != nil {
+		return err
+	}
+	if err := resPHY.EncryptJoinAcceptPayload(lorawan.AES128Key(appKey)); err != nil {
+		return err
+	}
+	if ans.PHYPayload, err = resPHY.MarshalBinary(); err != nil {
+		return err
+	}
+
+	if ans.NwkSKey, err = s.envelope(nwkSKey[:]); err != nil {
+		return err
+	}
+	if ans.AppSKey, err = s.envelope(appSKey[:]); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.joins++
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MockJoinServer) envelope(key []byte) (*KeyEnvelope, error) {
+	if s.KEKLabel == "" {
+		return &KeyEnvelope{AESKey: key}, nil
+	}
+	wrapped, err := WrapKey(s.KEK, key)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyEnvelope{KEKLabel: s.KEKLabel, AESKey: wrapped}, nil
+}
@@ -0,0 +1,88 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+var keyWrapIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// WrapKey wraps the key with the key encryption key (RFC 3394)
+func WrapKey(kek, key []byte) ([]byte, error) {
+	if len(key)%8 != 0 || len(key) < 16 {
+		return nil, errors.NewErrInvalidArgument("Key", "length must be a multiple of 8 and at least 16")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(key) / 8
+	r := make([]byte, len(key))
+	copy(r, key)
+	a := make([]byte, 8)
+	copy(a, keyWrapIV)
+	buf := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(buf[:8], a)
+			copy(buf[8:], r[i*8:i*8+8])
+			block.Encrypt(buf, buf)
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(buf[:8])^t)
+			copy(r[i*8:i*8+8], buf[8:])
+		}
+	}
+	return append(a, r...), nil
+}
+
+// UnwrapKey unwraps the key with the key encryption key (RFC 3394)
+func UnwrapKey(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, errors.NewErrInvalidArgument("Wrapped Key", "length must be a multiple of 8 and at least 24")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(wrapped)/8 - 1
+	r := make([]byte, n*8)
+	copy(r, wrapped[8:])
+	a := make([]byte, 8)
+	copy(a, wrapped[:8])
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n - 1; i >= 0; i-- {
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], r[i*8:i*8+8])
+			block.Decrypt(buf, buf)
+			copy(a, buf[:8])
+			copy(r[i*8:i*8+8], buf[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(a, keyWrapIV) != 1 {
+		return nil, errors.NewErrPermissionDenied("Key integrity check failed")
+	}
+	return r, nil
+}
+
+// UnwrapKeyEnvelope returns the key in the envelope, unwrapping it with the KEK of the label if needed
+func UnwrapKeyEnvelope(keks map[string][]byte, envelope *KeyEnvelope) ([]byte, error) {
+	if envelope == nil {
+		return nil, errors.NewErrInvalidArgument("KeyEnvelope", "missing")
+	}
+	if envelope.KEKLabel == "" {
+		return envelope.AESKey, nil
+	}
+	kek, ok := keks[envelope.KEKLabel]
+	if !ok {
+		return nil, errors.NewErrNotFound("KEK " + envelope.KEKLabel)
+	}
+	return UnwrapKey(kek, envelope.AESKey)
+}
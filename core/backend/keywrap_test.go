@@ -0,0 +1,43 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"encoding/hex"
+	"testing"
+
+	. "github.com/smartystreets/assertions"
+)
+
+func TestKeyWrap(t *testing.T) {
+	a := New(t)
+
+	// Test vector from RFC 3394 section 4.1
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF")
+	expected, _ := hex.DecodeString("1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5")
+
+	wrapped, err := WrapKey(kek, key)
+	a.So(err, ShouldBeNil)
+	a.So(wrapped, ShouldResemble, expected)
+
+	unwrapped, err := UnwrapKey(kek, wrapped)
+	a.So(err, ShouldBeNil)
+	a.So(unwrapped, ShouldResemble, key)
+
+	wrapped[0] ^= 0xFF
+	_, err = UnwrapKey(kek, wrapped)
+	a.So(err, ShouldNotBeNil)
+
+	unwrapped, err = UnwrapKeyEnvelope(map[string][]byte{"kek": kek}, &KeyEnvelope{KEKLabel: "kek", AESKey: expected})
+	a.So(err, ShouldBeNil)
+	a.So(unwrapped, ShouldResemble, key)
+
+	_, err = UnwrapKeyEnvelope(nil, &KeyEnvelope{KEKLabel: "kek", AESKey: expected})
+	a.So(err, ShouldNotBeNil)
+
+	unwrapped, err = UnwrapKeyEnvelope(nil, &KeyEnvelope{AESKey: key})
+	a.So(err, ShouldBeNil)
+	a.So(unwrapped, ShouldResemble, key)
+}
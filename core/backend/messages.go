@@ -0,0 +1,201 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package backend implements the HTTP/JSON messages of the LoRaWAN Backend Interfaces
+package backend
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+)
+
+// ProtocolVersion of the LoRaWAN Backend Interfaces
+const ProtocolVersion = "1.0"
+
+// MessageType of a Backend Interfaces message
+type MessageType string
+
+// Message types
+const (
+	PRStartReq  MessageType = "PRStartReq"
+	PRStartAns  MessageType = "PRStartAns"
+	XmitDataReq MessageType = "XmitDataReq"
+	XmitDataAns MessageType = "XmitDataAns"
+	JoinReq     MessageType = "JoinReq"
+	JoinAns     MessageType = "JoinAns"
+)
+
+// ResultCode of a Backend Interfaces answer
+type ResultCode string
+
+// Result codes
+const (
+	ResultSuccess           ResultCode = "Success"
+	ResultMalformedRequest  ResultCode = "MalformedRequest"
+	ResultFrameSizeError    ResultCode = "FrameSizeError"
+	ResultUnknownSender     ResultCode = "UnknownSender"
+	ResultUnknownReceiver   ResultCode = "UnknownReceiver"
+	ResultUnknownDevAddr    ResultCode = "UnknownDevAddr"
+	ResultUnknownDevEUI     ResultCode = "UnknownDevEUI"
+	ResultJoinReqFailed     ResultCode = "JoinReqFailed"
+	ResultMICFailed         ResultCode = "MICFailed"
+	ResultRoamingNotAllowed ResultCode = "RoamingActDisallowed"
+	ResultOther             ResultCode = "Other"
+)
+
+// HEXBytes is a byte slice that is marshaled to JSON as a HEX string
+type HEXBytes []byte
+
+// MarshalText implements the TextMarshaler interface.
+func (b HEXBytes) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(hex.EncodeToString(b))), nil
+}
+
+// UnmarshalText implements the TextUnmarshaler interface.
+func (b *HEXBytes) UnmarshalText(data []byte) error {
+	parsed, err := hex.DecodeString(strings.TrimPrefix(string(data), "0x"))
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+// Header is included in every Backend Interfaces message
+type Header struct {
+	ProtocolVersion string      `json:"ProtocolVersion"`
+	SenderID        string      `json:"SenderID"`
+	ReceiverID      string      `json:"ReceiverID"`
+	TransactionID   uint32      `json:"TransactionID"`
+	MessageType     MessageType `json:"MessageType"`
+	SenderToken     HEXBytes    `json:"SenderToken,omitempty"`
+	ReceiverToken   HEXBytes    `json:"ReceiverToken,omitempty"`
+}
+
+// GetHeader returns the header of the message
+func (h *Header) GetHeader() *Header { return h }
+
+// Message is a Backend Interfaces message
+type Message interface {
+	GetHeader() *Header
+}
+
+// Result is included in every Backend Interfaces answer
+type Result struct {
+	ResultCode  ResultCode `json:"ResultCode"`
+	Description string     `json:"Description,omitempty"`
+}
+
+// GWInfo contains the metadata of a gateway that received an uplink
+type GWInfo struct {
+	ID        HEXBytes `json:"ID,omitempty"`
+	RFRegion  string   `json:"RFRegion,omitempty"`
+	RSSI      *int     `json:"RSSI,omitempty"`
+	SNR       *float32 `json:"SNR,omitempty"`
+	Lat       *float32 `json:"Lat,omitempty"`
+	Lon       *float32 `json:"Lon,omitempty"`
+	ULToken   HEXBytes `json:"ULToken,omitempty"`
+	DLAllowed bool     `json:"DLAllowed,omitempty"`
+}
+
+// ULMetaData contains the metadata of an uplink
+type ULMetaData struct {
+	DevEUI     *types.DevEUI  `json:"DevEUI,omitempty"`
+	DevAddr    *types.DevAddr `json:"DevAddr,omitempty"`
+	FPort      *uint8         `json:"FPort,omitempty"`
+	FCntDown   *uint32        `json:"FCntDown,omitempty"`
+	FCntUp     *uint32        `json:"FCntUp,omitempty"`
+	Confirmed  bool           `json:"Confirmed,omitempty"`
+	DataRate   *int           `json:"DataRate,omitempty"`
+	ULFreq     *float64       `json:"ULFreq,omitempty"`
+	Margin     *int           `json:"Margin,omitempty"`
+	Battery    *int           `json:"Battery,omitempty"`
+	FNSULToken HEXBytes       `json:"FNSULToken,omitempty"`
+	RecvTime   string         `json:"RecvTime"`
+	RFRegion   string         `json:"RFRegion,omitempty"`
+	GWCnt      int            `json:"GWCnt,omitempty"`
+	GWInfo     []GWInfo       `json:"GWInfo"`
+}
+
+// DLMetaData contains the metadata for a downlink
+type DLMetaData struct {
+	DevEUI         *types.DevEUI `json:"DevEUI,omitempty"`
+	FPort          *uint8        `json:"FPort,omitempty"`
+	FCntDown       *uint32       `json:"FCntDown,omitempty"`
+	Confirmed      bool          `json:"Confirmed,omitempty"`
+	DLFreq1        *float64      `json:"DLFreq1,omitempty"`
+	DLFreq2        *float64      `json:"DLFreq2,omitempty"`
+	RXDelay1       int           `json:"RXDelay1"`
+	ClassMode      string        `json:"ClassMode,omitempty"`
+	DataRate1      *int          `json:"DataRate1,omitempty"`
+	DataRate2      *int          `json:"DataRate2,omitempty"`
+	FNSULToken     HEXBytes      `json:"FNSULToken,omitempty"`
+	GWInfo         []GWInfo      `json:"GWInfo"`
+	HiPriorityFlag bool          `json:"HiPriorityFlag,omitempty"`
+}
+
+// PRStartReqMessage is sent by the serving network to start passive roaming for a device
+type PRStartReqMessage struct {
+	Header
+	PHYPayload HEXBytes   `json:"PHYPayload"`
+	ULMetaData ULMetaData `json:"ULMetaData"`
+}
+
+// PRStartAnsMessage is the answer to a PRStartReqMessage
+type PRStartAnsMessage struct {
+	Header
+	Result     Result        `json:"Result"`
+	PHYPayload HEXBytes      `json:"PHYPayload,omitempty"`
+	DLMetaData *DLMetaData   `json:"DLMetaData,omitempty"`
+	DevEUI     *types.DevEUI `json:"DevEUI,omitempty"`
+	Lifetime   *int          `json:"Lifetime,omitempty"`
+	FCntUp     *uint32       `json:"FCntUp,omitempty"`
+}
+
+// XmitDataReqMessage is used to transmit uplinks or downlinks within a roaming session
+type XmitDataReqMessage struct {
+	Header
+	PHYPayload HEXBytes    `json:"PHYPayload,omitempty"`
+	FRMPayload HEXBytes    `json:"FRMPayload,omitempty"`
+	ULMetaData *ULMetaData `json:"ULMetaData,omitempty"`
+	DLMetaData *DLMetaData `json:"DLMetaData,omitempty"`
+}
+
+// XmitDataAnsMessage is the answer to a XmitDataReqMessage
+type XmitDataAnsMessage struct {
+	Header
+	Result  Result   `json:"Result"`
+	DLFreq1 *float64 `json:"DLFreq1,omitempty"`
+	DLFreq2 *float64 `json:"DLFreq2,omitempty"`
+}
+
+// KeyEnvelope contains a session key, optionally wrapped with the key encryption key (KEK) identified by the label
+type KeyEnvelope struct {
+	KEKLabel string   `json:"KEKLabel,omitempty"`
+	AESKey   HEXBytes `json:"AESKey"`
+}
+
+// JoinReqMessage is sent by the Network Server to the Join Server to handle a join-request
+type JoinReqMessage struct {
+	Header
+	MACVersion string        `json:"MACVersion"`
+	PHYPayload HEXBytes      `json:"PHYPayload"`
+	DevEUI     types.DevEUI  `json:"DevEUI"`
+	DevAddr    types.DevAddr `json:"DevAddr"`
+	DLSettings HEXBytes      `json:"DLSettings"`
+	RxDelay    int           `json:"RxDelay"`
+	CFList     HEXBytes      `json:"CFList,omitempty"`
+}
+
+// JoinAnsMessage is the answer to a JoinReqMessage
+type JoinAnsMessage struct {
+	Header
+	Result       Result       `json:"Result"`
+	PHYPayload   HEXBytes     `json:"PHYPayload,omitempty"`
+	Lifetime     *int         `json:"Lifetime,omitempty"`
+	NwkSKey      *KeyEnvelope `json:"NwkSKey,omitempty"`
+	AppSKey      *KeyEnvelope `json:"AppSKey,omitempty"`
+	SessionKeyID HEXBytes     `json:"SessionKeyID,omitempty"`
+}
@@ -0,0 +1,63 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
+	"github.com/TheThingsNetwork/ttn/core/band"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+var rfRegions = map[pb_lorawan.FrequencyPlan]string{
+	pb_lorawan.FrequencyPlan_EU_863_870: "EU868",
+	pb_lorawan.FrequencyPlan_US_902_928: "US902",
+	pb_lorawan.FrequencyPlan_CN_779_787: "China779",
+	pb_lorawan.FrequencyPlan_EU_433:     "EU433",
+	pb_lorawan.FrequencyPlan_AU_915_928: "Australia915",
+	pb_lorawan.FrequencyPlan_CN_470_510: "China470",
+	pb_lorawan.FrequencyPlan_AS_923:     "AS923",
+	pb_lorawan.FrequencyPlan_KR_920_923: "SouthKorea920",
+	pb_lorawan.FrequencyPlan_IN_865_867: "India865",
+	pb_lorawan.FrequencyPlan_RU_864_870: "RU864",
+}
+
+// RFRegion returns the Backend Interfaces RFRegion for a frequency plan
+func RFRegion(frequencyPlan pb_lorawan.FrequencyPlan) string {
+	switch frequencyPlan {
+	case pb_lorawan.FrequencyPlan_AS_920_923, pb_lorawan.FrequencyPlan_AS_923_925:
+		return rfRegions[pb_lorawan.FrequencyPlan_AS_923]
+	}
+	return rfRegions[frequencyPlan]
+}
+
+// FrequencyPlan returns the frequency plan for a Backend Interfaces RFRegion
+func FrequencyPlan(rfRegion string) (pb_lorawan.FrequencyPlan, error) {
+	for frequencyPlan, region := range rfRegions {
+		if region == rfRegion {
+			return frequencyPlan, nil
+		}
+	}
+	return 0, errors.NewErrInvalidArgument("RFRegion", "unknown region "+rfRegion)
+}
+
+// DataRateIndex returns the index of a data rate (SF7BW125) in the frequency plan
+func DataRateIndex(frequencyPlan pb_lorawan.FrequencyPlan, dataRate string) (int, error) {
+	fp, err := band.Get(frequencyPlan.String())
+	if err != nil {
+		return 0, err
+	}
+	return fp.GetDataRateIndexFor(dataRate)
+}
+
+// DataRateString returns the data rate (SF7BW125) for an index in the frequency plan
+func DataRateString(frequencyPlan pb_lorawan.FrequencyPlan, index int) (string, error) {
+	fp, err := band.Get(frequencyPlan.String())
+	if err != nil {
+		return "", err
+	}
+	if index < 0 || index >= len(fp.DataRates) {
+		return "", errors.NewErrInvalidArgument("DataRate", "index out of range")
+	}
+	return fp.GetDataRateStringForIndex(index)
+}
@@ -81,13 +81,14 @@ func (b *broker) HandleActivation(activation *pb.DeviceActivationRequest) (res *
 	var downlinkOptions []*pb.DownlinkOption
 	for _, duplicate := range duplicates {
 		deduplicatedActivationRequest.GatewayMetadata = append(deduplicatedActivationRequest.GatewayMetadata, &duplicate.GatewayMetadata)
+		setDownlinkDeadlines(duplicate.DownlinkOptions, duplicate.GatewayMetadata.Timestamp, start)
 		downlinkOptions = append(downlinkOptions, duplicate.DownlinkOptions...)
 	}
 
 	// Select best DownlinkOption
 	if len(downlinkOptions) > 0 {
 		deduplicatedActivationRequest.ResponseTemplate = &pb.DeviceActivationResponse{
-			DownlinkOption: selectBestDownlink(downlinkOptions),
+			DownlinkOption: b.selectDownlinkOptions(downlinkOptions),
 		}
 	}
 
@@ -171,7 +172,14 @@ func (b *broker) HandleActivation(activation *pb.DeviceActivationRequest) (res *
 	var gotFirst bool
 	var joinHandler *pb_discovery.Announcement
 	var joinHandlerClient pb_handler.HandlerClient
+	var delegated []*challengeResponseWithHandler
 	for res := range responses {
+		// An empty response means that the MIC is validated by an external Join Server
+		if len(res.response.Payload) == 0 {
+			delegated = append(delegated, res)
+			continue
+		}
+
 		var phyPayload lorawan.PHYPayload
 		err = phyPayload.UnmarshalBinary(res.response.Payload)
 		if err != nil {
@@ -190,6 +198,18 @@ func (b *broker) HandleActivation(activation *pb.DeviceActivationRequest) (res *
 		}
 	}
 
+	// Forward to a Handler that uses an external Join Server if no Handler validated the MIC
+	if !gotFirst && len(delegated) > 0 {
+		selected, err := b.selectDelegatedHandler(deduplicatedActivationRequest, delegated)
+		if err != nil {
+			return nil, err
+		}
+		gotFirst = true
+		joinHandler = selected.handler
+		joinHandlerClient = selected.client
+		ctx = ctx.WithField("JoinServer", true)
+	}
+
 	// Activation not accepted by any broker
 	if !gotFirst {
 		ctx.Debug("Activation not accepted by any Handler")
@@ -213,18 +233,58 @@ func (b *broker) HandleActivation(activation *pb.DeviceActivationRequest) (res *
 		return nil, errors.Wrap(errors.FromGRPCError(err), "NetworkServer refused activation")
 	}
 
-	handlerResponse.Trace = handlerResponse.Trace.WithEvent(trace.ForwardEvent)
-
-	res = &pb.DeviceActivationResponse{
+	// The Broker sends the join-accept itself, so that it can fall back to the alternative DownlinkOptions
+	downlink, fallbacks, err := b.forwardDownlink(&pb.DownlinkMessage{
 		Payload:        handlerResponse.Payload,
 		Message:        handlerResponse.Message,
+		DevEUI:         deduplicatedActivationRequest.DevEUI,
+		AppEUI:         deduplicatedActivationRequest.AppEUI,
+		AppID:          deduplicatedActivationRequest.AppID,
+		DevID:          deduplicatedActivationRequest.DevID,
 		DownlinkOption: &handlerResponse.DownlinkOption,
 		Trace:          handlerResponse.Trace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not send join-accept")
+	}
+	if fallbacks > 0 {
+		ctx = ctx.WithField("Fallbacks", fallbacks)
+		go b.reportDownlinkFallback(downlink)
+	}
+
+	// The response has no DownlinkOption, because the join-accept was already sent
+	res = &pb.DeviceActivationResponse{
+		Payload: downlink.Payload,
+		Message: downlink.Message,
+		Trace:   downlink.Trace,
 	}
 
 	return res, nil
 }
 
+// selectDelegatedHandler selects one of the Handlers that delegate the activation to an external Join Server. These
+// Handlers can not validate the MIC, so the Handler is selected in the same way as for uplink messages.
+func (b *broker) selectDelegatedHandler(activation *pb.DeduplicatedDeviceActivationRequest, delegated []*challengeResponseWithHandler) (*challengeResponseWithHandler, error) {
+	announcements := make([]*pb_discovery.Announcement, 0, len(delegated))
+	for _, res := range delegated {
+		announcements = append(announcements, res.handler)
+	}
+	ordered, err := b.orderHandlers(&pb.DeduplicatedUplinkMessage{
+		AppID:  activation.AppID,
+		DevID:  activation.DevID,
+		DevEUI: &activation.DevEUI,
+	}, announcements)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range delegated {
+		if res.handler == ordered[0] {
+			return res, nil
+		}
+	}
+	return nil, errors.NewErrInternal("Selected Handler did not respond")
+}
+
 func (b *broker) deduplicateActivation(duplicate *pb.DeviceActivationRequest) (activations []*pb.DeviceActivationRequest) {
 	sum := md5.Sum(duplicate.Payload)
 	key := hex.EncodeToString(sum[:])
@@ -5,6 +5,7 @@ package broker
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -13,10 +14,13 @@ import (
 	"github.com/TheThingsNetwork/api/networkserver"
 	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
 	"github.com/TheThingsNetwork/ttn/api"
+	"github.com/TheThingsNetwork/ttn/core/broker/usage"
 	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
 	"github.com/TheThingsNetwork/ttn/core/types"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"google.golang.org/grpc"
+	"gopkg.in/redis.v5"
 )
 
 type Broker interface {
@@ -24,10 +28,17 @@ type Broker interface {
 	component.ManagementInterface
 
 	SetNetworkServer(addr, cert, token string)
+	SetHandlerSelection(selection HandlerSelection)
+	SetRoaming(netID types.NetID, token string, partners ...RoamingPartner)
+	RoamingHandler() http.Handler
+	SetUsageStore(store usage.Store)
+	SetGatewayRouterStore(client *redis.Client)
+	SetDownlinkFallbackStore(client *redis.Client)
 
 	HandleUplink(uplink *pb.UplinkMessage) error
 	HandleDownlink(downlink *pb.DownlinkMessage) error
 	HandleActivation(activation *pb.DeviceActivationRequest) (*pb.DeviceActivationResponse, error)
+	HandleMulticastDownlink(message *multicast.DownlinkMessage) (*multicast.DownlinkMessage, error)
 
 	ActivateRouterDownlink(id string) (<-chan *pb.DownlinkMessage, error)
 	DeactivateRouterDownlink(id string) error
@@ -41,6 +52,7 @@ func NewBroker(timeout time.Duration) Broker {
 		handlers:               make(map[string]*handler),
 		uplinkDeduplicator:     NewDeduplicator(timeout),
 		activationDeduplicator: NewDeduplicator(timeout),
+		handlerSelection:       HandlerSelectionSingle,
 	}
 }
 
@@ -61,8 +73,14 @@ type broker struct {
 	nsToken                string
 	nsConn                 *grpc.ClientConn
 	ns                     networkserver.NetworkServerClient
+	nsMulticast            multicast.MulticastClient
 	uplinkDeduplicator     Deduplicator
 	activationDeduplicator Deduplicator
+	handlerSelection       HandlerSelection
+	roaming                *roaming
+	gatewayRouters         gatewayRouters
+	downlinkFallbacks      downlinkFallbacks
+	usage                  usage.Store
 	status                 *status
 	// monitorStream          monitorclient.Stream
 }
@@ -131,6 +149,7 @@ func (b *broker) Init(c *component.Component) error {
 	}
 	b.nsConn = conn
 	b.ns = networkserver.NewNetworkServerClient(conn)
+	b.nsMulticast = multicast.NewMulticastClient(conn)
 	b.checkPrefixAnnouncements()
 	b.Component.SetStatus(component.StatusHealthy)
 	// if b.Component.Monitor != nil {
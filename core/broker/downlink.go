@@ -10,6 +10,7 @@ import (
 	pb "github.com/TheThingsNetwork/api/broker"
 	"github.com/TheThingsNetwork/api/logfields"
 	"github.com/TheThingsNetwork/api/trace"
+	"github.com/TheThingsNetwork/ttn/core/handler/fallback"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 )
 
@@ -47,22 +48,116 @@ func (b *broker) HandleDownlink(downlink *pb.DownlinkMessage) error {
 		return errors.Wrap(errors.FromGRPCError(err), "NetworkServer did not handle downlink")
 	}
 
-	var routerID string
+	var fallbacks int
+	downlink, fallbacks, err = b.forwardDownlink(downlink)
+	if err != nil {
+		return err
+	}
 	if id := strings.Split(downlink.DownlinkOption.Identifier, ":"); len(id) == 2 {
-		routerID = id[0]
-	} else {
-		return errors.NewErrInvalidArgument("DownlinkOption Identifier", "invalid format")
+		ctx = ctx.WithField("RouterID", id[0])
 	}
-	ctx = ctx.WithField("RouterID", routerID)
+	if fallbacks > 0 {
+		ctx = ctx.WithField("Fallbacks", fallbacks)
+		go b.reportDownlinkFallback(downlink)
+	}
+	return nil
+}
 
-	router, err := b.getRouterDownlink(routerID)
+// forwardDownlink sends the downlink to the router of its DownlinkOption. If that router is not available, or does
+// not accept the downlink before the deadline, it falls back to the alternative DownlinkOptions of the uplink. It
+// returns the downlink as it was sent and the number of options that were skipped.
+func (b *broker) forwardDownlink(downlink *pb.DownlinkMessage) (sent *pb.DownlinkMessage, fallbacks int, err error) {
+	if downlink.DownlinkOption == nil {
+		return nil, 0, errors.NewErrInvalidArgument("Downlink", "no DownlinkOption")
+	}
+	alternatives, err := b.downlinkFallbacks.get(downlink.DownlinkOption.Identifier)
 	if err != nil {
-		return err
+		b.Ctx.WithError(err).Warn("Could not get alternative downlink options")
 	}
+	options := append([]*pb.DownlinkOption{downlink.DownlinkOption}, alternatives...)
+	for i, option := range options {
+		if option.Deadline != 0 && time.Now().After(time.Unix(0, option.Deadline)) {
+			err = errors.NewErrInternal("Downlink deadline passed")
+			continue
+		}
 
-	downlink.Trace = downlink.Trace.WithEvent(trace.ForwardEvent, "router", routerID)
+		var routerID string
+		if id := strings.Split(option.Identifier, ":"); len(id) == 2 {
+			routerID = id[0]
+		} else {
+			err = errors.NewErrInvalidArgument("DownlinkOption Identifier", "invalid format")
+			continue
+		}
 
-	router <- downlink
+		var router chan<- *pb.DownlinkMessage
+		router, err = b.getRouterDownlink(routerID)
+		if err != nil {
+			continue
+		}
 
-	return nil
+		msg := downlink
+		if i > 0 {
+			fallback := *downlink
+			fallback.DownlinkOption = option
+			msg = &fallback
+		}
+		msg.Trace = msg.Trace.WithEvent(trace.ForwardEvent, "router", routerID, "fallbacks", i)
+
+		if err = sendDownlink(router, msg, option.Deadline); err != nil {
+			continue
+		}
+
+		if i > 0 {
+			downlinkFallbackCounter.Add(float64(i))
+		}
+		b.accountDownlink(msg)
+		return msg, i, nil
+	}
+
+	return nil, 0, err
+}
+
+// reportDownlinkFallback tells the Handler of the application that the downlink was sent through an alternative
+// DownlinkOption, so that it can publish the gateway that was actually used
+func (b *broker) reportDownlinkFallback(downlink *pb.DownlinkMessage) {
+	ctx := b.Ctx.WithFields(logfields.ForMessage(downlink))
+	announcements, err := b.Discovery.GetAllHandlersForAppID(downlink.AppID)
+	if err == nil {
+		announcements, err = b.orderHandlers(&pb.DeduplicatedUplinkMessage{
+			AppID:  downlink.AppID,
+			DevID:  downlink.DevID,
+			DevEUI: &downlink.DevEUI,
+		}, announcements)
+	}
+	if err != nil {
+		ctx.WithError(err).Warn("Could not find Handler to report downlink fallback")
+		return
+	}
+	for _, announcement := range announcements {
+		conn, err := b.getHandlerConn(announcement.ID)
+		if err == nil {
+			_, err = fallback.NewDownlinkFallbackClient(conn).Report(b.Component.GetContext(""), downlink)
+		}
+		if err != nil {
+			ctx.WithField("HandlerID", announcement.ID).WithError(errors.FromGRPCError(err)).Warn("Could not report downlink fallback")
+			continue
+		}
+		return
+	}
+}
+
+// sendDownlink sends the downlink to the router, giving up if the router does not accept it before the deadline
+func sendDownlink(router chan<- *pb.DownlinkMessage, downlink *pb.DownlinkMessage, deadline int64) error {
+	if deadline == 0 {
+		router <- downlink
+		return nil
+	}
+	timer := time.NewTimer(time.Until(time.Unix(0, deadline)))
+	defer timer.Stop()
+	select {
+	case router <- downlink:
+		return nil
+	case <-timer.C:
+		return errors.NewErrInternal("Router did not accept downlink before deadline")
+	}
 }
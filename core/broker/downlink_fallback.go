@@ -0,0 +1,157 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package broker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/TheThingsNetwork/api/broker"
+	"gopkg.in/redis.v5"
+)
+
+// DownlinkFallbackTTL is the time that the alternative DownlinkOptions of an uplink are kept if they have no deadline
+var DownlinkFallbackTTL = 10 * time.Second
+
+// redisDownlinkFallbacksPrefix is the prefix of the Lists in which the alternative DownlinkOptions are stored
+const redisDownlinkFallbacksPrefix = "broker:downlink-fallbacks"
+
+type downlinkFallback struct {
+	options []*pb.DownlinkOption
+	expires time.Time
+}
+
+// downlinkFallbacks keeps the alternative DownlinkOptions of recent uplinks and activations, indexed by the
+// Identifier of the selected option, so that a downlink can be sent through another router if the router of the
+// selected option is unavailable. If the client is set, the options are stored in Redis instead, so that they can
+// be used by all Brokers that share it.
+type downlinkFallbacks struct {
+	sync.Mutex
+	fallbacks map[string]*downlinkFallback
+	client    *redis.Client
+}
+
+func (f *downlinkFallbacks) key(selected string) string {
+	return redisDownlinkFallbacksPrefix + ":" + selected
+}
+
+func (f *downlinkFallbacks) set(selected string, options []*pb.DownlinkOption) error {
+	now := time.Now()
+	expires := now.Add(DownlinkFallbackTTL)
+	var latest int64
+	for _, option := range options {
+		if option.Deadline == 0 {
+			latest = 0
+			break
+		}
+		if option.Deadline > latest {
+			latest = option.Deadline
+		}
+	}
+	if latest != 0 {
+		if deadline := time.Unix(0, latest); deadline.Before(expires) {
+			expires = deadline
+		}
+	}
+
+	if f.client != nil {
+		if len(options) == 0 || !expires.After(now) {
+			return nil
+		}
+		values := make([]interface{}, 0, len(options))
+		for _, option := range options {
+			data, err := option.Marshal()
+			if err != nil {
+				return err
+			}
+			values = append(values, data)
+		}
+		_, err := f.client.TxPipelined(func(pipe *redis.Pipeline) error {
+			pipe.Del(f.key(selected))
+			pipe.RPush(f.key(selected), values...)
+			pipe.PExpireAt(f.key(selected), expires)
+			return nil
+		})
+		return err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	if f.fallbacks == nil {
+		f.fallbacks = make(map[string]*downlinkFallback)
+	}
+	for identifier, existing := range f.fallbacks {
+		if now.After(existing.expires) {
+			delete(f.fallbacks, identifier)
+		}
+	}
+	if len(options) > 0 {
+		f.fallbacks[selected] = &downlinkFallback{options: options, expires: expires}
+	}
+	return nil
+}
+
+// get returns (and forgets) the alternative DownlinkOptions for the selected option
+func (f *downlinkFallbacks) get(selected string) ([]*pb.DownlinkOption, error) {
+	if f.client != nil {
+		var values *redis.StringSliceCmd
+		_, err := f.client.TxPipelined(func(pipe *redis.Pipeline) error {
+			values = pipe.LRange(f.key(selected), 0, -1)
+			pipe.Del(f.key(selected))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		options := make([]*pb.DownlinkOption, 0, len(values.Val()))
+		for _, value := range values.Val() {
+			option := new(pb.DownlinkOption)
+			if err := option.Unmarshal([]byte(value)); err != nil {
+				return nil, err
+			}
+			options = append(options, option)
+		}
+		return options, nil
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	fallback, ok := f.fallbacks[selected]
+	if !ok {
+		return nil, nil
+	}
+	delete(f.fallbacks, selected)
+	if time.Now().After(fallback.expires) {
+		return nil, nil
+	}
+	return fallback.options, nil
+}
+
+// SetDownlinkFallbackStore stores the alternative DownlinkOptions in Redis
+func (b *broker) SetDownlinkFallbackStore(client *redis.Client) {
+	b.downlinkFallbacks.client = client
+}
+
+// setDownlinkDeadlines sets the Deadline of the DownlinkOptions of an uplink (or activation) with the given gateway
+// timestamp that was received at the given time, based on the difference between the gateway timestamps of the
+// uplink and the downlink options
+func setDownlinkDeadlines(options []*pb.DownlinkOption, timestamp uint32, receivedAt time.Time) {
+	for _, option := range options {
+		if option.Deadline != 0 || option.GatewayConfiguration.Timestamp == 0 {
+			continue
+		}
+		delay := time.Duration(option.GatewayConfiguration.Timestamp-timestamp) * time.Microsecond
+		option.Deadline = receivedAt.Add(delay).UnixNano()
+	}
+}
+
+// selectDownlinkOptions sorts the DownlinkOptions by score, remembers the alternatives and returns the best option
+func (b *broker) selectDownlinkOptions(options []*pb.DownlinkOption) *pb.DownlinkOption {
+	sort.Stable(ByScore(options))
+	if err := b.downlinkFallbacks.set(options[0].Identifier, options[1:]); err != nil {
+		b.Ctx.WithError(err).Warn("Could not store alternative downlink options")
+	}
+	return options[0]
+}
@@ -5,8 +5,10 @@ package broker
 
 import (
 	"testing"
+	"time"
 
 	pb "github.com/TheThingsNetwork/api/broker"
+	pb_gateway "github.com/TheThingsNetwork/api/gateway"
 	"github.com/TheThingsNetwork/api/monitor/monitorclient"
 	"github.com/TheThingsNetwork/ttn/core/component"
 	"github.com/TheThingsNetwork/ttn/core/types"
@@ -62,3 +64,108 @@ func TestDownlink(t *testing.T) {
 	a.So(err, ShouldBeNil)
 	a.So(len(dlch), ShouldEqual, 1)
 }
+
+func TestDownlinkFallback(t *testing.T) {
+	a := New(t)
+
+	appEUI := types.AppEUI{0, 1, 2, 3, 4, 5, 6, 7}
+	devEUI := types.DevEUI{0, 1, 2, 3, 4, 5, 6, 7}
+
+	dlch := make(chan *pb.DownlinkMessage, 2)
+	b := getTestBroker(t)
+	b.broker.ns = &mockNetworkServer{}
+	b.routers = map[string]*router{
+		"routerID": &router{downlinkConns: 1, downlink: dlch},
+	}
+
+	future := time.Now().Add(time.Minute).UnixNano()
+	best := b.selectDownlinkOptions([]*pb.DownlinkOption{
+		{Identifier: "routerID:late", Score: 5, Deadline: time.Now().Add(-1 * time.Second).UnixNano()},
+		{Identifier: "routerID:second", Score: 20, Deadline: future},
+		{Identifier: "goneRouterID:first", Score: 10, Deadline: future},
+	})
+	a.So(best.Identifier, ShouldEqual, "routerID:late")
+
+	// The fallback is reported to the Handler of the application
+	reported := make(chan struct{})
+	b.discovery.EXPECT().GetAllHandlersForAppID("appid").Do(func(string) { close(reported) }).Return(nil, nil)
+
+	// The late option and the gone router are skipped
+	err := b.HandleDownlink(&pb.DownlinkMessage{
+		DevEUI:         devEUI,
+		AppEUI:         appEUI,
+		AppID:          "appid",
+		DevID:          "devid",
+		DownlinkOption: best,
+	})
+	a.So(err, ShouldBeNil)
+	a.So(len(dlch), ShouldEqual, 1)
+	a.So((<-dlch).DownlinkOption.Identifier, ShouldEqual, "routerID:second")
+
+	select {
+	case <-reported:
+	case <-time.After(time.Second):
+		t.Fatal("Downlink fallback was not reported")
+	}
+
+	// The fallbacks are only used once
+	err = b.HandleDownlink(&pb.DownlinkMessage{
+		DevEUI:         devEUI,
+		AppEUI:         appEUI,
+		AppID:          "appid",
+		DevID:          "devid",
+		DownlinkOption: best,
+	})
+	a.So(err, ShouldNotBeNil)
+	a.So(len(dlch), ShouldEqual, 0)
+}
+
+func TestDownlinkFallbacksRedis(t *testing.T) {
+	a := New(t)
+
+	client := GetRedisClient()
+	defer client.Del(redisDownlinkFallbacksPrefix + ":routerID:first")
+
+	fallbacks := &downlinkFallbacks{client: client}
+	err := fallbacks.set("routerID:first", []*pb.DownlinkOption{
+		{Identifier: "routerID:second", GatewayID: "gtw", Score: 20, Deadline: time.Now().Add(time.Minute).UnixNano()},
+	})
+	a.So(err, ShouldBeNil)
+
+	// Another Broker with the same Redis gets the fallbacks, but only once
+	options, err := (&downlinkFallbacks{client: client}).get("routerID:first")
+	a.So(err, ShouldBeNil)
+	a.So(options, ShouldHaveLength, 1)
+	a.So(options[0].Identifier, ShouldEqual, "routerID:second")
+	a.So(options[0].GatewayID, ShouldEqual, "gtw")
+	a.So(options[0].Score, ShouldEqual, 20)
+
+	options, err = fallbacks.get("routerID:first")
+	a.So(err, ShouldBeNil)
+	a.So(options, ShouldBeEmpty)
+
+	// Options of which the deadline passed are not stored
+	err = fallbacks.set("routerID:first", []*pb.DownlinkOption{
+		{Identifier: "routerID:second", Deadline: time.Now().Add(-1 * time.Second).UnixNano()},
+	})
+	a.So(err, ShouldBeNil)
+	options, err = fallbacks.get("routerID:first")
+	a.So(err, ShouldBeNil)
+	a.So(options, ShouldBeEmpty)
+}
+
+func TestSetDownlinkDeadlines(t *testing.T) {
+	a := New(t)
+
+	receivedAt := time.Now()
+	uplink := &pb.UplinkMessage{
+		DownlinkOptions: []*pb.DownlinkOption{
+			{GatewayConfiguration: pb_gateway.TxConfiguration{Timestamp: 1000}},
+			{GatewayConfiguration: pb_gateway.TxConfiguration{Timestamp: 2000999}},
+		},
+	}
+	uplink.GatewayMetadata.Timestamp = 4294967000 // overflows before RX1
+	setDownlinkDeadlines(uplink.DownlinkOptions, uplink.GatewayMetadata.Timestamp, receivedAt)
+	a.So(uplink.DownlinkOptions[0].Deadline, ShouldEqual, receivedAt.Add(1296*time.Microsecond).UnixNano())
+	a.So(uplink.DownlinkOptions[1].Deadline, ShouldEqual, receivedAt.Add(2001295*time.Microsecond).UnixNano())
+}
@@ -0,0 +1,86 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package broker
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	pb "github.com/TheThingsNetwork/api/broker"
+	pb_discovery "github.com/TheThingsNetwork/api/discovery"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// HandlerSelection determines which Handler receives an uplink if multiple Handlers announce the same AppID
+type HandlerSelection string
+
+const (
+	// HandlerSelectionSingle refuses to forward uplinks if multiple Handlers announce the same AppID
+	HandlerSelectionSingle HandlerSelection = "single"
+
+	// HandlerSelectionPrimary forwards uplinks to the first Handler (ordered by ID) and fails over to the next ones
+	HandlerSelectionPrimary HandlerSelection = "primary"
+
+	// HandlerSelectionHash distributes devices over the Handlers based on the hash of their DevEUI and fails over to
+	// the next Handlers
+	HandlerSelectionHash HandlerSelection = "hash"
+)
+
+// ParseHandlerSelection parses a HandlerSelection
+func ParseHandlerSelection(str string) (HandlerSelection, error) {
+	switch selection := HandlerSelection(str); selection {
+	case "":
+		return HandlerSelectionSingle, nil
+	case HandlerSelectionSingle, HandlerSelectionPrimary, HandlerSelectionHash:
+		return selection, nil
+	}
+	return "", errors.NewErrInvalidArgument("Handler Selection", fmt.Sprintf("unknown strategy %s", str))
+}
+
+func (b *broker) SetHandlerSelection(selection HandlerSelection) {
+	b.handlerSelection = selection
+}
+
+// orderHandlers returns the handlers that announced the AppID of the uplink, ordered by preference
+func (b *broker) orderHandlers(uplink *pb.DeduplicatedUplinkMessage, announcements []*pb_discovery.Announcement) ([]*pb_discovery.Announcement, error) {
+	if len(announcements) == 0 {
+		return nil, errors.NewErrNotFound(fmt.Sprintf("Handler for AppID %s", uplink.AppID))
+	}
+	if len(announcements) == 1 {
+		return announcements, nil
+	}
+
+	ordered := make([]*pb_discovery.Announcement, len(announcements))
+	copy(ordered, announcements)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].ID < ordered[j].ID })
+
+	switch b.handlerSelection {
+	case HandlerSelectionPrimary:
+		return ordered, nil
+	case HandlerSelectionHash:
+		h := fnv.New32a()
+		if uplink.DevEUI != nil {
+			h.Write(uplink.DevEUI.Bytes())
+		} else {
+			h.Write([]byte(uplink.AppID + "." + uplink.DevID))
+		}
+		first := int(h.Sum32() % uint32(len(ordered)))
+		return append(ordered[first:], ordered[:first]...), nil
+	default:
+		return nil, errors.NewErrInternal(fmt.Sprintf("Multiple Handlers for AppID %s", uplink.AppID))
+	}
+}
+
+// selectHandlerUplink returns the uplink channel of the first active Handler in the ordered list
+func (b *broker) selectHandlerUplink(ordered []*pb_discovery.Announcement) (id string, uplink chan<- *pb.DeduplicatedUplinkMessage, failovers int, err error) {
+	for _, announcement := range ordered {
+		uplink, err = b.getHandlerUplink(announcement.ID)
+		if err == nil {
+			return announcement.ID, uplink, failovers, nil
+		}
+		failovers++
+	}
+	return "", nil, failovers, err
+}
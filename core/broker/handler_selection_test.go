@@ -0,0 +1,132 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package broker
+
+import (
+	"testing"
+
+	pb "github.com/TheThingsNetwork/api/broker"
+	pb_discovery "github.com/TheThingsNetwork/api/discovery"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestParseHandlerSelection(t *testing.T) {
+	a := New(t)
+
+	selection, err := ParseHandlerSelection("")
+	a.So(err, ShouldBeNil)
+	a.So(selection, ShouldEqual, HandlerSelectionSingle)
+
+	selection, err = ParseHandlerSelection("hash")
+	a.So(err, ShouldBeNil)
+	a.So(selection, ShouldEqual, HandlerSelectionHash)
+
+	_, err = ParseHandlerSelection("random")
+	a.So(err, ShouldHaveSameTypeAs, &errors.ErrInvalidArgument{})
+}
+
+func TestOrderHandlers(t *testing.T) {
+	a := New(t)
+
+	b := getTestBroker(t)
+
+	devEUI := types.DevEUI{1, 2, 3, 4, 5, 6, 7, 8}
+	uplink := &pb.DeduplicatedUplinkMessage{AppID: "appid-1", DevEUI: &devEUI}
+
+	announcements := []*pb_discovery.Announcement{
+		{ID: "handler-c"},
+		{ID: "handler-a"},
+		{ID: "handler-b"},
+	}
+
+	_, err := b.orderHandlers(uplink, nil)
+	a.So(err, ShouldHaveSameTypeAs, &errors.ErrNotFound{})
+
+	ordered, err := b.orderHandlers(uplink, announcements[:1])
+	a.So(err, ShouldBeNil)
+	a.So(ordered, ShouldHaveLength, 1)
+
+	b.SetHandlerSelection(HandlerSelectionSingle)
+	_, err = b.orderHandlers(uplink, announcements)
+	a.So(err, ShouldHaveSameTypeAs, &errors.ErrInternal{})
+
+	b.SetHandlerSelection(HandlerSelectionPrimary)
+	ordered, err = b.orderHandlers(uplink, announcements)
+	a.So(err, ShouldBeNil)
+	a.So(ordered[0].ID, ShouldEqual, "handler-a")
+	a.So(ordered[1].ID, ShouldEqual, "handler-b")
+	a.So(ordered[2].ID, ShouldEqual, "handler-c")
+	a.So(announcements[0].ID, ShouldEqual, "handler-c") // input not modified
+
+	b.SetHandlerSelection(HandlerSelectionHash)
+	ordered, err = b.orderHandlers(uplink, announcements)
+	a.So(err, ShouldBeNil)
+	a.So(ordered, ShouldHaveLength, 3)
+	again, _ := b.orderHandlers(uplink, announcements)
+	a.So(again, ShouldResemble, ordered)
+
+	// Different devices should not all end up on the same Handler
+	first := map[string]bool{}
+	for i := byte(0); i < 32; i++ {
+		devEUI := types.DevEUI{1, 2, 3, 4, 5, 6, 7, i}
+		ordered, _ := b.orderHandlers(&pb.DeduplicatedUplinkMessage{AppID: "appid-1", DevEUI: &devEUI}, announcements)
+		first[ordered[0].ID] = true
+	}
+	a.So(len(first), ShouldBeGreaterThan, 1)
+}
+
+func TestSelectHandlerUplink(t *testing.T) {
+	a := New(t)
+
+	b := getTestBroker(t)
+
+	announcements := []*pb_discovery.Announcement{
+		{ID: "handler-a"},
+		{ID: "handler-b"},
+	}
+
+	_, _, failovers, err := b.selectHandlerUplink(announcements)
+	a.So(err, ShouldNotBeNil)
+	a.So(failovers, ShouldEqual, 2)
+
+	b.handlers["handler-b"] = &handler{uplink: make(chan *pb.DeduplicatedUplinkMessage, 1)}
+	id, uplink, failovers, err := b.selectHandlerUplink(announcements)
+	a.So(err, ShouldBeNil)
+	a.So(id, ShouldEqual, "handler-b")
+	a.So(uplink, ShouldNotBeNil)
+	a.So(failovers, ShouldEqual, 1)
+
+	b.handlers["handler-a"] = &handler{uplink: make(chan *pb.DeduplicatedUplinkMessage, 1)}
+	id, _, failovers, err = b.selectHandlerUplink(announcements)
+	a.So(err, ShouldBeNil)
+	a.So(id, ShouldEqual, "handler-a")
+	a.So(failovers, ShouldEqual, 0)
+}
+
+func TestSelectDelegatedHandler(t *testing.T) {
+	a := New(t)
+
+	b := getTestBroker(t)
+
+	activation := &pb.DeduplicatedDeviceActivationRequest{AppID: "appid-1", DevEUI: types.DevEUI{1, 2, 3, 4, 5, 6, 7, 8}}
+	delegated := []*challengeResponseWithHandler{
+		{handler: &pb_discovery.Announcement{ID: "handler-b"}},
+		{handler: &pb_discovery.Announcement{ID: "handler-a"}},
+	}
+
+	selected, err := b.selectDelegatedHandler(activation, delegated[:1])
+	a.So(err, ShouldBeNil)
+	a.So(selected.handler.ID, ShouldEqual, "handler-b")
+
+	b.SetHandlerSelection(HandlerSelectionSingle)
+	_, err = b.selectDelegatedHandler(activation, delegated)
+	a.So(err, ShouldNotBeNil)
+
+	b.SetHandlerSelection(HandlerSelectionPrimary)
+	selected, err = b.selectDelegatedHandler(activation, delegated)
+	a.So(err, ShouldBeNil)
+	a.So(selected.handler.ID, ShouldEqual, "handler-a")
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/TheThingsNetwork/go-account-lib/rights"
 	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
 	"github.com/TheThingsNetwork/ttn/api/ratelimit"
+	"github.com/TheThingsNetwork/ttn/core/broker/usage"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"github.com/gogo/protobuf/types"
 	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
@@ -27,6 +29,8 @@ type brokerManager struct {
 	deviceManager  pb_lorawan.DeviceManagerClient
 	devAddrManager pb_lorawan.DevAddrManagerClient
 	clientRate     *ratelimit.Registry
+
+	multicastManager multicast.MulticastManagerClient
 }
 
 func (b *brokerManager) validateClient(ctx context.Context) (*claims.Claims, error) {
@@ -133,11 +137,53 @@ func (b *brokerManager) GetStatus(ctx context.Context, in *pb.StatusRequest) (*p
 	return status, nil
 }
 
+func (b *brokerManager) GetUsage(ctx context.Context, in *usage.GetUsageRequest) (*usage.GetUsageResponse, error) {
+	claims, err := b.validateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		scope usage.Scope
+		id    string
+	)
+	switch {
+	case in.AppID != "" && in.GatewayID == "":
+		if !claims.AppRight(in.AppID, rights.AppSettings) {
+			return nil, errors.NewErrPermissionDenied(fmt.Sprintf(`No "%s" rights to Application "%s"`, rights.AppSettings, in.AppID))
+		}
+		scope, id = usage.Application, in.AppID
+	case in.GatewayID != "" && in.AppID == "":
+		if !claims.GatewayRight(in.GatewayID, rights.GatewaySettings) {
+			return nil, errors.NewErrPermissionDenied(fmt.Sprintf(`No "%s" rights to Gateway "%s"`, rights.GatewaySettings, in.GatewayID))
+		}
+		scope, id = usage.Gateway, in.GatewayID
+	default:
+		return nil, errors.NewErrInvalidArgument("Usage Request", "must contain either AppID or GatewayID")
+	}
+	if b.broker.usage == nil {
+		return nil, errors.NewErrInternal("Usage accounting is not enabled on this Broker")
+	}
+	from, to := time.Now(), time.Now()
+	if in.From != 0 {
+		from = time.Unix(0, in.From)
+	}
+	if in.To != 0 {
+		to = time.Unix(0, in.To)
+	}
+	res, err := b.broker.usage.Get(scope, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &usage.GetUsageResponse{Usage: res}, nil
+}
+
 func (b *broker) RegisterManager(s *grpc.Server) {
 	server := &brokerManager{
 		broker:         b,
 		deviceManager:  pb_lorawan.NewDeviceManagerClient(b.nsConn),
 		devAddrManager: pb_lorawan.NewDevAddrManagerClient(b.nsConn),
+
+		multicastManager: multicast.NewMulticastManagerClient(b.nsConn),
 	}
 
 	server.clientRate = ratelimit.NewRegistry(5, time.Second)
@@ -145,4 +191,6 @@ func (b *broker) RegisterManager(s *grpc.Server) {
 	pb.RegisterBrokerManagerServer(s, server)
 	lorawan.RegisterDeviceManagerServer(s, server)
 	lorawan.RegisterDevAddrManagerServer(s, server)
+	usage.RegisterUsageManagerServer(s, server)
+	multicast.RegisterMulticastManagerServer(s, server)
 }
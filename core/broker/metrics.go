@@ -45,6 +45,33 @@ var connectedHandlers = prometheus.NewGauge(
 	},
 )
 
+var handlerFailoverCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "ttn",
+		Subsystem: "broker",
+		Name:      "handler_failovers_total",
+		Help:      "Total number of uplinks that failed over to another handler.",
+	},
+)
+
+var roamingUplinkCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "ttn",
+		Subsystem: "broker",
+		Name:      "roaming_uplinks_total",
+		Help:      "Total number of uplinks exchanged with roaming partners.",
+	}, []string{"direction"},
+)
+
+var downlinkFallbackCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "ttn",
+		Subsystem: "broker",
+		Name:      "downlink_fallbacks_total",
+		Help:      "Total number of downlinks that fell back to another downlink option.",
+	},
+)
+
 var initialized = false
 
 func initMetrics() {
@@ -56,4 +83,7 @@ func initMetrics() {
 	prometheus.MustRegister(micChecksHistogram)
 	prometheus.MustRegister(connectedRouters)
 	prometheus.MustRegister(connectedHandlers)
+	prometheus.MustRegister(handlerFailoverCounter)
+	prometheus.MustRegister(roamingUplinkCounter)
+	prometheus.MustRegister(downlinkFallbackCounter)
 }
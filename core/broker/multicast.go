@@ -0,0 +1,215 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package broker
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/TheThingsNetwork/api/broker"
+	pb_gateway "github.com/TheThingsNetwork/api/gateway"
+	pb_protocol "github.com/TheThingsNetwork/api/protocol"
+	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"gopkg.in/redis.v5"
+)
+
+// MulticastSendTimeout is the time that a Router gets to accept a multicast downlink
+var MulticastSendTimeout = time.Second
+
+// redisGatewayRoutersKey is the key of the Hash in which the Router of each Gateway is stored
+const redisGatewayRoutersKey = "broker:gateway-routers"
+
+// gatewayRouters keeps the Router that most recently forwarded a downlink-capable uplink of each Gateway, so that
+// downlink that is not a response to an uplink (such as multicast downlink) can be sent to that Gateway. If the
+// client is set, the Routers are also stored in Redis, so that they are still known after a restart.
+type gatewayRouters struct {
+	sync.RWMutex
+	routers map[string]string
+	client  *redis.Client
+}
+
+func (g *gatewayRouters) set(options []*pb.DownlinkOption) error {
+	g.Lock()
+	defer g.Unlock()
+	if g.routers == nil {
+		g.routers = make(map[string]string)
+	}
+	changed := make(map[string]string)
+	for _, option := range options {
+		parts := strings.SplitN(option.Identifier, ":", 2)
+		if option.GatewayID == "" || len(parts) != 2 {
+			continue
+		}
+		if g.routers[option.GatewayID] != parts[0] {
+			changed[option.GatewayID] = parts[0]
+		}
+		g.routers[option.GatewayID] = parts[0]
+	}
+	if g.client == nil || len(changed) == 0 {
+		return nil
+	}
+	return g.client.HMSet(redisGatewayRoutersKey, changed).Err()
+}
+
+func (g *gatewayRouters) get(gatewayID string) (routerID string, ok bool) {
+	g.RLock()
+	routerID, ok = g.routers[gatewayID]
+	g.RUnlock()
+	if ok || g.client == nil {
+		return
+	}
+	routerID, err := g.client.HGet(redisGatewayRoutersKey, gatewayID).Result()
+	if err != nil {
+		return "", false
+	}
+	g.Lock()
+	defer g.Unlock()
+	if g.routers == nil {
+		g.routers = make(map[string]string)
+	}
+	if _, ok := g.routers[gatewayID]; !ok {
+		g.routers[gatewayID] = routerID
+	}
+	return g.routers[gatewayID], true
+}
+
+// SetGatewayRouterStore stores the Routers of the Gateways in Redis
+func (b *broker) SetGatewayRouterStore(client *redis.Client) {
+	b.gatewayRouters.client = client
+}
+
+func (b *broker) HandleMulticastDownlink(message *multicast.DownlinkMessage) (res *multicast.DownlinkMessage, err error) {
+	ctx := b.Ctx.WithFields(ttnlog.Fields{
+		"AppID":   message.AppID,
+		"GroupID": message.GroupID,
+	})
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			ctx.WithError(err).Warn("Could not handle multicast downlink")
+		} else {
+			ctx.WithField("Duration", time.Now().Sub(start)).Info("Handled multicast downlink")
+		}
+	}()
+
+	b.status.downlink.Mark(1)
+
+	res, err = b.nsMulticast.Downlink(b.Component.GetContext(b.nsToken), message)
+	if err != nil {
+		return nil, errors.Wrap(errors.FromGRPCError(err), "NetworkServer did not accept multicast downlink")
+	}
+
+	var sent []string
+	for _, gatewayID := range res.Gateways {
+		ctx := ctx.WithField("GatewayID", gatewayID)
+		routerID, ok := b.gatewayRouters.get(gatewayID)
+		if !ok {
+			ctx.Warn("No Router known for Gateway")
+			continue
+		}
+		router, err := b.getRouterDownlink(routerID)
+		if err != nil {
+			ctx.WithError(err).Warn("Router not available for Gateway")
+			continue
+		}
+		downlink := &pb.DownlinkMessage{
+			Payload: res.Payload,
+			AppID:   res.AppID,
+			DownlinkOption: &pb.DownlinkOption{
+				GatewayID:  gatewayID,
+				Identifier: routerID + ":", // Without schedule ID, the Router sends the downlink as soon as possible
+				ProtocolConfiguration: pb_protocol.TxConfiguration{Protocol: &pb_protocol.TxConfiguration_LoRaWAN{LoRaWAN: &pb_lorawan.TxConfiguration{
+					Modulation: pb_lorawan.Modulation_LORA,
+					DataRate:   res.DataRate,
+					CodingRate: "4/5",
+					FCnt:       res.FCnt,
+				}}},
+				GatewayConfiguration: pb_gateway.TxConfiguration{
+					RfChain:               0,
+					PolarizationInversion: true,
+					Frequency:             res.Frequency,
+					Power:                 res.Power,
+				},
+			},
+		}
+		if err := sendDownlink(router, downlink, time.Now().Add(MulticastSendTimeout).UnixNano()); err != nil {
+			ctx.WithError(err).Warn("Could not send multicast downlink to Router")
+			continue
+		}
+		b.accountDownlink(downlink)
+		sent = append(sent, gatewayID)
+	}
+
+	if len(sent) == 0 {
+		return nil, errors.NewErrNotFound("Router for the Gateways of the multicast group")
+	}
+	ctx.WithField("Gateways", len(sent)).Debug("Sent multicast downlink")
+
+	res.Gateways = sent
+	return res, nil
+}
+
+type brokerMulticastRPC struct {
+	broker *broker
+}
+
+func (b *brokerMulticastRPC) Downlink(ctx context.Context, message *multicast.DownlinkMessage) (*multicast.DownlinkMessage, error) {
+	handler, err := b.broker.ValidateNetworkContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Get latest Handler metadata
+	handler, err = b.broker.Component.Discover("handler", handler.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, announcedID := range handler.AppIDs() {
+		if announcedID == message.AppID {
+			return b.broker.HandleMulticastDownlink(message)
+		}
+	}
+	return nil, errors.NewErrPermissionDenied("Handler did not announce Application")
+}
+
+func (b *brokerManager) GetGroup(ctx context.Context, in *multicast.GroupIdentifier) (*multicast.Group, error) {
+	if _, err := b.validateClient(ctx); err != nil {
+		return nil, err
+	}
+	token, _ := ttnctx.TokenFromIncomingContext(ctx)
+	res, err := b.multicastManager.GetGroup(ttnctx.OutgoingContextWithToken(ctx, token), in)
+	if err != nil {
+		return nil, errors.Wrap(errors.FromGRPCError(err), "NetworkServer did not return group")
+	}
+	return res, nil
+}
+
+func (b *brokerManager) SetGroup(ctx context.Context, in *multicast.Group) (*multicast.Empty, error) {
+	if _, err := b.validateClient(ctx); err != nil {
+		return nil, err
+	}
+	token, _ := ttnctx.TokenFromIncomingContext(ctx)
+	res, err := b.multicastManager.SetGroup(ttnctx.OutgoingContextWithToken(ctx, token), in)
+	if err != nil {
+		return nil, errors.Wrap(errors.FromGRPCError(err), "NetworkServer did not set group")
+	}
+	return res, nil
+}
+
+func (b *brokerManager) DeleteGroup(ctx context.Context, in *multicast.GroupIdentifier) (*multicast.Empty, error) {
+	if _, err := b.validateClient(ctx); err != nil {
+		return nil, err
+	}
+	token, _ := ttnctx.TokenFromIncomingContext(ctx)
+	res, err := b.multicastManager.DeleteGroup(ttnctx.OutgoingContextWithToken(ctx, token), in)
+	if err != nil {
+		return nil, errors.Wrap(errors.FromGRPCError(err), "NetworkServer did not delete group")
+	}
+	return res, nil
+}
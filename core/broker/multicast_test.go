@@ -0,0 +1,95 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package broker
+
+import (
+	"testing"
+
+	pb "github.com/TheThingsNetwork/api/broker"
+	"github.com/TheThingsNetwork/api/monitor/monitorclient"
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+type mockMulticast struct {
+	gateways []string
+}
+
+func (m *mockMulticast) Downlink(ctx context.Context, in *multicast.DownlinkMessage, opts ...grpc.CallOption) (*multicast.DownlinkMessage, error) {
+	out := *in
+	out.Frequency = 869525000
+	out.DataRate = "SF9BW125"
+	out.Power = 27
+	out.Gateways = m.gateways
+	return &out, nil
+}
+
+func TestHandleMulticastDownlink(t *testing.T) {
+	a := New(t)
+
+	dlch := make(chan *pb.DownlinkMessage, 2)
+	b := &broker{
+		Component: &component.Component{
+			Ctx:     GetLogger(t, "TestHandleMulticastDownlink"),
+			Monitor: monitorclient.NewMonitorClient(),
+		},
+		nsMulticast: &mockMulticast{gateways: []string{"gtw-1", "gtw-2", "gtw-3"}},
+		routers: map[string]*router{
+			"routerID":      &router{downlinkConns: 1, downlink: dlch},
+			"otherRouterID": &router{},
+		},
+	}
+	b.InitStatus()
+
+	message := &multicast.DownlinkMessage{AppID: "appid", GroupID: "groupid", Payload: []byte{1, 2, 3, 4}, FCnt: 42}
+
+	// No known routes
+	_, err := b.HandleMulticastDownlink(message)
+	a.So(err, ShouldNotBeNil)
+
+	b.gatewayRouters.set([]*pb.DownlinkOption{
+		{GatewayID: "gtw-1", Identifier: "routerID:1"},
+		{GatewayID: "gtw-2", Identifier: "otherRouterID:1"}, // Router not active
+		{GatewayID: "gtw-4", Identifier: "routerID:2"},
+	})
+
+	res, err := b.HandleMulticastDownlink(message)
+	a.So(err, ShouldBeNil)
+	a.So(res.Gateways, ShouldResemble, []string{"gtw-1"})
+	a.So(len(dlch), ShouldEqual, 1)
+
+	downlink := <-dlch
+	a.So(downlink.Payload, ShouldResemble, []byte{1, 2, 3, 4})
+	a.So(downlink.DownlinkOption.GatewayID, ShouldEqual, "gtw-1")
+	a.So(downlink.DownlinkOption.Identifier, ShouldEqual, "routerID:")
+	a.So(downlink.DownlinkOption.GatewayConfiguration.Frequency, ShouldEqual, 869525000)
+	a.So(downlink.DownlinkOption.GatewayConfiguration.Power, ShouldEqual, 27)
+	a.So(downlink.DownlinkOption.ProtocolConfiguration.GetLoRaWAN().DataRate, ShouldEqual, "SF9BW125")
+	a.So(downlink.DownlinkOption.ProtocolConfiguration.GetLoRaWAN().FCnt, ShouldEqual, 42)
+}
+
+func TestGatewayRoutersRedis(t *testing.T) {
+	a := New(t)
+
+	client := GetRedisClient()
+	defer client.Del(redisGatewayRoutersKey)
+
+	routers := &gatewayRouters{client: client}
+	err := routers.set([]*pb.DownlinkOption{
+		{GatewayID: "gtw-1", Identifier: "routerID:1"},
+	})
+	a.So(err, ShouldBeNil)
+
+	// The Routers are still known after a restart
+	routers = &gatewayRouters{client: client}
+	routerID, ok := routers.get("gtw-1")
+	a.So(ok, ShouldBeTrue)
+	a.So(routerID, ShouldEqual, "routerID")
+	_, ok = routers.get("gtw-2")
+	a.So(ok, ShouldBeFalse)
+}
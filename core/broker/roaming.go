@@ -0,0 +1,343 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package broker
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/TheThingsNetwork/api/broker"
+	"github.com/TheThingsNetwork/api/gateway"
+	"github.com/TheThingsNetwork/api/protocol"
+	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
+	"github.com/TheThingsNetwork/api/trace"
+	"github.com/TheThingsNetwork/ttn/core/backend"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// RoamingPartner is an external network that we exchange passive roaming traffic with
+type RoamingPartner struct {
+	NetID types.NetID
+	URL   string
+	Token string
+}
+
+// ParseRoamingPartner parses a roaming partner in the format NetID=URL
+func ParseRoamingPartner(str string) (partner RoamingPartner, err error) {
+	parts := strings.SplitN(str, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return partner, errors.NewErrInvalidArgument("Roaming Partner", "expected format NetID=URL")
+	}
+	if err = partner.NetID.UnmarshalText([]byte(parts[0])); err != nil {
+		return partner, errors.NewErrInvalidArgument("Roaming Partner", err.Error())
+	}
+	partner.URL = parts[1]
+	return partner, nil
+}
+
+type roaming struct {
+	netID    types.NetID
+	partners map[types.NetID]*RoamingPartner
+
+	sessions     map[types.DevAddr]time.Time
+	sessionsLock sync.Mutex
+}
+
+func netIDString(netID types.NetID) string {
+	return strings.ToUpper(hex.EncodeToString(netID.Bytes()))
+}
+
+func (b *broker) SetRoaming(netID types.NetID, token string, partners ...RoamingPartner) {
+	b.roaming = &roaming{
+		netID:    netID,
+		partners: make(map[types.NetID]*RoamingPartner),
+		sessions: make(map[types.DevAddr]time.Time),
+	}
+	for _, partner := range partners {
+		partner := partner
+		if partner.Token == "" {
+			partner.Token = token
+		}
+		b.roaming.partners[partner.NetID] = &partner
+	}
+}
+
+// getRoamingPartner returns the roaming partner that the DevAddr belongs to, or nil if it belongs to no partner
+func (b *broker) getRoamingPartner(devAddr types.DevAddr) *RoamingPartner {
+	if b.roaming == nil {
+		return nil
+	}
+	netID, err := devAddr.NetID()
+	if err != nil || netID == b.roaming.netID {
+		return nil
+	}
+	return b.roaming.partners[netID]
+}
+
+func (r *roaming) hasSession(devAddr types.DevAddr) bool {
+	r.sessionsLock.Lock()
+	defer r.sessionsLock.Unlock()
+	expires, ok := r.sessions[devAddr]
+	if ok && time.Now().After(expires) {
+		delete(r.sessions, devAddr)
+		return false
+	}
+	return ok
+}
+
+func (r *roaming) setSession(devAddr types.DevAddr, lifetime time.Duration) {
+	r.sessionsLock.Lock()
+	defer r.sessionsLock.Unlock()
+	if lifetime <= 0 {
+		delete(r.sessions, devAddr)
+		return
+	}
+	r.sessions[devAddr] = time.Now().Add(lifetime)
+}
+
+// gatewayIDBytes returns the EUI of EUI-based gateway IDs, and the ID itself for other gateways
+func gatewayIDBytes(gatewayID string) backend.HEXBytes {
+	if strings.HasPrefix(gatewayID, "eui-") {
+		if eui, err := types.ParseEUI64(strings.TrimPrefix(gatewayID, "eui-")); err == nil {
+			return eui.Bytes()
+		}
+	}
+	return backend.HEXBytes(gatewayID)
+}
+
+// buildULMetaData converts the (deduplicated) uplinks to Backend Interfaces metadata
+func buildULMetaData(devAddr types.DevAddr, duplicates []*pb.UplinkMessage) (*backend.ULMetaData, error) {
+	lorawan := duplicates[0].ProtocolMetadata.GetLoRaWAN()
+	if lorawan == nil {
+		return nil, errors.NewErrInvalidArgument("Uplink", "does not contain LoRaWAN metadata")
+	}
+	rfRegion := backend.RFRegion(lorawan.FrequencyPlan)
+	meta := &backend.ULMetaData{
+		DevAddr:  &devAddr,
+		RecvTime: time.Now().UTC().Format(time.RFC3339Nano),
+		RFRegion: rfRegion,
+		GWCnt:    len(duplicates),
+	}
+	if dataRate, err := backend.DataRateIndex(lorawan.FrequencyPlan, lorawan.DataRate); err == nil {
+		meta.DataRate = &dataRate
+	}
+	if freq := duplicates[0].GatewayMetadata.Frequency; freq != 0 {
+		mhz := float64(freq) / 1000000
+		meta.ULFreq = &mhz
+	}
+	for _, duplicate := range duplicates {
+		gtw := duplicate.GatewayMetadata
+		rssi := int(gtw.RSSI)
+		snr := gtw.SNR
+		info := backend.GWInfo{
+			ID:       gatewayIDBytes(gtw.GatewayID),
+			RFRegion: rfRegion,
+			RSSI:     &rssi,
+			SNR:      &snr,
+		}
+		if gtw.Location != nil {
+			lat, lon := gtw.Location.Latitude, gtw.Location.Longitude
+			info.Lat, info.Lon = &lat, &lon
+		}
+		meta.GWInfo = append(meta.GWInfo, info)
+	}
+	return meta, nil
+}
+
+// forwardRoamingUplink forwards an uplink to the roaming partner that owns the DevAddr. The first uplink is sent in a
+// PRStartReq; if the partner starts a session, subsequent uplinks are sent in a XmitDataReq until the session expires.
+func (b *broker) forwardRoamingUplink(partner *RoamingPartner, devAddr types.DevAddr, duplicates []*pb.UplinkMessage, deduplicatedUplink *pb.DeduplicatedUplinkMessage) error {
+	meta, err := buildULMetaData(devAddr, duplicates)
+	if err != nil {
+		return err
+	}
+	client := &backend.Client{
+		SenderID:   netIDString(b.roaming.netID),
+		ReceiverID: netIDString(partner.NetID),
+		URL:        partner.URL,
+		Token:      partner.Token,
+	}
+
+	if b.roaming.hasSession(devAddr) {
+		ans := new(backend.XmitDataAnsMessage)
+		if err := client.Do(backend.XmitDataReq, &backend.XmitDataReqMessage{
+			PHYPayload: deduplicatedUplink.Payload,
+			ULMetaData: meta,
+		}, ans); err != nil {
+			return errors.Wrap(err, "Roaming partner did not handle uplink")
+		}
+		if err := backend.CheckResult(ans.Result); err != nil {
+			b.roaming.setSession(devAddr, 0)
+			return errors.Wrap(err, "Roaming partner did not accept uplink")
+		}
+	} else {
+		ans := new(backend.PRStartAnsMessage)
+		if err := client.Do(backend.PRStartReq, &backend.PRStartReqMessage{
+			PHYPayload: deduplicatedUplink.Payload,
+			ULMetaData: *meta,
+		}, ans); err != nil {
+			return errors.Wrap(err, "Roaming partner did not handle uplink")
+		}
+		if err := backend.CheckResult(ans.Result); err != nil {
+			return errors.Wrap(err, "Roaming partner did not accept uplink")
+		}
+		if ans.Lifetime != nil {
+			b.roaming.setSession(devAddr, time.Duration(*ans.Lifetime)*time.Second)
+		}
+	}
+
+	roamingUplinkCounter.WithLabelValues("out").Inc()
+	deduplicatedUplink.Trace = deduplicatedUplink.Trace.WithEvent(trace.ForwardEvent,
+		"roaming partner", netIDString(partner.NetID),
+	)
+	return nil
+}
+
+// RoamingHandler returns the HTTP handler that accepts passive roaming uplinks from roaming partners
+func (b *broker) RoamingHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			backend.Header
+			PHYPayload backend.HEXBytes    `json:"PHYPayload"`
+			ULMetaData *backend.ULMetaData `json:"ULMetaData"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ans := backend.Header{
+			ProtocolVersion: backend.ProtocolVersion,
+			SenderID:        req.ReceiverID,
+			ReceiverID:      req.SenderID,
+			TransactionID:   req.TransactionID,
+			ReceiverToken:   req.SenderToken,
+		}
+		var res interface{}
+		err := b.handleRoamingUplink(r, &req.Header, req.PHYPayload, req.ULMetaData)
+		switch req.MessageType {
+		case backend.XmitDataReq:
+			ans.MessageType = backend.XmitDataAns
+			res = &backend.XmitDataAnsMessage{Header: ans, Result: backend.ResultFor(err)}
+		default:
+			ans.MessageType = backend.PRStartAns
+			res = &backend.PRStartAnsMessage{Header: ans, Result: backend.ResultFor(err)}
+		}
+		if err != nil {
+			b.Ctx.WithError(err).WithField("SenderID", req.SenderID).Warn("Could not handle roaming uplink")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	})
+}
+
+func (b *broker) handleRoamingUplink(r *http.Request, header *backend.Header, payload []byte, meta *backend.ULMetaData) error {
+	if b.roaming == nil {
+		return errors.NewErrPermissionDenied("Roaming not enabled")
+	}
+	var senderID types.NetID
+	if err := senderID.UnmarshalText([]byte(header.SenderID)); err != nil {
+		return errors.NewErrPermissionDenied(fmt.Sprintf("Unknown sender %s", header.SenderID))
+	}
+	partner, ok := b.roaming.partners[senderID]
+	if !ok {
+		return errors.NewErrPermissionDenied(fmt.Sprintf("Unknown sender %s", header.SenderID))
+	}
+	if partner.Token != "" && r.Header.Get("Authorization") != "Bearer "+partner.Token {
+		return errors.NewErrPermissionDenied("Invalid token")
+	}
+	switch header.MessageType {
+	case backend.PRStartReq, backend.XmitDataReq:
+	default:
+		return errors.NewErrInvalidArgument("MessageType", fmt.Sprintf("%s not supported", header.MessageType))
+	}
+	if len(payload) == 0 || meta == nil || len(meta.GWInfo) == 0 {
+		return errors.NewErrInvalidArgument("Uplink", "does not contain PHYPayload, ULMetaData or GWInfo")
+	}
+
+	uplinks, err := buildRoamingUplinks(partner, payload, meta)
+	if err != nil {
+		return err
+	}
+	roamingUplinkCounter.WithLabelValues("in").Inc()
+
+	// The deduplicator merges the uplinks, so only one of them returns the result of HandleUplink
+	var wg sync.WaitGroup
+	errs := make(chan error, len(uplinks))
+	for _, uplink := range uplinks {
+		wg.Add(1)
+		go func(uplink *pb.UplinkMessage) {
+			defer wg.Done()
+			errs <- b.HandleUplink(uplink)
+		}(uplink)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildRoamingUplinks converts the Backend Interfaces uplink to an UplinkMessage for every gateway that received it
+func buildRoamingUplinks(partner *RoamingPartner, payload []byte, meta *backend.ULMetaData) ([]*pb.UplinkMessage, error) {
+	lorawan := &pb_lorawan.Metadata{Modulation: pb_lorawan.Modulation_LORA}
+	if meta.RFRegion != "" {
+		frequencyPlan, err := backend.FrequencyPlan(meta.RFRegion)
+		if err != nil {
+			return nil, err
+		}
+		lorawan.FrequencyPlan = frequencyPlan
+		if meta.DataRate != nil {
+			if lorawan.DataRate, err = backend.DataRateString(frequencyPlan, *meta.DataRate); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var frequency uint64
+	if meta.ULFreq != nil {
+		frequency = uint64(*meta.ULFreq*1000000 + 0.5)
+	}
+	recvTime, _ := time.Parse(time.RFC3339Nano, meta.RecvTime)
+
+	uplinks := make([]*pb.UplinkMessage, 0, len(meta.GWInfo))
+	for _, info := range meta.GWInfo {
+		lorawan := *lorawan
+		uplink := &pb.UplinkMessage{
+			Payload: payload,
+			GatewayMetadata: gateway.RxMetadata{
+				GatewayID: fmt.Sprintf("roaming-%s-%s", strings.ToLower(netIDString(partner.NetID)), strings.ToLower(hex.EncodeToString(info.ID))),
+				Frequency: frequency,
+			},
+			ProtocolMetadata: protocol.RxMetadata{Protocol: &protocol.RxMetadata_LoRaWAN{LoRaWAN: &lorawan}},
+		}
+		if !recvTime.IsZero() {
+			uplink.GatewayMetadata.Time = recvTime.UnixNano()
+		}
+		if info.RSSI != nil {
+			uplink.GatewayMetadata.RSSI = float32(*info.RSSI)
+		}
+		if info.SNR != nil {
+			uplink.GatewayMetadata.SNR = *info.SNR
+		}
+		if info.Lat != nil && info.Lon != nil {
+			uplink.GatewayMetadata.Location = &gateway.LocationMetadata{Latitude: *info.Lat, Longitude: *info.Lon}
+		}
+		uplinks = append(uplinks, uplink)
+	}
+	return uplinks, nil
+}
@@ -0,0 +1,186 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package broker
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pb "github.com/TheThingsNetwork/api/broker"
+	"github.com/TheThingsNetwork/api/gateway"
+	pb_networkserver "github.com/TheThingsNetwork/api/networkserver"
+	"github.com/TheThingsNetwork/api/protocol"
+	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
+	"github.com/TheThingsNetwork/ttn/core/backend"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/brocaar/lorawan"
+	"github.com/golang/mock/gomock"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestParseRoamingPartner(t *testing.T) {
+	a := New(t)
+
+	partner, err := ParseRoamingPartner("000024=https://partner.example.com/roaming")
+	a.So(err, ShouldBeNil)
+	a.So(partner.NetID, ShouldEqual, types.NetID{0x00, 0x00, 0x24})
+	a.So(partner.URL, ShouldEqual, "https://partner.example.com/roaming")
+
+	_, err = ParseRoamingPartner("000024")
+	a.So(err, ShouldNotBeNil)
+
+	_, err = ParseRoamingPartner("XYZ=https://partner.example.com/roaming")
+	a.So(err, ShouldNotBeNil)
+}
+
+func buildRoamingTestUplink(devAddr types.DevAddr, fCnt uint32) *pb.UplinkMessage {
+	phy := lorawan.PHYPayload{
+		MHDR: lorawan.MHDR{MType: lorawan.UnconfirmedDataUp, Major: lorawan.LoRaWANR1},
+		MACPayload: &lorawan.MACPayload{
+			FHDR: lorawan.FHDR{DevAddr: lorawan.DevAddr(devAddr), FCnt: fCnt},
+		},
+	}
+	payload, _ := phy.MarshalBinary()
+	return &pb.UplinkMessage{
+		Payload: payload,
+		GatewayMetadata: gateway.RxMetadata{
+			GatewayID: "eui-0102030405060708",
+			Frequency: 868100000,
+			RSSI:      -42,
+			SNR:       7.5,
+		},
+		ProtocolMetadata: protocol.RxMetadata{Protocol: &protocol.RxMetadata_LoRaWAN{LoRaWAN: &pb_lorawan.Metadata{
+			FrequencyPlan: pb_lorawan.FrequencyPlan_EU_863_870,
+			DataRate:      "SF7BW125",
+		}}},
+	}
+}
+
+func TestForwardRoamingUplink(t *testing.T) {
+	a := New(t)
+
+	var received []map[string]interface{}
+	partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req backend.PRStartReqMessage
+		json.NewDecoder(r.Body).Decode(&req)
+		a.So(r.Header.Get("Authorization"), ShouldEqual, "Bearer secret")
+		received = append(received, map[string]interface{}{
+			"MessageType": req.MessageType,
+			"ULMetaData":  req.ULMetaData,
+		})
+		header := backend.Header{
+			ProtocolVersion: backend.ProtocolVersion,
+			SenderID:        req.ReceiverID,
+			ReceiverID:      req.SenderID,
+			TransactionID:   req.TransactionID,
+		}
+		if req.MessageType == backend.XmitDataReq {
+			header.MessageType = backend.XmitDataAns
+			json.NewEncoder(w).Encode(backend.XmitDataAnsMessage{Header: header, Result: backend.Result{ResultCode: backend.ResultSuccess}})
+			return
+		}
+		lifetime := 60
+		header.MessageType = backend.PRStartAns
+		json.NewEncoder(w).Encode(backend.PRStartAnsMessage{Header: header, Result: backend.Result{ResultCode: backend.ResultSuccess}, Lifetime: &lifetime})
+	}))
+	defer partner.Close()
+
+	b := getTestBroker(t)
+	b.SetRoaming(types.NetID{0x00, 0x00, 0x13}, "secret", RoamingPartner{NetID: types.NetID{0x00, 0x00, 0x24}, URL: partner.URL})
+
+	// NetID 000024 has DevAddr prefix 48000000/7
+	devAddr := types.DevAddr{0x48, 0x01, 0x02, 0x03}
+
+	// Start passive roaming (the NetworkServer is not contacted)
+	err := b.HandleUplink(buildRoamingTestUplink(devAddr, 1))
+	a.So(err, ShouldBeNil)
+	a.So(received, ShouldHaveLength, 1)
+	a.So(received[0]["MessageType"], ShouldEqual, backend.PRStartReq)
+	meta := received[0]["ULMetaData"].(backend.ULMetaData)
+	a.So(*meta.DevAddr, ShouldEqual, devAddr)
+	a.So(*meta.DataRate, ShouldEqual, 5)
+	a.So(*meta.ULFreq, ShouldEqual, 868.1)
+	a.So(meta.RFRegion, ShouldEqual, "EU868")
+	a.So(meta.GWInfo, ShouldHaveLength, 1)
+	a.So(meta.GWInfo[0].ID, ShouldResemble, backend.HEXBytes{1, 2, 3, 4, 5, 6, 7, 8})
+
+	// Within the session
+	err = b.HandleUplink(buildRoamingTestUplink(devAddr, 2))
+	a.So(err, ShouldBeNil)
+	a.So(received, ShouldHaveLength, 2)
+	a.So(received[1]["MessageType"], ShouldEqual, backend.XmitDataReq)
+
+	// Our own NetID
+	b.ns.EXPECT().GetDevices(gomock.Any(), gomock.Any()).Return(&pb_networkserver.DevicesResponse{}, nil)
+	err = b.HandleUplink(buildRoamingTestUplink(types.DevAddr{0x26, 0x01, 0x02, 0x03}, 1))
+	a.So(err, ShouldNotBeNil)
+	a.So(received, ShouldHaveLength, 2)
+}
+
+func TestRoamingHandler(t *testing.T) {
+	a := New(t)
+
+	b := getTestBroker(t)
+	b.SetRoaming(types.NetID{0x00, 0x00, 0x13}, "secret", RoamingPartner{NetID: types.NetID{0x00, 0x00, 0x24}})
+	server := httptest.NewServer(b.RoamingHandler())
+	defer server.Close()
+
+	uplink := buildRoamingTestUplink(types.DevAddr{0x26, 0x01, 0x02, 0x03}, 1)
+	meta, err := buildULMetaData(types.DevAddr{0x26, 0x01, 0x02, 0x03}, []*pb.UplinkMessage{uplink})
+	a.So(err, ShouldBeNil)
+
+	send := func(senderID string) *backend.PRStartAnsMessage {
+		client := &backend.Client{SenderID: senderID, ReceiverID: "000013", URL: server.URL, Token: "secret"}
+		ans := new(backend.PRStartAnsMessage)
+		err := client.Do(backend.PRStartReq, &backend.PRStartReqMessage{PHYPayload: uplink.Payload, ULMetaData: *meta}, ans)
+		a.So(err, ShouldBeNil)
+		return ans
+	}
+
+	// Unknown partner
+	a.So(send("000099").Result.ResultCode, ShouldEqual, backend.ResultUnknownSender)
+
+	// Known partner, handled as local uplink
+	b.ns.EXPECT().GetDevices(gomock.Any(), gomock.Any()).Return(&pb_networkserver.DevicesResponse{}, nil)
+	ans := send("000024")
+	a.So(ans.Result.ResultCode, ShouldEqual, backend.ResultUnknownDevAddr)
+	a.So(ans.MessageType, ShouldEqual, backend.PRStartAns)
+
+	// Invalid JSON
+	res, err := http.Post(server.URL, "application/json", bytes.NewBufferString("{"))
+	a.So(err, ShouldBeNil)
+	a.So(res.StatusCode, ShouldEqual, http.StatusBadRequest)
+}
+
+func TestBuildRoamingUplinks(t *testing.T) {
+	a := New(t)
+
+	dataRate := 5
+	freq := 868.1
+	rssi := -42
+	lat, lon := float32(52.37), float32(4.89)
+	uplinks, err := buildRoamingUplinks(&RoamingPartner{NetID: types.NetID{0x00, 0x00, 0x24}}, []byte{0x40}, &backend.ULMetaData{
+		RFRegion: "EU868",
+		DataRate: &dataRate,
+		ULFreq:   &freq,
+		GWInfo: []backend.GWInfo{
+			{ID: backend.HEXBytes{1, 2, 3, 4, 5, 6, 7, 8}, RSSI: &rssi, Lat: &lat, Lon: &lon},
+			{ID: backend.HEXBytes{1, 2, 3, 4, 5, 6, 7, 9}},
+		},
+	})
+	a.So(err, ShouldBeNil)
+	a.So(uplinks, ShouldHaveLength, 2)
+	a.So(uplinks[0].GatewayMetadata.GatewayID, ShouldEqual, "roaming-000024-0102030405060708")
+	a.So(uplinks[0].GatewayMetadata.Frequency, ShouldEqual, 868100000)
+	a.So(uplinks[0].GatewayMetadata.RSSI, ShouldEqual, -42)
+	a.So(uplinks[0].GatewayMetadata.Location.Latitude, ShouldEqual, lat)
+	a.So(uplinks[0].ProtocolMetadata.GetLoRaWAN().DataRate, ShouldEqual, "SF7BW125")
+	a.So(uplinks[1].GatewayMetadata.Location, ShouldBeNil)
+
+	_, err = buildRoamingUplinks(&RoamingPartner{}, []byte{0x40}, &backend.ULMetaData{RFRegion: "Mars"})
+	a.So(err, ShouldNotBeNil)
+}
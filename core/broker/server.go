@@ -9,6 +9,7 @@ import (
 	pb "github.com/TheThingsNetwork/api/broker"
 	"github.com/TheThingsNetwork/api/broker/brokerclient"
 	"github.com/TheThingsNetwork/ttn/api/ratelimit"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
 	"google.golang.org/grpc"
@@ -135,4 +136,5 @@ func (b *broker) RegisterRPC(s *grpc.Server) {
 	server.handlerDownRate = ratelimit.NewRegistry(125, time.Second) // one eight of uplink
 
 	pb.RegisterBrokerServer(s, server)
+	multicast.RegisterMulticastServer(s, &brokerMulticastRPC{broker: b})
 }
@@ -66,6 +66,9 @@ func (b *broker) HandleUplink(uplink *pb.UplinkMessage) (err error) {
 		"duplicates", len(duplicates),
 	)
 	for _, duplicate := range duplicates {
+		if err := b.gatewayRouters.set(duplicate.DownlinkOptions); err != nil {
+			ctx.WithError(err).Warn("Could not store Routers of Gateways")
+		}
 		if duplicate.Trace != nil {
 			deduplicatedUplink.Trace.Parents = append(deduplicatedUplink.Trace.Parents, duplicate.Trace)
 		}
@@ -86,12 +89,19 @@ func (b *broker) HandleUplink(uplink *pb.UplinkMessage) (err error) {
 		return errors.NewErrInvalidArgument("Uplink", "does not contain a MAC payload")
 	}
 
-	// Request devices from NS
 	devAddr := types.DevAddr(macPayload.FHDR.DevAddr)
 	ctx = ctx.WithFields(ttnlog.Fields{
 		"DevAddr": devAddr,
 		"FCnt":    macPayload.FHDR.FCnt,
 	})
+
+	// Forward uplinks of foreign networks to the roaming partner
+	if partner := b.getRoamingPartner(devAddr); partner != nil {
+		ctx = ctx.WithField("RoamingPartner", netIDString(partner.NetID))
+		return b.forwardRoamingUplink(partner, devAddr, duplicates, deduplicatedUplink)
+	}
+
+	// Request devices from NS
 	var getDevicesResp *networkserver.DevicesResponse
 	getDevicesResp, err = b.ns.GetDevices(b.Component.GetContext(b.nsToken), &networkserver.DevicesRequest{
 		DevAddr: devAddr,
@@ -201,6 +211,7 @@ func (b *broker) HandleUplink(uplink *pb.UplinkMessage) (err error) {
 	var downlinkOptions []*pb.DownlinkOption
 	for _, duplicate := range duplicates {
 		deduplicatedUplink.GatewayMetadata = append(deduplicatedUplink.GatewayMetadata, &duplicate.GatewayMetadata)
+		setDownlinkDeadlines(duplicate.DownlinkOptions, duplicate.GatewayMetadata.Timestamp, start)
 		downlinkOptions = append(downlinkOptions, duplicate.DownlinkOptions...)
 	}
 
@@ -211,7 +222,7 @@ func (b *broker) HandleUplink(uplink *pb.UplinkMessage) (err error) {
 			AppEUI:         device.AppEUI,
 			AppID:          device.AppID,
 			DevID:          device.DevID,
-			DownlinkOption: selectBestDownlink(downlinkOptions),
+			DownlinkOption: b.selectDownlinkOptions(downlinkOptions),
 		}
 	}
 
@@ -226,25 +237,31 @@ func (b *broker) HandleUplink(uplink *pb.UplinkMessage) (err error) {
 	if err != nil {
 		return err
 	}
-	if len(announcements) == 0 {
-		return errors.NewErrNotFound(fmt.Sprintf("Handler for AppID %s", device.AppID))
-	}
-	if len(announcements) > 1 {
-		return errors.NewErrInternal(fmt.Sprintf("Multiple Handlers for AppID %s", device.AppID))
+	announcements, err = b.orderHandlers(deduplicatedUplink, announcements)
+	if err != nil {
+		return err
 	}
 
-	var handler chan<- *pb.DeduplicatedUplinkMessage
-	handler, err = b.getHandlerUplink(announcements[0].ID)
+	handlerID, handler, failovers, err := b.selectHandlerUplink(announcements)
 	if err != nil {
 		return err
 	}
+	ctx = ctx.WithField("HandlerID", handlerID)
+	if failovers > 0 {
+		ctx = ctx.WithField("Failovers", failovers)
+		handlerFailoverCounter.Add(float64(failovers))
+	}
 
 	deduplicatedUplink.Trace = deduplicatedUplink.Trace.WithEvent(trace.ForwardEvent,
-		"handler", announcements[0].ID,
+		"handler", handlerID,
+		"candidates", len(announcements),
+		"failovers", failovers,
 	)
 
 	handler <- deduplicatedUplink
 
+	b.accountUplink(device.AppID, duplicates)
+
 	return nil
 }
 
@@ -261,11 +278,6 @@ func (b *broker) deduplicateUplink(duplicate *pb.UplinkMessage) (uplinks []*pb.U
 	return
 }
 
-func selectBestDownlink(options []*pb.DownlinkOption) *pb.DownlinkOption {
-	sort.Sort(ByScore(options))
-	return options[0]
-}
-
 // ByFCntUp implements sort.Interface for []*pb_lorawan.Device based on FCnt
 type ByFCntUp []*pb_lorawan.Device
 
@@ -0,0 +1,63 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package broker
+
+import (
+	"time"
+
+	pb "github.com/TheThingsNetwork/api/broker"
+	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
+	"github.com/TheThingsNetwork/ttn/core/broker/usage"
+	"github.com/TheThingsNetwork/ttn/utils/toa"
+)
+
+func (b *broker) SetUsageStore(store usage.Store) {
+	b.usage = store
+}
+
+func airtime(payloadSize int, modulation pb_lorawan.Modulation, dataRate string, bitRate uint32, codingRate string) (t time.Duration) {
+	switch modulation {
+	case pb_lorawan.Modulation_LORA:
+		t, _ = toa.ComputeLoRa(uint(payloadSize), dataRate, codingRate)
+	case pb_lorawan.Modulation_FSK:
+		t, _ = toa.ComputeFSK(uint(payloadSize), int(bitRate))
+	}
+	return
+}
+
+// accountUplink adds the uplink to the usage of the application and of the gateways that received it
+func (b *broker) accountUplink(appID string, duplicates []*pb.UplinkMessage) {
+	if b.usage == nil || len(duplicates) == 0 {
+		return
+	}
+	var t time.Duration
+	if lorawan := duplicates[0].ProtocolMetadata.GetLoRaWAN(); lorawan != nil {
+		t = airtime(len(duplicates[0].Payload), lorawan.Modulation, lorawan.DataRate, lorawan.BitRate, lorawan.CodingRate)
+	}
+	traffic := []usage.Traffic{{Scope: usage.Application, ID: appID, Uplinks: 1, Airtime: t}}
+	for _, duplicate := range duplicates {
+		traffic = append(traffic, usage.Traffic{Scope: usage.Gateway, ID: duplicate.GatewayMetadata.GatewayID, Uplinks: 1, Airtime: t})
+	}
+	if err := b.usage.Add(time.Now(), traffic...); err != nil {
+		b.Ctx.WithError(err).Warn("Could not account uplink")
+	}
+}
+
+// accountDownlink adds the downlink to the usage of the application and of the gateway that sends it
+func (b *broker) accountDownlink(downlink *pb.DownlinkMessage) {
+	if b.usage == nil || downlink.DownlinkOption == nil {
+		return
+	}
+	var t time.Duration
+	if lorawan := downlink.DownlinkOption.ProtocolConfiguration.GetLoRaWAN(); lorawan != nil {
+		t = airtime(len(downlink.Payload), lorawan.Modulation, lorawan.DataRate, lorawan.BitRate, lorawan.CodingRate)
+	}
+	err := b.usage.Add(time.Now(),
+		usage.Traffic{Scope: usage.Application, ID: downlink.AppID, Downlinks: 1, Airtime: t},
+		usage.Traffic{Scope: usage.Gateway, ID: downlink.DownlinkOption.GatewayID, Downlinks: 1, Airtime: t},
+	)
+	if err != nil {
+		b.Ctx.WithError(err).Warn("Could not account downlink")
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package usage
+
+import (
+	"github.com/TheThingsNetwork/ttn/utils/jsoncodec"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+// GetUsageRequest requests the daily usage of an application or a gateway. From and To are Unix nanoseconds; if empty,
+// the usage of the current day is returned.
+type GetUsageRequest struct {
+	AppID     string `json:"app_id,omitempty"`
+	GatewayID string `json:"gateway_id,omitempty"`
+	From      int64  `json:"from,omitempty"`
+	To        int64  `json:"to,omitempty"`
+}
+
+// GetUsageResponse contains the daily usage
+type GetUsageResponse struct {
+	Usage []*Usage `json:"usage"`
+}
+
+// UsageManagerServer is the server API for the UsageManager service
+type UsageManagerServer interface {
+	GetUsage(context.Context, *GetUsageRequest) (*GetUsageResponse, error)
+}
+
+// UsageManagerClient is the client API for the UsageManager service
+type UsageManagerClient interface {
+	GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error)
+}
+
+type usageManagerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewUsageManagerClient returns a new UsageManagerClient
+func NewUsageManagerClient(cc *grpc.ClientConn) UsageManagerClient {
+	return &usageManagerClient{cc}
+}
+
+func (c *usageManagerClient) GetUsage(ctx context.Context, in *GetUsageRequest, opts ...grpc.CallOption) (*GetUsageResponse, error) {
+	out := new(GetUsageResponse)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/broker.UsageManager/GetUsage", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var usageManagerServiceDesc = jsoncodec.ServiceDesc("broker.UsageManager", (*UsageManagerServer)(nil))
+
+// RegisterUsageManagerServer registers the UsageManager service
+func RegisterUsageManagerServer(s *grpc.Server, srv UsageManagerServer) {
+	s.RegisterService(usageManagerServiceDesc, srv)
+}
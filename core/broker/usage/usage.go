@@ -0,0 +1,162 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package usage implements the traffic accounting of the Broker. The traffic of applications and gateways is counted in
+// daily buckets that are stored in Redis.
+package usage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"gopkg.in/redis.v5"
+)
+
+// DateFormat is the format of the date of a daily bucket
+const DateFormat = "2006-01-02"
+
+// Retention is the time that daily buckets are kept in Redis. It is also the longest time range that can be requested.
+var Retention = 90 * 24 * time.Hour
+
+// Scope of the traffic
+type Scope string
+
+// Scopes of the traffic
+const (
+	Application Scope = "application"
+	Gateway     Scope = "gateway"
+)
+
+// Traffic of an application or gateway that should be accounted
+type Traffic struct {
+	Scope     Scope
+	ID        string
+	Uplinks   uint64
+	Downlinks uint64
+	Airtime   time.Duration
+}
+
+// Usage of an application or gateway on a single (UTC) day
+type Usage struct {
+	Date      string        `json:"date"`
+	Uplinks   uint64        `json:"uplinks"`
+	Downlinks uint64        `json:"downlinks"`
+	Airtime   time.Duration `json:"airtime"`
+}
+
+// Store stores the usage of applications and gateways
+type Store interface {
+	// Add the traffic to the daily buckets of the given time
+	Add(t time.Time, traffic ...Traffic) error
+	// Get the daily usage of an application or gateway between from and to (inclusive)
+	Get(scope Scope, id string, from, to time.Time) ([]*Usage, error)
+}
+
+const (
+	uplinksField   = "uplinks"
+	downlinksField = "downlinks"
+	airtimeField   = "airtime" // in microseconds
+)
+
+// NewRedisStore creates a new Redis-based usage store
+func NewRedisStore(client *redis.Client, prefix string) Store {
+	if !strings.HasSuffix(prefix, ":") {
+		prefix += ":"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// RedisStore stores the usage in Redis hashes with the fields uplinks, downlinks and airtime
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func (s *RedisStore) key(scope Scope, id string, t time.Time) string {
+	return fmt.Sprintf("%s%s:%s:%s", s.prefix, scope, id, t.UTC().Format(DateFormat))
+}
+
+// Add implements the Store interface
+func (s *RedisStore) Add(t time.Time, traffic ...Traffic) error {
+	if len(traffic) == 0 {
+		return nil
+	}
+	pipe := s.client.Pipeline()
+	defer pipe.Close()
+	for _, traffic := range traffic {
+		if traffic.ID == "" {
+			continue
+		}
+		key := s.key(traffic.Scope, traffic.ID, t)
+		if traffic.Uplinks != 0 {
+			pipe.HIncrBy(key, uplinksField, int64(traffic.Uplinks))
+		}
+		if traffic.Downlinks != 0 {
+			pipe.HIncrBy(key, downlinksField, int64(traffic.Downlinks))
+		}
+		if traffic.Airtime != 0 {
+			pipe.HIncrBy(key, airtimeField, int64(traffic.Airtime/time.Microsecond))
+		}
+		pipe.Expire(key, Retention)
+	}
+	_, err := pipe.Exec()
+	return err
+}
+
+// Get implements the Store interface
+func (s *RedisStore) Get(scope Scope, id string, from, to time.Time) ([]*Usage, error) {
+	from, to = truncateDay(from), truncateDay(to)
+	if to.Before(from) {
+		return nil, errors.NewErrInvalidArgument("Time range", "end before start")
+	}
+	if maxDays := int(Retention / (24 * time.Hour)); int(to.Sub(from)/(24*time.Hour))+1 > maxDays {
+		return nil, errors.NewErrInvalidArgument("Time range", fmt.Sprintf("can not be longer than %d days", maxDays))
+	}
+
+	pipe := s.client.Pipeline()
+	defer pipe.Close()
+	var cmds []*redis.StringStringMapCmd
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		cmds = append(cmds, pipe.HGetAll(s.key(scope, id, day)))
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	usage := make([]*Usage, 0, len(cmds))
+	for i, cmd := range cmds {
+		res, err := cmd.Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		day := &Usage{Date: from.AddDate(0, 0, i).Format(DateFormat)}
+		day.Uplinks, _ = strconv.ParseUint(res[uplinksField], 10, 64)
+		day.Downlinks, _ = strconv.ParseUint(res[downlinksField], 10, 64)
+		if airtime, err := strconv.ParseInt(res[airtimeField], 10, 64); err == nil {
+			day.Airtime = time.Duration(airtime) * time.Microsecond
+		}
+		usage = append(usage, day)
+	}
+	return usage, nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Total returns the total of the given usage
+func Total(usage []*Usage) (total Usage) {
+	for _, day := range usage {
+		total.Uplinks += day.Uplinks
+		total.Downlinks += day.Downlinks
+		total.Airtime += day.Airtime
+	}
+	return
+}
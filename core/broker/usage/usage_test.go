@@ -0,0 +1,61 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package usage
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestRedisStore(t *testing.T) {
+	a := New(t)
+
+	client := GetRedisClient()
+	s := NewRedisStore(client, "test-usage")
+	defer func() {
+		keys, _ := client.Keys("test-usage:*").Result()
+		for _, key := range keys {
+			client.Del(key)
+		}
+	}()
+
+	day1 := time.Date(2017, 6, 5, 12, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	a.So(s.Add(day1,
+		Traffic{Scope: Application, ID: "app", Uplinks: 1, Airtime: 50 * time.Millisecond},
+		Traffic{Scope: Gateway, ID: "gtw", Uplinks: 1, Airtime: 50 * time.Millisecond},
+	), ShouldBeNil)
+	a.So(s.Add(day1, Traffic{Scope: Application, ID: "app", Downlinks: 1, Airtime: 25 * time.Millisecond}), ShouldBeNil)
+	a.So(s.Add(day2, Traffic{Scope: Application, ID: "app", Uplinks: 2, Airtime: 100 * time.Millisecond}), ShouldBeNil)
+
+	usage, err := s.Get(Application, "app", day1.Add(-1*time.Hour), day2.AddDate(0, 0, 1))
+	a.So(err, ShouldBeNil)
+	a.So(usage, ShouldHaveLength, 3)
+	a.So(*usage[0], ShouldResemble, Usage{Date: "2017-06-05", Uplinks: 1, Downlinks: 1, Airtime: 75 * time.Millisecond})
+	a.So(*usage[1], ShouldResemble, Usage{Date: "2017-06-06", Uplinks: 2, Airtime: 100 * time.Millisecond})
+	a.So(*usage[2], ShouldResemble, Usage{Date: "2017-06-07"})
+	a.So(Total(usage), ShouldResemble, Usage{Uplinks: 3, Downlinks: 1, Airtime: 175 * time.Millisecond})
+
+	usage, err = s.Get(Gateway, "gtw", day1, day1)
+	a.So(err, ShouldBeNil)
+	a.So(usage, ShouldHaveLength, 1)
+	a.So(usage[0].Uplinks, ShouldEqual, 1)
+
+	_, err = s.Get(Application, "app", day2, day1)
+	a.So(err, ShouldNotBeNil)
+
+	_, err = s.Get(Application, "app", day1, day1.AddDate(2, 0, 0))
+	a.So(err, ShouldNotBeNil)
+
+	// The time range can not be longer than the retention
+	retentionDays := int(Retention / (24 * time.Hour))
+	_, err = s.Get(Application, "app", day1, day1.AddDate(0, 0, retentionDays-1))
+	a.So(err, ShouldBeNil)
+	_, err = s.Get(Application, "app", day1, day1.AddDate(0, 0, retentionDays))
+	a.So(err, ShouldNotBeNil)
+}
@@ -44,6 +44,11 @@ func (h *handler) HandleActivationChallenge(challenge *pb_broker.ActivationChall
 		return nil, err
 	}
 
+	// The external Join Server validates the MIC when handling the activation
+	if h.getJoinServer(dev) != nil {
+		return &pb_broker.ActivationChallengeResponse{}, nil
+	}
+
 	if dev.AppKey.IsEmpty() {
 		err = errors.NewErrNotFound(fmt.Sprintf("AppKey for device %s", challenge.DevID))
 		return nil, err
@@ -117,7 +122,8 @@ func (h *handler) HandleActivation(activation *pb_broker.DeduplicatedDeviceActiv
 		return nil, err
 	}
 
-	if dev.AppKey.IsEmpty() {
+	joinServer := h.getJoinServer(dev)
+	if joinServer == nil && dev.AppKey.IsEmpty() {
 		return nil, errors.NewErrNotFound(fmt.Sprintf("AppKey for device %s", devID))
 	}
 
@@ -146,10 +152,14 @@ func (h *handler) HandleActivation(activation *pb_broker.DeduplicatedDeviceActiv
 		return nil, errors.NewErrInvalidArgument("Activation Payload", "inconsistent")
 	}
 
-	// Validate MIC
-	activation.Trace = activation.Trace.WithEvent(trace.CheckMICEvent)
-	if ok, err = reqPHY.ValidateMIC(lorawan.AES128Key(dev.AppKey)); err != nil || !ok {
-		return nil, errors.NewErrNotFound("device that validates MIC")
+	// Validate MIC (the external Join Server validates the MIC itself and answers with MICFailed)
+	if joinServer == nil {
+		activation.Trace = activation.Trace.WithEvent(trace.CheckMICEvent)
+		if ok, err = reqPHY.ValidateMIC(lorawan.AES128Key(dev.AppKey)); err != nil || !ok {
+			return nil, errors.NewErrNotFound("device that validates MIC")
+		}
+	} else if dev.DevEUI.IsEmpty() {
+		return nil, errors.NewErrInvalidArgument("Device", "registration on join is not supported with an external Join Server")
 	}
 
 	if dev.DevEUI.IsEmpty() {
@@ -192,6 +202,34 @@ func (h *handler) HandleActivation(activation *pb_broker.DeduplicatedDeviceActiv
 	}
 	resPHY.MACPayload = joinAccept
 
+	var resBytes []byte
+	if joinServer != nil {
+		ctx = ctx.WithField("JoinServer", joinServer.URL)
+		var appSKey types.AppSKey
+		var nwkSKey types.NwkSKey
+		resBytes, appSKey, nwkSKey, err = h.joinWithJoinServer(joinServer, dev, activation, joinAccept)
+		if err != nil {
+			return nil, err
+		}
+
+		// Update Device
+		dev.StartUpdate()
+		dev.DevAddr = types.DevAddr(joinAccept.DevAddr)
+		dev.AppSKey = appSKey
+		dev.NwkSKey = nwkSKey
+		dev.UsedDevNonces = append(dev.UsedDevNonces, device.DevNonce(reqMAC.DevNonce))
+		dev.DecoderState = ""
+		err = h.devices.Set(dev)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		resBytes, err = h.joinWithAppKey(dev, reqMAC, &resPHY, joinAccept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Publish Activation
 	mqttMetadata, _ := h.getActivationMetadata(ctx, activation, dev)
 	select {
@@ -210,12 +248,26 @@ func (h *handler) HandleActivation(activation *pb_broker.DeduplicatedDeviceActiv
 		ctx.Warnf("Could not emit %q event", types.ActivationEvent)
 	}
 
+	metadata.NwkSKey = &dev.NwkSKey
+	metadata.DevAddr = &dev.DevAddr
+	res = &pb.DeviceActivationResponse{
+		Payload:            resBytes,
+		DownlinkOption:     *activation.ResponseTemplate.DownlinkOption,
+		ActivationMetadata: *activation.ActivationMetadata,
+		Trace:              activation.Trace,
+	}
+
+	return res, nil
+}
+
+// joinWithAppKey generates the AppNonce and session keys using the AppKey and returns the encrypted join-accept
+func (h *handler) joinWithAppKey(dev *device.Device, reqMAC *lorawan.JoinRequestPayload, resPHY *lorawan.PHYPayload, joinAccept *lorawan.JoinAcceptPayload) ([]byte, error) {
 	// Generate random AppNonce
 	var appNonce device.AppNonce
 	for {
 		// NOTE: As DevNonces are only 2 bytes, we will start rejecting those before we run out of AppNonces.
 		// It might just take some time to get one we didn't use yet...
-		alreadyUsed = false
+		alreadyUsed := false
 		random.FillBytes(appNonce[:])
 		for _, usedNonce := range dev.UsedAppNonces {
 			if usedNonce == appNonce {
@@ -242,6 +294,7 @@ func (h *handler) HandleActivation(activation *pb_broker.DeduplicatedDeviceActiv
 	dev.NwkSKey = nwkSKey
 	dev.UsedAppNonces = append(dev.UsedAppNonces, appNonce)
 	dev.UsedDevNonces = append(dev.UsedDevNonces, device.DevNonce(reqMAC.DevNonce))
+	dev.DecoderState = "" // Frames of a previous session can not be reassembled
 	err = h.devices.Set(dev)
 	if err != nil {
 		return nil, err
@@ -254,22 +307,7 @@ func (h *handler) HandleActivation(activation *pb_broker.DeduplicatedDeviceActiv
 		return nil, err
 	}
 
-	var resBytes []byte
-	resBytes, err = resPHY.MarshalBinary()
-	if err != nil {
-		return nil, err
-	}
-
-	metadata.NwkSKey = &dev.NwkSKey
-	metadata.DevAddr = &dev.DevAddr
-	res = &pb.DeviceActivationResponse{
-		Payload:            resBytes,
-		DownlinkOption:     *activation.ResponseTemplate.DownlinkOption,
-		ActivationMetadata: *activation.ActivationMetadata,
-		Trace:              activation.Trace,
-	}
-
-	return res, nil
+	return resPHY.MarshalBinary()
 }
 
 func (h *handler) registerDeviceOnJoin(base *device.Device, activation *pb_broker.DeduplicatedDeviceActivationRequest) (*device.Device, error) {
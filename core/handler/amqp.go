@@ -14,24 +14,24 @@ import (
 // AMQPBufferSize indicates the size for uplink channel buffers
 var AMQPBufferSize = 10
 
-func (h *handler) assertAMQPExchange() error {
+func (h *handler) assertAMQPExchange(exchange string) error {
 	ch, err := h.amqpClient.(*amqp.DefaultClient).GetChannel()
 	if err != nil {
 		return err
 	}
-	err = ch.ExchangeDeclarePassive(h.amqpExchange, "topic", true, false, false, false, nil)
+	err = ch.ExchangeDeclarePassive(exchange, "topic", true, false, false, false, nil)
 	if err != nil {
-		h.Ctx.Warnf("Could not assert presence of AMQP Exchange %s, trying to create...", h.amqpExchange)
+		h.Ctx.Warnf("Could not assert presence of AMQP Exchange %s, trying to create...", exchange)
 		ch, err := h.amqpClient.(*amqp.DefaultClient).GetChannel()
 		if err != nil {
 			return err
 		}
-		err = ch.ExchangeDeclare(h.amqpExchange, "topic", true, false, false, false, nil)
+		err = ch.ExchangeDeclare(exchange, "topic", true, false, false, false, nil)
 		if err != nil {
-			h.Ctx.Errorf("Could not create AMQP Exchange %s.", h.amqpExchange)
+			h.Ctx.Errorf("Could not create AMQP Exchange %s.", exchange)
 			return err
 		}
-		h.Ctx.Infof("Created AMQP Exchange %s", h.amqpExchange)
+		h.Ctx.Infof("Created AMQP Exchange %s", exchange)
 	}
 	return nil
 }
@@ -49,10 +49,16 @@ func (h *handler) HandleAMQP(username, password, host, exchange, downlinkQueue s
 		}
 	}()
 
-	if err := h.assertAMQPExchange(); err != nil {
+	if err := h.assertAMQPExchange(h.amqpExchange); err != nil {
 		return err
 	}
 
+	if h.amqpDeadLetterExchange != "" {
+		if err := h.assertAMQPExchange(h.amqpDeadLetterExchange); err != nil {
+			return err
+		}
+	}
+
 	h.amqpUp = make(chan *types.UplinkMessage, AMQPBufferSize)
 	h.amqpEvent = make(chan *types.DeviceEvent, AMQPBufferSize)
 
@@ -66,8 +72,9 @@ func (h *handler) HandleAMQP(username, password, host, exchange, downlinkQueue s
 			subscriber.Close()
 		}
 	}()
-	err = subscriber.SubscribeDownlink(func(_ amqp.Subscriber, _, _ string, req types.DownlinkMessage) {
-		h.EnqueueDownlink(&req)
+	subscriber.SetDeadLetterExchange(h.amqpDeadLetterExchange)
+	err = subscriber.SubscribeDownlinkChecked(func(_ amqp.Subscriber, _, _ string, req types.DownlinkMessage) error {
+		return h.EnqueueDownlink(&req)
 	})
 	if err != nil {
 		return err
@@ -20,6 +20,14 @@ const (
 	PayloadFormatCustom PayloadFormat = "custom"
 	// PayloadFormatCayenneLPP indicates that the payload is formatted as CayenneLPP
 	PayloadFormatCayenneLPP PayloadFormat = "cayennelpp"
+	// PayloadFormatCayenneLPPPacked indicates that the payload is formatted as packed CayenneLPP, in which the
+	// channel of every value is its index in the payload
+	PayloadFormatCayenneLPPPacked PayloadFormat = "cayennelpp-packed"
+	// PayloadFormatProtobuf indicates that the payload is a Protocol Buffers message
+	PayloadFormatProtobuf PayloadFormat = "protobuf"
+	// PayloadFormatStruct indicates that the payload is a binary struct with the field layout (JSON or YAML) in
+	// the CustomDecoder
+	PayloadFormatStruct PayloadFormat = "struct"
 )
 
 // Application contains the state of an application
@@ -45,9 +53,33 @@ type Application struct {
 	// Returns an object containing the converted values in []byte when the PayloadFormat is
 	// set to PayloadFormatCustom
 	CustomEncoder string `redis:"custom_encoder"`
+	// ProtobufDescriptorSet is a serialized FileDescriptorSet that contains the Protocol Buffers messages when the
+	// PayloadFormat is set to PayloadFormatProtobuf
+	ProtobufDescriptorSet []byte `redis:"protobuf_descriptor_set"`
+	// ProtobufMessages are the full names of the Protocol Buffers messages per FPort when the PayloadFormat is set to
+	// PayloadFormatProtobuf
+	ProtobufMessages map[uint8]string `redis:"protobuf_messages"`
+	// DecoderState enables the per-device state that is passed to the CustomDecoder when the PayloadFormat is set to
+	// PayloadFormatCustom
+	DecoderState bool `redis:"decoder_state"`
+	// PayloadFormatterRevision is the revision of the payload formatter. It is incremented every time that the
+	// payload formatter changes.
+	PayloadFormatterRevision uint64 `redis:"payload_formatter_revision"`
+
+	// TestVectors are run against the payload formatter before it is changed
+	TestVectors []TestVector `redis:"test_vectors"`
+
+	// AttributePayloadFormatters override the payload formatter for devices with specific attributes
+	AttributePayloadFormatters []AttributePayloadFormatter `redis:"attribute_payload_formatters"`
 
 	RegisterOnJoinAccessKey string `redis:"register_on_join_access_key"`
 
+	// Webhooks are the HTTP integrations of the application
+	Webhooks []Webhook `redis:"webhooks"`
+
+	// ClockSync indicates that the Handler answers the clock synchronization requests of the devices
+	ClockSync bool `redis:"clock_sync"`
+
 	CreatedAt time.Time `redis:"created_at"`
 	UpdatedAt time.Time `redis:"updated_at"`
 }
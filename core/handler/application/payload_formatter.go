@@ -0,0 +1,102 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package application
+
+import (
+	"github.com/TheThingsNetwork/ttn/core/handler/binarystruct"
+	"github.com/TheThingsNetwork/ttn/core/handler/protobuf"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// PayloadFormatter is a payload format with its payload functions. It is used for the payload formatter overrides of
+// devices, which are resolved before the payload formatter of the application.
+type PayloadFormatter struct {
+	PayloadFormat   PayloadFormat `json:"payload_format"`
+	CustomDecoder   string        `json:"custom_decoder,omitempty"`
+	CustomConverter string        `json:"custom_converter,omitempty"`
+	CustomValidator string        `json:"custom_validator,omitempty"`
+	CustomEncoder   string        `json:"custom_encoder,omitempty"`
+
+	ProtobufDescriptorSet []byte           `json:"protobuf_descriptor_set,omitempty"`
+	ProtobufMessages      map[uint8]string `json:"protobuf_messages,omitempty"`
+
+	// DecoderState enables the per-device state that is passed to the custom Decoder function
+	DecoderState bool `json:"decoder_state,omitempty"`
+}
+
+// Validate the payload formatter. If the payload format is empty while payload functions are set, it is set to
+// PayloadFormatCustom.
+func (f *PayloadFormatter) Validate() error {
+	if f.PayloadFormat == "" && (f.CustomDecoder != "" || f.CustomConverter != "" || f.CustomValidator != "" || f.CustomEncoder != "") {
+		f.PayloadFormat = PayloadFormatCustom
+	}
+	switch f.PayloadFormat {
+	case PayloadFormatCustom, PayloadFormatCayenneLPP, PayloadFormatCayenneLPPPacked:
+		return nil
+	case PayloadFormatProtobuf:
+		return protobuf.Validate(f.ProtobufDescriptorSet, f.ProtobufMessages)
+	case PayloadFormatStruct:
+		_, err := binarystruct.Parse(f.CustomDecoder)
+		return err
+	case "":
+		return errors.NewErrInvalidArgument("Payload Format", "can not be empty")
+	default:
+		return errors.NewErrInvalidArgument("Payload Format", "unknown")
+	}
+}
+
+// AttributePayloadFormatter overrides the payload formatter of the application for devices that have the attribute
+// with the given value, for example all devices with ttn-model set to a specific model.
+type AttributePayloadFormatter struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+	PayloadFormatter
+}
+
+// Validate the attribute payload formatter
+func (f *AttributePayloadFormatter) Validate() error {
+	if f.Attribute == "" {
+		return errors.NewErrInvalidArgument("Attribute", "can not be empty")
+	}
+	return f.PayloadFormatter.Validate()
+}
+
+// GetPayloadFormatter returns the default payload formatter of the application
+func (a *Application) GetPayloadFormatter() *PayloadFormatter {
+	return &PayloadFormatter{
+		PayloadFormat:   a.PayloadFormat,
+		CustomDecoder:   a.CustomDecoder,
+		CustomConverter: a.CustomConverter,
+		CustomValidator: a.CustomValidator,
+		CustomEncoder:   a.CustomEncoder,
+
+		ProtobufDescriptorSet: a.ProtobufDescriptorSet,
+		ProtobufMessages:      a.ProtobufMessages,
+
+		DecoderState: a.DecoderState,
+	}
+}
+
+// SetPayloadFormatter sets the default payload formatter of the application
+func (a *Application) SetPayloadFormatter(f *PayloadFormatter) {
+	a.PayloadFormat = f.PayloadFormat
+	a.CustomDecoder = f.CustomDecoder
+	a.CustomConverter = f.CustomConverter
+	a.CustomValidator = f.CustomValidator
+	a.CustomEncoder = f.CustomEncoder
+	a.ProtobufDescriptorSet = f.ProtobufDescriptorSet
+	a.ProtobufMessages = f.ProtobufMessages
+	a.DecoderState = f.DecoderState
+}
+
+// GetAttributePayloadFormatter returns the first attribute payload formatter that matches the given device
+// attributes, or nil if none matches
+func (a *Application) GetAttributePayloadFormatter(attributes map[string]string) *AttributePayloadFormatter {
+	for i, formatter := range a.AttributePayloadFormatters {
+		if value, ok := attributes[formatter.Attribute]; ok && value == formatter.Value {
+			return &a.AttributePayloadFormatters[i]
+		}
+	}
+	return nil
+}
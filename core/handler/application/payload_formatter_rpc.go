@@ -0,0 +1,202 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package application
+
+import (
+	"github.com/TheThingsNetwork/ttn/utils/jsoncodec"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+// DeviceIdentifier identifies a device
+type DeviceIdentifier struct {
+	AppID string `json:"app_id"`
+	DevID string `json:"dev_id"`
+}
+
+// DevicePayloadFormatter is the payload formatter override of a device. If the PayloadFormatter is nil, the device
+// uses the payload formatter of the application.
+type DevicePayloadFormatter struct {
+	AppID            string            `json:"app_id"`
+	DevID            string            `json:"dev_id"`
+	PayloadFormatter *PayloadFormatter `json:"payload_formatter,omitempty"`
+}
+
+// ApplicationIdentifier identifies an application
+type ApplicationIdentifier struct {
+	AppID string `json:"app_id"`
+}
+
+// ApplicationPayloadFormatter is the default payload formatter of an application. The Revision is set by the
+// Handler and ignored when setting the payload formatter.
+type ApplicationPayloadFormatter struct {
+	AppID            string            `json:"app_id"`
+	PayloadFormatter *PayloadFormatter `json:"payload_formatter"`
+	Revision         uint64            `json:"revision,omitempty"`
+}
+
+// PayloadFormatterRevisionIdentifier identifies a payload formatter revision of an application
+type PayloadFormatterRevisionIdentifier struct {
+	AppID    string `json:"app_id"`
+	Revision uint64 `json:"revision"`
+}
+
+// PayloadFormatterHistory contains the payload formatter revisions of an application
+type PayloadFormatterHistory struct {
+	AppID     string                      `json:"app_id"`
+	Revisions []*PayloadFormatterRevision `json:"revisions"`
+}
+
+// AttributePayloadFormatters are the payload formatter overrides for device attributes of an application
+type AttributePayloadFormatters struct {
+	AppID      string                      `json:"app_id"`
+	Formatters []AttributePayloadFormatter `json:"formatters"`
+}
+
+// ApplicationTestVectors are the test vectors of the payload formatter of an application
+type ApplicationTestVectors struct {
+	AppID       string       `json:"app_id"`
+	TestVectors []TestVector `json:"test_vectors"`
+}
+
+// Empty is the response of requests that do not return anything
+type Empty struct{}
+
+// PayloadFormatterManagerServer is the server API for the PayloadFormatterManager service
+type PayloadFormatterManagerServer interface {
+	GetApplicationPayloadFormatter(context.Context, *ApplicationIdentifier) (*ApplicationPayloadFormatter, error)
+	SetApplicationPayloadFormatter(context.Context, *ApplicationPayloadFormatter) (*Empty, error)
+	GetDevicePayloadFormatter(context.Context, *DeviceIdentifier) (*DevicePayloadFormatter, error)
+	SetDevicePayloadFormatter(context.Context, *DevicePayloadFormatter) (*Empty, error)
+	GetAttributePayloadFormatters(context.Context, *ApplicationIdentifier) (*AttributePayloadFormatters, error)
+	SetAttributePayloadFormatters(context.Context, *AttributePayloadFormatters) (*Empty, error)
+	GetPayloadFormatterHistory(context.Context, *ApplicationIdentifier) (*PayloadFormatterHistory, error)
+	RollbackPayloadFormatter(context.Context, *PayloadFormatterRevisionIdentifier) (*PayloadFormatterRevisionIdentifier, error)
+	GetTestVectors(context.Context, *ApplicationIdentifier) (*ApplicationTestVectors, error)
+	SetTestVectors(context.Context, *ApplicationTestVectors) (*Empty, error)
+	ResetDecoderState(context.Context, *DeviceIdentifier) (*Empty, error)
+}
+
+// PayloadFormatterManagerClient is the client API for the PayloadFormatterManager service
+type PayloadFormatterManagerClient interface {
+	GetApplicationPayloadFormatter(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*ApplicationPayloadFormatter, error)
+	SetApplicationPayloadFormatter(ctx context.Context, in *ApplicationPayloadFormatter, opts ...grpc.CallOption) (*Empty, error)
+	GetDevicePayloadFormatter(ctx context.Context, in *DeviceIdentifier, opts ...grpc.CallOption) (*DevicePayloadFormatter, error)
+	SetDevicePayloadFormatter(ctx context.Context, in *DevicePayloadFormatter, opts ...grpc.CallOption) (*Empty, error)
+	GetAttributePayloadFormatters(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*AttributePayloadFormatters, error)
+	SetAttributePayloadFormatters(ctx context.Context, in *AttributePayloadFormatters, opts ...grpc.CallOption) (*Empty, error)
+	GetPayloadFormatterHistory(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*PayloadFormatterHistory, error)
+	RollbackPayloadFormatter(ctx context.Context, in *PayloadFormatterRevisionIdentifier, opts ...grpc.CallOption) (*PayloadFormatterRevisionIdentifier, error)
+	GetTestVectors(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*ApplicationTestVectors, error)
+	SetTestVectors(ctx context.Context, in *ApplicationTestVectors, opts ...grpc.CallOption) (*Empty, error)
+	ResetDecoderState(ctx context.Context, in *DeviceIdentifier, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type payloadFormatterManagerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewPayloadFormatterManagerClient returns a new PayloadFormatterManagerClient
+func NewPayloadFormatterManagerClient(cc *grpc.ClientConn) PayloadFormatterManagerClient {
+	return &payloadFormatterManagerClient{cc}
+}
+
+func (c *payloadFormatterManagerClient) invoke(ctx context.Context, method string, in, out interface{}, opts []grpc.CallOption) error {
+	return jsoncodec.Invoke(ctx, c.cc, "/handler.PayloadFormatterManager/"+method, in, out, opts...)
+}
+
+func (c *payloadFormatterManagerClient) GetApplicationPayloadFormatter(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*ApplicationPayloadFormatter, error) {
+	out := new(ApplicationPayloadFormatter)
+	if err := c.invoke(ctx, "GetApplicationPayloadFormatter", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *payloadFormatterManagerClient) SetApplicationPayloadFormatter(ctx context.Context, in *ApplicationPayloadFormatter, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := c.invoke(ctx, "SetApplicationPayloadFormatter", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *payloadFormatterManagerClient) GetDevicePayloadFormatter(ctx context.Context, in *DeviceIdentifier, opts ...grpc.CallOption) (*DevicePayloadFormatter, error) {
+	out := new(DevicePayloadFormatter)
+	if err := c.invoke(ctx, "GetDevicePayloadFormatter", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *payloadFormatterManagerClient) SetDevicePayloadFormatter(ctx context.Context, in *DevicePayloadFormatter, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := c.invoke(ctx, "SetDevicePayloadFormatter", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *payloadFormatterManagerClient) GetAttributePayloadFormatters(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*AttributePayloadFormatters, error) {
+	out := new(AttributePayloadFormatters)
+	if err := c.invoke(ctx, "GetAttributePayloadFormatters", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *payloadFormatterManagerClient) SetAttributePayloadFormatters(ctx context.Context, in *AttributePayloadFormatters, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := c.invoke(ctx, "SetAttributePayloadFormatters", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *payloadFormatterManagerClient) GetPayloadFormatterHistory(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*PayloadFormatterHistory, error) {
+	out := new(PayloadFormatterHistory)
+	if err := c.invoke(ctx, "GetPayloadFormatterHistory", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *payloadFormatterManagerClient) RollbackPayloadFormatter(ctx context.Context, in *PayloadFormatterRevisionIdentifier, opts ...grpc.CallOption) (*PayloadFormatterRevisionIdentifier, error) {
+	out := new(PayloadFormatterRevisionIdentifier)
+	if err := c.invoke(ctx, "RollbackPayloadFormatter", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *payloadFormatterManagerClient) GetTestVectors(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*ApplicationTestVectors, error) {
+	out := new(ApplicationTestVectors)
+	if err := c.invoke(ctx, "GetTestVectors", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *payloadFormatterManagerClient) SetTestVectors(ctx context.Context, in *ApplicationTestVectors, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := c.invoke(ctx, "SetTestVectors", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *payloadFormatterManagerClient) ResetDecoderState(ctx context.Context, in *DeviceIdentifier, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := c.invoke(ctx, "ResetDecoderState", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var payloadFormatterManagerServiceDesc = jsoncodec.ServiceDesc("handler.PayloadFormatterManager", (*PayloadFormatterManagerServer)(nil))
+
+// RegisterPayloadFormatterManagerServer registers the PayloadFormatterManager service
+func RegisterPayloadFormatterManagerServer(s *grpc.Server, srv PayloadFormatterManagerServer) {
+	s.RegisterService(payloadFormatterManagerServiceDesc, srv)
+}
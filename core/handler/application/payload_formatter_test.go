@@ -0,0 +1,46 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package application
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/assertions"
+)
+
+func TestPayloadFormatter(t *testing.T) {
+	a := New(t)
+
+	a.So((&PayloadFormatter{}).Validate(), ShouldNotBeNil)
+	a.So((&PayloadFormatter{PayloadFormat: "unknown"}).Validate(), ShouldNotBeNil)
+	a.So((&PayloadFormatter{PayloadFormat: PayloadFormatCayenneLPP}).Validate(), ShouldBeNil)
+	a.So((&PayloadFormatter{PayloadFormat: PayloadFormatCayenneLPPPacked}).Validate(), ShouldBeNil)
+
+	formatter := &PayloadFormatter{CustomDecoder: `function Decoder (bytes) { return {}; }`}
+	a.So(formatter.Validate(), ShouldBeNil)
+	a.So(formatter.PayloadFormat, ShouldEqual, PayloadFormatCustom)
+
+	a.So((&AttributePayloadFormatter{Value: "v", PayloadFormatter: PayloadFormatter{PayloadFormat: PayloadFormatCayenneLPP}}).Validate(), ShouldNotBeNil)
+	a.So((&AttributePayloadFormatter{Attribute: "ttn-model", Value: "v", PayloadFormatter: PayloadFormatter{PayloadFormat: PayloadFormatCayenneLPP}}).Validate(), ShouldBeNil)
+}
+
+func TestAttributePayloadFormatter(t *testing.T) {
+	a := New(t)
+
+	app := &Application{
+		AppID:         "app",
+		PayloadFormat: PayloadFormatCustom,
+		CustomDecoder: `function Decoder (bytes) { return {}; }`,
+		AttributePayloadFormatters: []AttributePayloadFormatter{
+			{Attribute: "ttn-model", Value: "a", PayloadFormatter: PayloadFormatter{PayloadFormat: PayloadFormatCayenneLPP}},
+			{Attribute: "ttn-model", Value: "b", PayloadFormatter: PayloadFormatter{PayloadFormat: PayloadFormatCustom}},
+		},
+	}
+
+	a.So(app.GetPayloadFormatter().CustomDecoder, ShouldEqual, app.CustomDecoder)
+	a.So(app.GetAttributePayloadFormatter(nil), ShouldBeNil)
+	a.So(app.GetAttributePayloadFormatter(map[string]string{"ttn-model": "c"}), ShouldBeNil)
+	a.So(app.GetAttributePayloadFormatter(map[string]string{"ttn-model": "a"}).PayloadFormat, ShouldEqual, PayloadFormatCayenneLPP)
+	a.So(app.GetAttributePayloadFormatter(map[string]string{"ttn-model": "b"}).PayloadFormat, ShouldEqual, PayloadFormatCustom)
+}
@@ -0,0 +1,214 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package application
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/storage"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"gopkg.in/redis.v5"
+)
+
+// PayloadFormatterRevision is a revision of the payload formatter of an application
+type PayloadFormatterRevision struct {
+	AppID    string `redis:"app_id" json:"app_id"`
+	Revision uint64 `redis:"revision" json:"revision"`
+	// Hash is the hash of the content of the PayloadFormatter
+	Hash string `redis:"hash" json:"hash"`
+	// Author is the user or client that set the payload formatter
+	Author           string            `redis:"author" json:"author,omitempty"`
+	PayloadFormatter *PayloadFormatter `redis:"payload_formatter" json:"payload_formatter"`
+	CreatedAt        time.Time         `redis:"created_at" json:"created_at"`
+}
+
+// Hash returns the hex-encoded SHA-256 hash of the content of the payload formatter
+func (f *PayloadFormatter) Hash() string {
+	content, _ := json.Marshal(f)
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// payloadFormatterFields are the fields of the Application that make up its payload formatter
+var payloadFormatterFields = []string{
+	"PayloadFormat",
+	"CustomDecoder",
+	"CustomConverter",
+	"CustomValidator",
+	"CustomEncoder",
+	"ProtobufDescriptorSet",
+	"ProtobufMessages",
+	"DecoderState",
+}
+
+// PayloadFormatterChanged returns true if the payload formatter of the application changed since the last call to
+// StartUpdate
+func (a Application) PayloadFormatterChanged() bool {
+	for _, changed := range a.ChangedFields() {
+		for _, field := range payloadFormatterFields {
+			if changed == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewPayloadFormatterRevision sets the payload formatter revision of the application to the given revision, and
+// returns that revision
+func (a *Application) NewPayloadFormatterRevision(revision uint64, author string) *PayloadFormatterRevision {
+	formatter := a.GetPayloadFormatter()
+	a.PayloadFormatterRevision = revision
+	return &PayloadFormatterRevision{
+		AppID:            a.AppID,
+		Revision:         revision,
+		Hash:             formatter.Hash(),
+		Author:           author,
+		PayloadFormatter: formatter,
+	}
+}
+
+// PreviousPayloadFormatter returns the payload formatter of the application before the last call to StartUpdate if
+// it has no revision yet, so that a payload formatter that was set before revisions were kept is not lost. It returns
+// nil if the application already has a revision or did not have a payload formatter.
+func (a *Application) PreviousPayloadFormatter() *PayloadFormatter {
+	if a.old == nil || a.old.PayloadFormatterRevision != 0 {
+		return nil
+	}
+	formatter := a.old.GetPayloadFormatter()
+	if formatter.Hash() == new(PayloadFormatter).Hash() {
+		return nil
+	}
+	return formatter
+}
+
+// RevisionStore interface for PayloadFormatterRevisions
+type RevisionStore interface {
+	List(appID string) ([]*PayloadFormatterRevision, error)
+	Get(appID string, revision uint64) (*PayloadFormatterRevision, error)
+	Next(appID string) (uint64, error)
+	Add(new *PayloadFormatterRevision) error
+	DeleteAll(appID string) error
+}
+
+// MaxRevisions is the number of payload formatter revisions that is kept per application
+const MaxRevisions = 32
+
+const redisRevisionPrefix = "payload-formatter-revision"
+const redisRevisionCounterPrefix = "payload-formatter-revision-counter"
+
+// NewRedisRevisionStore creates a new Redis-based PayloadFormatterRevision store
+// if an empty prefix is passed, a default prefix will be used.
+func NewRedisRevisionStore(client *redis.Client, prefix string) RevisionStore {
+	if prefix == "" {
+		prefix = defaultRedisPrefix
+	}
+	store := storage.NewRedisMapStore(client, prefix+":"+redisRevisionPrefix)
+	store.SetBase(PayloadFormatterRevision{}, "")
+	return &RedisRevisionStore{
+		client: client,
+		prefix: prefix,
+		store:  store,
+	}
+}
+
+// RedisRevisionStore stores PayloadFormatterRevisions in Redis.
+// - Revisions are stored as a Hash
+// - Only the last MaxRevisions revisions of an application are kept
+// - The last revision number of an application is stored as a counter
+type RedisRevisionStore struct {
+	client *redis.Client
+	prefix string
+	store  *storage.RedisMapStore
+}
+
+func (s *RedisRevisionStore) counterKey(appID string) string {
+	return fmt.Sprintf("%s:%s:%s", s.prefix, redisRevisionCounterPrefix, appID)
+}
+
+func (s *RedisRevisionStore) key(appID string, revision uint64) string {
+	return fmt.Sprintf("%s:%d", appID, revision)
+}
+
+// List the revisions of an Application, ordered by revision
+func (s *RedisRevisionStore) List(appID string) ([]*PayloadFormatterRevision, error) {
+	revisionsI, err := s.store.List(appID+":*", nil)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]*PayloadFormatterRevision, 0, len(revisionsI))
+	for _, revisionI := range revisionsI {
+		if revision, ok := revisionI.(PayloadFormatterRevision); ok {
+			revisions = append(revisions, &revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// Get a specific revision
+func (s *RedisRevisionStore) Get(appID string, revision uint64) (*PayloadFormatterRevision, error) {
+	revisionI, err := s.store.Get(s.key(appID, revision))
+	if err != nil {
+		return nil, err
+	}
+	if revision, ok := revisionI.(PayloadFormatterRevision); ok {
+		return &revision, nil
+	}
+	return nil, errors.New("Database did not return a PayloadFormatterRevision")
+}
+
+// Next allocates the next revision number of an Application
+func (s *RedisRevisionStore) Next(appID string) (uint64, error) {
+	revision, err := s.client.Incr(s.counterKey(appID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(revision), nil
+}
+
+// Add a new revision, deleting the revisions that are older than MaxRevisions
+func (s *RedisRevisionStore) Add(new *PayloadFormatterRevision) error {
+	new.CreatedAt = time.Now()
+	if err := s.store.Set(s.key(new.AppID, new.Revision), *new); err != nil {
+		return err
+	}
+	if new.Revision <= MaxRevisions {
+		return nil
+	}
+	revisions, err := s.List(new.AppID)
+	if err != nil {
+		return err
+	}
+	for _, revision := range revisions {
+		if revision.Revision > new.Revision-MaxRevisions {
+			break
+		}
+		if err := s.store.Delete(s.key(revision.AppID, revision.Revision)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteAll deletes all revisions of an Application
+func (s *RedisRevisionStore) DeleteAll(appID string) error {
+	revisions, err := s.List(appID)
+	if err != nil {
+		return err
+	}
+	for _, revision := range revisions {
+		if err := s.store.Delete(s.key(revision.AppID, revision.Revision)); err != nil {
+			return err
+		}
+	}
+	return s.client.Del(s.counterKey(appID)).Err()
+}
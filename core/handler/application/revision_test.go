@@ -0,0 +1,109 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package application
+
+import (
+	"testing"
+
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestPayloadFormatterRevision(t *testing.T) {
+	a := New(t)
+
+	app := &Application{
+		AppID:         "test",
+		PayloadFormat: PayloadFormatCustom,
+		CustomDecoder: `function Decoder (bytes) { return {}; }`,
+	}
+
+	app.StartUpdate()
+	app.ClockSync = true
+	a.So(app.PayloadFormatterChanged(), ShouldBeFalse)
+
+	app.StartUpdate()
+	app.CustomDecoder = `function Decoder (bytes) { return { size: bytes.length }; }`
+	a.So(app.PayloadFormatterChanged(), ShouldBeTrue)
+
+	first := app.NewPayloadFormatterRevision(1, "alice")
+	a.So(first.Revision, ShouldEqual, 1)
+	a.So(app.PayloadFormatterRevision, ShouldEqual, 1)
+	a.So(first.Author, ShouldEqual, "alice")
+	a.So(first.PayloadFormatter.CustomDecoder, ShouldEqual, app.CustomDecoder)
+	a.So(first.Hash, ShouldHaveLength, 64)
+
+	second := app.NewPayloadFormatterRevision(2, "bob")
+	a.So(second.Revision, ShouldEqual, 2)
+	a.So(second.Hash, ShouldEqual, first.Hash)
+
+	app.CustomEncoder = `function Encoder (object) { return []; }`
+	a.So(app.NewPayloadFormatterRevision(3, "bob").Hash, ShouldNotEqual, first.Hash)
+}
+
+func TestPreviousPayloadFormatter(t *testing.T) {
+	a := New(t)
+
+	app := &Application{AppID: "test"}
+	app.StartUpdate()
+	app.PayloadFormat = PayloadFormatCayenneLPP
+	a.So(app.PreviousPayloadFormatter(), ShouldBeNil)
+
+	app = &Application{
+		AppID:         "test",
+		PayloadFormat: PayloadFormatCustom,
+		CustomDecoder: `function Decoder (bytes) { return {}; }`,
+	}
+	app.StartUpdate()
+	app.PayloadFormat = PayloadFormatCayenneLPP
+	previous := app.PreviousPayloadFormatter()
+	a.So(previous, ShouldNotBeNil)
+	a.So(previous.PayloadFormat, ShouldEqual, PayloadFormatCustom)
+	a.So(previous.CustomDecoder, ShouldEqual, app.CustomDecoder)
+
+	app.NewPayloadFormatterRevision(1, "alice")
+	app.StartUpdate()
+	app.PayloadFormat = PayloadFormatCustom
+	a.So(app.PreviousPayloadFormatter(), ShouldBeNil)
+}
+
+func TestRevisionStore(t *testing.T) {
+	a := New(t)
+
+	s := NewRedisRevisionStore(GetRedisClient(), "handler-test-revision-store")
+	defer s.DeleteAll("test")
+
+	_, err := s.Get("test", 1)
+	a.So(err, ShouldNotBeNil)
+
+	app := &Application{AppID: "test", PayloadFormat: PayloadFormatCayenneLPP}
+	for i := 0; i < MaxRevisions+2; i++ {
+		revision, err := s.Next("test")
+		a.So(err, ShouldBeNil)
+		a.So(revision, ShouldEqual, i+1)
+		err = s.Add(app.NewPayloadFormatterRevision(revision, "alice"))
+		a.So(err, ShouldBeNil)
+	}
+
+	revisions, err := s.List("test")
+	a.So(err, ShouldBeNil)
+	a.So(revisions, ShouldHaveLength, MaxRevisions)
+	a.So(revisions[0].Revision, ShouldEqual, 3)
+	a.So(revisions[MaxRevisions-1].Revision, ShouldEqual, MaxRevisions+2)
+
+	revision, err := s.Get("test", MaxRevisions+2)
+	a.So(err, ShouldBeNil)
+	a.So(revision.Author, ShouldEqual, "alice")
+	a.So(revision.PayloadFormatter.PayloadFormat, ShouldEqual, PayloadFormatCayenneLPP)
+	a.So(revision.CreatedAt.IsZero(), ShouldBeFalse)
+
+	err = s.DeleteAll("test")
+	a.So(err, ShouldBeNil)
+	revisions, err = s.List("test")
+	a.So(err, ShouldBeNil)
+	a.So(revisions, ShouldBeEmpty)
+	next, err := s.Next("test")
+	a.So(err, ShouldBeNil)
+	a.So(next, ShouldEqual, 1)
+}
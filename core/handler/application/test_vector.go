@@ -0,0 +1,53 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package application
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// TestVector is a test of the payload formatter of an application. An uplink test vector decodes the Payload and
+// expects the Fields. A downlink test vector encodes the Fields and expects the Payload.
+type TestVector struct {
+	Name     string                 `json:"name,omitempty"`
+	Downlink bool                   `json:"downlink,omitempty"`
+	FPort    uint8                  `json:"port"`
+	Payload  string                 `json:"payload"`
+	Fields   map[string]interface{} `json:"fields"`
+
+	// Metadata is passed to the uplink payload functions
+	Metadata *UplinkMetadata `json:"metadata,omitempty"`
+}
+
+// String returns the name of the test vector, or a description if it has no name
+func (v TestVector) String() string {
+	if v.Name != "" {
+		return v.Name
+	}
+	direction := "uplink"
+	if v.Downlink {
+		direction = "downlink"
+	}
+	return fmt.Sprintf("%s %s on port %d", direction, v.Payload, v.FPort)
+}
+
+// PayloadBytes returns the hex-encoded Payload as bytes. Spaces in the Payload are ignored.
+func (v TestVector) PayloadBytes() ([]byte, error) {
+	return hex.DecodeString(strings.Replace(v.Payload, " ", "", -1))
+}
+
+// Validate the test vector
+func (v TestVector) Validate() error {
+	if _, err := v.PayloadBytes(); err != nil {
+		return errors.NewErrInvalidArgument(fmt.Sprintf("Test vector %s payload", v), err.Error())
+	}
+	if v.Downlink && len(v.Fields) == 0 {
+		return errors.NewErrInvalidArgument(fmt.Sprintf("Test vector %s fields", v), "can not be empty for downlink")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package application
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/assertions"
+)
+
+func TestTestVector(t *testing.T) {
+	a := New(t)
+
+	vector := TestVector{FPort: 1, Payload: "01 02"}
+	a.So(vector.Validate(), ShouldBeNil)
+	a.So(vector.String(), ShouldEqual, "uplink 01 02 on port 1")
+	payload, err := vector.PayloadBytes()
+	a.So(err, ShouldBeNil)
+	a.So(payload, ShouldResemble, []byte{0x01, 0x02})
+
+	vector.Name = "test"
+	a.So(vector.String(), ShouldEqual, "test")
+
+	a.So(TestVector{Payload: "zz"}.Validate(), ShouldNotBeNil)
+	a.So(TestVector{Downlink: true, Payload: "01"}.Validate(), ShouldNotBeNil)
+	a.So(TestVector{Downlink: true, Payload: "01", Fields: map[string]interface{}{"led": true}}.Validate(), ShouldBeNil)
+}
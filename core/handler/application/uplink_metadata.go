@@ -0,0 +1,36 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package application
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// UplinkMetadata is the metadata of an uplink message that is passed to the uplink payload functions
+type UplinkMetadata struct {
+	AppID          string            `json:"app_id"`
+	DevID          string            `json:"dev_id,omitempty"`
+	HardwareSerial string            `json:"hardware_serial,omitempty"`
+	FCnt           uint32            `json:"counter"`
+	Confirmed      bool              `json:"confirmed"`
+	Time           time.Time         `json:"time"`
+	Frequency      float32           `json:"frequency,omitempty"`
+	DataRate       string            `json:"data_rate,omitempty"`
+	Gateways       int               `json:"gateways"`
+	Attributes     map[string]string `json:"attributes"`
+}
+
+// JSON returns the JSON encoding of the metadata. A nil metadata is encoded as empty metadata.
+func (m *UplinkMetadata) JSON() (string, error) {
+	metadata := UplinkMetadata{}
+	if m != nil {
+		metadata = *m
+	}
+	if metadata.Attributes == nil {
+		metadata.Attributes = make(map[string]string)
+	}
+	b, err := json.Marshal(metadata)
+	return string(b), err
+}
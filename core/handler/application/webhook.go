@@ -0,0 +1,84 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package application
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// Webhook is an HTTP integration of an application. Messages are sent to the BaseURL joined with the path of the
+// message type; message types without a path are not sent.
+type Webhook struct {
+	WebhookID string `json:"webhook_id"`
+	BaseURL   string `json:"base_url"`
+	// Headers that are added to the requests, for example for authentication
+	Headers         []WebhookHeader `json:"headers,omitempty"`
+	UplinkPath      string          `json:"uplink_path,omitempty"`
+	ActivationsPath string          `json:"activations_path,omitempty"`
+	EventsPath      string          `json:"events_path,omitempty"`
+	// DownlinkKey authenticates downlink messages that are sent to the Handler for this webhook
+	DownlinkKey string `json:"downlink_key,omitempty"`
+}
+
+// WebhookHeader is an HTTP header that is added to the requests to a webhook
+type WebhookHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Validate the webhook
+func (w Webhook) Validate() error {
+	if err := api.NotEmptyAndValidID(w.WebhookID, "Webhook ID"); err != nil {
+		return err
+	}
+	base, err := url.Parse(w.BaseURL)
+	if err != nil {
+		return errors.NewErrInvalidArgument("Base URL", err.Error())
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return errors.NewErrInvalidArgument("Base URL", "scheme must be http or https")
+	}
+	return nil
+}
+
+// URL returns the URL for the given path
+func (w Webhook) URL(path string) string {
+	return strings.TrimSuffix(w.BaseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// GetWebhook returns the webhook with the given ID, or nil if it does not exist
+func (a *Application) GetWebhook(webhookID string) *Webhook {
+	for i, webhook := range a.Webhooks {
+		if webhook.WebhookID == webhookID {
+			return &a.Webhooks[i]
+		}
+	}
+	return nil
+}
+
+// SetWebhook adds the webhook, or replaces the webhook with the same ID
+func (a *Application) SetWebhook(webhook Webhook) {
+	webhooks := make([]Webhook, 0, len(a.Webhooks)+1)
+	for _, existing := range a.Webhooks {
+		if existing.WebhookID != webhook.WebhookID {
+			webhooks = append(webhooks, existing)
+		}
+	}
+	a.Webhooks = append(webhooks, webhook)
+}
+
+// DeleteWebhook deletes the webhook with the given ID
+func (a *Application) DeleteWebhook(webhookID string) {
+	webhooks := make([]Webhook, 0, len(a.Webhooks))
+	for _, existing := range a.Webhooks {
+		if existing.WebhookID != webhookID {
+			webhooks = append(webhooks, existing)
+		}
+	}
+	a.Webhooks = webhooks
+}
@@ -0,0 +1,32 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package application
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/assertions"
+)
+
+func TestWebhooks(t *testing.T) {
+	a := New(t)
+
+	app := &Application{AppID: "app"}
+	app.SetWebhook(Webhook{WebhookID: "hook-a", BaseURL: "https://a.example.com"})
+	app.SetWebhook(Webhook{WebhookID: "hook-b", BaseURL: "https://b.example.com"})
+	app.StartUpdate()
+	app.SetWebhook(Webhook{WebhookID: "hook-a", BaseURL: "https://c.example.com"})
+	a.So(app.Webhooks, ShouldHaveLength, 2)
+	a.So(app.GetWebhook("hook-a").BaseURL, ShouldEqual, "https://c.example.com")
+	a.So(app.ChangedFields(), ShouldContain, "Webhooks")
+
+	app.DeleteWebhook("hook-a")
+	a.So(app.GetWebhook("hook-a"), ShouldBeNil)
+	a.So(app.Webhooks, ShouldHaveLength, 1)
+
+	a.So(Webhook{WebhookID: "hook-a", BaseURL: "ftp://example.com"}.Validate(), ShouldNotBeNil)
+	a.So(Webhook{WebhookID: "Hook-A", BaseURL: "https://example.com"}.Validate(), ShouldNotBeNil)
+	a.So(Webhook{WebhookID: "hook-a", BaseURL: "https://example.com"}.Validate(), ShouldBeNil)
+	a.So(Webhook{BaseURL: "https://example.com/"}.URL("/up"), ShouldEqual, "https://example.com/up")
+}
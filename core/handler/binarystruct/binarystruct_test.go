@@ -0,0 +1,157 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package binarystruct
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/assertions"
+)
+
+const testLayout = `
+ports:
+  1:
+    - name: temperature
+      offset: 0
+      length: 2
+      signed: true
+      scale: 0.01
+      unit: "°C"
+    - name: humidity
+      offset: 2
+      length: 1
+      scale: 0.5
+    - name: battery
+      offset: 3
+      length: 2
+      endianness: little
+    - name: state
+      offset: 5
+      length: 1
+      enum:
+        0: idle
+        1: moving
+  2:
+    - name: pressure
+      type: float
+      offset: 0
+      length: 4
+`
+
+func TestParse(t *testing.T) {
+	a := New(t)
+
+	layout, err := Parse(testLayout)
+	a.So(err, ShouldBeNil)
+	a.So(layout.Ports, ShouldHaveLength, 2)
+	a.So(layout.Size(1), ShouldEqual, 6)
+	a.So(layout.Size(3), ShouldEqual, 0)
+
+	layout, err = Parse(`{"ports":{"1":[{"name":"counter","offset":0,"length":4}]}}`)
+	a.So(err, ShouldBeNil)
+	a.So(layout.Ports[1][0].Name, ShouldEqual, "counter")
+
+	for _, invalid := range []string{
+		``,
+		`ports: {}`,
+		`{"ports":`,
+		`ports: {0: [{name: a, offset: 0, length: 1}]}`,
+		`ports: {1: []}`,
+		`ports: {1: [{offset: 0, length: 1}]}`,
+		`ports: {1: [{name: a, offset: -1, length: 1}]}`,
+		`ports: {1: [{name: a, offset: 0, length: 0}]}`,
+		`ports: {1: [{name: a, offset: 0, length: 9}]}`,
+		`ports: {1: [{name: a, type: float, offset: 0, length: 2}]}`,
+		`ports: {1: [{name: a, type: string, offset: 0, length: 2}]}`,
+		`ports: {1: [{name: a, offset: 0, length: 2, endianness: middle}]}`,
+		`ports: {1: [{name: a, offset: 0, length: 2}, {name: a, offset: 2, length: 2}]}`,
+		`ports: {1: [{name: a, offset: 0, length: 2}, {name: b, offset: 1, length: 2}]}`,
+	} {
+		_, err := Parse(invalid)
+		a.So(err, ShouldNotBeNil)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	a := New(t)
+	decoder := &Decoder{Layout: testLayout}
+
+	fields, valid, err := decoder.Decode([]byte{0xff, 0x38, 0x50, 0x10, 0x0e, 0x01}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(fields["temperature"], ShouldResemble, map[string]interface{}{"value": -2.0, "unit": "°C"})
+	a.So(fields["humidity"], ShouldEqual, 40.0)
+	a.So(fields["battery"], ShouldEqual, uint64(3600))
+	a.So(fields["state"], ShouldEqual, "moving")
+
+	// Unknown enum value
+	fields, _, err = decoder.Decode([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x07}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(fields["state"], ShouldEqual, int64(7))
+
+	fields, _, err = decoder.Decode([]byte{0x44, 0x86, 0x30, 0x00}, 2)
+	a.So(err, ShouldBeNil)
+	a.So(fields["pressure"], ShouldEqual, 1073.5)
+
+	// Too short
+	_, _, err = decoder.Decode([]byte{0xff, 0x38}, 1)
+	a.So(err, ShouldNotBeNil)
+
+	// Port without layout
+	fields, valid, err = decoder.Decode([]byte{0x01}, 3)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(fields, ShouldBeNil)
+
+	// Invalid layout
+	_, _, err = (&Decoder{Layout: "ports: {}"}).Decode([]byte{0x01}, 1)
+	a.So(err, ShouldNotBeNil)
+}
+
+func TestEncode(t *testing.T) {
+	a := New(t)
+	encoder := &Encoder{Layout: testLayout}
+
+	payload, valid, err := encoder.Encode(map[string]interface{}{
+		"temperature": map[string]interface{}{"value": -2.0, "unit": "°C"},
+		"humidity":    40.0,
+		"battery":     3600,
+		"state":       "moving",
+	}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(payload, ShouldResemble, []byte{0xff, 0x38, 0x50, 0x10, 0x0e, 0x01})
+
+	payload, _, err = encoder.Encode(map[string]interface{}{"pressure": 1073.5}, 2)
+	a.So(err, ShouldBeNil)
+	a.So(payload, ShouldResemble, []byte{0x44, 0x86, 0x30, 0x00})
+
+	// Missing field
+	_, _, err = encoder.Encode(map[string]interface{}{"temperature": 20.0}, 1)
+	a.So(err, ShouldNotBeNil)
+
+	// Out of range
+	_, _, err = encoder.Encode(map[string]interface{}{"temperature": 0, "humidity": 200.0, "battery": 0, "state": 0}, 1)
+	a.So(err, ShouldNotBeNil)
+	_, _, err = encoder.Encode(map[string]interface{}{"temperature": 0, "humidity": 0, "battery": -1, "state": 0}, 1)
+	a.So(err, ShouldNotBeNil)
+
+	// Unknown enum value
+	_, _, err = encoder.Encode(map[string]interface{}{"temperature": 0, "humidity": 0, "battery": 0, "state": "flying"}, 1)
+	a.So(err, ShouldNotBeNil)
+
+	// Port without layout
+	_, _, err = encoder.Encode(map[string]interface{}{}, 3)
+	a.So(err, ShouldNotBeNil)
+}
+
+func TestRoundTrip(t *testing.T) {
+	a := New(t)
+	payload := []byte{0x09, 0xc4, 0x64, 0xff, 0xff, 0x00}
+	fields, _, err := (&Decoder{Layout: testLayout}).Decode(payload, 1)
+	a.So(err, ShouldBeNil)
+	encoded, _, err := (&Encoder{Layout: testLayout}).Encode(fields, 1)
+	a.So(err, ShouldBeNil)
+	a.So(encoded, ShouldResemble, payload)
+}
@@ -0,0 +1,100 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package binarystruct
+
+import (
+	"fmt"
+	"math"
+
+	pb_handler "github.com/TheThingsNetwork/api/handler"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// Decoder is a binary struct PayloadDecoder
+type Decoder struct {
+	// Layout is the layout in JSON or YAML
+	Layout string
+}
+
+// Decode decodes the payload to fields according to the layout of the port. Fields with a unit are decoded to an
+// object with the value and the unit. Payload on ports without layout is not decoded.
+func (d *Decoder) Decode(payload []byte, fPort uint8) (map[string]interface{}, bool, error) {
+	layout, err := parseCached(d.Layout)
+	if err != nil {
+		return nil, false, err
+	}
+	fields, ok := layout.Ports[fPort]
+	if !ok {
+		return nil, true, nil
+	}
+	if size := layout.Size(fPort); len(payload) < size {
+		return nil, false, errors.NewErrInvalidArgument("Payload", fmt.Sprintf("expected at least %d bytes, got %d", size, len(payload)))
+	}
+
+	decoded := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value := field.decode(payload[field.Offset : field.Offset+field.Length])
+		if field.Unit != "" {
+			value = map[string]interface{}{
+				"value": value,
+				"unit":  field.Unit,
+			}
+		}
+		decoded[field.Name] = value
+	}
+	return decoded, true, nil
+}
+
+func (f Field) decode(b []byte) interface{} {
+	raw := readUint(b, f.Endianness)
+	if f.Type == FieldTypeFloat {
+		var value float64
+		if f.Length == 4 {
+			value = float64(math.Float32frombits(uint32(raw)))
+		} else {
+			value = math.Float64frombits(raw)
+		}
+		return value*f.scale() + f.ValueOffset
+	}
+
+	var value int64
+	if f.Signed {
+		shift := uint(64 - 8*f.Length)
+		value = int64(raw<<shift) >> shift
+	} else {
+		value = int64(raw)
+	}
+	if len(f.Enum) > 0 {
+		if name, ok := f.Enum[value]; ok {
+			return name
+		}
+		return value
+	}
+	if f.plain() {
+		if !f.Signed {
+			return raw
+		}
+		return value
+	}
+	if !f.Signed {
+		return float64(raw)*f.scale() + f.ValueOffset
+	}
+	return float64(value)*f.scale() + f.ValueOffset
+}
+
+func readUint(b []byte, endianness Endianness) (raw uint64) {
+	for i := range b {
+		if endianness == LittleEndian {
+			raw |= uint64(b[i]) << (8 * uint(i))
+		} else {
+			raw = raw<<8 | uint64(b[i])
+		}
+	}
+	return
+}
+
+// Log returns the log
+func (d *Decoder) Log() []*pb_handler.LogEntry {
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package binarystruct
+
+import (
+	"fmt"
+	"math"
+
+	pb_handler "github.com/TheThingsNetwork/api/handler"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// Encoder is a binary struct PayloadEncoder
+type Encoder struct {
+	// Layout is the layout in JSON or YAML
+	Layout string
+}
+
+// Encode encodes the fields to a payload according to the layout of the port. All fields of the layout are required.
+// Values can also be given as object with a value, as they are decoded for fields with a unit.
+func (e *Encoder) Encode(values map[string]interface{}, fPort uint8) ([]byte, bool, error) {
+	layout, err := parseCached(e.Layout)
+	if err != nil {
+		return nil, false, err
+	}
+	fields, ok := layout.Ports[fPort]
+	if !ok {
+		return nil, false, errors.NewErrInvalidArgument("FPort", fmt.Sprintf("no struct layout for port %d", fPort))
+	}
+
+	payload := make([]byte, layout.Size(fPort))
+	for _, field := range fields {
+		value, ok := values[field.Name]
+		if !ok {
+			return nil, false, errors.NewErrInvalidArgument("Fields", fmt.Sprintf("missing field %s", field.Name))
+		}
+		if obj, ok := value.(map[string]interface{}); ok {
+			value = obj["value"]
+		}
+		raw, err := field.encode(value)
+		if err != nil {
+			return nil, false, errors.NewErrInvalidArgument("Fields", err.Error())
+		}
+		writeUint(payload[field.Offset:field.Offset+field.Length], field.Endianness, raw)
+	}
+	return payload, true, nil
+}
+
+func (f Field) encode(value interface{}) (uint64, error) {
+	var number float64
+	switch value := value.(type) {
+	case string:
+		for raw, name := range f.Enum {
+			if name == value {
+				return f.truncate(raw)
+			}
+		}
+		return 0, fmt.Errorf("field %s has no enum value %s", f.Name, value)
+	case bool:
+		if value {
+			number = 1
+		}
+	case float64:
+		number = value
+	case float32:
+		number = float64(value)
+	case int:
+		if f.plain() {
+			return f.truncate(int64(value))
+		}
+		number = float64(value)
+	case int64:
+		if f.plain() {
+			return f.truncate(value)
+		}
+		number = float64(value)
+	case uint64:
+		if f.plain() {
+			return f.truncate(int64(value))
+		}
+		number = float64(value)
+	default:
+		return 0, fmt.Errorf("field %s has invalid value %v", f.Name, value)
+	}
+
+	number = (number - f.ValueOffset) / f.scale()
+	if f.Type == FieldTypeFloat {
+		if f.Length == 4 {
+			return uint64(math.Float32bits(float32(number))), nil
+		}
+		return math.Float64bits(number), nil
+	}
+	return f.truncate(int64(math.Round(number)))
+}
+
+// truncate checks that the value fits in the field and returns the raw value
+func (f Field) truncate(value int64) (uint64, error) {
+	bits := uint(8 * f.Length)
+	if f.Signed {
+		if bits < 64 && (value < -(1<<(bits-1)) || value >= 1<<(bits-1)) {
+			return 0, fmt.Errorf("field %s value %d out of range", f.Name, value)
+		}
+		if bits == 64 {
+			return uint64(value), nil
+		}
+		return uint64(value) & (1<<bits - 1), nil
+	}
+	if value < 0 || (bits < 64 && value >= 1<<bits) {
+		return 0, fmt.Errorf("field %s value %d out of range", f.Name, value)
+	}
+	return uint64(value), nil
+}
+
+func writeUint(b []byte, endianness Endianness, raw uint64) {
+	for i := range b {
+		if endianness == LittleEndian {
+			b[i] = byte(raw >> (8 * uint(i)))
+		} else {
+			b[len(b)-1-i] = byte(raw >> (8 * uint(i)))
+		}
+	}
+}
+
+// Log returns the log
+func (e *Encoder) Log() []*pb_handler.LogEntry {
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package binarystruct
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/bluele/gcache"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// layoutCacheSize is the number of parsed layouts that is kept in memory
+const layoutCacheSize = 128
+
+var layoutCache = gcache.New(layoutCacheSize).LRU().Build()
+
+// FieldType is the type of a field
+type FieldType string
+
+const (
+	// FieldTypeInt is an integer field of 1 to 8 bytes (default)
+	FieldTypeInt FieldType = "int"
+	// FieldTypeFloat is an IEEE 754 floating point field of 4 or 8 bytes
+	FieldTypeFloat FieldType = "float"
+)
+
+// Endianness is the byte order of a field
+type Endianness string
+
+const (
+	// BigEndian is the big-endian byte order (default)
+	BigEndian Endianness = "big"
+	// LittleEndian is the little-endian byte order
+	LittleEndian Endianness = "little"
+)
+
+// Field is a field in the binary payload. Its value is calculated as raw * scale + value_offset, or looked up in the
+// enum.
+type Field struct {
+	Name        string           `json:"name" yaml:"name"`
+	Type        FieldType        `json:"type,omitempty" yaml:"type,omitempty"`
+	Offset      int              `json:"offset" yaml:"offset"`
+	Length      int              `json:"length" yaml:"length"`
+	Signed      bool             `json:"signed,omitempty" yaml:"signed,omitempty"`
+	Endianness  Endianness       `json:"endianness,omitempty" yaml:"endianness,omitempty"`
+	Scale       float64          `json:"scale,omitempty" yaml:"scale,omitempty"`
+	ValueOffset float64          `json:"value_offset,omitempty" yaml:"value_offset,omitempty"`
+	Unit        string           `json:"unit,omitempty" yaml:"unit,omitempty"`
+	Enum        map[int64]string `json:"enum,omitempty" yaml:"enum,omitempty"`
+}
+
+// Layout is the layout of the binary payload per FPort
+type Layout struct {
+	Ports map[uint8][]Field `json:"ports" yaml:"ports"`
+}
+
+// Parse parses and validates a layout in JSON or YAML
+func Parse(text string) (*Layout, error) {
+	layout := new(Layout)
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(text), "{") {
+		err = json.Unmarshal([]byte(text), layout)
+	} else {
+		err = yaml.Unmarshal([]byte(text), layout)
+	}
+	if err != nil {
+		return nil, errors.NewErrInvalidArgument("Struct Layout", err.Error())
+	}
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+// parseCached parses a layout, using the cache of parsed layouts
+func parseCached(text string) (*Layout, error) {
+	key := sha256.Sum256([]byte(text))
+	if layout, err := layoutCache.Get(key); err == nil {
+		return layout.(*Layout), nil
+	}
+	layout, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	layoutCache.Set(key, layout)
+	return layout, nil
+}
+
+// Validate the layout
+func (l *Layout) Validate() error {
+	if len(l.Ports) == 0 {
+		return errors.NewErrInvalidArgument("Struct Layout", "must contain at least one port")
+	}
+	for port, fields := range l.Ports {
+		if port == 0 {
+			return errors.NewErrInvalidArgument("Struct Layout", "FPort 0 is reserved for MAC commands")
+		}
+		if len(fields) == 0 {
+			return errors.NewErrInvalidArgument("Struct Layout", fmt.Sprintf("port %d has no fields", port))
+		}
+		names := make(map[string]bool, len(fields))
+		for _, field := range fields {
+			if err := field.validate(); err != nil {
+				return errors.NewErrInvalidArgument("Struct Layout", fmt.Sprintf("port %d: %s", port, err))
+			}
+			if names[field.Name] {
+				return errors.NewErrInvalidArgument("Struct Layout", fmt.Sprintf("port %d: duplicate field %s", port, field.Name))
+			}
+			names[field.Name] = true
+		}
+		sorted := sortedFields(fields)
+		for i := 1; i < len(sorted); i++ {
+			if sorted[i].Offset < sorted[i-1].Offset+sorted[i-1].Length {
+				return errors.NewErrInvalidArgument("Struct Layout", fmt.Sprintf("port %d: field %s overlaps with field %s", port, sorted[i].Name, sorted[i-1].Name))
+			}
+		}
+	}
+	return nil
+}
+
+func (f Field) validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("field name can not be empty")
+	}
+	if f.Offset < 0 {
+		return fmt.Errorf("field %s has a negative offset", f.Name)
+	}
+	switch f.Type {
+	case "", FieldTypeInt:
+		if f.Length < 1 || f.Length > 8 {
+			return fmt.Errorf("field %s must have a length of 1 to 8 bytes", f.Name)
+		}
+	case FieldTypeFloat:
+		if f.Length != 4 && f.Length != 8 {
+			return fmt.Errorf("field %s must have a length of 4 or 8 bytes", f.Name)
+		}
+		if len(f.Enum) > 0 {
+			return fmt.Errorf("field %s can not have an enum", f.Name)
+		}
+	default:
+		return fmt.Errorf("field %s has unknown type %s", f.Name, f.Type)
+	}
+	switch f.Endianness {
+	case "", BigEndian, LittleEndian:
+	default:
+		return fmt.Errorf("field %s has unknown endianness %s", f.Name, f.Endianness)
+	}
+	return nil
+}
+
+// Size returns the size of the payload of the port
+func (l *Layout) Size(port uint8) (size int) {
+	for _, field := range l.Ports[port] {
+		if end := field.Offset + field.Length; end > size {
+			size = end
+		}
+	}
+	return
+}
+
+func sortedFields(fields []Field) []Field {
+	sorted := make([]Field, len(fields))
+	copy(sorted, fields)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+	return sorted
+}
+
+func (f Field) scale() float64 {
+	if f.Scale == 0 {
+		return 1
+	}
+	return f.Scale
+}
+
+// plain returns whether the field is an integer without scale and value offset
+func (f Field) plain() bool {
+	return f.Type != FieldTypeFloat && f.Scale == 0 && f.ValueOffset == 0
+}
@@ -4,25 +4,48 @@
 package cayennelpp
 
 import (
-	"bytes"
+	"fmt"
 
 	pb_handler "github.com/TheThingsNetwork/api/handler"
-	protocol "github.com/TheThingsNetwork/go-cayenne-lib/cayennelpp"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
 )
 
-// Decoder is a CayenneLPP PayloadDecoder
+// Decoder is a CayenneLPP PayloadDecoder. By default, the payload is a dynamic sensor payload, in which every value is
+// preceded by its channel and data type. In a packed sensor payload, the channels are left out and the channel of
+// every value is its index in the payload.
 type Decoder struct {
-	result map[string]interface{}
+	Packed bool
 }
 
-// Decodes decodes the CayenneLPP payload to fields
+// Decode decodes the CayenneLPP payload to fields
 func (d *Decoder) Decode(payload []byte, fPort uint8) (map[string]interface{}, bool, error) {
-	decoder := protocol.NewDecoder(bytes.NewBuffer(payload))
-	d.result = make(map[string]interface{})
-	if err := decoder.DecodeUplink(d); err != nil {
-		return nil, false, err
+	result := make(map[string]interface{})
+	for index := 0; len(payload) > 0; index++ {
+		var channel uint8
+		if d.Packed {
+			if index > 255 {
+				return nil, false, errors.NewErrInvalidArgument("Payload", "too many values for packed CayenneLPP")
+			}
+			channel = uint8(index)
+		} else {
+			channel, payload = payload[0], payload[1:]
+			if len(payload) == 0 {
+				return nil, false, errors.NewErrInvalidArgument("Payload", fmt.Sprintf("missing data type of channel %d", channel))
+			}
+		}
+		id := payload[0]
+		t, ok := dataTypes[id]
+		if !ok {
+			return nil, false, errors.NewErrInvalidArgument("Payload", fmt.Sprintf("unknown CayenneLPP data type %d", id))
+		}
+		payload = payload[1:]
+		if len(payload) < t.size() {
+			return nil, false, errors.NewErrInvalidArgument("Payload", fmt.Sprintf("missing data of %s", formatName(t.key, channel)))
+		}
+		result[formatName(t.key, channel)] = t.decode(payload)
+		payload = payload[t.size():]
 	}
-	return d.result, true, nil
+	return result, true, nil
 }
 
 // Log returns the log
@@ -30,62 +53,49 @@ func (d *Decoder) Log() []*pb_handler.LogEntry {
 	return nil
 }
 
-func (d *Decoder) DigitalInput(channel, value uint8) {
-	d.result[formatName(digitalInputKey, channel)] = value
-}
-
-func (d *Decoder) DigitalOutput(channel, value uint8) {
-	d.result[formatName(digitalOutputKey, channel)] = value
-}
-
-func (d *Decoder) AnalogInput(channel uint8, value float32) {
-	d.result[formatName(analogInputKey, channel)] = value
-}
-
-func (d *Decoder) AnalogOutput(channel uint8, value float32) {
-	d.result[formatName(analogOutputKey, channel)] = value
-}
-
-func (d *Decoder) Luminosity(channel uint8, value uint16) {
-	d.result[formatName(luminosityKey, channel)] = value
-}
-
-func (d *Decoder) Presence(channel, value uint8) {
-	d.result[formatName(presenceKey, channel)] = value
-}
-
-func (d *Decoder) Temperature(channel uint8, celcius float32) {
-	d.result[formatName(temperatureKey, channel)] = celcius
-}
-
-func (d *Decoder) RelativeHumidity(channel uint8, rh float32) {
-	d.result[formatName(relativeHumidityKey, channel)] = rh
-}
-
-func (d *Decoder) Accelerometer(channel uint8, x, y, z float32) {
-	d.result[formatName(accelerometerKey, channel)] = map[string]float32{
-		"x": x,
-		"y": y,
-		"z": z,
+// decode decodes the data of the data type. The data must be at least the size of the data type.
+func (t dataType) decode(data []byte) interface{} {
+	if len(t.values) == 1 {
+		return t.values[0].decode(data)
 	}
+	values := make(map[string]float32, len(t.values))
+	for _, v := range t.values {
+		values[v.name] = float32(v.raw(data)) / v.divisor
+		data = data[v.size:]
+	}
+	return values
 }
 
-func (d *Decoder) BarometricPressure(channel uint8, hpa float32) {
-	d.result[formatName(barometricPressureKey, channel)] = hpa
-}
-
-func (d *Decoder) Gyrometer(channel uint8, x, y, z float32) {
-	d.result[formatName(gyrometerKey, channel)] = map[string]float32{
-		"x": x,
-		"y": y,
-		"z": z,
+// raw returns the encoded value
+func (v value) raw(data []byte) (raw int64) {
+	for _, b := range data[:v.size] {
+		raw = raw<<8 | int64(b)
 	}
+	if bits := uint(v.size * 8); v.signed && raw >= 1<<(bits-1) {
+		raw -= 1 << bits
+	}
+	return
 }
 
-func (d *Decoder) GPS(channel uint8, latitude, longitude, altitude float32) {
-	d.result[formatName(gpsKey, channel)] = map[string]float32{
-		"latitude":  latitude,
-		"longitude": longitude,
-		"altitude":  altitude,
+// decode decodes the value. Values that have a divisor are decoded to a float32, other values to an integer of the
+// size of the value.
+func (v value) decode(data []byte) interface{} {
+	raw := v.raw(data)
+	if v.divisor != 1 {
+		return float32(raw) / v.divisor
+	}
+	switch {
+	case v.signed && v.size == 1:
+		return int8(raw)
+	case v.signed && v.size == 2:
+		return int16(raw)
+	case v.signed:
+		return int32(raw)
+	case v.size == 1:
+		return uint8(raw)
+	case v.size == 2:
+		return uint16(raw)
+	default:
+		return uint32(raw)
 	}
 }
@@ -6,7 +6,6 @@ package cayennelpp
 import (
 	"testing"
 
-	protocol "github.com/TheThingsNetwork/go-cayenne-lib/cayennelpp"
 	. "github.com/smartystreets/assertions"
 )
 
@@ -14,18 +13,18 @@ func TestDecode(t *testing.T) {
 	a := New(t)
 
 	buf := []byte{
-		1, protocol.DigitalInput, 255,
-		2, protocol.DigitalOutput, 100,
-		3, protocol.AnalogInput, 21, 74,
-		4, protocol.AnalogOutput, 234, 182,
-		5, protocol.Luminosity, 1, 244,
-		6, protocol.Presence, 50,
-		7, protocol.Temperature, 255, 100,
-		8, protocol.RelativeHumidity, 99,
-		9, protocol.Accelerometer, 254, 88, 0, 15, 6, 130,
-		10, protocol.BarometricPressure, 41, 239,
-		11, protocol.Gyrometer, 1, 99, 2, 49, 254, 102,
-		12, protocol.GPS, 7, 253, 135, 0, 190, 245, 0, 8, 106,
+		1, DigitalInput, 255,
+		2, DigitalOutput, 100,
+		3, AnalogInput, 21, 74,
+		4, AnalogOutput, 234, 182,
+		5, Luminosity, 1, 244,
+		6, Presence, 50,
+		7, Temperature, 255, 100,
+		8, RelativeHumidity, 99,
+		9, Accelerometer, 254, 88, 0, 15, 6, 130,
+		10, BarometricPressure, 41, 239,
+		11, Gyrometer, 1, 99, 2, 49, 254, 102,
+		12, GPS, 7, 253, 135, 0, 190, 245, 0, 8, 106,
 	}
 
 	decoder := new(Decoder)
@@ -58,3 +57,82 @@ func TestDecode(t *testing.T) {
 		"altitude":  21.54,
 	})
 }
+
+func TestDecodeExtended(t *testing.T) {
+	a := New(t)
+
+	buf := []byte{
+		1, Voltage, 1, 74,
+		2, Current, 3, 232,
+		3, Frequency, 0, 0, 3, 232,
+		4, Percentage, 85,
+		5, Altitude, 255, 246,
+		6, Concentration, 1, 144,
+		7, Power, 0, 60,
+		8, Distance, 0, 0, 48, 57,
+		9, Energy, 0, 1, 226, 64,
+		10, Direction, 0, 90,
+		11, UnixTime, 89, 169, 76, 64,
+		12, Colour, 255, 128, 0,
+		13, Switch, 1,
+	}
+
+	decoder := new(Decoder)
+	fields, valid, err := decoder.Decode(buf, 1)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(fields, ShouldHaveLength, 13)
+	a.So(fields["voltage_1"], ShouldEqual, 3.3)
+	a.So(fields["current_2"], ShouldEqual, 1)
+	a.So(fields["frequency_3"], ShouldEqual, 1000)
+	a.So(fields["percentage_4"], ShouldEqual, 85)
+	a.So(fields["altitude_5"], ShouldEqual, -10)
+	a.So(fields["concentration_6"], ShouldEqual, 400)
+	a.So(fields["power_7"], ShouldEqual, 60)
+	a.So(fields["distance_8"], ShouldEqual, 12.345)
+	a.So(fields["energy_9"], ShouldEqual, 123.456)
+	a.So(fields["direction_10"], ShouldEqual, 90)
+	a.So(fields["unix_time_11"], ShouldEqual, 1504267328)
+	a.So(fields["colour_12"], ShouldResemble, map[string]float32{
+		"r": 255,
+		"g": 128,
+		"b": 0,
+	})
+	a.So(fields["switch_13"], ShouldEqual, 1)
+}
+
+func TestDecodePacked(t *testing.T) {
+	a := New(t)
+
+	decoder := &Decoder{Packed: true}
+	fields, valid, err := decoder.Decode([]byte{
+		Temperature, 0, 215,
+		RelativeHumidity, 99,
+		Colour, 1, 2, 3,
+	}, 2)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(fields, ShouldHaveLength, 3)
+	a.So(fields["temperature_0"], ShouldEqual, 21.5)
+	a.So(fields["relative_humidity_1"], ShouldEqual, 49.5)
+	a.So(fields["colour_2"], ShouldResemble, map[string]float32{
+		"r": 1,
+		"g": 2,
+		"b": 3,
+	})
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	a := New(t)
+
+	decoder := new(Decoder)
+	for _, payload := range [][]byte{
+		{1},
+		{1, 200, 0},
+		{1, Temperature, 0},
+		{1, GPS, 0, 0, 0, 0, 0, 0, 0, 0},
+	} {
+		_, _, err := decoder.Decode(payload, 1)
+		a.So(err, ShouldNotBeNil)
+	}
+}
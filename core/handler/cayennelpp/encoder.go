@@ -4,33 +4,167 @@
 package cayennelpp
 
 import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
 	pb_handler "github.com/TheThingsNetwork/api/handler"
-	protocol "github.com/TheThingsNetwork/go-cayenne-lib/cayennelpp"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
 )
 
-// Encoder is a CayenneLPP PayloadEncoder
+// Encoder is a CayenneLPP PayloadEncoder. Fields named value_<channel> are encoded as actuator commands: the channel
+// followed by the value in 2 bytes, 0.01 unsigned. If there are no actuator commands, the fields are encoded as a
+// dynamic sensor payload, or as a packed sensor payload if Packed is set. Fields that are not CayenneLPP values are
+// ignored.
 type Encoder struct {
+	Packed bool
+}
+
+// sensorValue is a field that is encoded as sensor payload
+type sensorValue struct {
+	name    string
+	channel uint8
+	id      uint8
+	values  []float64
 }
 
 // Encode encodes the fields to CayenneLPP
 func (e *Encoder) Encode(fields map[string]interface{}, fPort uint8) ([]byte, bool, error) {
-	encoder := protocol.NewEncoder()
-	for name, value := range fields {
+	commands := make(map[uint8]float64)
+	var sensors []sensorValue
+	for name, field := range fields {
 		key, channel, err := parseName(name)
 		if err != nil {
 			continue
 		}
-		switch key {
-		case valueKey:
-			if val, ok := value.(float64); ok {
-				encoder.AddPort(channel, float32(val))
+		if key == valueKey {
+			if val, ok := number(field); ok {
+				commands[channel] = val
+			}
+			continue
+		}
+		id, ok := dataTypeIDs[key]
+		if !ok {
+			continue
+		}
+		if values, ok := dataTypes[id].fieldValues(field); ok {
+			sensors = append(sensors, sensorValue{name: name, channel: channel, id: id, values: values})
+		}
+	}
+
+	// Actuator commands take precedence, the sensor values are ignored
+	if len(commands) > 0 {
+		return encodeCommands(commands), true, nil
+	}
+
+	sort.Slice(sensors, func(i, j int) bool {
+		if sensors[i].channel != sensors[j].channel {
+			return sensors[i].channel < sensors[j].channel
+		}
+		return sensors[i].name < sensors[j].name
+	})
+	var payload []byte
+	for i, sensor := range sensors {
+		if e.Packed {
+			if int(sensor.channel) != i {
+				return nil, false, errors.NewErrInvalidArgument("Fields", "must have channels 0 to n for packed CayenneLPP")
 			}
+		} else {
+			payload = append(payload, sensor.channel)
+		}
+		payload = append(payload, sensor.id)
+		for j, v := range dataTypes[sensor.id].values {
+			encoded, err := v.encode(sensor.values[j])
+			if err != nil {
+				return nil, false, errors.NewErrInvalidArgument(sensor.name, err.Error())
+			}
+			payload = append(payload, encoded...)
 		}
 	}
-	return encoder.Bytes(), true, nil
+	return payload, true, nil
 }
 
 // Log returns the log
 func (e *Encoder) Log() []*pb_handler.LogEntry {
 	return nil
 }
+
+func encodeCommands(commands map[uint8]float64) []byte {
+	channels := make([]int, 0, len(commands))
+	for channel := range commands {
+		channels = append(channels, int(channel))
+	}
+	sort.Ints(channels)
+	var payload []byte
+	for _, channel := range channels {
+		// Negative values wrap around, as in the encoding of actuator commands before sensor values were supported
+		val := uint16(int64(float32(commands[uint8(channel)]) * 100))
+		payload = append(payload, uint8(channel), byte(val>>8), byte(val))
+	}
+	return payload
+}
+
+// fieldValues returns the values of the field for the data type, or false if the field does not match the data type
+func (t dataType) fieldValues(field interface{}) ([]float64, bool) {
+	if len(t.values) == 1 {
+		val, ok := number(field)
+		return []float64{val}, ok
+	}
+	m := reflect.ValueOf(field)
+	if m.Kind() != reflect.Map || m.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	values := make([]float64, len(t.values))
+	for i, v := range t.values {
+		item := m.MapIndex(reflect.ValueOf(v.name).Convert(m.Type().Key()))
+		if !item.IsValid() {
+			return nil, false
+		}
+		val, ok := number(item.Interface())
+		if !ok {
+			return nil, false
+		}
+		values[i] = val
+	}
+	return values, true
+}
+
+// encode encodes the value
+func (v value) encode(val float64) ([]byte, error) {
+	raw := math.Round(val * float64(v.divisor))
+	bits := uint(v.size * 8)
+	min, max := 0.0, float64(uint64(1)<<bits-1)
+	if v.signed {
+		min, max = -float64(uint64(1)<<(bits-1)), float64(uint64(1)<<(bits-1)-1)
+	}
+	if raw < min || raw > max {
+		return nil, fmt.Errorf("%v out of range", val)
+	}
+	encoded := make([]byte, v.size)
+	u := uint64(int64(raw))
+	for i := v.size - 1; i >= 0; i-- {
+		encoded[i] = byte(u)
+		u >>= 8
+	}
+	return encoded, nil
+}
+
+// number returns the field as a number. Booleans are converted to 0 or 1.
+func number(field interface{}) (float64, bool) {
+	val := reflect.ValueOf(field)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(val.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), true
+	case reflect.Bool:
+		if val.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -4,6 +4,7 @@
 package cayennelpp
 
 import (
+	"encoding/json"
 	"testing"
 
 	. "github.com/smartystreets/assertions"
@@ -41,3 +42,124 @@ func TestEncode(t *testing.T) {
 		a.So(payload, ShouldBeEmpty)
 	}
 }
+
+func TestEncodeSensors(t *testing.T) {
+	a := New(t)
+
+	encoder := new(Encoder)
+
+	payload, valid, err := encoder.Encode(map[string]interface{}{
+		"temperature_2": 21.5,
+		"switch_1":      true,
+		"colour_3": map[string]interface{}{
+			"r": 255,
+			"g": 128,
+			"b": 0,
+		},
+	}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(payload, ShouldResemble, []byte{
+		1, Switch, 1,
+		2, Temperature, 0, 215,
+		3, Colour, 255, 128, 0,
+	})
+
+	// Values out of range
+	_, _, err = encoder.Encode(map[string]interface{}{"percentage_1": 256}, 1)
+	a.So(err, ShouldNotBeNil)
+
+	// Actuator values are unsigned
+	payload, _, err = encoder.Encode(map[string]interface{}{"value_1": 400}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(payload, ShouldResemble, []byte{1, 156, 64})
+
+	// Sensor values are ignored if there are actuator values
+	payload, _, err = encoder.Encode(map[string]interface{}{"value_1": 1, "switch_2": 1}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(payload, ShouldResemble, []byte{1, 0, 100})
+}
+
+func TestEncodePacked(t *testing.T) {
+	a := New(t)
+
+	encoder := &Encoder{Packed: true}
+
+	payload, valid, err := encoder.Encode(map[string]interface{}{
+		"temperature_0":       21.5,
+		"relative_humidity_1": 49.5,
+	}, 2)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(payload, ShouldResemble, []byte{
+		Temperature, 0, 215,
+		RelativeHumidity, 99,
+	})
+
+	// Channels must be 0 to n
+	_, _, err = encoder.Encode(map[string]interface{}{
+		"temperature_0":       21.5,
+		"relative_humidity_2": 49.5,
+	}, 2)
+	a.So(err, ShouldNotBeNil)
+}
+
+func TestRoundTrip(t *testing.T) {
+	a := New(t)
+
+	dynamic := []byte{
+		1, DigitalInput, 255,
+		2, DigitalOutput, 100,
+		3, AnalogInput, 21, 74,
+		4, AnalogOutput, 234, 182,
+		5, Luminosity, 1, 244,
+		6, Presence, 50,
+		7, Temperature, 255, 100,
+		8, RelativeHumidity, 99,
+		9, Accelerometer, 254, 88, 0, 15, 6, 130,
+		10, BarometricPressure, 41, 239,
+		11, Gyrometer, 1, 99, 2, 49, 254, 102,
+		12, GPS, 7, 253, 135, 0, 190, 245, 0, 8, 106,
+		13, Voltage, 1, 74,
+		14, Current, 3, 232,
+		15, Frequency, 0, 0, 3, 232,
+		16, Percentage, 85,
+		17, Altitude, 255, 246,
+		18, Concentration, 1, 144,
+		19, Power, 0, 60,
+		20, Distance, 0, 0, 48, 57,
+		21, Energy, 0, 1, 226, 64,
+		22, Direction, 0, 90,
+		23, UnixTime, 89, 169, 76, 64,
+		24, Colour, 255, 128, 0,
+		25, Switch, 1,
+	}
+	packed := []byte{
+		GPS, 248, 2, 121, 255, 65, 11, 255, 247, 150,
+		Accelerometer, 254, 88, 0, 15, 6, 130,
+		Energy, 0, 1, 226, 64,
+	}
+
+	for _, tt := range []struct {
+		packed  bool
+		payload []byte
+	}{
+		{false, dynamic},
+		{true, packed},
+	} {
+		fields, _, err := (&Decoder{Packed: tt.packed}).Decode(tt.payload, 1)
+		a.So(err, ShouldBeNil)
+
+		payload, _, err := (&Encoder{Packed: tt.packed}).Encode(fields, 1)
+		a.So(err, ShouldBeNil)
+		a.So(payload, ShouldResemble, tt.payload)
+
+		// The fields also survive a JSON round trip, as they do for downlink messages
+		data, _ := json.Marshal(fields)
+		var jsonFields map[string]interface{}
+		json.Unmarshal(data, &jsonFields)
+		payload, _, err = (&Encoder{Packed: tt.packed}).Encode(jsonFields, 1)
+		a.So(err, ShouldBeNil)
+		a.So(payload, ShouldResemble, tt.payload)
+	}
+}
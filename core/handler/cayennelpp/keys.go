@@ -24,6 +24,19 @@ const (
 	barometricPressureKey = "barometric_pressure"
 	gyrometerKey          = "gyrometer"
 	gpsKey                = "gps"
+	voltageKey            = "voltage"
+	currentKey            = "current"
+	frequencyKey          = "frequency"
+	percentageKey         = "percentage"
+	altitudeKey           = "altitude"
+	concentrationKey      = "concentration"
+	powerKey              = "power"
+	distanceKey           = "distance"
+	energyKey             = "energy"
+	directionKey          = "direction"
+	unixTimeKey           = "unix_time"
+	colourKey             = "colour"
+	switchKey             = "switch"
 )
 
 func formatName(key string, channel uint8) string {
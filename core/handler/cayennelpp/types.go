@@ -0,0 +1,113 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cayennelpp
+
+// Cayenne LPP data types
+const (
+	DigitalInput       = 0   // 1 byte
+	DigitalOutput      = 1   // 1 byte
+	AnalogInput        = 2   // 2 bytes, 0.01 signed
+	AnalogOutput       = 3   // 2 bytes, 0.01 signed
+	Luminosity         = 101 // 2 bytes, 1 lux unsigned
+	Presence           = 102 // 1 byte
+	Temperature        = 103 // 2 bytes, 0.1 °C signed
+	RelativeHumidity   = 104 // 1 byte, 0.5 % unsigned
+	Accelerometer      = 113 // 2 bytes per axis, 0.001 G signed
+	BarometricPressure = 115 // 2 bytes, 0.1 hPa unsigned
+	Voltage            = 116 // 2 bytes, 0.01 V unsigned
+	Current            = 117 // 2 bytes, 0.001 A unsigned
+	Frequency          = 118 // 4 bytes, 1 Hz unsigned
+	Percentage         = 120 // 1 byte, 1 % unsigned
+	Altitude           = 121 // 2 bytes, 1 m signed
+	Concentration      = 125 // 2 bytes, 1 ppm unsigned
+	Power              = 128 // 2 bytes, 1 W unsigned
+	Distance           = 130 // 4 bytes, 0.001 m unsigned
+	Energy             = 131 // 4 bytes, 0.001 kWh unsigned
+	Direction          = 132 // 2 bytes, 1 ° unsigned
+	UnixTime           = 133 // 4 bytes, 1 s unsigned
+	Gyrometer          = 134 // 2 bytes per axis, 0.01 °/s signed
+	Colour             = 135 // 1 byte per component (r, g, b)
+	GPS                = 136 // 3 bytes latitude and longitude 0.0001 ° signed, 3 bytes altitude 0.01 m signed
+	Switch             = 142 // 1 byte, 0 or 1
+)
+
+// value is a value of a data type with its size in bytes. The encoded value is the value multiplied by the divisor.
+type value struct {
+	name    string
+	size    int
+	signed  bool
+	divisor float32
+}
+
+// dataType is a Cayenne LPP data type. A data type with a single value is decoded to a number, a data type with
+// multiple values is decoded to an object with the names of the values as keys.
+type dataType struct {
+	key    string
+	values []value
+}
+
+// size returns the size of the encoded data type in bytes
+func (t dataType) size() (size int) {
+	for _, v := range t.values {
+		size += v.size
+	}
+	return
+}
+
+func single(key string, size int, signed bool, divisor float32) dataType {
+	return dataType{key: key, values: []value{{size: size, signed: signed, divisor: divisor}}}
+}
+
+func xyz(key string, divisor float32) dataType {
+	return dataType{key: key, values: []value{
+		{name: "x", size: 2, signed: true, divisor: divisor},
+		{name: "y", size: 2, signed: true, divisor: divisor},
+		{name: "z", size: 2, signed: true, divisor: divisor},
+	}}
+}
+
+var dataTypes = map[uint8]dataType{
+	DigitalInput:       single(digitalInputKey, 1, false, 1),
+	DigitalOutput:      single(digitalOutputKey, 1, false, 1),
+	AnalogInput:        single(analogInputKey, 2, true, 100),
+	AnalogOutput:       single(analogOutputKey, 2, true, 100),
+	Luminosity:         single(luminosityKey, 2, false, 1),
+	Presence:           single(presenceKey, 1, false, 1),
+	Temperature:        single(temperatureKey, 2, true, 10),
+	RelativeHumidity:   single(relativeHumidityKey, 1, false, 2),
+	Accelerometer:      xyz(accelerometerKey, 1000),
+	BarometricPressure: single(barometricPressureKey, 2, false, 10),
+	Voltage:            single(voltageKey, 2, false, 100),
+	Current:            single(currentKey, 2, false, 1000),
+	Frequency:          single(frequencyKey, 4, false, 1),
+	Percentage:         single(percentageKey, 1, false, 1),
+	Altitude:           single(altitudeKey, 2, true, 1),
+	Concentration:      single(concentrationKey, 2, false, 1),
+	Power:              single(powerKey, 2, false, 1),
+	Distance:           single(distanceKey, 4, false, 1000),
+	Energy:             single(energyKey, 4, false, 1000),
+	Direction:          single(directionKey, 2, false, 1),
+	UnixTime:           single(unixTimeKey, 4, false, 1),
+	Gyrometer:          xyz(gyrometerKey, 100),
+	Colour: {key: colourKey, values: []value{
+		{name: "r", size: 1, divisor: 1},
+		{name: "g", size: 1, divisor: 1},
+		{name: "b", size: 1, divisor: 1},
+	}},
+	GPS: {key: gpsKey, values: []value{
+		{name: "latitude", size: 3, signed: true, divisor: 10000},
+		{name: "longitude", size: 3, signed: true, divisor: 10000},
+		{name: "altitude", size: 3, signed: true, divisor: 100},
+	}},
+	Switch: single(switchKey, 1, false, 1),
+}
+
+// dataTypeIDs are the data types by key
+var dataTypeIDs = make(map[string]uint8, len(dataTypes))
+
+func init() {
+	for id, t := range dataTypes {
+		dataTypeIDs[t.key] = id
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"time"
+
+	pb_broker "github.com/TheThingsNetwork/api/broker"
+	"github.com/TheThingsNetwork/go-account-lib/rights"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/clocksync"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/TheThingsNetwork/ttn/utils/gpstime"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+)
+
+// ClockSyncAnswerTTL is the time in which a clock synchronization answer needs to be sent. A later answer would
+// correct the clock of the device with an outdated correction.
+var ClockSyncAnswerTTL = time.Minute
+
+// HandleClockSync answers the clock synchronization requests of devices if the application enabled clock
+// synchronization. The time correction is calculated from the reception time of the uplink.
+func (h *handler) HandleClockSync(ctx ttnlog.Interface, _ *pb_broker.DeduplicatedUplinkMessage, appUp *types.UplinkMessage, _ *device.Device) error {
+	if appUp.FPort != clocksync.Port {
+		return nil
+	}
+	app, err := h.applications.Get(appUp.AppID)
+	if err != nil {
+		return err
+	}
+	if !app.ClockSync {
+		return nil
+	}
+
+	requests, err := clocksync.UnmarshalRequests(appUp.PayloadRaw)
+	if err != nil {
+		ctx.WithError(err).Warn("Could not read clock synchronization requests")
+	}
+
+	var answers []byte
+
+	serverTime := time.Time(appUp.Metadata.Time)
+	var events []*types.ClockSyncEventData
+	for _, req := range requests.AppTime {
+		correction := int32(int64(gpstime.ToGPS(serverTime)/time.Second) - int64(req.DeviceTime))
+		event := &types.ClockSyncEventData{
+			DeviceTime:     types.JSONTime(gpstime.FromGPS(time.Duration(req.DeviceTime) * time.Second)),
+			ServerTime:     appUp.Metadata.Time,
+			TimeCorrection: correction,
+			Token:          req.TokenReq,
+		}
+		if req.AnsRequired || correction != 0 {
+			answer, _ := clocksync.AppTimeAns{TimeCorrection: correction, TokenAns: req.TokenReq}.MarshalBinary()
+			answers = append(answers, answer...)
+			event.AnswerSent = true
+		}
+		events = append(events, event)
+	}
+
+	if len(answers) > 0 {
+		// The answer is sent in the response to this uplink, before the rest of the queue
+		err := h.EnqueueDownlink(&types.DownlinkMessage{
+			AppID:      appUp.AppID,
+			DevID:      appUp.DevID,
+			FPort:      clocksync.Port,
+			PayloadRaw: answers,
+			Schedule:   types.ScheduleFirst,
+			TTL:        ClockSyncAnswerTTL.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, event := range events {
+		select {
+		case h.qEvent <- &types.DeviceEvent{
+			AppID: appUp.AppID,
+			DevID: appUp.DevID,
+			Event: types.ClockSyncEvent,
+			Data:  event,
+		}:
+		case <-time.After(eventPublishTimeout):
+			ctx.Warnf("Could not emit %q event", types.ClockSyncEvent)
+		}
+	}
+
+	return ErrNotPublished
+}
+
+func (h *handlerManager) GetClockSync(ctx context.Context, in *clocksync.ApplicationIdentifier) (*clocksync.Settings, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	app, err := h.handler.applications.Get(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	return &clocksync.Settings{AppID: app.AppID, Enabled: app.ClockSync}, nil
+}
+
+func (h *handlerManager) SetClockSync(ctx context.Context, in *clocksync.Settings) (*clocksync.Empty, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	app, err := h.handler.applications.Get(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	app.StartUpdate()
+	app.ClockSync = in.Enabled
+	if err := h.handler.applications.Set(app, "ClockSync"); err != nil {
+		return nil, err
+	}
+	return &clocksync.Empty{}, nil
+}
@@ -0,0 +1,83 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/clocksync"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/gpstime"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestHandleClockSync(t *testing.T) {
+	a := New(t)
+
+	h := &handler{
+		Component:    &component.Component{Ctx: GetLogger(t, "TestHandleClockSync")},
+		applications: application.NewRedisApplicationStore(GetRedisClient(), "handler-test-clock-sync"),
+		devices:      device.NewRedisDeviceStore(GetRedisClient(), "handler-test-clock-sync"),
+		qEvent:       make(chan *types.DeviceEvent, 10),
+	}
+	app := &application.Application{AppID: "app1"}
+	h.applications.Set(app)
+	defer h.applications.Delete("app1")
+	dev := &device.Device{AppID: "app1", DevID: "dev1"}
+	h.devices.Set(dev)
+	defer h.devices.Delete("app1", "dev1")
+
+	serverTime := time.Date(2017, time.September, 1, 12, 0, 0, 0, time.UTC)
+	deviceTime := uint32(gpstime.ToGPS(serverTime)/time.Second) - 42
+	payload := []byte{0x01, 0, 0, 0, 0, 0x15} // AppTimeReq with token 5, answer required
+	binary.LittleEndian.PutUint32(payload[1:5], deviceTime)
+
+	appUp := &types.UplinkMessage{
+		AppID:      "app1",
+		DevID:      "dev1",
+		FPort:      clocksync.Port,
+		PayloadRaw: payload,
+		Metadata:   types.Metadata{Time: types.JSONTime(serverTime)},
+	}
+
+	// Not enabled
+	err := h.HandleClockSync(GetLogger(t, "TestHandleClockSync"), nil, appUp, dev)
+	a.So(err, ShouldBeNil)
+
+	app.StartUpdate()
+	app.ClockSync = true
+	h.applications.Set(app)
+
+	// Other port
+	err = h.HandleClockSync(GetLogger(t, "TestHandleClockSync"), nil, &types.UplinkMessage{AppID: "app1", DevID: "dev1", FPort: 1}, dev)
+	a.So(err, ShouldBeNil)
+
+	err = h.HandleClockSync(GetLogger(t, "TestHandleClockSync"), nil, appUp, dev)
+	a.So(err, ShouldEqual, ErrNotPublished)
+
+	queue, _ := h.devices.DownlinkQueue("app1", "dev1")
+	next, _ := queue.Next()
+	a.So(next, ShouldNotBeNil)
+	a.So(next.FPort, ShouldEqual, clocksync.Port)
+	a.So(next.PayloadRaw, ShouldResemble, []byte{0x01, 42, 0, 0, 0, 0x05})
+	a.So(next.ExpiresAt, ShouldNotBeNil)
+
+	var event *types.DeviceEvent
+	for event = range h.qEvent {
+		if event.Event == types.ClockSyncEvent {
+			break
+		}
+	}
+	data := event.Data.(*types.ClockSyncEventData)
+	a.So(data.TimeCorrection, ShouldEqual, 42)
+	a.So(data.Token, ShouldEqual, 5)
+	a.So(data.AnswerSent, ShouldBeTrue)
+	a.So(time.Time(data.DeviceTime), ShouldEqual, serverTime.Add(-42*time.Second))
+}
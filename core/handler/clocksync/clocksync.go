@@ -0,0 +1,82 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package clocksync implements the LoRaWAN Application Layer Clock Synchronization package
+package clocksync
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// Port is the FPort of the Clock Synchronization package
+const Port = 202
+
+// Command identifiers of the Clock Synchronization package
+const (
+	cidPackageVersion = 0x00
+	cidAppTime        = 0x01
+	cidPeriodicity    = 0x02
+)
+
+// AppTimeReq is sent by a device to request a time correction
+type AppTimeReq struct {
+	DeviceTime  uint32 // in seconds since the GPS epoch
+	TokenReq    uint8
+	AnsRequired bool
+}
+
+// AppTimeAns is the answer to an AppTimeReq
+type AppTimeAns struct {
+	TimeCorrection int32 // in seconds
+	TokenAns       uint8
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (a AppTimeAns) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 6)
+	b[0] = cidAppTime
+	binary.LittleEndian.PutUint32(b[1:5], uint32(a.TimeCorrection))
+	b[5] = a.TokenAns & 0x0f
+	return b, nil
+}
+
+// Requests contains the requests in an uplink message on the Port
+type Requests struct {
+	AppTime []AppTimeReq
+}
+
+// UnmarshalRequests unmarshals the requests in an uplink message on the Port. Answers to requests of the server are
+// skipped.
+func UnmarshalRequests(b []byte) (*Requests, error) {
+	requests := new(Requests)
+	for len(b) > 0 {
+		cid := b[0]
+		b = b[1:]
+		var length int
+		switch cid {
+		case cidPackageVersion:
+			length = 2
+		case cidAppTime, cidPeriodicity:
+			length = 5
+		default:
+			return requests, errors.NewErrInvalidArgument("Clock Synchronization", fmt.Sprintf("unknown command 0x%02X", cid))
+		}
+		if len(b) < length {
+			return requests, errors.NewErrInvalidArgument("Clock Synchronization", fmt.Sprintf("command 0x%02X too short", cid))
+		}
+		payload := b[:length]
+		b = b[length:]
+		switch cid {
+		case cidAppTime:
+			requests.AppTime = append(requests.AppTime, AppTimeReq{
+				DeviceTime:  binary.LittleEndian.Uint32(payload[0:4]),
+				TokenReq:    payload[4] & 0x0f,
+				AnsRequired: payload[4]&0x10 != 0,
+			})
+		}
+	}
+	return requests, nil
+}
@@ -0,0 +1,32 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package clocksync
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/assertions"
+)
+
+func TestClockSync(t *testing.T) {
+	a := New(t)
+
+	requests, err := UnmarshalRequests([]byte{
+		0x00, 0x01, 0x01, // PackageVersionAns
+		0x01, 0x04, 0x03, 0x02, 0x01, 0x13, // AppTimeReq with token 3, answer required
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x00, // DeviceAppTimePeriodicityAns
+	})
+	a.So(err, ShouldBeNil)
+	a.So(requests.AppTime, ShouldResemble, []AppTimeReq{{DeviceTime: 0x01020304, TokenReq: 3, AnsRequired: true}})
+
+	_, err = UnmarshalRequests([]byte{0x01, 0x04, 0x03})
+	a.So(err, ShouldNotBeNil)
+
+	_, err = UnmarshalRequests([]byte{0x42})
+	a.So(err, ShouldNotBeNil)
+
+	b, err := AppTimeAns{TimeCorrection: -2, TokenAns: 3}.MarshalBinary()
+	a.So(err, ShouldBeNil)
+	a.So(b, ShouldResemble, []byte{0x01, 0xfe, 0xff, 0xff, 0xff, 0x03})
+}
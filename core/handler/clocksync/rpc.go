@@ -0,0 +1,69 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package clocksync
+
+import (
+	"github.com/TheThingsNetwork/ttn/utils/jsoncodec"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+// ApplicationIdentifier identifies an application
+type ApplicationIdentifier struct {
+	AppID string `json:"app_id"`
+}
+
+// Settings contains the clock synchronization settings of an application. If enabled, the Handler answers the
+// requests of the devices on the Port instead of publishing them to the application.
+type Settings struct {
+	AppID   string `json:"app_id"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Empty is the response of requests that do not return anything
+type Empty struct{}
+
+// ClockSyncManagerServer is the server API for the ClockSyncManager service
+type ClockSyncManagerServer interface {
+	GetClockSync(context.Context, *ApplicationIdentifier) (*Settings, error)
+	SetClockSync(context.Context, *Settings) (*Empty, error)
+}
+
+// ClockSyncManagerClient is the client API for the ClockSyncManager service
+type ClockSyncManagerClient interface {
+	GetClockSync(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*Settings, error)
+	SetClockSync(ctx context.Context, in *Settings, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type clockSyncManagerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewClockSyncManagerClient returns a new ClockSyncManagerClient
+func NewClockSyncManagerClient(cc *grpc.ClientConn) ClockSyncManagerClient {
+	return &clockSyncManagerClient{cc}
+}
+
+func (c *clockSyncManagerClient) GetClockSync(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*Settings, error) {
+	out := new(Settings)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.ClockSyncManager/GetClockSync", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clockSyncManagerClient) SetClockSync(ctx context.Context, in *Settings, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.ClockSyncManager/SetClockSync", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var clockSyncManagerServiceDesc = jsoncodec.ServiceDesc("handler.ClockSyncManager", (*ClockSyncManagerServer)(nil))
+
+// RegisterClockSyncManagerServer registers the ClockSyncManager service
+func RegisterClockSyncManagerServer(s *grpc.Server, srv ClockSyncManagerServer) {
+	s.RegisterService(clockSyncManagerServiceDesc, srv)
+}
@@ -9,48 +9,69 @@ import (
 	"time"
 
 	pb_broker "github.com/TheThingsNetwork/api/broker"
-	pb_handler "github.com/TheThingsNetwork/api/handler"
 	ttnlog "github.com/TheThingsNetwork/go-utils/log"
 	"github.com/TheThingsNetwork/ttn/core/handler/application"
-	"github.com/TheThingsNetwork/ttn/core/handler/cayennelpp"
 	"github.com/TheThingsNetwork/ttn/core/handler/device"
 	"github.com/TheThingsNetwork/ttn/core/handler/functions"
+	"github.com/TheThingsNetwork/ttn/core/handler/payload"
 	"github.com/TheThingsNetwork/ttn/core/types"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 )
 
-// PayloadDecoder decodes raw payload to fields
-type PayloadDecoder interface {
-	Decode(payload []byte, fPort uint8) (map[string]interface{}, bool, error)
-	Log() []*pb_handler.LogEntry
+// payloadFormatter returns the payload formatter for the device, and which payload formatter it is. The payload
+// formatter of the device itself ("device") takes precedence over the payload formatters for device attributes
+// ("attribute:<attribute>=<value>"), which take precedence over the payload formatter of the application
+// ("application"). The revision is only set for the payload formatter of the application.
+func payloadFormatter(app *application.Application, dev *device.Device) (formatter *application.PayloadFormatter, source string, revision uint64) {
+	if dev != nil {
+		if dev.PayloadFormatter != nil {
+			return dev.PayloadFormatter, "device", 0
+		}
+		if formatter := app.GetAttributePayloadFormatter(dev.Attributes); formatter != nil {
+			return &formatter.PayloadFormatter, fmt.Sprintf("attribute:%s=%s", formatter.Attribute, formatter.Value), 0
+		}
+	}
+	return app.GetPayloadFormatter(), "application", app.PayloadFormatterRevision
 }
 
-// PayloadEncoder encodes fields to raw payload
-type PayloadEncoder interface {
-	Encode(fields map[string]interface{}, fPort uint8) ([]byte, bool, error)
-	Log() []*pb_handler.LogEntry
+// uplinkMetadata returns the metadata of the uplink message that is passed to the payload functions
+func uplinkMetadata(ttnUp *pb_broker.DeduplicatedUplinkMessage, appUp *types.UplinkMessage, dev *device.Device) *application.UplinkMetadata {
+	metadata := &application.UplinkMetadata{
+		AppID:          appUp.AppID,
+		DevID:          appUp.DevID,
+		HardwareSerial: appUp.HardwareSerial,
+		FCnt:           appUp.FCnt,
+		Confirmed:      appUp.Confirmed,
+		Time:           time.Time(appUp.Metadata.Time).UTC(),
+		Frequency:      appUp.Metadata.Frequency,
+		DataRate:       appUp.Metadata.DataRate,
+	}
+	if ttnUp != nil {
+		metadata.Gateways = len(ttnUp.GatewayMetadata)
+	}
+	if dev != nil {
+		metadata.Attributes = dev.Attributes
+	}
+	return metadata
 }
 
-// ConvertFieldsUp converts the payload to fields using the application's payload formatter
-func (h *handler) ConvertFieldsUp(ctx ttnlog.Interface, _ *pb_broker.DeduplicatedUplinkMessage, appUp *types.UplinkMessage, dev *device.Device) error {
+// ConvertFieldsUp converts the payload to fields using the device's payload formatter
+func (h *handler) ConvertFieldsUp(ctx ttnlog.Interface, ttnUp *pb_broker.DeduplicatedUplinkMessage, appUp *types.UplinkMessage, dev *device.Device) error {
 	// Find Application
 	app, err := h.applications.Get(appUp.AppID)
 	if err != nil {
 		return nil // Do not process if application not found
 	}
 
-	var decoder PayloadDecoder
-	switch app.PayloadFormat {
-	case application.PayloadFormatCustom:
-		decoder = &CustomUplinkFunctions{
-			Decoder:   app.CustomDecoder,
-			Converter: app.CustomConverter,
-			Validator: app.CustomValidator,
-			Logger:    functions.Ignore,
-		}
-	case application.PayloadFormatCayenneLPP:
-		decoder = &cayennelpp.Decoder{}
-	default:
+	formatter, source, revision := payloadFormatter(app, dev)
+
+	var state *string
+	if dev != nil {
+		state = &dev.DecoderState
+	}
+
+	decoder := payload.NewDecoder(functions.ApplicationRuntime(appUp.AppID), formatter, uplinkMetadata(ttnUp, appUp, dev), state, functions.Ignore)
+	if decoder == nil {
 		return nil
 	}
 
@@ -98,6 +119,8 @@ func (h *handler) ConvertFieldsUp(ctx ttnlog.Interface, _ *pb_broker.Deduplicate
 	}
 
 	appUp.PayloadFields = fields
+	appUp.Metadata.PayloadFormatter = source
+	appUp.Metadata.PayloadFormatterRevision = revision
 	if appUp.Attributes == nil {
 		appUp.Attributes = make(map[string]string)
 	}
@@ -108,8 +131,8 @@ func (h *handler) ConvertFieldsUp(ctx ttnlog.Interface, _ *pb_broker.Deduplicate
 	return nil
 }
 
-// ConvertFieldsDown converts the fields into a payload
-func (h *handler) ConvertFieldsDown(ctx ttnlog.Interface, appDown *types.DownlinkMessage, ttnDown *pb_broker.DownlinkMessage, _ *device.Device) error {
+// ConvertFieldsDown converts the fields into a payload using the device's payload formatter
+func (h *handler) ConvertFieldsDown(ctx ttnlog.Interface, appDown *types.DownlinkMessage, ttnDown *pb_broker.DownlinkMessage, dev *device.Device) error {
 	if appDown.PayloadFields == nil || len(appDown.PayloadFields) == 0 {
 		return nil
 	}
@@ -123,16 +146,10 @@ func (h *handler) ConvertFieldsDown(ctx ttnlog.Interface, appDown *types.Downlin
 		return nil
 	}
 
-	var encoder PayloadEncoder
-	switch app.PayloadFormat {
-	case application.PayloadFormatCustom:
-		encoder = &CustomDownlinkFunctions{
-			Encoder: app.CustomEncoder,
-			Logger:  functions.Ignore,
-		}
-	case application.PayloadFormatCayenneLPP:
-		encoder = &cayennelpp.Encoder{}
-	default:
+	formatter, _, _ := payloadFormatter(app, dev)
+
+	encoder := payload.NewEncoder(functions.ApplicationRuntime(appDown.AppID), formatter, functions.Ignore)
+	if encoder == nil {
 		return nil
 	}
 
@@ -7,12 +7,15 @@ import (
 	"testing"
 
 	pb_broker "github.com/TheThingsNetwork/api/broker"
+	pb_gateway "github.com/TheThingsNetwork/api/gateway"
 
 	"github.com/TheThingsNetwork/ttn/core/handler/application"
 	"github.com/TheThingsNetwork/ttn/core/handler/device"
 	"github.com/TheThingsNetwork/ttn/core/types"
 	. "github.com/TheThingsNetwork/ttn/utils/testing"
 	. "github.com/smartystreets/assertions"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func buildCustomUplink(appID string) (*pb_broker.DeduplicatedUplinkMessage, *types.UplinkMessage) {
@@ -33,6 +36,25 @@ var attributes = map[string]string{
 	"ttn-model": "The Things Uno",
 }
 
+func TestUplinkMetadata(t *testing.T) {
+	a := New(t)
+
+	ttnUp, appUp := buildCustomUplink("AppID-1")
+	ttnUp.GatewayMetadata = []*pb_gateway.RxMetadata{{}, {}}
+	appUp.FCnt = 42
+	appUp.Metadata.DataRate = "SF7BW125"
+
+	metadata := uplinkMetadata(ttnUp, appUp, &device.Device{Attributes: attributes})
+	a.So(metadata.AppID, ShouldEqual, "AppID-1")
+	a.So(metadata.DevID, ShouldEqual, "DevID-1")
+	a.So(metadata.FCnt, ShouldEqual, 42)
+	a.So(metadata.DataRate, ShouldEqual, "SF7BW125")
+	a.So(metadata.Gateways, ShouldEqual, 2)
+	a.So(metadata.Attributes, ShouldResemble, attributes)
+
+	a.So(uplinkMetadata(nil, appUp, nil).Attributes, ShouldBeNil)
+}
+
 func TestConvertFieldsUpCustom(t *testing.T) {
 	a := New(t)
 	appID := "AppID-1"
@@ -307,3 +329,116 @@ func TestConvertFieldsDownCayenneLPP(t *testing.T) {
 		a.So(appDown.PayloadRaw, ShouldResemble, []byte{7, 249, 232})
 	}
 }
+
+func buildProtobufDescriptorSet(t *testing.T) []byte {
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("test.proto"),
+			Package: proto.String("test"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Measurement"),
+				Field: []*descriptorpb.FieldDescriptorProto{{
+					Name:     proto.String("temperature"),
+					JsonName: proto.String("temperature"),
+					Number:   proto.Int32(1),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_FLOAT.Enum(),
+				}},
+			}},
+		}},
+	}
+	b, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestConvertFieldsProtobuf(t *testing.T) {
+	a := New(t)
+	appID := "AppID-1"
+	ctx := GetLogger(t, "TestConvertFieldsProtobuf")
+
+	h := &handler{
+		applications: application.NewRedisApplicationStore(GetRedisClient(), "handler-test-convert-fields-protobuf"),
+		qEvent:       make(chan *types.DeviceEvent, 1),
+	}
+
+	a.So(h.applications.Set(&application.Application{
+		AppID:                 appID,
+		PayloadFormat:         application.PayloadFormatProtobuf,
+		ProtobufDescriptorSet: buildProtobufDescriptorSet(t),
+		ProtobufMessages:      map[uint8]string{1: "test.Measurement"},
+	}), ShouldBeNil)
+	defer func() {
+		h.applications.Delete(appID)
+	}()
+
+	// Uplink
+	{
+		dev := new(device.Device)
+		ttnUp, appUp := buildCustomUplink(appID)
+		appUp.PayloadRaw = []byte{0x0d, 0x00, 0x00, 0xac, 0x41}
+		err := h.ConvertFieldsUp(ctx, ttnUp, appUp, dev)
+		a.So(err, ShouldBeNil)
+		a.So(appUp.PayloadFields, ShouldResemble, map[string]interface{}{
+			"temperature": 21.5,
+		})
+	}
+
+	// Downlink
+	{
+		ttnDown, appDown := buildCustomDownlink()
+		appDown.PayloadFields = map[string]interface{}{"temperature": 21.5}
+		err := h.ConvertFieldsDown(ctx, appDown, ttnDown, nil)
+		a.So(err, ShouldBeNil)
+		a.So(appDown.PayloadRaw, ShouldResemble, []byte{0x0d, 0x00, 0x00, 0xac, 0x41})
+	}
+}
+
+func TestPayloadFormatter(t *testing.T) {
+	a := New(t)
+
+	app := &application.Application{
+		AppID:         "AppID-1",
+		PayloadFormat: application.PayloadFormatCustom,
+		CustomDecoder: `function Decoder (data) { return { app: true }; }`,
+
+		PayloadFormatterRevision: 3,
+		AttributePayloadFormatters: []application.AttributePayloadFormatter{
+			{
+				Attribute:        "ttn-model",
+				Value:            "The Things Uno",
+				PayloadFormatter: application.PayloadFormatter{PayloadFormat: application.PayloadFormatCayenneLPP},
+			},
+		},
+	}
+
+	// Application default
+	formatter, source, revision := payloadFormatter(app, nil)
+	a.So(formatter.CustomDecoder, ShouldEqual, app.CustomDecoder)
+	a.So(source, ShouldEqual, "application")
+	a.So(revision, ShouldEqual, 3)
+	formatter, _, _ = payloadFormatter(app, &device.Device{})
+	a.So(formatter.CustomDecoder, ShouldEqual, app.CustomDecoder)
+	formatter, _, _ = payloadFormatter(app, &device.Device{Attributes: map[string]string{"ttn-model": "Other"}})
+	a.So(formatter.CustomDecoder, ShouldEqual, app.CustomDecoder)
+
+	// Attribute override
+	dev := &device.Device{Attributes: attributes}
+	formatter, source, revision = payloadFormatter(app, dev)
+	a.So(formatter.PayloadFormat, ShouldEqual, application.PayloadFormatCayenneLPP)
+	a.So(source, ShouldEqual, "attribute:ttn-model=The Things Uno")
+	a.So(revision, ShouldEqual, 0)
+
+	// Device override
+	dev.PayloadFormatter = &application.PayloadFormatter{
+		PayloadFormat: application.PayloadFormatCustom,
+		CustomDecoder: `function Decoder (data) { return { dev: true }; }`,
+	}
+	formatter, source, revision = payloadFormatter(app, dev)
+	a.So(formatter, ShouldEqual, dev.PayloadFormatter)
+	a.So(source, ShouldEqual, "device")
+	a.So(revision, ShouldEqual, 0)
+}
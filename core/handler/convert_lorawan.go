@@ -4,6 +4,7 @@
 package handler
 
 import (
+	"fmt"
 	"time"
 
 	pb_broker "github.com/TheThingsNetwork/api/broker"
@@ -58,7 +59,7 @@ func (h *handler) ConvertFromLoRaWAN(ctx ttnlog.Interface, ttnUp *pb_broker.Dedu
 	if dev.CurrentDownlink != nil && !appUp.IsRetry {
 		// We have a downlink pending
 		if dev.CurrentDownlink.Confirmed {
-			// If it's confirmed, we can only unset it if we receive an ack.
+			// If it's confirmed, we can only unset it if we receive an ack or if we give up retransmitting it.
 			if macPayload.Ack {
 				// Send event over MQTT
 				select {
@@ -74,6 +75,24 @@ func (h *handler) ConvertFromLoRaWAN(ctx ttnlog.Interface, ttnUp *pb_broker.Dedu
 					ctx.Warnf("Could not emit %q event", types.DownlinkAckEvent)
 				}
 				dev.CurrentDownlink = nil
+				dev.CurrentDownlinkAttempts = 0
+				dev.ConfirmedFCntReserved = false
+			} else if dev.CurrentDownlinkAttempts > h.confirmedDownlinkRetries {
+				select {
+				case h.qEvent <- &types.DeviceEvent{
+					AppID: appUp.AppID,
+					DevID: appUp.DevID,
+					Event: types.DownlinkNackEvent,
+					Data: types.DownlinkEventData{
+						ErrorEventData: types.ErrorEventData{Error: fmt.Sprintf("Downlink not acknowledged after %d attempts", dev.CurrentDownlinkAttempts)},
+						Message:        dev.CurrentDownlink,
+					},
+				}:
+				case <-time.After(eventPublishTimeout):
+					ctx.Warnf("Could not emit %q event", types.DownlinkNackEvent)
+				}
+				dev.CurrentDownlink = nil
+				dev.CurrentDownlinkAttempts = 0
 			}
 		} else {
 			// If it's unconfirmed, we can unset it.
@@ -111,6 +130,18 @@ func (h *handler) ConvertToLoRaWAN(ctx ttnlog.Interface, appDown *types.Downlink
 		phyPayload.MType = pb_lorawan.MType_CONFIRMED_DOWN
 	}
 
+	// Retransmissions of a confirmed downlink reuse its FCnt, which the Network Server reserves for them. Any other
+	// payload uses the FCnt of the template, which makes the Network Server abandon the confirmed downlink.
+	retransmission := appDown.Confirmed && dev.CurrentDownlink != nil && dev.CurrentDownlink.ID == appDown.ID && dev.CurrentDownlinkAttempts > 0
+	if retransmission && dev.ConfirmedFCntReserved {
+		macPayload.FCnt = dev.ConfirmedFCntDown
+		if ttnDown.DownlinkOption != nil {
+			if lorawan := ttnDown.DownlinkOption.ProtocolConfiguration.GetLoRaWAN(); lorawan != nil {
+				lorawan.FCnt = dev.ConfirmedFCntDown
+			}
+		}
+	}
+
 	if queue, err := h.devices.DownlinkQueue(dev.AppID, dev.DevID); err == nil {
 		if length, _ := queue.Length(); length > 0 {
 			macPayload.FPending = true
@@ -89,6 +89,37 @@ func TestConvertFromLoRaWAN(t *testing.T) {
 	a.So(appUp.Confirmed, ShouldBeTrue)
 
 	wg.Wait()
+
+	// Confirmed downlink that is not acknowledged after all retries
+	h.confirmedDownlinkRetries = 2
+	device.CurrentDownlink = &types.DownlinkMessage{ID: "nack", Confirmed: true}
+	nextUplink := func() {
+		ttnUp.UnmarshalPayload()
+		ttnUp.Message.GetLoRaWAN().GetMACPayload().FCnt++
+		md = ttnUp.GetProtocolMetadata()
+		md.GetLoRaWAN().FCnt = ttnUp.Message.GetLoRaWAN().GetMACPayload().FCnt
+		ttnUp.Message.GetLoRaWAN().GetMACPayload().Ack = false
+		ttnUp.Message.GetLoRaWAN().SetMIC(device.NwkSKey)
+		ttnUp.Payload = ttnUp.Message.GetLoRaWAN().PHYPayloadBytes()
+	}
+
+	device.CurrentDownlinkAttempts = 2
+	nextUplink()
+	err = h.ConvertFromLoRaWAN(h.Ctx, ttnUp, appUp, device)
+	a.So(err, ShouldBeNil)
+	a.So(device.CurrentDownlink, ShouldNotBeNil)
+	a.So(h.qEvent, ShouldBeEmpty)
+
+	device.CurrentDownlinkAttempts = 3
+	nextUplink()
+	err = h.ConvertFromLoRaWAN(h.Ctx, ttnUp, appUp, device)
+	a.So(err, ShouldBeNil)
+	a.So(device.CurrentDownlink, ShouldBeNil)
+	a.So(device.CurrentDownlinkAttempts, ShouldEqual, 0)
+	a.So(h.qEvent, ShouldHaveLength, 1)
+	evt := <-h.qEvent
+	a.So(evt.Event, ShouldEqual, types.DownlinkNackEvent)
+	a.So(evt.Data.(types.DownlinkEventData).Message.ID, ShouldEqual, "nack")
 }
 
 func buildLoRaWANDownlink(payload []byte) (*types.DownlinkMessage, *pb_broker.DownlinkMessage) {
@@ -141,3 +172,42 @@ func TestConvertToLoRaWAN(t *testing.T) {
 	a.So(err, ShouldBeNil)
 	a.So(ttnDown.Payload, ShouldResemble, []byte{0x60, 0x04, 0x03, 0x02, 0x01, 0x20, 0x01, 0x00, 0x94, 0xf8, 0xcf, 0x0d})
 }
+
+func TestConvertToLoRaWANReservedFCnt(t *testing.T) {
+	a := New(t)
+	h := &handler{
+		Component: &component.Component{Ctx: GetLogger(t, "TestConvertToLoRaWANReservedFCnt")},
+		devices:   device.NewRedisDeviceStore(GetRedisClient(), "handler-test-convert-to-lorawan-reserved-fcnt"),
+	}
+	confirmed := &types.DownlinkMessage{ID: "confirmed", PayloadRaw: []byte{0xaa, 0xbc}, Confirmed: true}
+	dev := &device.Device{
+		DevID:                   "devid",
+		AppID:                   "appid",
+		CurrentDownlink:         confirmed,
+		CurrentDownlinkAttempts: 1,
+		ConfirmedFCntDown:       1,
+		ConfirmedFCntReserved:   true,
+	}
+
+	// Retransmissions of the confirmed downlink use the reserved FCnt
+	appDown, ttnDown := buildLoRaWANDownlink([]byte{0xaa, 0xbc})
+	appDown.ID, appDown.Confirmed = confirmed.ID, true
+	ttnDown.UnmarshalPayload()
+	ttnDown.GetMessage().GetLoRaWAN().GetMACPayload().FCnt = 2
+	ttnDown.DownlinkOption.ProtocolConfiguration.GetLoRaWAN().FCnt = 2
+	err := h.ConvertToLoRaWAN(h.Ctx, appDown, ttnDown, dev)
+	a.So(err, ShouldBeNil)
+	a.So(ttnDown.GetMessage().GetLoRaWAN().GetMACPayload().FCnt, ShouldEqual, 1)
+	a.So(ttnDown.DownlinkOption.ProtocolConfiguration.GetLoRaWAN().FCnt, ShouldEqual, 1)
+
+	// Other payloads use the FCnt of the template
+	dev.CurrentDownlink = nil
+	appDown, ttnDown = buildLoRaWANDownlink([]byte{0xaa, 0xbc})
+	ttnDown.UnmarshalPayload()
+	ttnDown.GetMessage().GetLoRaWAN().GetMACPayload().FCnt = 2
+	ttnDown.DownlinkOption.ProtocolConfiguration.GetLoRaWAN().FCnt = 2
+	err = h.ConvertToLoRaWAN(h.Ctx, appDown, ttnDown, dev)
+	a.So(err, ShouldBeNil)
+	a.So(ttnDown.GetMessage().GetLoRaWAN().GetMACPayload().FCnt, ShouldEqual, 2)
+	a.So(ttnDown.DownlinkOption.ProtocolConfiguration.GetLoRaWAN().FCnt, ShouldEqual, 2)
+}
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
 	"github.com/TheThingsNetwork/ttn/core/types"
 	"github.com/fatih/structs"
 )
@@ -49,7 +50,17 @@ type Device struct {
 	AppSKey types.AppSKey `redis:"app_s_key"`
 	FCntUp  uint32        `redis:"f_cnt_up"` // Only used to detect retries
 
-	CurrentDownlink *types.DownlinkMessage `redis:"current_downlink"`
+	CurrentDownlink         *types.DownlinkMessage `redis:"current_downlink"`
+	CurrentDownlinkAttempts int                    `redis:"current_downlink_attempts"` // Number of times the (confirmed) current downlink was sent
+
+	// The Network Server keeps the FCnt of the last confirmed downlink reserved for retransmissions until it is acknowledged
+	ConfirmedFCntDown     uint32 `redis:"confirmed_f_cnt_down"`
+	ConfirmedFCntReserved bool   `redis:"confirmed_f_cnt_reserved"`
+
+	// PayloadFormatter overrides the payload formatter of the application for this device
+	PayloadFormatter *application.PayloadFormatter `redis:"payload_formatter"`
+	// DecoderState is the JSON-encoded state of a payload formatter that has DecoderState enabled
+	DecoderState string `redis:"decoder_state"`
 
 	CreatedAt time.Time `redis:"created_at"`
 	UpdatedAt time.Time `redis:"updated_at"`
@@ -72,6 +83,10 @@ func (d *Device) Clone() *Device {
 		n.CurrentDownlink = new(types.DownlinkMessage)
 		*n.CurrentDownlink = *d.CurrentDownlink
 	}
+	if d.PayloadFormatter != nil {
+		n.PayloadFormatter = new(application.PayloadFormatter)
+		*n.PayloadFormatter = *d.PayloadFormatter
+	}
 	return n
 }
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/TheThingsNetwork/ttn/core/storage"
 	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
 )
 
 // DownlinkQueue stores the Downlink queue
@@ -18,6 +19,8 @@ type DownlinkQueue interface {
 	Replace(msg *types.DownlinkMessage) error
 	PushFirst(msg *types.DownlinkMessage) error
 	PushLast(msg *types.DownlinkMessage) error
+	List() ([]*types.DownlinkMessage, error)
+	Delete(id string) error
 }
 
 // RedisDownlinkQueue implements the downlink queue in Redis
@@ -77,3 +80,45 @@ func (s *RedisDownlinkQueue) PushLast(msg *types.DownlinkMessage) error {
 	}
 	return s.queues.AddEnd(s.key(), string(qd))
 }
+
+// List the messages in the downlink queue
+func (s *RedisDownlinkQueue) List() ([]*types.DownlinkMessage, error) {
+	qd, err := s.queues.Get(s.key())
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]*types.DownlinkMessage, 0, len(qd))
+	for _, qd := range qd {
+		msg := new(types.DownlinkMessage)
+		if err := json.Unmarshal([]byte(qd), msg); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// Delete the message with the given ID from the downlink queue
+func (s *RedisDownlinkQueue) Delete(id string) error {
+	if id == "" {
+		return errors.NewErrInvalidArgument("Downlink ID", "empty")
+	}
+	qd, err := s.queues.Get(s.key())
+	if err != nil {
+		return err
+	}
+	for _, qd := range qd {
+		msg := new(types.DownlinkMessage)
+		if err := json.Unmarshal([]byte(qd), msg); err != nil || msg.ID != id {
+			continue
+		}
+		removed, err := s.queues.Remove(s.key(), qd)
+		if err != nil {
+			return err
+		}
+		if removed {
+			return nil
+		}
+	}
+	return errors.NewErrNotFound(fmt.Sprintf("Downlink %s", id))
+}
@@ -0,0 +1,78 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package device
+
+import (
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/jsoncodec"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+// DownlinkQueueRequest identifies the downlink queue of a device
+type DownlinkQueueRequest struct {
+	AppID string `json:"app_id"`
+	DevID string `json:"dev_id"`
+}
+
+// DownlinkQueueResponse contains the downlink messages of a device, starting with the message that is currently being
+// sent, and the downlink messages that are scheduled to be added to the queue later
+type DownlinkQueueResponse struct {
+	Downlinks []*types.DownlinkMessage `json:"downlinks"`
+	Scheduled []*types.DownlinkMessage `json:"scheduled,omitempty"`
+}
+
+// DeleteDownlinkRequest identifies a downlink message in the downlink queue of a device
+type DeleteDownlinkRequest struct {
+	AppID string `json:"app_id"`
+	DevID string `json:"dev_id"`
+	ID    string `json:"id"`
+}
+
+// DeleteDownlinkResponse is the response to a DeleteDownlinkRequest
+type DeleteDownlinkResponse struct{}
+
+// DownlinkQueueManagerServer is the server API for the DownlinkQueueManager service
+type DownlinkQueueManagerServer interface {
+	ListDownlinkQueue(context.Context, *DownlinkQueueRequest) (*DownlinkQueueResponse, error)
+	DeleteDownlink(context.Context, *DeleteDownlinkRequest) (*DeleteDownlinkResponse, error)
+}
+
+// DownlinkQueueManagerClient is the client API for the DownlinkQueueManager service
+type DownlinkQueueManagerClient interface {
+	ListDownlinkQueue(ctx context.Context, in *DownlinkQueueRequest, opts ...grpc.CallOption) (*DownlinkQueueResponse, error)
+	DeleteDownlink(ctx context.Context, in *DeleteDownlinkRequest, opts ...grpc.CallOption) (*DeleteDownlinkResponse, error)
+}
+
+type downlinkQueueManagerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewDownlinkQueueManagerClient returns a new DownlinkQueueManagerClient
+func NewDownlinkQueueManagerClient(cc *grpc.ClientConn) DownlinkQueueManagerClient {
+	return &downlinkQueueManagerClient{cc}
+}
+
+func (c *downlinkQueueManagerClient) ListDownlinkQueue(ctx context.Context, in *DownlinkQueueRequest, opts ...grpc.CallOption) (*DownlinkQueueResponse, error) {
+	out := new(DownlinkQueueResponse)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.DownlinkQueueManager/ListDownlinkQueue", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *downlinkQueueManagerClient) DeleteDownlink(ctx context.Context, in *DeleteDownlinkRequest, opts ...grpc.CallOption) (*DeleteDownlinkResponse, error) {
+	out := new(DeleteDownlinkResponse)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.DownlinkQueueManager/DeleteDownlink", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var downlinkQueueManagerServiceDesc = jsoncodec.ServiceDesc("handler.DownlinkQueueManager", (*DownlinkQueueManagerServer)(nil))
+
+// RegisterDownlinkQueueManagerServer registers the DownlinkQueueManager service
+func RegisterDownlinkQueueManagerServer(s *grpc.Server, srv DownlinkQueueManagerServer) {
+	s.RegisterService(downlinkQueueManagerServiceDesc, srv)
+}
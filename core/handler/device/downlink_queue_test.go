@@ -80,4 +80,26 @@ func TestDownlinkQueue(t *testing.T) {
 		a.So(next.PayloadRaw, ShouldResemble, []byte{0xaa, 0xbc})
 	}
 
+	{
+		a.So(s.PushLast(&types.DownlinkMessage{ID: "first", PayloadRaw: []byte{0x01}}), ShouldBeNil)
+		a.So(s.PushLast(&types.DownlinkMessage{ID: "second", PayloadRaw: []byte{0x02}}), ShouldBeNil)
+		a.So(s.PushLast(&types.DownlinkMessage{ID: "third", PayloadRaw: []byte{0x03}}), ShouldBeNil)
+	}
+
+	{
+		err := s.Delete("second")
+		a.So(err, ShouldBeNil)
+		err = s.Delete("second")
+		a.So(err, ShouldNotBeNil)
+		err = s.Delete("")
+		a.So(err, ShouldNotBeNil)
+	}
+
+	{
+		list, err := s.List()
+		a.So(err, ShouldBeNil)
+		a.So(list, ShouldHaveLength, 2)
+		a.So(list[0].ID, ShouldEqual, "first")
+		a.So(list[1].ID, ShouldEqual, "third")
+	}
 }
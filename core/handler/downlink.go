@@ -10,6 +10,7 @@ import (
 	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
 	"github.com/TheThingsNetwork/api/trace"
 	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/go-utils/random"
 	"github.com/TheThingsNetwork/ttn/core/types"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"github.com/TheThingsNetwork/ttn/utils/toa"
@@ -60,6 +61,35 @@ func (h *handler) EnqueueDownlink(appDownlink *types.DownlinkMessage) (err error
 		return errors.NewErrInvalidArgument("Downlink Payload", "empty")
 	}
 
+	scheduled := appDownlink.At != nil || appDownlink.Recurrence != ""
+
+	if appDownlink.TTL != "" {
+		ttl, parseErr := time.ParseDuration(appDownlink.TTL)
+		if parseErr != nil || ttl <= 0 {
+			return errors.NewErrInvalidArgument("Downlink TTL", "must be a positive duration")
+		}
+		// The TTL of a scheduled downlink starts when it is added to the queue
+		if !scheduled {
+			expiresAt := types.JSONTime(time.Now().Add(ttl))
+			appDownlink.ExpiresAt = &expiresAt
+			appDownlink.TTL = ""
+		}
+	}
+
+	switch appDownlink.Schedule {
+	case types.ScheduleReplace, types.ScheduleFirst, types.ScheduleLast, "": // Empty string for default
+	default:
+		return errors.NewErrInvalidArgument("ScheduleType", "unknown")
+	}
+
+	if appDownlink.ID == "" {
+		appDownlink.ID = random.String(16)
+	}
+
+	if scheduled {
+		return h.scheduleDownlink(ctx, appDownlink)
+	}
+
 	// Clear redundant fields
 	appDownlink.AppID = ""
 	appDownlink.DevID = ""
@@ -80,8 +110,6 @@ func (h *handler) EnqueueDownlink(appDownlink *types.DownlinkMessage) (err error
 		err = queue.PushFirst(appDownlink)
 	case types.ScheduleLast:
 		err = queue.PushLast(appDownlink)
-	default:
-		return errors.NewErrInvalidArgument("ScheduleType", "unknown")
 	}
 
 	if err != nil {
@@ -107,6 +135,9 @@ func (h *handler) EnqueueDownlink(appDownlink *types.DownlinkMessage) (err error
 	return nil
 }
 
+// DefaultConfirmedDownlinkRetries is the number of times a confirmed downlink is retransmitted before it is dropped
+var DefaultConfirmedDownlinkRetries = 8
+
 func (h *handler) HandleDownlink(appDownlink *types.DownlinkMessage, downlink *pb_broker.DownlinkMessage) (err error) {
 	appID, devID := appDownlink.AppID, appDownlink.DevID
 
@@ -184,8 +215,35 @@ func (h *handler) HandleDownlink(appDownlink *types.DownlinkMessage, downlink *p
 
 	h.downlink <- downlink
 
-	downlinkConfig := types.DownlinkEventConfigInfo{}
+	if appDownlink.Confirmed && dev.CurrentDownlink != nil && dev.CurrentDownlink.ID == appDownlink.ID {
+		dev.CurrentDownlinkAttempts++
+	}
+	if lorawan := downlink.GetMessage().GetLoRaWAN(); lorawan != nil && lorawan.GetMACPayload() != nil {
+		dev.ConfirmedFCntDown = lorawan.GetMACPayload().FCnt
+		dev.ConfirmedFCntReserved = lorawan.MType == pb_lorawan.MType_CONFIRMED_DOWN
+	}
+
+	select {
+	case h.qEvent <- &types.DeviceEvent{
+		AppID: appDownlink.AppID,
+		DevID: appDownlink.DevID,
+		Event: types.DownlinkSentEvent,
+		Data: types.DownlinkEventData{
+			Payload:   downlink.Payload,
+			Message:   appDownlink,
+			GatewayID: downlink.DownlinkOption.GatewayID,
+			Config:    downlinkEventConfig(downlink),
+		},
+	}:
+	case <-time.After(eventPublishTimeout):
+		ctx.Warnf("Could not emit %q event", types.DownlinkSentEvent)
+	}
+	return nil
+}
 
+// downlinkEventConfig returns the transmission settings of the downlink for the events
+func downlinkEventConfig(downlink *pb_broker.DownlinkMessage) *types.DownlinkEventConfigInfo {
+	downlinkConfig := &types.DownlinkEventConfigInfo{}
 	if lorawan := downlink.DownlinkOption.ProtocolConfiguration.GetLoRaWAN(); lorawan != nil {
 		downlinkConfig.Modulation = lorawan.Modulation.String()
 		downlinkConfig.DataRate = lorawan.DataRate
@@ -200,21 +258,34 @@ func (h *handler) HandleDownlink(appDownlink *types.DownlinkMessage, downlink *p
 	}
 	downlinkConfig.Frequency = uint(downlink.DownlinkOption.GatewayConfiguration.Frequency)
 	downlinkConfig.Power = int(downlink.DownlinkOption.GatewayConfiguration.Power)
+	return downlinkConfig
+}
 
+// HandleDownlinkFallback publishes an event for a downlink that the Broker sent through another DownlinkOption than
+// the one that the Handler selected
+func (h *handler) HandleDownlinkFallback(downlink *pb_broker.DownlinkMessage) error {
+	if downlink.AppID == "" || downlink.DevID == "" || downlink.DownlinkOption == nil {
+		return errors.NewErrInvalidArgument("Downlink", "must contain AppID, DevID and DownlinkOption")
+	}
+	ctx := h.Ctx.WithFields(ttnlog.Fields{
+		"AppID":     downlink.AppID,
+		"DevID":     downlink.DevID,
+		"GatewayID": downlink.DownlinkOption.GatewayID,
+	})
+	ctx.Debug("Downlink sent through fallback")
 	select {
 	case h.qEvent <- &types.DeviceEvent{
-		AppID: appDownlink.AppID,
-		DevID: appDownlink.DevID,
-		Event: types.DownlinkSentEvent,
+		AppID: downlink.AppID,
+		DevID: downlink.DevID,
+		Event: types.DownlinkFallbackEvent,
 		Data: types.DownlinkEventData{
 			Payload:   downlink.Payload,
-			Message:   appDownlink,
 			GatewayID: downlink.DownlinkOption.GatewayID,
-			Config:    &downlinkConfig,
+			Config:    downlinkEventConfig(downlink),
 		},
 	}:
 	case <-time.After(eventPublishTimeout):
-		ctx.Warnf("Could not emit %q event", types.DownlinkSentEvent)
+		ctx.Warnf("Could not emit %q event", types.DownlinkFallbackEvent)
 	}
 	return nil
 }
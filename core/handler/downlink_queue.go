@@ -0,0 +1,130 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TheThingsNetwork/go-account-lib/rights"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+)
+
+// nextDownlink returns the next downlink message in the queue, skipping (and emitting events for) expired messages
+func (h *handler) nextDownlink(ctx ttnlog.Interface, appID, devID string, queue device.DownlinkQueue) (*types.DownlinkMessage, error) {
+	for {
+		next, err := queue.Next()
+		if err != nil || next == nil {
+			return nil, err
+		}
+		if !next.Expired(time.Now()) {
+			return next, nil
+		}
+		h.emitExpiredDownlink(ctx, appID, devID, next)
+	}
+}
+
+func (h *handler) emitExpiredDownlink(ctx ttnlog.Interface, appID, devID string, msg *types.DownlinkMessage) {
+	ctx.WithField("DownlinkID", msg.ID).Debug("Downlink expired")
+	select {
+	case h.qEvent <- &types.DeviceEvent{
+		AppID: appID,
+		DevID: devID,
+		Event: types.DownlinkErrorEvent,
+		Data: types.DownlinkEventData{
+			ErrorEventData: types.ErrorEventData{Error: "Downlink expired"},
+			Message:        msg,
+		},
+	}:
+	case <-time.After(eventPublishTimeout):
+		ctx.Warnf("Could not emit %q event", types.DownlinkErrorEvent)
+	}
+}
+
+func (h *handlerManager) ListDownlinkQueue(ctx context.Context, in *device.DownlinkQueueRequest) (*device.DownlinkQueueResponse, error) {
+	if in.AppID == "" || in.DevID == "" {
+		return nil, errors.NewErrInvalidArgument("Downlink Queue Request", "must contain AppID and DevID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+	dev, err := h.handler.devices.Get(in.AppID, in.DevID)
+	if err != nil {
+		return nil, err
+	}
+	queue, err := h.handler.devices.DownlinkQueue(in.AppID, in.DevID)
+	if err != nil {
+		return nil, err
+	}
+	downlinks, err := queue.List()
+	if err != nil {
+		return nil, err
+	}
+	if dev.CurrentDownlink != nil {
+		downlinks = append([]*types.DownlinkMessage{dev.CurrentDownlink}, downlinks...)
+	}
+	res := &device.DownlinkQueueResponse{Downlinks: downlinks}
+	if h.handler.scheduled != nil {
+		scheduled, err := h.handler.scheduled.List(in.AppID, in.DevID)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(scheduled, func(i, j int) bool { return scheduled[i].NextAt.Before(scheduled[j].NextAt) })
+		for _, downlink := range scheduled {
+			res.Scheduled = append(res.Scheduled, downlink.ToDownlinkMessage())
+		}
+	}
+	return res, nil
+}
+
+func (h *handlerManager) DeleteDownlink(ctx context.Context, in *device.DeleteDownlinkRequest) (*device.DeleteDownlinkResponse, error) {
+	if in.AppID == "" || in.DevID == "" || in.ID == "" {
+		return nil, errors.NewErrInvalidArgument("Delete Downlink Request", "must contain AppID, DevID and ID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.WriteDownlink); err != nil {
+		return nil, err
+	}
+	if err := h.handler.deleteDownlink(in.AppID, in.DevID, in.ID); err != nil {
+		return nil, err
+	}
+	return &device.DeleteDownlinkResponse{}, nil
+}
+
+// deleteDownlink deletes the downlink message with the given ID from the queue of the device, cancels it if it is
+// currently being sent, or removes it from the scheduled downlinks
+func (h *handler) deleteDownlink(appID, devID, id string) error {
+	dev, err := h.devices.Get(appID, devID)
+	if err != nil {
+		return err
+	}
+	if h.scheduled != nil {
+		if _, err := h.scheduled.Get(appID, devID, id); err == nil {
+			return h.scheduled.Delete(appID, devID, id)
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	if dev.CurrentDownlink != nil && dev.CurrentDownlink.ID == id {
+		dev.StartUpdate()
+		dev.CurrentDownlink = nil
+		return h.devices.Set(dev)
+	}
+	queue, err := h.devices.DownlinkQueue(appID, devID)
+	if err != nil {
+		return err
+	}
+	return queue.Delete(id)
+}
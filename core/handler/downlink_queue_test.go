@@ -0,0 +1,79 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+	"golang.org/x/net/context"
+)
+
+func TestDownlinkQueueExpiry(t *testing.T) {
+	a := New(t)
+
+	appID, devID := "downlink-queue-app", "dev"
+	h := &handler{
+		Component: &component.Component{Ctx: GetLogger(t, "TestDownlinkQueueExpiry")},
+		devices:   device.NewRedisDeviceStore(GetRedisClient(), "handler-test-downlink-queue-expiry"),
+		qEvent:    make(chan *types.DeviceEvent, 10),
+	}
+	h.devices.Set(&device.Device{AppID: appID, DevID: devID})
+	defer h.devices.Delete(appID, devID)
+
+	queue, _ := h.devices.DownlinkQueue(appID, devID)
+	expired := types.JSONTime(time.Now().Add(-1 * time.Minute))
+	valid := types.JSONTime(time.Now().Add(time.Minute))
+	queue.PushLast(&types.DownlinkMessage{ID: "expired", PayloadRaw: []byte{0x01}, ExpiresAt: &expired})
+	queue.PushLast(&types.DownlinkMessage{ID: "valid", PayloadRaw: []byte{0x02}, ExpiresAt: &valid})
+
+	next, err := h.nextDownlink(h.Ctx, appID, devID, queue)
+	a.So(err, ShouldBeNil)
+	a.So(next, ShouldNotBeNil)
+	a.So(next.ID, ShouldEqual, "valid")
+
+	select {
+	case event := <-h.qEvent:
+		a.So(event.Event, ShouldEqual, types.DownlinkErrorEvent)
+		a.So(event.Data.(types.DownlinkEventData).Message.ID, ShouldEqual, "expired")
+	default:
+		t.Fatal("No event for expired downlink")
+	}
+
+	next, err = h.nextDownlink(h.Ctx, appID, devID, queue)
+	a.So(err, ShouldBeNil)
+	a.So(next, ShouldBeNil)
+}
+
+func TestDeleteDownlink(t *testing.T) {
+	a := New(t)
+
+	appID, devID := "downlink-queue-app", "dev"
+	h := &handler{
+		Component: &component.Component{Ctx: GetLogger(t, "TestDeleteDownlink")},
+		devices:   device.NewRedisDeviceStore(GetRedisClient(), "handler-test-delete-downlink"),
+	}
+	h.devices.Set(&device.Device{AppID: appID, DevID: devID})
+	defer h.devices.Delete(appID, devID)
+
+	queue, _ := h.devices.DownlinkQueue(appID, devID)
+	queue.PushLast(&types.DownlinkMessage{ID: "queued", PayloadRaw: []byte{0x02}})
+
+	a.So(h.deleteDownlink(appID, devID, "queued"), ShouldBeNil)
+	a.So(h.deleteDownlink(appID, devID, "queued"), ShouldNotBeNil)
+	length, _ := queue.Length()
+	a.So(length, ShouldEqual, 0)
+
+	// The RPCs require authentication
+	m := &handlerManager{handler: h}
+	_, err := m.ListDownlinkQueue(context.Background(), &device.DownlinkQueueRequest{AppID: appID, DevID: devID})
+	a.So(err, ShouldNotBeNil)
+	_, err = m.DeleteDownlink(context.Background(), &device.DeleteDownlinkRequest{AppID: appID, DevID: devID})
+	a.So(err, ShouldNotBeNil)
+}
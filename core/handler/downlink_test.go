@@ -189,3 +189,32 @@ func TestHandleDownlink(t *testing.T) {
 	a.So(err, ShouldBeNil)
 	wg.WaitFor(100 * time.Millisecond)
 }
+
+func TestHandleDownlinkFallback(t *testing.T) {
+	a := New(t)
+	h := &handler{
+		Component: &component.Component{Ctx: GetLogger(t, "TestHandleDownlinkFallback")},
+		qEvent:    make(chan *types.DeviceEvent, 10),
+	}
+
+	downlink := pb_broker.RandomDownlinkMessage()
+	downlink.AppID, downlink.DevID = "", ""
+
+	err := h.HandleDownlinkFallback(downlink)
+	a.So(err, ShouldNotBeNil)
+
+	downlink.AppID = "app3"
+	downlink.DevID = "dev3"
+	err = h.HandleDownlinkFallback(downlink)
+	a.So(err, ShouldBeNil)
+
+	a.So(h.qEvent, ShouldHaveLength, 1)
+	evt := <-h.qEvent
+	a.So(evt.AppID, ShouldEqual, "app3")
+	a.So(evt.DevID, ShouldEqual, "dev3")
+	a.So(evt.Event, ShouldEqual, types.DownlinkFallbackEvent)
+	data, ok := evt.Data.(types.DownlinkEventData)
+	a.So(ok, ShouldBeTrue)
+	a.So(data.GatewayID, ShouldEqual, downlink.DownlinkOption.GatewayID)
+	a.So(data.Config.Frequency, ShouldEqual, downlink.DownlinkOption.GatewayConfiguration.Frequency)
+}
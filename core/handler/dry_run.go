@@ -5,11 +5,16 @@ package handler
 
 import (
 	"encoding/json"
+	"time"
 
 	pb "github.com/TheThingsNetwork/api/handler"
+	"github.com/TheThingsNetwork/go-account-lib/rights"
 	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/binarystruct"
 	"github.com/TheThingsNetwork/ttn/core/handler/cayennelpp"
 	"github.com/TheThingsNetwork/ttn/core/handler/functions"
+	"github.com/TheThingsNetwork/ttn/core/handler/payload"
+	"github.com/TheThingsNetwork/ttn/core/handler/protobuf"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"golang.org/x/net/context"
 )
@@ -23,17 +28,35 @@ func (h *handlerManager) DryUplink(ctx context.Context, in *pb.DryUplinkMessage)
 	flds := ""
 	valid := true
 	var logs []*pb.LogEntry
-	var decoder PayloadDecoder
+	var decoder payload.Decoder
 	switch application.PayloadFormat(app.PayloadFormat) {
 	case "", application.PayloadFormatCustom:
-		decoder = &CustomUplinkFunctions{
+		decoder = &payload.CustomUplinkFunctions{
 			Decoder:   app.Decoder,
 			Converter: app.Converter,
 			Validator: app.Validator,
 			Logger:    functions.NewEntryLogger(),
+			Runtime:   functions.Isolated,
+			Metadata: &application.UplinkMetadata{
+				AppID: app.AppID,
+				Time:  time.Now().UTC(),
+			},
 		}
 	case application.PayloadFormatCayenneLPP:
 		decoder = &cayennelpp.Decoder{}
+	case application.PayloadFormatCayenneLPPPacked:
+		decoder = &cayennelpp.Decoder{Packed: true}
+	case application.PayloadFormatProtobuf:
+		formatter, err := h.getStoredPayloadFormatter(ctx, app.AppID)
+		if err != nil {
+			return nil, err
+		}
+		decoder = &protobuf.Decoder{
+			DescriptorSet: formatter.ProtobufDescriptorSet,
+			Messages:      formatter.ProtobufMessages,
+		}
+	case application.PayloadFormatStruct:
+		decoder = &binarystruct.Decoder{Layout: app.Decoder}
 	default:
 		return nil, errors.NewErrInvalidArgument("App", "unknown payload format")
 	}
@@ -80,15 +103,29 @@ func (h *handlerManager) DryDownlink(ctx context.Context, in *pb.DryDownlinkMess
 		return nil, errors.NewErrInvalidArgument("Downlink", "Neither Fields nor Payload provided")
 	}
 
-	var encoder PayloadEncoder
+	var encoder payload.Encoder
 	switch application.PayloadFormat(in.App.PayloadFormat) {
 	case "", application.PayloadFormatCustom:
-		encoder = &CustomDownlinkFunctions{
+		encoder = &payload.CustomDownlinkFunctions{
 			Encoder: app.Encoder,
 			Logger:  functions.NewEntryLogger(),
+			Runtime: functions.Isolated,
 		}
 	case application.PayloadFormatCayenneLPP:
 		encoder = &cayennelpp.Encoder{}
+	case application.PayloadFormatCayenneLPPPacked:
+		encoder = &cayennelpp.Encoder{Packed: true}
+	case application.PayloadFormatProtobuf:
+		formatter, err := h.getStoredPayloadFormatter(ctx, app.AppID)
+		if err != nil {
+			return nil, err
+		}
+		encoder = &protobuf.Encoder{
+			DescriptorSet: formatter.ProtobufDescriptorSet,
+			Messages:      formatter.ProtobufMessages,
+		}
+	case application.PayloadFormatStruct:
+		encoder = &binarystruct.Encoder{Layout: app.Decoder}
 	default:
 		return nil, errors.NewErrInvalidArgument("App", "unknown payload format")
 	}
@@ -109,3 +146,24 @@ func (h *handlerManager) DryDownlink(ctx context.Context, in *pb.DryDownlinkMess
 		Logs:    encoder.Log(),
 	}, nil
 }
+
+// getStoredPayloadFormatter returns the payload formatter that is stored for the application. This is used for dry
+// runs of payload formats that have settings that are not part of the application in the DryUplinkMessage and
+// DryDownlinkMessage, such as the Protocol Buffers descriptors.
+func (h *handlerManager) getStoredPayloadFormatter(ctx context.Context, appID string) (*application.PayloadFormatter, error) {
+	if appID == "" {
+		return nil, errors.NewErrInvalidArgument("App", "must contain AppID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, appID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	app, err := h.handler.applications.Get(appID)
+	if err != nil {
+		return nil, err
+	}
+	return app.GetPayloadFormatter(), nil
+}
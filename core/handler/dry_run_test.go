@@ -114,6 +114,24 @@ func TestDryUplinkFieldsCustom(t *testing.T) {
 	a.So(store.count("delete"), ShouldEqual, 0)
 }
 
+func TestDryUplinkFieldsMetadata(t *testing.T) {
+	a := New(t)
+
+	m := &handlerManager{handler: &handler{}}
+
+	res, err := m.DryUplink(context.TODO(), &pb.DryUplinkMessage{
+		Payload: []byte{11},
+		App: pb.Application{
+			AppID:         "DryUplinkMetadata",
+			PayloadFormat: "custom",
+			Decoder: `function Decoder (bytes, port, metadata) {
+				return { app: metadata.app_id, attributes: metadata.attributes }}`,
+		},
+	})
+	a.So(err, ShouldBeNil)
+	a.So(res.Fields, ShouldEqual, `{"app":"DryUplinkMetadata","attributes":{}}`)
+}
+
 func TestDryUplinkFieldsCayenneLPP(t *testing.T) {
 	a := New(t)
 
@@ -139,6 +157,48 @@ func TestDryUplinkFieldsCayenneLPP(t *testing.T) {
 	a.So(res.Valid, ShouldBeTrue)
 }
 
+const dryRunStructLayout = `
+ports:
+  1:
+    - name: temperature
+      offset: 0
+      length: 2
+      signed: true
+      scale: 0.1
+`
+
+func TestDryUplinkFieldsStruct(t *testing.T) {
+	a := New(t)
+
+	store := newCountingStore(application.NewRedisApplicationStore(GetRedisClient(), "handler-test-dry-uplink"))
+	h := &handler{
+		applications: store,
+	}
+	m := &handlerManager{handler: h}
+
+	dryUplinkMessage := &pb.DryUplinkMessage{
+		Payload: []byte{0x00, 0xf5},
+		App: pb.Application{
+			AppID:         "DryUplinkFields",
+			PayloadFormat: "struct",
+			Decoder:       dryRunStructLayout,
+		},
+		Port: 1,
+	}
+
+	res, err := m.DryUplink(context.TODO(), dryUplinkMessage)
+	a.So(err, ShouldBeNil)
+
+	a.So(res.Payload, ShouldResemble, dryUplinkMessage.Payload)
+	a.So(res.Fields, ShouldEqual, `{"temperature":24.5}`)
+	a.So(res.Valid, ShouldBeTrue)
+
+	// Invalid layout
+	dryUplinkMessage.App.Decoder = "ports: {}"
+	_, err = m.DryUplink(context.TODO(), dryUplinkMessage)
+	a.So(err, ShouldNotBeNil)
+}
+
 func TestDryUplinkEmptyApp(t *testing.T) {
 	a := New(t)
 
@@ -227,6 +287,30 @@ func TestDryDownlinkFieldsCayenneLPP(t *testing.T) {
 	a.So(res.Payload, ShouldResemble, []byte{5, 249, 232})
 }
 
+func TestDryDownlinkFieldsStruct(t *testing.T) {
+	a := New(t)
+
+	store := newCountingStore(application.NewRedisApplicationStore(GetRedisClient(), "handler-test-dry-downlink"))
+	h := &handler{
+		applications: store,
+	}
+	m := &handlerManager{handler: h}
+
+	msg := &pb.DryDownlinkMessage{
+		Fields: `{ "temperature": -1.5 }`,
+		App: pb.Application{
+			PayloadFormat: "struct",
+			Decoder:       dryRunStructLayout,
+		},
+		Port: 1,
+	}
+
+	res, err := m.DryDownlink(context.TODO(), msg)
+	a.So(err, ShouldBeNil)
+
+	a.So(res.Payload, ShouldResemble, []byte{0xff, 0xf1})
+}
+
 func TestDryDownlinkPayload(t *testing.T) {
 	a := New(t)
 
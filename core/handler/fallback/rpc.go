@@ -0,0 +1,77 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package fallback contains the DownlinkFallback service, that the Broker uses to tell the Handler that it sent a
+// downlink through another DownlinkOption than the one that the Handler selected, because the Router of that option
+// was not available.
+package fallback
+
+import (
+	pb_broker "github.com/TheThingsNetwork/api/broker"
+	gogo "github.com/gogo/protobuf/types"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+// DownlinkFallbackServer is the server API for the DownlinkFallback service
+type DownlinkFallbackServer interface {
+	// Report the downlink as it was sent, with the DownlinkOption that was used
+	Report(context.Context, *pb_broker.DownlinkMessage) (*gogo.Empty, error)
+}
+
+// DownlinkFallbackClient is the client API for the DownlinkFallback service
+type DownlinkFallbackClient interface {
+	Report(ctx context.Context, in *pb_broker.DownlinkMessage, opts ...grpc.CallOption) (*gogo.Empty, error)
+}
+
+type downlinkFallbackClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewDownlinkFallbackClient returns a new DownlinkFallbackClient
+func NewDownlinkFallbackClient(cc *grpc.ClientConn) DownlinkFallbackClient {
+	return &downlinkFallbackClient{cc}
+}
+
+func (c *downlinkFallbackClient) Report(ctx context.Context, in *pb_broker.DownlinkMessage, opts ...grpc.CallOption) (*gogo.Empty, error) {
+	out := new(gogo.Empty)
+	if err := c.cc.Invoke(ctx, "/handler.DownlinkFallback/Report", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func reportHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(pb_broker.DownlinkMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DownlinkFallbackServer).Report(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/handler.DownlinkFallback/Report",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DownlinkFallbackServer).Report(ctx, req.(*pb_broker.DownlinkMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var downlinkFallbackServiceDesc = grpc.ServiceDesc{
+	ServiceName: "handler.DownlinkFallback",
+	HandlerType: (*DownlinkFallbackServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Report",
+			Handler:    reportHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// RegisterDownlinkFallbackServer registers the DownlinkFallback service
+func RegisterDownlinkFallbackServer(s *grpc.Server, srv DownlinkFallbackServer) {
+	s.RegisterService(&downlinkFallbackServiceDesc, srv)
+}
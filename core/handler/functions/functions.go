@@ -4,81 +4,18 @@
 package functions
 
 import (
-	"fmt"
 	"time"
-
-	"github.com/TheThingsNetwork/ttn/utils/errors"
-	"github.com/robertkrimen/otto"
 )
 
-var errTimeOutExceeded = errors.NewErrInternal("Code has been running to long")
-
-func RunCode(name, code string, env map[string]interface{}, timeout time.Duration, logger Logger) (val interface{}, err error) {
-	vm := otto.New()
-	vm.SetStackDepthLimit(32)
-
-	// load the environment
-	for key, val := range env {
-		vm.Set(key, val)
-	}
-
-	if logger == nil {
-		logger = Ignore
-	}
-	logger.Enter(name)
-
-	vm.Set("__log", func(call otto.FunctionCall) otto.Value {
-		logger.Log(call)
-		return otto.UndefinedValue()
-	})
-	vm.Run("console.log = __log")
-
-	start := time.Now()
-
-	defer func() {
-		duration := time.Since(start)
-		if caught := recover(); caught != nil {
-			val = nil
-			switch {
-			case caught == errTimeOutExceeded:
-				err = errors.NewErrInternal(fmt.Sprintf("Interrupted javascript execution for %s after %v", name, duration))
-			default:
-				err = errors.NewErrInternal(fmt.Sprintf("Fatal error in %s: %s", name, caught))
-			}
-			return
-		}
-	}()
-
-	vm.Interrupt = make(chan func(), 1)
-
-	go func() {
-		time.Sleep(timeout)
-		vm.Interrupt <- func() {
-			panic(errTimeOutExceeded)
-		}
-	}()
-
-	oVal, err := vm.Run(code)
-	if err != nil {
-		return nil, errors.NewErrInternal(fmt.Sprintf("%s threw error: %s", name, err))
-	}
+// Runtime runs JavaScript code
+type Runtime interface {
+	// RunCode runs the code with the values of env as globals and returns the value of the last statement
+	RunCode(name, code string, env map[string]interface{}, timeout time.Duration, logger Logger) (interface{}, error)
+}
 
-	switch {
-	case oVal.IsBoolean():
-		return oVal.ToBoolean()
-	case oVal.IsNull(), oVal.IsUndefined():
-		return nil, nil
-	case oVal.IsNumber():
-		f, _ := oVal.ToFloat()
-		if float64(int64(f)) == f {
-			return oVal.ToInteger()
-		}
-		return f, nil
-	case oVal.IsObject():
-		return oVal.Export()
-	case oVal.IsString():
-		return oVal.ToString()
-	}
+var defaultPool = NewPool()
 
-	return nil, errors.NewErrInternal(fmt.Sprintf("%s return value invalid", name))
+// RunCode runs the code in a VM of the default pool
+func RunCode(name, code string, env map[string]interface{}, timeout time.Duration, logger Logger) (val interface{}, err error) {
+	return defaultPool.RunCode(name, code, env, timeout, logger)
 }
@@ -4,6 +4,7 @@
 package functions
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -107,3 +108,158 @@ func TestRunDangerousCode(t *testing.T) {
 	a.So(err, ShouldNotBeNil)
 	a.So(out, ShouldBeNil)
 }
+
+func TestRunModernCode(t *testing.T) {
+	a := New(t)
+
+	env := map[string]interface{}{
+		"payload": []byte{0x01, 0x02, 0x03, 0x04},
+	}
+
+	code := `
+		const decode = (bytes) => {
+			const view = new DataView(new Uint8Array(bytes).buffer);
+			let { a, b } = { a: view.getUint16(0), b: view.getUint16(2, true) };
+			return { a, b, text: ` + "`${a}-${b}`" + ` };
+		};
+		decode(payload);
+	`
+
+	val, err := RunCode("test", code, env, time.Second, nil)
+	a.So(err, ShouldBeNil)
+	a.So(val, ShouldResemble, map[string]interface{}{
+		"a":    int64(0x0102),
+		"b":    int64(0x0403),
+		"text": "258-1027",
+	})
+
+	val, err = RunCode("test", `new Uint8Array([1, 2, 255])`, nil, time.Second, nil)
+	a.So(err, ShouldBeNil)
+	a.So(val, ShouldResemble, []interface{}{int64(1), int64(2), int64(255)})
+}
+
+func TestRunLegacyCode(t *testing.T) {
+	a := New(t)
+
+	logger := NewEntryLogger()
+	code := `
+		(function (port) {
+			console.log({ b: 1, a: 2 })
+			return {
+				foo: 1
+				port: port,
+			}
+		})(port)
+	`
+
+	val, err := RunCode("test", code, map[string]interface{}{"port": 1}, time.Second, logger)
+	a.So(err, ShouldBeNil)
+	a.So(val, ShouldNotBeNil)
+	a.So(val.(map[string]interface{})["port"], ShouldEqual, 1)
+	a.So(logger.Entries()[0].Fields, ShouldResemble, []string{`{"a":2,"b":1}`})
+}
+
+func TestRunCodeResetsEnvironment(t *testing.T) {
+	a := New(t)
+
+	pool := NewPool()
+	_, err := pool.RunCode("test", `payload`, map[string]interface{}{"payload": 1}, time.Second, nil)
+	a.So(err, ShouldBeNil)
+
+	val, err := pool.RunCode("test", `typeof payload`, nil, time.Second, nil)
+	a.So(err, ShouldBeNil)
+	a.So(val, ShouldEqual, "undefined")
+}
+
+func TestRunCodeTimeout(t *testing.T) {
+	a := New(t)
+
+	pool := NewPool()
+	_, err := pool.RunCode("test", `while (true) {}`, nil, 10*time.Millisecond, nil)
+	a.So(err, ShouldNotBeNil)
+	a.So(err.Error(), ShouldContainSubstring, "Interrupted")
+
+	// The pool does not reuse the interrupted VM
+	val, err := pool.RunCode("test", `1 + 1`, nil, time.Second, nil)
+	a.So(err, ShouldBeNil)
+	a.So(val, ShouldEqual, 2)
+}
+
+func TestRunCodeStackDepth(t *testing.T) {
+	a := New(t)
+
+	_, err := RunCode("test", `function f(n) { return f(n + 1); } f(0)`, nil, time.Second, nil)
+	a.So(err, ShouldNotBeNil)
+}
+
+func TestApplicationRuntime(t *testing.T) {
+	a := New(t)
+
+	_, err := ApplicationRuntime("app-1").RunCode("test", `var leaked = 42; leaked`, nil, time.Second, nil)
+	a.So(err, ShouldBeNil)
+
+	val, err := ApplicationRuntime("app-2").RunCode("test", `typeof leaked`, nil, time.Second, nil)
+	a.So(err, ShouldBeNil)
+	a.So(val, ShouldEqual, "undefined")
+}
+
+const benchmarkDecoder = `
+	function Decoder(bytes, port) {
+		var decoded = {};
+		if (port === 1) {
+			decoded.temperature = ((bytes[0] << 8) | bytes[1]) / 100;
+			decoded.humidity = bytes[2] / 2;
+			decoded.battery = (bytes[3] << 8) | bytes[4];
+		}
+		return decoded;
+	}
+	Decoder(payload.slice(0), port);
+`
+
+var benchmarkEnv = map[string]interface{}{
+	"payload": []byte{0x08, 0x70, 0x50, 0x0e, 0x10},
+	"port":    uint8(1),
+}
+
+var benchmarkResult interface{}
+
+// BenchmarkRunCode measures the cost of running a decoder for an uplink, with a compiled program and a VM from the
+// pool
+func BenchmarkRunCode(b *testing.B) {
+	pool := NewPool()
+	var r interface{}
+	for n := 0; n < b.N; n++ {
+		r, _ = pool.RunCode("Decoder", benchmarkDecoder, benchmarkEnv, time.Second, Ignore)
+	}
+	benchmarkResult = r
+}
+
+// BenchmarkRunCodeNewVM measures the cost of running a decoder for an uplink in a new VM, with a compiled program
+func BenchmarkRunCodeNewVM(b *testing.B) {
+	var r interface{}
+	for n := 0; n < b.N; n++ {
+		r, _ = NewPool().RunCode("Decoder", benchmarkDecoder, benchmarkEnv, time.Second, Ignore)
+	}
+	benchmarkResult = r
+}
+
+// BenchmarkRunCodeUncached measures the cost of running a decoder for an uplink in a new VM, compiling the program
+// for every uplink
+func BenchmarkRunCodeUncached(b *testing.B) {
+	var r interface{}
+	for n := 0; n < b.N; n++ {
+		code := fmt.Sprintf("%s // %d", benchmarkDecoder, n)
+		r, _ = NewPool().RunCode("Decoder", code, benchmarkEnv, time.Second, Ignore)
+	}
+	benchmarkResult = r
+}
+
+// BenchmarkLog measures the cost of console.log, which stringifies its arguments as JSON
+func BenchmarkLog(b *testing.B) {
+	pool := NewPool()
+	logger := NewEntryLogger()
+	for n := 0; n < b.N; n++ {
+		pool.RunCode("test", `console.log("foo", { bar: [1, 2, 3] })`, nil, time.Second, logger)
+	}
+	benchmarkResult = logger.Entries()
+}
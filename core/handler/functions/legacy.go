@@ -0,0 +1,109 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package functions
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/robertkrimen/otto"
+)
+
+// The legacy runtime runs payload functions that were written for the previous runtime, but that the stricter parser
+// of the current runtime rejects (such as object literals with missing commas), so that stored functions keep working.
+
+// compileLegacy compiles the code for the legacy runtime
+func compileLegacy(code string) (*otto.Script, error) {
+	return otto.New().Compile("", code)
+}
+
+// runLegacy runs the script in a new legacy VM
+func runLegacy(name string, script *otto.Script, env map[string]interface{}, timeout time.Duration, logger Logger) (val interface{}, err error) {
+	vm := otto.New()
+	vm.SetStackDepthLimit(maxCallStackSize)
+
+	// load the environment
+	for key, val := range env {
+		vm.Set(key, val)
+	}
+
+	logger.Enter(name)
+
+	vm.Set("__log", func(call otto.FunctionCall) otto.Value {
+		fields := make([]string, 0, len(call.ArgumentList))
+		for _, field := range call.ArgumentList {
+			fields = append(fields, JSON(field))
+		}
+		logger.Log(fields)
+		return otto.UndefinedValue()
+	})
+	vm.Run("console.log = __log")
+
+	start := time.Now()
+
+	defer func() {
+		duration := time.Since(start)
+		if caught := recover(); caught != nil {
+			val = nil
+			switch {
+			case caught == errTimeOutExceeded:
+				err = errors.NewErrInternal(fmt.Sprintf("Interrupted javascript execution for %s after %v", name, duration))
+			default:
+				err = errors.NewErrInternal(fmt.Sprintf("Fatal error in %s: %s", name, caught))
+			}
+			return
+		}
+	}()
+
+	vm.Interrupt = make(chan func(), 1)
+	timer := time.AfterFunc(timeout, func() {
+		vm.Interrupt <- func() {
+			panic(errTimeOutExceeded)
+		}
+	})
+	defer timer.Stop()
+
+	oVal, err := vm.Run(script)
+	if err != nil {
+		return nil, errors.NewErrInternal(fmt.Sprintf("%s threw error: %s", name, err))
+	}
+
+	switch {
+	case oVal.IsBoolean():
+		return oVal.ToBoolean()
+	case oVal.IsNull(), oVal.IsUndefined():
+		return nil, nil
+	case oVal.IsNumber():
+		f, _ := oVal.ToFloat()
+		if float64(int64(f)) == f {
+			return oVal.ToInteger()
+		}
+		return f, nil
+	case oVal.IsObject():
+		exported, err := oVal.Export()
+		if err != nil {
+			return nil, errors.NewErrInternal(fmt.Sprintf("%s return value invalid: %s", name, err))
+		}
+		if err := checkCycles(reflect.ValueOf(exported), nil); err != nil {
+			return nil, errors.NewErrInternal(fmt.Sprintf("%s return value invalid: %s", name, err))
+		}
+		return exported, nil
+	case oVal.IsString():
+		return oVal.ToString()
+	}
+
+	return nil, errors.NewErrInternal(fmt.Sprintf("%s return value invalid", name))
+}
+
+// JSON stringifies a value inside of the otto vm, yielding better
+// results than Export for Object-like class such as Date, but being much
+// slower.
+func JSON(val otto.Value) string {
+	vm := otto.New()
+	vm.Set("value", val)
+	res, _ := vm.Run(`JSON.stringify(value)`)
+	return res.String()
+}
@@ -5,13 +5,12 @@ package functions
 
 import (
 	pb_handler "github.com/TheThingsNetwork/api/handler"
-	"github.com/robertkrimen/otto"
 )
 
 // Logger is something that can be logged to, saving the logs for later use
 type Logger interface {
-	// Log passes the console.log function call to the logger
-	Log(call otto.FunctionCall)
+	// Log passes the arguments of a console.log function call, stringified as JSON, to the logger
+	Log(fields []string)
 
 	// Enter tells the Logger what function it is currently in
 	Enter(function string)
@@ -33,22 +32,7 @@ func NewEntryLogger() *EntryLogger {
 	}
 }
 
-// JSON stringifies a value inside of the otto vm, yielding better
-// results than Export for Object-like class such as Date, but being much
-// slower.
-func JSON(val otto.Value) string {
-	vm := otto.New()
-	vm.Set("value", val)
-	res, _ := vm.Run(`JSON.stringify(value)`)
-	return res.String()
-}
-
-func (c *EntryLogger) Log(call otto.FunctionCall) {
-	fields := []string{}
-	for _, field := range call.ArgumentList {
-		fields = append(fields, JSON(field))
-	}
-
+func (c *EntryLogger) Log(fields []string) {
 	c.logs = append(c.logs, &pb_handler.LogEntry{
 		Function: c.function,
 		Fields:   fields,
@@ -67,6 +51,6 @@ type IgnoreLogger struct{}
 
 var Ignore = &IgnoreLogger{}
 
-func (c *IgnoreLogger) Log(call otto.FunctionCall)      {}
+func (c *IgnoreLogger) Log(fields []string)             {}
 func (c *IgnoreLogger) Enter(function string)           {}
 func (c *IgnoreLogger) Entries() []*pb_handler.LogEntry { return nil }
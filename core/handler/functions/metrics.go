@@ -0,0 +1,41 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package functions
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var executionTime = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "ttn",
+		Subsystem: "handler",
+		Name:      "payload_function_seconds_total",
+		Help:      "Total execution time of payload functions.",
+	}, []string{"app_id"},
+)
+
+var executions = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "ttn",
+		Subsystem: "handler",
+		Name:      "payload_function_executions_total",
+		Help:      "Total number of payload function executions.",
+	}, []string{"app_id", "result"},
+)
+
+var disables = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "ttn",
+		Subsystem: "handler",
+		Name:      "payload_function_disables_total",
+		Help:      "Total number of times that payload functions were disabled for exceeding their quota.",
+	}, []string{"app_id"},
+)
+
+func init() {
+	prometheus.Register(executionTime)
+	prometheus.Register(executions)
+	prometheus.Register(disables)
+}
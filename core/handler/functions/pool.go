@@ -0,0 +1,331 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package functions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/bluele/gcache"
+	"github.com/dop251/goja"
+	"github.com/robertkrimen/otto"
+)
+
+var errTimeOutExceeded = errors.NewErrInternal("Code has been running to long")
+
+const (
+	// maxCallStackSize is the maximum depth of the call stack of the code
+	maxCallStackSize = 32
+	// programCacheSize is the number of compiled programs that is kept in memory
+	programCacheSize = 1024
+	// applicationPoolsSize is the number of application pools that is kept in memory
+	applicationPoolsSize = 1024
+)
+
+// programCache contains the compiled programs by the hash of their code. Compiled programs are immutable, so they
+// are shared by all VMs.
+var programCache = gcache.New(programCacheSize).LRU().Build()
+
+// compile compiles the code to a *goja.Program. Code that goja can not compile, but that the legacy runtime accepts,
+// is compiled to an *otto.Script.
+func compile(code string) (interface{}, error) {
+	key := sha256.Sum256([]byte(code))
+	if program, err := programCache.Get(key); err == nil {
+		return program, nil
+	}
+	var program interface{}
+	program, err := goja.Compile("", code, false)
+	if err != nil {
+		script, legacyErr := compileLegacy(code)
+		if legacyErr != nil {
+			return nil, err
+		}
+		program = script
+	}
+	programCache.Set(key, program)
+	return program, nil
+}
+
+var applicationPools = gcache.New(applicationPoolsSize).LRU().LoaderFunc(func(appID interface{}) (interface{}, error) {
+	return newApplicationPool(appID.(string), ApplicationQuota), nil
+}).Build()
+
+// ApplicationRuntime returns the Runtime of an application. Applications do not share VMs, so globals that the
+// payload functions of one application leave behind are not visible to other applications. The functions of an
+// application are limited by the ApplicationQuota.
+func ApplicationRuntime(appID string) Runtime {
+	pool, _ := applicationPools.Get(appID)
+	return pool.(*Pool)
+}
+
+// Isolated is a Runtime that runs the code in a new VM for every execution and that is not limited by a quota. It is
+// used for dry runs and test vectors, so that they can not affect the VMs or the quota of the application.
+var Isolated Runtime = newIsolatedPool()
+
+// Pool is a Runtime that reuses VMs
+type Pool struct {
+	vms      sync.Pool
+	appID    string
+	usage    *usage
+	isolated bool
+}
+
+// NewPool returns a new Pool
+func NewPool() *Pool {
+	p := new(Pool)
+	p.vms.New = func() interface{} {
+		vm := goja.New()
+		vm.SetMaxCallStackSize(maxCallStackSize)
+		return vm
+	}
+	return p
+}
+
+func newIsolatedPool() *Pool {
+	p := NewPool()
+	p.isolated = true
+	return p
+}
+
+func newApplicationPool(appID string, quota Quota) *Pool {
+	p := NewPool()
+	p.appID = appID
+	p.usage = newUsage(quota)
+	return p
+}
+
+// RunCode runs the code in a VM of the pool
+func (p *Pool) RunCode(name, code string, env map[string]interface{}, timeout time.Duration, logger Logger) (val interface{}, err error) {
+	if p.usage == nil {
+		return p.run(name, code, env, timeout, logger)
+	}
+
+	start := time.Now()
+	if err := p.usage.check(start); err != nil {
+		executions.WithLabelValues(p.appID, "disabled").Inc()
+		return nil, err
+	}
+
+	val, err = p.run(name, code, env, timeout, logger)
+	if err == nil && p.usage.quota.ResultSize != 0 {
+		if size := resultSize(reflect.ValueOf(val)); size > p.usage.quota.ResultSize {
+			val, err = nil, errors.NewErrInternal(fmt.Sprintf("%s return value too large: %d bytes is more than %d bytes", name, size, p.usage.quota.ResultSize))
+		}
+	}
+
+	duration := time.Since(start)
+	executionTime.WithLabelValues(p.appID).Add(duration.Seconds())
+	total := p.usage.add(start, duration)
+
+	var reason string
+	switch {
+	case p.usage.exceeded(total):
+		reason = fmt.Sprintf("used more than %v of execution time in %v", p.usage.quota.ExecutionTime, p.usage.quota.Window)
+	case err != nil:
+		executions.WithLabelValues(p.appID, "error").Inc()
+		return nil, err
+	default:
+		executions.WithLabelValues(p.appID, "ok").Inc()
+		return val, nil
+	}
+
+	until := p.usage.disable(start)
+	executions.WithLabelValues(p.appID, "disabled").Inc()
+	disables.WithLabelValues(p.appID).Inc()
+	return nil, errors.NewErrUnavailable(fmt.Sprintf("Payload functions are disabled until %s because %s %s", until.UTC().Format(time.RFC3339), name, reason))
+}
+
+// run runs the code in a VM of the pool, interrupting it when it exceeds the timeout
+func (p *Pool) run(name, code string, env map[string]interface{}, timeout time.Duration, logger Logger) (val interface{}, err error) {
+	compiled, err := compile(code)
+	if err != nil {
+		return nil, errors.NewErrInternal(fmt.Sprintf("%s threw error: %s", name, err))
+	}
+
+	if logger == nil {
+		logger = Ignore
+	}
+
+	program, ok := compiled.(*goja.Program)
+	if !ok {
+		return runLegacy(name, compiled.(*otto.Script), env, timeout, logger)
+	}
+
+	vm := p.vms.Get().(*goja.Runtime)
+
+	// load the environment
+	for key, val := range env {
+		vm.Set(key, toValue(vm, val))
+	}
+
+	logger.Enter(name)
+
+	stringify, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("stringify"))
+	console := vm.NewObject()
+	console.Set("log", func(call goja.FunctionCall) goja.Value {
+		fields := make([]string, 0, len(call.Arguments))
+		for _, arg := range call.Arguments {
+			str, err := stringify(goja.Undefined(), arg)
+			if err != nil {
+				fields = append(fields, arg.String())
+				continue
+			}
+			fields = append(fields, sortKeys(str.String()))
+		}
+		logger.Log(fields)
+		return goja.Undefined()
+	})
+	vm.Set("console", console)
+
+	start := time.Now()
+	watchdog := watch(vm, timeout)
+
+	defer func() {
+		// VMs that were interrupted or that panicked are not reused
+		if !watchdog.stop() || err != nil || p.isolated {
+			return
+		}
+		// the environment of this execution should not be visible to the next one
+		global := vm.GlobalObject()
+		for key := range env {
+			global.Delete(key)
+		}
+		global.Delete("console")
+		p.vms.Put(vm)
+	}()
+
+	defer func() {
+		if caught := recover(); caught != nil {
+			val = nil
+			err = errors.NewErrInternal(fmt.Sprintf("Fatal error in %s: %s", name, caught))
+		}
+	}()
+
+	result, err := vm.RunProgram(program)
+	if err != nil {
+		if _, ok := err.(*goja.InterruptedError); ok {
+			return nil, errors.NewErrInternal(fmt.Sprintf("Interrupted javascript execution for %s after %v", name, time.Since(start)))
+		}
+		return nil, errors.NewErrInternal(fmt.Sprintf("%s threw error: %s", name, err))
+	}
+
+	return export(name, fromTypedArray(vm, result))
+}
+
+// sortKeys sorts the keys of the objects in the JSON string, so that logged objects do not depend on the order in
+// which their properties were set
+func sortKeys(str string) string {
+	decoder := json.NewDecoder(bytes.NewBufferString(str))
+	decoder.UseNumber()
+	var val interface{}
+	if err := decoder.Decode(&val); err != nil {
+		return str
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(val); err != nil {
+		return str
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// fromTypedArray converts typed arrays, such as an Uint8Array, to arrays of numbers
+func fromTypedArray(vm *goja.Runtime, val goja.Value) goja.Value {
+	if _, ok := val.(*goja.Object); !ok {
+		return val
+	}
+	isView, _ := goja.AssertFunction(vm.Get("ArrayBuffer").ToObject(vm).Get("isView"))
+	if view, err := isView(goja.Undefined(), val); err != nil || !view.ToBoolean() {
+		return val
+	}
+	from, _ := goja.AssertFunction(vm.Get("Array").ToObject(vm).Get("from"))
+	array, err := from(goja.Undefined(), val)
+	if err != nil {
+		return val
+	}
+	return array
+}
+
+// toValue converts a value of the environment to a JavaScript value. Byte slices are converted to arrays of numbers.
+func toValue(vm *goja.Runtime, val interface{}) goja.Value {
+	if b, ok := val.([]byte); ok {
+		items := make([]interface{}, len(b))
+		for i, v := range b {
+			items[i] = int64(v)
+		}
+		return vm.NewArray(items...)
+	}
+	return vm.ToValue(val)
+}
+
+// export converts the result of the code to a Go value
+func export(name string, val goja.Value) (interface{}, error) {
+	if val == nil || goja.IsUndefined(val) || goja.IsNull(val) {
+		return nil, nil
+	}
+	if _, ok := val.(*goja.Object); ok {
+		exported := val.Export()
+		if err := checkCycles(reflect.ValueOf(exported), nil); err != nil {
+			return nil, errors.NewErrInternal(fmt.Sprintf("%s return value invalid: %s", name, err))
+		}
+		return exported, nil
+	}
+	switch val.ExportType().Kind() {
+	case reflect.Bool:
+		return val.ToBoolean(), nil
+	case reflect.Int64, reflect.Float64:
+		f := val.ToFloat()
+		if float64(int64(f)) == f {
+			return int64(f), nil
+		}
+		return f, nil
+	case reflect.String:
+		return val.String(), nil
+	}
+	return nil, errors.NewErrInternal(fmt.Sprintf("%s return value invalid", name))
+}
+
+// checkCycles returns an error if the exported value references itself
+func checkCycles(val reflect.Value, path []uintptr) error {
+	if val.Kind() == reflect.Interface {
+		val = val.Elem()
+	}
+	switch val.Kind() {
+	case reflect.Map, reflect.Slice:
+		if val.IsNil() {
+			return nil
+		}
+		ptr := val.Pointer()
+		for _, p := range path {
+			if p == ptr {
+				return fmt.Errorf("cyclic reference")
+			}
+		}
+		path = append(path, ptr)
+		if val.Kind() == reflect.Map {
+			for _, key := range val.MapKeys() {
+				if err := checkCycles(val.MapIndex(key), path); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if val.Type().Elem().Kind() != reflect.Interface {
+			return nil
+		}
+		for i := 0; i < val.Len(); i++ {
+			if err := checkCycles(val.Index(i), path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package functions
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/dop251/goja"
+)
+
+// Quota limits the resources that the payload functions of an application use
+type Quota struct {
+	// ExecutionTime is the (wall clock) time that the functions of an application can use within the Window. Zero
+	// means no limit.
+	ExecutionTime time.Duration
+	// Window is the duration of the sliding window of the ExecutionTime
+	Window time.Duration
+	// Disable is the duration for which the functions of an application are disabled after exceeding the quota
+	Disable time.Duration
+	// ResultSize is the maximum (approximate) size in bytes of the value that a single execution returns. Zero means
+	// no limit. The memory that an execution uses while it runs is only bounded by its timeout.
+	ResultSize int
+}
+
+// DefaultQuota is the default Quota of an application
+var DefaultQuota = Quota{
+	ExecutionTime: 10 * time.Second,
+	Window:        time.Minute,
+	Disable:       10 * time.Minute,
+	ResultSize:    64 * 1024,
+}
+
+// ApplicationQuota is the Quota of the runtimes returned by ApplicationRuntime. It should be set before the first
+// call to ApplicationRuntime.
+var ApplicationQuota = DefaultQuota
+
+// quotaBuckets is the number of buckets of the sliding window
+const quotaBuckets = 10
+
+// usage keeps track of the execution time of the functions of an application
+type usage struct {
+	mu            sync.Mutex
+	quota         Quota
+	buckets       [quotaBuckets]time.Duration
+	bucket        int64
+	disabledUntil time.Time
+}
+
+func newUsage(quota Quota) *usage {
+	return &usage{quota: quota}
+}
+
+// check returns an error if the functions are disabled
+func (u *usage) check(now time.Time) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if now.Before(u.disabledUntil) {
+		return errors.NewErrUnavailable(fmt.Sprintf("Payload functions are disabled until %s because they exceeded their quota", u.disabledUntil.UTC().Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// add adds the execution time to the sliding window and returns the total execution time within the window
+func (u *usage) add(now time.Time, d time.Duration) (total time.Duration) {
+	if u.quota.ExecutionTime == 0 || u.quota.Window == 0 {
+		return 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	bucket := now.UnixNano() / int64(u.quota.Window/quotaBuckets)
+	if bucket-u.bucket >= quotaBuckets {
+		u.buckets = [quotaBuckets]time.Duration{}
+	} else {
+		for b := u.bucket + 1; b <= bucket; b++ {
+			u.buckets[b%quotaBuckets] = 0
+		}
+	}
+	if bucket > u.bucket {
+		u.bucket = bucket
+	}
+	u.buckets[u.bucket%quotaBuckets] += d
+	for _, d := range u.buckets {
+		total += d
+	}
+	return total
+}
+
+// exceeded returns true if the total execution time exceeds the quota
+func (u *usage) exceeded(total time.Duration) bool {
+	return u.quota.ExecutionTime != 0 && total > u.quota.ExecutionTime
+}
+
+// resultSize returns the approximate size in bytes of a value that was returned by a function. Strings count their
+// length, other values and the items of maps and slices count 8 bytes. The value should not contain cycles.
+func resultSize(val reflect.Value) (size int) {
+	if val.Kind() == reflect.Interface {
+		val = val.Elem()
+	}
+	switch val.Kind() {
+	case reflect.String:
+		return len(val.String())
+	case reflect.Map:
+		for _, key := range val.MapKeys() {
+			size += 8 + resultSize(key) + resultSize(val.MapIndex(key))
+		}
+		return size
+	case reflect.Slice:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return val.Len()
+		}
+		for i := 0; i < val.Len(); i++ {
+			size += 8 + resultSize(val.Index(i))
+		}
+		return size
+	}
+	return 8
+}
+
+// disable disables the functions and resets the sliding window
+func (u *usage) disable(now time.Time) time.Time {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.disabledUntil = now.Add(u.quota.Disable)
+	u.buckets = [quotaBuckets]time.Duration{}
+	return u.disabledUntil
+}
+
+// watchdog interrupts a VM when the execution takes too long
+type watchdog struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+	fired   bool
+}
+
+func watch(vm *goja.Runtime, timeout time.Duration) *watchdog {
+	w := new(watchdog)
+	w.mu.Lock()
+	w.timer = time.AfterFunc(timeout, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.stopped {
+			return
+		}
+		w.fired = true
+		vm.Interrupt(errTimeOutExceeded)
+	})
+	w.mu.Unlock()
+	return w
+}
+
+// stop stops the watchdog and returns true if it did not interrupt the VM
+func (w *watchdog) stop() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	w.timer.Stop()
+	return !w.fired
+}
@@ -0,0 +1,89 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package functions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestUsage(t *testing.T) {
+	a := New(t)
+
+	u := newUsage(Quota{ExecutionTime: time.Second, Window: 10 * time.Second, Disable: time.Minute})
+	now := time.Unix(1000, 0)
+
+	a.So(u.add(now, 300*time.Millisecond), ShouldEqual, 300*time.Millisecond)
+	a.So(u.add(now.Add(5*time.Second), 300*time.Millisecond), ShouldEqual, 600*time.Millisecond)
+	a.So(u.exceeded(600*time.Millisecond), ShouldBeFalse)
+
+	// The first execution slides out of the window
+	a.So(u.add(now.Add(10*time.Second), 300*time.Millisecond), ShouldEqual, 600*time.Millisecond)
+	a.So(u.add(now.Add(11*time.Second), 500*time.Millisecond), ShouldEqual, 1100*time.Millisecond)
+	a.So(u.exceeded(1100*time.Millisecond), ShouldBeTrue)
+
+	// Everything slides out of the window
+	a.So(u.add(now.Add(time.Minute), 100*time.Millisecond), ShouldEqual, 100*time.Millisecond)
+
+	a.So(u.check(now), ShouldBeNil)
+	until := u.disable(now)
+	a.So(until, ShouldResemble, now.Add(time.Minute))
+	a.So(errors.IsUnavailable(u.check(now.Add(time.Second))), ShouldBeTrue)
+	a.So(u.check(now.Add(time.Minute)), ShouldBeNil)
+}
+
+func TestApplicationQuotaExecutionTime(t *testing.T) {
+	a := New(t)
+
+	p := newApplicationPool("test", Quota{ExecutionTime: 10 * time.Millisecond, Window: time.Minute, Disable: time.Minute})
+
+	val, err := p.RunCode("test", `1 + 1`, nil, time.Second, Ignore)
+	a.So(err, ShouldBeNil)
+	a.So(val, ShouldEqual, 2)
+
+	_, err = p.RunCode("test", `
+		var start = Date.now();
+		while (Date.now() - start < 20) {}
+	`, nil, time.Second, Ignore)
+	a.So(err, ShouldNotBeNil)
+	a.So(errors.IsUnavailable(err), ShouldBeTrue)
+	a.So(err.Error(), ShouldContainSubstring, "execution time")
+
+	_, err = p.RunCode("test", `1 + 1`, nil, time.Second, Ignore)
+	a.So(err, ShouldNotBeNil)
+	a.So(errors.IsUnavailable(err), ShouldBeTrue)
+
+	// Other applications are not affected
+	val, err = newApplicationPool("other", p.usage.quota).RunCode("test", `1 + 1`, nil, time.Second, Ignore)
+	a.So(err, ShouldBeNil)
+	a.So(val, ShouldEqual, 2)
+}
+
+func TestApplicationQuotaResultSize(t *testing.T) {
+	a := New(t)
+
+	p := newApplicationPool("test", Quota{ResultSize: 1024})
+
+	val, err := p.RunCode("test", `({ temperature: 21.5, name: "sensor" })`, nil, time.Second, Ignore)
+	a.So(err, ShouldBeNil)
+	a.So(val, ShouldNotBeNil)
+
+	_, err = p.RunCode("test", `
+		var items = [];
+		for (var i = 0; i < 1000; i++) { items.push(i); }
+		items
+	`, nil, time.Second, Ignore)
+	a.So(err, ShouldNotBeNil)
+	a.So(err.Error(), ShouldContainSubstring, "too large")
+
+	// A result that is too large does not disable the functions
+	_, err = p.RunCode("test", `"a".repeat(2048)`, nil, time.Second, Ignore)
+	a.So(err, ShouldNotBeNil)
+	val, err = p.RunCode("test", `1 + 1`, nil, time.Second, Ignore)
+	a.So(err, ShouldBeNil)
+	a.So(val, ShouldEqual, 2)
+}
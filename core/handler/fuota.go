@@ -0,0 +1,538 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	pb_broker "github.com/TheThingsNetwork/api/broker"
+	"github.com/TheThingsNetwork/go-account-lib/rights"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/band"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/handler/fuota"
+	ns_multicast "github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/TheThingsNetwork/ttn/utils/gpstime"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+)
+
+var (
+	// DefaultFUOTAFragmentSize is the size of the firmware fragments if the session does not specify it. Together with
+	// the 3 byte fragment header it fits in a downlink at the lowest data rate of most frequency plans.
+	DefaultFUOTAFragmentSize uint8 = 48
+	// DefaultFUOTARedundancy is the number of redundancy fragments per 100 fragments if the session does not specify it
+	DefaultFUOTARedundancy = 10
+	// FUOTAStartDelay is the time between the creation of a session and sending the first fragment if the session does
+	// not specify it. In this time, the devices need to send enough uplinks to receive the setup requests.
+	FUOTAStartDelay = time.Hour
+	// FUOTAFragmentInterval is the time between fragments if the session does not specify it
+	FUOTAFragmentInterval = 5 * time.Second
+	// FUOTAStatusTimeout is the time after the last fragment in which the devices need to report that they received
+	// the firmware
+	FUOTAStatusTimeout = 24 * time.Hour
+	// FUOTAPollInterval is the interval in which the Handler checks if FUOTA sessions need to make progress
+	FUOTAPollInterval = time.Second
+)
+
+// HandleFUOTAUplink updates the progress of the device in the FUOTA sessions it participates in from the answers in
+// uplinks on the ports of the Remote Multicast Setup and Fragmented Data Block Transport packages
+func (h *handler) HandleFUOTAUplink(ctx ttnlog.Interface, _ *pb_broker.DeduplicatedUplinkMessage, appUp *types.UplinkMessage, dev *device.Device) error {
+	if h.sessions == nil || (appUp.FPort != fuota.MulticastSetupPort && appUp.FPort != fuota.FragmentationPort) {
+		return nil
+	}
+
+	var (
+		multicastAnswers     *fuota.MulticastSetupAnswers
+		fragmentationAnswers *fuota.FragmentationAnswers
+		err                  error
+	)
+	switch appUp.FPort {
+	case fuota.MulticastSetupPort:
+		multicastAnswers, err = fuota.UnmarshalMulticastSetupAnswers(appUp.PayloadRaw)
+	case fuota.FragmentationPort:
+		fragmentationAnswers, err = fuota.UnmarshalFragmentationAnswers(appUp.PayloadRaw)
+	}
+	if err != nil {
+		ctx.WithError(err).Warn("Could not read FUOTA answers")
+	}
+
+	sessions, err := h.sessions.List(dev.AppID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if !session.Status.IsActive() {
+			continue
+		}
+		status := session.Device(dev.DevID)
+		if status == nil {
+			continue
+		}
+		if multicastAnswers != nil {
+			for _, ans := range multicastAnswers.McGroupSetup {
+				if ans.McGroupID != session.McGroupID {
+					continue
+				}
+				if ans.IDError {
+					status.Error = "Multicast group ID not supported"
+				} else {
+					status.McGroupSetup = true
+				}
+			}
+			for _, ans := range multicastAnswers.McClassCSession {
+				if ans.McGroupID != session.McGroupID {
+					continue
+				}
+				if err := ans.Error(); err != nil {
+					status.Error = err.Error()
+				} else {
+					status.ClassCSessionSetup = true
+				}
+			}
+		}
+		if fragmentationAnswers != nil {
+			for _, ans := range fragmentationAnswers.FragSessionSetup {
+				if ans.FragIndex != session.FragIndex {
+					continue
+				}
+				if err := ans.Error(); err != nil {
+					status.Error = err.Error()
+				} else {
+					status.FragSessionSetup = true
+				}
+			}
+			for _, ans := range fragmentationAnswers.FragSessionStatus {
+				if ans.FragIndex != session.FragIndex {
+					continue
+				}
+				status.FragmentsReceived = ans.NbFragReceived
+				status.MissingFragments = ans.MissingFrag
+				if ans.NotEnoughMatrixMemory {
+					status.Error = "Not enough memory to reconstruct the firmware"
+				} else if ans.MissingFrag == 0 {
+					status.Done = true
+				}
+			}
+		}
+		if err := h.sessions.SetDevice(session.AppID, session.SessionID, status); err != nil {
+			return err
+		}
+		ctx.WithField("SessionID", session.SessionID).Debug("Updated FUOTA progress of device")
+	}
+	return nil
+}
+
+func (h *handler) runFUOTASessions() {
+	for now := range time.Tick(FUOTAPollInterval) {
+		h.processFUOTASessions(now)
+	}
+}
+
+// processFUOTASessions makes progress on the active FUOTA sessions
+func (h *handler) processFUOTASessions(now time.Time) {
+	sessions, err := h.sessions.ListActive()
+	if err != nil {
+		h.Ctx.WithError(err).Warn("Could not list FUOTA sessions")
+		return
+	}
+	for _, session := range sessions {
+		ctx := h.Ctx.WithFields(ttnlog.Fields{
+			"AppID":     session.AppID,
+			"SessionID": session.SessionID,
+		})
+		session.StartUpdate()
+		if err := h.processFUOTASession(ctx, session, now); err != nil {
+			ctx.WithError(err).Warn("Could not process FUOTA session")
+		}
+	}
+}
+
+func (h *handler) processFUOTASession(ctx ttnlog.Interface, session *fuota.Session, now time.Time) error {
+	switch session.Status {
+	case fuota.StatusSetup:
+		if now.Before(session.StartAt) {
+			return nil
+		}
+		if len(session.ReadyDevices()) == 0 {
+			session.Status = fuota.StatusFailed
+			session.Error = "No devices were set up for the session"
+			ctx.Warn("FUOTA session failed: no devices were set up")
+		} else {
+			session.Status = fuota.StatusSending
+			ctx.WithField("Devices", len(session.ReadyDevices())).Info("Start sending FUOTA fragments")
+		}
+		return h.sessions.Set(session, "Status", "Error")
+
+	case fuota.StatusSending:
+		if now.Before(session.LastFragmentAt.Add(session.FragmentInterval)) {
+			return nil
+		}
+		firmware, err := h.sessions.GetFirmware(session.AppID, session.SessionID)
+		if err != nil {
+			return err
+		}
+		fragmenter, err := fuota.NewFragmenter(firmware, int(session.FragSize))
+		if err != nil {
+			return err
+		}
+		fragment := fuota.DataFragment{
+			FragIndex: session.FragIndex,
+			N:         session.FragmentsSent + 1,
+			Payload:   fragmenter.Fragment(int(session.FragmentsSent) + 1),
+		}
+		payload, err := fragment.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if _, err := h.groups.Get(session.AppID, session.GroupID); errors.GetErrType(err) == errors.NotFound {
+			session.Status = fuota.StatusFailed
+			session.Error = "Multicast group of the session was deleted"
+			return h.sessions.Set(session, "Status", "Error")
+		}
+		_, err = h.EnqueueMulticastDownlink(&types.MulticastDownlinkMessage{
+			AppID:      session.AppID,
+			GroupID:    session.GroupID,
+			FPort:      fuota.FragmentationPort,
+			PayloadRaw: payload,
+		})
+		if err != nil {
+			// A fragment that could not be sent is lost, the redundancy fragments make up for it
+			ctx.WithError(err).WithField("Fragment", fragment.N).Warn("Could not send FUOTA fragment")
+		}
+		session.FragmentsSent++
+		session.LastFragmentAt = now
+		if session.FragmentsSent < session.TotalFragments() {
+			return h.sessions.Set(session, "FragmentsSent", "LastFragmentAt")
+		}
+		session.Status = fuota.StatusSent
+		ctx.Info("Sent all FUOTA fragments")
+		if err := h.sessions.Set(session, "FragmentsSent", "LastFragmentAt", "Status"); err != nil {
+			return err
+		}
+		statusReq, _ := fuota.FragSessionStatusReq{FragIndex: session.FragIndex, Participants: true}.MarshalBinary()
+		for _, dev := range session.ReadyDevices() {
+			h.EnqueueDownlink(&types.DownlinkMessage{
+				AppID:      session.AppID,
+				DevID:      dev.DevID,
+				FPort:      fuota.FragmentationPort,
+				PayloadRaw: statusReq,
+				Schedule:   types.ScheduleLast,
+			})
+		}
+		return nil
+
+	case fuota.StatusSent:
+		done := true
+		for _, dev := range session.ReadyDevices() {
+			if !dev.Done {
+				done = false
+			}
+		}
+		switch {
+		case done:
+			session.Status = fuota.StatusCompleted
+			ctx.Info("Completed FUOTA session")
+		case now.After(session.LastFragmentAt.Add(FUOTAStatusTimeout)):
+			session.Status = fuota.StatusFailed
+			session.Error = "Not all devices reported that they received the firmware"
+			ctx.Warn("FUOTA session failed: not all devices received the firmware")
+		default:
+			return nil
+		}
+		return h.sessions.Set(session, "Status", "Error")
+	}
+	return nil
+}
+
+// sessionTimeOut returns the SessionTimeOut of a Class C multicast session that lasts at least the given duration
+func sessionTimeOut(duration time.Duration) (uint8, error) {
+	for timeOut := uint8(0); timeOut <= 15; timeOut++ {
+		if time.Duration(1<<timeOut)*time.Second >= duration {
+			return timeOut, nil
+		}
+	}
+	return 0, errors.NewErrInvalidArgument("Session", "sending the fragments takes too long, use fewer or larger fragments or a shorter interval")
+}
+
+func (h *handlerManager) CreateSession(ctx context.Context, in *fuota.SessionRequest) (*fuota.Session, error) {
+	if h.handler.sessions == nil {
+		return nil, errors.NewErrUnavailable("FUOTA sessions are not enabled on this Handler")
+	}
+	if len(in.DevIDs) == 0 {
+		return nil, errors.NewErrInvalidArgument("Session", "must contain devices")
+	}
+	if in.McAddr.IsEmpty() {
+		return nil, errors.NewErrInvalidArgument("McAddr", "can not be empty")
+	}
+
+	session := &fuota.Session{
+		AppID:            in.AppID,
+		SessionID:        in.SessionID,
+		GroupID:          in.SessionID,
+		McGroupID:        in.McGroupID,
+		Firmware:         in.Firmware,
+		FragIndex:        in.FragIndex,
+		FragSize:         in.FragSize,
+		Redundancy:       in.Redundancy,
+		Descriptor:       in.Descriptor,
+		StartAt:          time.Now().Add(FUOTAStartDelay),
+		FragmentInterval: FUOTAFragmentInterval,
+		Status:           fuota.StatusSetup,
+	}
+	for _, devID := range in.DevIDs {
+		session.Devices = append(session.Devices, &fuota.DeviceStatus{DevID: devID})
+	}
+	if err := session.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Invalid Session")
+	}
+	if session.FragSize == 0 {
+		session.FragSize = DefaultFUOTAFragmentSize
+	}
+	if in.StartAt != nil {
+		session.StartAt = time.Time(*in.StartAt)
+		if session.StartAt.Before(time.Now()) {
+			return nil, errors.NewErrInvalidArgument("Start Time", "must be in the future")
+		}
+	}
+	if in.FragmentInterval != "" {
+		interval, err := time.ParseDuration(in.FragmentInterval)
+		if err != nil || interval < FUOTAPollInterval {
+			return nil, errors.NewErrInvalidArgument("Fragment Interval", fmt.Sprintf("must be a duration of at least %s", FUOTAPollInterval))
+		}
+		session.FragmentInterval = interval
+	}
+
+	fragmenter, err := fuota.NewFragmenter(in.Firmware, int(session.FragSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid Firmware")
+	}
+	session.NbFrag = uint16(fragmenter.NbFrag())
+	session.Padding = uint8(fragmenter.Padding())
+	if session.Redundancy == 0 {
+		session.Redundancy = uint16((fragmenter.NbFrag()*DefaultFUOTARedundancy + 99) / 100)
+	}
+	if int(session.TotalFragments()) > 1<<14-1 {
+		return nil, errors.NewErrInvalidArgument("Redundancy", "too many fragments")
+	}
+	session.SessionTimeOut, err = sessionTimeOut(time.Duration(session.TotalFragments()) * session.FragmentInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	region := in.FrequencyPlan
+	if region == "" {
+		region = band.Guess(in.Frequency)
+	}
+	frequencyPlan, err := band.Get(region)
+	if err != nil {
+		return nil, errors.NewErrInvalidArgument("Frequency Plan", "unknown, can not be guessed from the frequency")
+	}
+	dataRate, err := frequencyPlan.GetDataRateIndexFor(in.DataRate)
+	if err != nil {
+		return nil, errors.NewErrInvalidArgument("Data Rate", fmt.Sprintf("not available in %s", region))
+	}
+
+	ctx, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	token, _ := ttnctx.TokenFromIncomingContext(ctx)
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+
+	if _, err := h.handler.applications.Get(in.AppID); err != nil {
+		return nil, errors.Wrap(err, "Application not registered to this Handler")
+	}
+	if _, err := h.handler.sessions.Get(in.AppID, in.SessionID); err == nil {
+		return nil, errors.NewErrAlreadyExists(fmt.Sprintf("Session %s", in.SessionID))
+	}
+	if _, err := h.handler.groups.Get(in.AppID, session.GroupID); err == nil {
+		return nil, errors.NewErrAlreadyExists(fmt.Sprintf("Group %s", session.GroupID))
+	}
+
+	devices := make([]*device.Device, 0, len(in.DevIDs))
+	for _, devID := range in.DevIDs {
+		dev, err := h.handler.devices.Get(in.AppID, devID)
+		if err != nil {
+			return nil, err
+		}
+		if dev.AppKey.IsEmpty() {
+			return nil, errors.NewErrInvalidArgument("Device", fmt.Sprintf("%s does not have an AppKey", devID))
+		}
+		devices = append(devices, dev)
+	}
+
+	var mcKey types.AppKey
+	if _, err := rand.Read(mcKey[:]); err != nil {
+		return nil, err
+	}
+	mcAppSKey, mcNwkSKey := fuota.GroupSessionKeys(mcKey, in.McAddr)
+
+	nsGroup := &ns_multicast.Group{
+		AppID:     in.AppID,
+		GroupID:   session.GroupID,
+		McAddr:    in.McAddr,
+		McNwkSKey: mcNwkSKey,
+		Frequency: in.Frequency,
+		DataRate:  in.DataRate,
+		Power:     in.Power,
+		Gateways:  in.Gateways,
+	}
+	if err := nsGroup.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Invalid Group")
+	}
+
+	fragSessionSetupReq, err := fuota.FragSessionSetupReq{
+		FragIndex:      session.FragIndex,
+		McGroupBitMask: 1 << session.McGroupID,
+		NbFrag:         session.NbFrag,
+		FragSize:       session.FragSize,
+		Padding:        session.Padding,
+		Descriptor:     session.Descriptor,
+	}.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	classCSessionReq, err := fuota.McClassCSessionReq{
+		McGroupID:      session.McGroupID,
+		SessionTime:    uint32(gpstime.ToGPS(session.StartAt) / time.Second),
+		SessionTimeOut: session.SessionTimeOut,
+		DLFrequency:    in.Frequency,
+		DataRate:       uint8(dataRate),
+	}.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	setupDownlinks := make([]*types.DownlinkMessage, 0, 3*len(devices))
+	for _, dev := range devices {
+		groupSetupReq, err := fuota.McGroupSetupReq{
+			McGroupID:      session.McGroupID,
+			McAddr:         in.McAddr,
+			McKeyEncrypted: fuota.EncryptMcKey(dev.AppKey, mcKey),
+			MinMcFCount:    nsGroup.FCntDown,
+			MaxMcFCount:    nsGroup.FCntDown + uint32(session.TotalFragments()),
+		}.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		for _, req := range []struct {
+			fPort   uint8
+			payload []byte
+		}{
+			{fuota.MulticastSetupPort, groupSetupReq},
+			{fuota.FragmentationPort, fragSessionSetupReq},
+			{fuota.MulticastSetupPort, classCSessionReq},
+		} {
+			setupDownlinks = append(setupDownlinks, &types.DownlinkMessage{
+				AppID:      in.AppID,
+				DevID:      dev.DevID,
+				FPort:      req.fPort,
+				PayloadRaw: req.payload,
+				Schedule:   types.ScheduleLast,
+			})
+		}
+	}
+
+	ctx = ttnctx.OutgoingContextWithToken(ctx, token)
+	if err := h.handler.setGroup(ctx, nsGroup, mcAppSKey); err != nil {
+		return nil, err
+	}
+	if err := h.handler.sessions.Set(session); err != nil {
+		h.handler.deleteSession(ctx, session)
+		return nil, err
+	}
+	for _, downlink := range setupDownlinks {
+		if err := h.handler.EnqueueDownlink(downlink); err != nil {
+			h.handler.deleteSession(ctx, session)
+			return nil, err
+		}
+	}
+
+	return session, nil
+}
+
+// deleteSession deletes the session and its multicast group from the Broker and the Handler
+func (h *handler) deleteSession(ctx context.Context, session *fuota.Session) error {
+	_, err := h.ttnMulticastManager.DeleteGroup(ctx, &ns_multicast.GroupIdentifier{
+		AppID:   session.AppID,
+		GroupID: session.GroupID,
+	})
+	if err != nil && errors.GetErrType(errors.FromGRPCError(err)) != errors.NotFound {
+		return errors.Wrap(errors.FromGRPCError(err), "Broker did not delete group")
+	}
+	if err := h.groups.Delete(session.AppID, session.GroupID); err != nil && errors.GetErrType(err) != errors.NotFound {
+		return err
+	}
+	return h.sessions.Delete(session.AppID, session.SessionID)
+}
+
+func (h *handlerManager) GetSession(ctx context.Context, in *fuota.SessionIdentifier) (*fuota.Session, error) {
+	if h.handler.sessions == nil {
+		return nil, errors.NewErrUnavailable("FUOTA sessions are not enabled on this Handler")
+	}
+	if in.AppID == "" || in.SessionID == "" {
+		return nil, errors.NewErrInvalidArgument("Session Identifier", "must contain AppID and SessionID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+	return h.handler.sessions.Get(in.AppID, in.SessionID)
+}
+
+func (h *handlerManager) ListSessions(ctx context.Context, in *fuota.ApplicationIdentifier) (*fuota.SessionList, error) {
+	if h.handler.sessions == nil {
+		return nil, errors.NewErrUnavailable("FUOTA sessions are not enabled on this Handler")
+	}
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+	sessions, err := h.handler.sessions.List(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	return &fuota.SessionList{Sessions: sessions}, nil
+}
+
+func (h *handlerManager) DeleteSession(ctx context.Context, in *fuota.SessionIdentifier) (*fuota.Empty, error) {
+	if h.handler.sessions == nil {
+		return nil, errors.NewErrUnavailable("FUOTA sessions are not enabled on this Handler")
+	}
+	if in.AppID == "" || in.SessionID == "" {
+		return nil, errors.NewErrInvalidArgument("Session Identifier", "must contain AppID and SessionID")
+	}
+	ctx, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	token, _ := ttnctx.TokenFromIncomingContext(ctx)
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+
+	session, err := h.handler.sessions.Get(in.AppID, in.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.handler.deleteSession(ttnctx.OutgoingContextWithToken(ctx, token), session); err != nil {
+		return nil, err
+	}
+	return &fuota.Empty{}, nil
+}
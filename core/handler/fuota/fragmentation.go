@@ -0,0 +1,258 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package fuota
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// FragmentationPort is the FPort of the Fragmented Data Block Transport package
+const FragmentationPort = 201
+
+// Command identifiers of the Fragmented Data Block Transport package
+const (
+	cidFragPackageVersion = 0x00
+	cidFragSessionStatus  = 0x01
+	cidFragSessionSetup   = 0x02
+	cidFragSessionDelete  = 0x03
+	cidDataFragment       = 0x08
+)
+
+const (
+	maxFragIndex = 3
+	maxNbFrag    = 1<<14 - 1
+)
+
+// FragSessionSetupReq creates a fragmentation session on a device
+type FragSessionSetupReq struct {
+	FragIndex      uint8
+	McGroupBitMask uint8
+	NbFrag         uint16
+	FragSize       uint8
+	Padding        uint8
+	Descriptor     uint32
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (r FragSessionSetupReq) MarshalBinary() ([]byte, error) {
+	if r.FragIndex > maxFragIndex {
+		return nil, errors.NewErrInvalidArgument("FragIndex", fmt.Sprintf("must be at most %d", maxFragIndex))
+	}
+	b := make([]byte, 11)
+	b[0] = cidFragSessionSetup
+	b[1] = r.FragIndex<<4 | r.McGroupBitMask&0x0f
+	binary.LittleEndian.PutUint16(b[2:4], r.NbFrag)
+	b[4] = r.FragSize
+	b[5] = 0 // FragmentationMatrix 0 (the parity matrix of the specification), no BlockAckDelay
+	b[6] = r.Padding
+	binary.LittleEndian.PutUint32(b[7:11], r.Descriptor)
+	return b, nil
+}
+
+// FragSessionStatusReq requests the status of a fragmentation session
+type FragSessionStatusReq struct {
+	FragIndex    uint8
+	Participants bool // if false, only devices that are missing fragments answer
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (r FragSessionStatusReq) MarshalBinary() ([]byte, error) {
+	if r.FragIndex > maxFragIndex {
+		return nil, errors.NewErrInvalidArgument("FragIndex", fmt.Sprintf("must be at most %d", maxFragIndex))
+	}
+	b := []byte{cidFragSessionStatus, r.FragIndex << 1}
+	if r.Participants {
+		b[1] |= 1
+	}
+	return b, nil
+}
+
+// DataFragment is a fragment of a data block
+type DataFragment struct {
+	FragIndex uint8
+	N         uint16 // starting at 1
+	Payload   []byte
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (f DataFragment) MarshalBinary() ([]byte, error) {
+	if f.FragIndex > maxFragIndex {
+		return nil, errors.NewErrInvalidArgument("FragIndex", fmt.Sprintf("must be at most %d", maxFragIndex))
+	}
+	if f.N == 0 || f.N > maxNbFrag {
+		return nil, errors.NewErrInvalidArgument("N", fmt.Sprintf("must be between 1 and %d", maxNbFrag))
+	}
+	b := make([]byte, 3, 3+len(f.Payload))
+	b[0] = cidDataFragment
+	binary.LittleEndian.PutUint16(b[1:3], uint16(f.FragIndex)<<14|f.N)
+	return append(b, f.Payload...), nil
+}
+
+// FragSessionSetupAns is the answer of a device to a FragSessionSetupReq
+type FragSessionSetupAns struct {
+	FragIndex            uint8
+	EncodingUnsupported  bool
+	NotEnoughMemory      bool
+	FragIndexUnsupported bool
+	WrongDescriptor      bool
+}
+
+// Error returns the error that the device reported, or nil
+func (a FragSessionSetupAns) Error() error {
+	switch {
+	case a.EncodingUnsupported:
+		return errors.New("Fragmentation matrix not supported")
+	case a.NotEnoughMemory:
+		return errors.New("Not enough memory for fragmentation session")
+	case a.FragIndexUnsupported:
+		return errors.New("Fragmentation session index not supported")
+	case a.WrongDescriptor:
+		return errors.New("Wrong fragmentation session descriptor")
+	}
+	return nil
+}
+
+// FragSessionStatusAns is the answer of a device to a FragSessionStatusReq
+type FragSessionStatusAns struct {
+	FragIndex             uint8
+	NbFragReceived        uint16
+	MissingFrag           uint8
+	NotEnoughMatrixMemory bool
+}
+
+// FragmentationAnswers contains the answers in an uplink message on the FragmentationPort
+type FragmentationAnswers struct {
+	FragSessionSetup  []FragSessionSetupAns
+	FragSessionStatus []FragSessionStatusAns
+}
+
+// UnmarshalFragmentationAnswers unmarshals the answers in an uplink message on the FragmentationPort. Answers that
+// are not needed for FUOTA are skipped.
+func UnmarshalFragmentationAnswers(b []byte) (*FragmentationAnswers, error) {
+	answers := new(FragmentationAnswers)
+	for len(b) > 0 {
+		cid := b[0]
+		b = b[1:]
+		var length int
+		switch cid {
+		case cidFragPackageVersion:
+			length = 2
+		case cidFragSessionStatus:
+			length = 4
+		case cidFragSessionSetup, cidFragSessionDelete:
+			length = 1
+		default:
+			return answers, errors.NewErrInvalidArgument("Fragmentation", fmt.Sprintf("unknown command 0x%02X", cid))
+		}
+		if len(b) < length {
+			return answers, errors.NewErrInvalidArgument("Fragmentation", fmt.Sprintf("command 0x%02X too short", cid))
+		}
+		payload := b[:length]
+		b = b[length:]
+		switch cid {
+		case cidFragSessionSetup:
+			answers.FragSessionSetup = append(answers.FragSessionSetup, FragSessionSetupAns{
+				FragIndex:            payload[0] >> 6,
+				EncodingUnsupported:  payload[0]&0x01 != 0,
+				NotEnoughMemory:      payload[0]&0x02 != 0,
+				FragIndexUnsupported: payload[0]&0x04 != 0,
+				WrongDescriptor:      payload[0]&0x08 != 0,
+			})
+		case cidFragSessionStatus:
+			receivedAndIndex := binary.LittleEndian.Uint16(payload[0:2])
+			answers.FragSessionStatus = append(answers.FragSessionStatus, FragSessionStatusAns{
+				FragIndex:             uint8(receivedAndIndex >> 14),
+				NbFragReceived:        receivedAndIndex & maxNbFrag,
+				MissingFrag:           payload[2],
+				NotEnoughMatrixMemory: payload[3]&0x01 != 0,
+			})
+		}
+	}
+	return answers, nil
+}
+
+// Fragmenter splits a data block into fragments, followed by redundancy fragments for forward error correction
+type Fragmenter struct {
+	data    []byte
+	size    int
+	nbFrag  int
+	padding int
+}
+
+// NewFragmenter returns a new Fragmenter for the data block, with fragments of the given size
+func NewFragmenter(data []byte, size int) (*Fragmenter, error) {
+	if len(data) == 0 {
+		return nil, errors.NewErrInvalidArgument("Data", "can not be empty")
+	}
+	if size <= 0 || size > 255 {
+		return nil, errors.NewErrInvalidArgument("Fragment Size", "must be between 1 and 255")
+	}
+	nbFrag := (len(data) + size - 1) / size
+	if nbFrag > maxNbFrag {
+		return nil, errors.NewErrInvalidArgument("Data", fmt.Sprintf("does not fit in %d fragments", maxNbFrag))
+	}
+	padded := make([]byte, nbFrag*size)
+	copy(padded, data)
+	return &Fragmenter{data: padded, size: size, nbFrag: nbFrag, padding: len(padded) - len(data)}, nil
+}
+
+// NbFrag returns the number of (uncoded) fragments
+func (f *Fragmenter) NbFrag() int {
+	return f.nbFrag
+}
+
+// Padding returns the number of padding bytes in the last fragment
+func (f *Fragmenter) Padding() int {
+	return f.padding
+}
+
+// Fragment returns fragment n (starting at 1). Fragments after NbFrag are redundancy fragments.
+func (f *Fragmenter) Fragment(n int) []byte {
+	if n <= f.nbFrag {
+		return f.data[(n-1)*f.size : n*f.size]
+	}
+	fragment := make([]byte, f.size)
+	for i, set := range parityMatrixLine(n-f.nbFrag, f.nbFrag) {
+		if !set {
+			continue
+		}
+		for j, b := range f.data[i*f.size : (i+1)*f.size] {
+			fragment[j] ^= b
+		}
+	}
+	return fragment
+}
+
+func prbs23(x uint32) uint32 {
+	b0 := x & 1
+	b1 := (x & 0x20) >> 5
+	return (x >> 1) + ((b0 ^ b1) << 22)
+}
+
+func isPowerOfTwo(x int) bool {
+	return x > 0 && x&(x-1) == 0
+}
+
+// parityMatrixLine returns line n (starting at 1) of the parity matrix for m fragments, as defined in the
+// Fragmented Data Block Transport specification
+func parityMatrixLine(n, m int) []bool {
+	line := make([]bool, m)
+	mm := m
+	if isPowerOfTwo(m) {
+		mm = m + 1
+	}
+	x := uint32(1 + 1001*n)
+	for nbCoeff := 0; nbCoeff < m/2; nbCoeff++ {
+		r := 1 << 16
+		for r >= m {
+			x = prbs23(x)
+			r = int(x % uint32(mm))
+		}
+		line[r] = true
+	}
+	return line
+}
@@ -0,0 +1,117 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package fuota
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/assertions"
+)
+
+func TestFragmentationCommands(t *testing.T) {
+	a := New(t)
+
+	b, err := FragSessionSetupReq{FragIndex: 1, McGroupBitMask: 0x01, NbFrag: 300, FragSize: 50, Padding: 7, Descriptor: 0x01020304}.MarshalBinary()
+	a.So(err, ShouldBeNil)
+	a.So(b, ShouldResemble, []byte{0x02, 0x11, 0x2c, 0x01, 50, 0x00, 7, 0x04, 0x03, 0x02, 0x01})
+
+	_, err = FragSessionSetupReq{FragIndex: 4}.MarshalBinary()
+	a.So(err, ShouldNotBeNil)
+
+	b, err = FragSessionStatusReq{FragIndex: 1, Participants: true}.MarshalBinary()
+	a.So(err, ShouldBeNil)
+	a.So(b, ShouldResemble, []byte{0x01, 0x03})
+
+	b, err = DataFragment{FragIndex: 1, N: 2, Payload: []byte{0xaa, 0xbb}}.MarshalBinary()
+	a.So(err, ShouldBeNil)
+	a.So(b, ShouldResemble, []byte{0x08, 0x02, 0x40, 0xaa, 0xbb})
+
+	_, err = DataFragment{N: 0}.MarshalBinary()
+	a.So(err, ShouldNotBeNil)
+
+	answers, err := UnmarshalFragmentationAnswers([]byte{
+		0x02, 0x40, // FragSessionSetupAns for index 1
+		0x02, 0x82, // FragSessionSetupAns for index 2 with not enough memory
+		0x01, 0x2c, 0x41, 0x03, 0x00, // FragSessionStatusAns for index 1
+	})
+	a.So(err, ShouldBeNil)
+	a.So(answers.FragSessionSetup, ShouldHaveLength, 2)
+	a.So(answers.FragSessionSetup[0].FragIndex, ShouldEqual, 1)
+	a.So(answers.FragSessionSetup[0].Error(), ShouldBeNil)
+	a.So(answers.FragSessionSetup[1].FragIndex, ShouldEqual, 2)
+	a.So(answers.FragSessionSetup[1].Error(), ShouldNotBeNil)
+	a.So(answers.FragSessionStatus, ShouldResemble, []FragSessionStatusAns{{FragIndex: 1, NbFragReceived: 300, MissingFrag: 3}})
+
+	_, err = UnmarshalFragmentationAnswers([]byte{0x01, 0x2c})
+	a.So(err, ShouldNotBeNil)
+
+	_, err = UnmarshalFragmentationAnswers([]byte{0x42})
+	a.So(err, ShouldNotBeNil)
+}
+
+func TestFragmenter(t *testing.T) {
+	a := New(t)
+
+	_, err := NewFragmenter(nil, 10)
+	a.So(err, ShouldNotBeNil)
+
+	_, err = NewFragmenter([]byte{1}, 0)
+	a.So(err, ShouldNotBeNil)
+
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	f, err := NewFragmenter(data, 16)
+	a.So(err, ShouldBeNil)
+	a.So(f.NbFrag(), ShouldEqual, 7)
+	a.So(f.Padding(), ShouldEqual, 12)
+	a.So(f.Fragment(1), ShouldResemble, data[:16])
+	a.So(f.Fragment(7), ShouldResemble, append(data[96:], make([]byte, 12)...))
+
+	// Each redundancy fragment is the XOR of the uncoded fragments in its parity matrix line, so a lost fragment can
+	// be recovered from a redundancy fragment and the other fragments in that line
+	for n := 1; n <= 10; n++ {
+		line := parityMatrixLine(n, f.NbFrag())
+		lost := -1
+		for i, set := range line {
+			if set {
+				lost = i
+				break
+			}
+		}
+		a.So(lost, ShouldBeGreaterThanOrEqualTo, 0)
+
+		recovered := append([]byte{}, f.Fragment(f.NbFrag()+n)...)
+		for i, set := range line {
+			if !set || i == lost {
+				continue
+			}
+			for j, b := range f.Fragment(i + 1) {
+				recovered[j] ^= b
+			}
+		}
+		a.So(recovered, ShouldResemble, f.Fragment(lost+1))
+	}
+}
+
+func TestParityMatrixLine(t *testing.T) {
+	a := New(t)
+
+	for _, m := range []int{2, 7, 16, 100} {
+		for n := 1; n <= 5; n++ {
+			line := parityMatrixLine(n, m)
+			a.So(line, ShouldHaveLength, m)
+			var count int
+			for _, set := range line {
+				if set {
+					count++
+				}
+			}
+			a.So(count, ShouldBeGreaterThan, 0)
+			a.So(count, ShouldBeLessThanOrEqualTo, m/2)
+		}
+	}
+}
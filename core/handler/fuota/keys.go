@@ -0,0 +1,37 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package fuota
+
+import (
+	"crypto/aes"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+)
+
+// EncryptMcKey encrypts the McKey for a device with the McKEKey that is derived from its GenAppKey. LoRaWAN 1.0.x
+// devices without a separate GenAppKey use their AppKey.
+func EncryptMcKey(genAppKey types.AppKey, mcKey types.AppKey) (encrypted [16]byte) {
+	var mcRootKey, mcKEKey [16]byte
+	block, _ := aes.NewCipher(genAppKey[:])
+	block.Encrypt(mcRootKey[:], make([]byte, 16))
+	block, _ = aes.NewCipher(mcRootKey[:])
+	block.Encrypt(mcKEKey[:], make([]byte, 16))
+	block, _ = aes.NewCipher(mcKEKey[:])
+	block.Decrypt(encrypted[:], mcKey[:]) // The device uses aes128_encrypt to obtain the McKey
+	return
+}
+
+// GroupSessionKeys derives the McAppSKey and McNwkSKey of a multicast group from the McKey
+func GroupSessionKeys(mcKey types.AppKey, mcAddr types.DevAddr) (mcAppSKey types.AppSKey, mcNwkSKey types.NwkSKey) {
+	buf := make([]byte, 16)
+	for i, v := range mcAddr { // LSB first
+		buf[4-i] = v
+	}
+	block, _ := aes.NewCipher(mcKey[:])
+	buf[0] = 0x01
+	block.Encrypt(mcAppSKey[:], buf)
+	buf[0] = 0x02
+	block.Encrypt(mcNwkSKey[:], buf)
+	return
+}
@@ -0,0 +1,182 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package fuota implements firmware updates over the air with the LoRaWAN application layer packages for Remote
+// Multicast Setup and Fragmented Data Block Transport
+package fuota
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// MulticastSetupPort is the FPort of the Remote Multicast Setup package
+const MulticastSetupPort = 200
+
+// Command identifiers of the Remote Multicast Setup package
+const (
+	cidPackageVersion  = 0x00
+	cidMcGroupStatus   = 0x01
+	cidMcGroupSetup    = 0x02
+	cidMcGroupDelete   = 0x03
+	cidMcClassCSession = 0x04
+	cidMcClassBSession = 0x05
+)
+
+const (
+	maxMcGroupID        = 3
+	maxSessionTimeOut   = 15
+	dlFrequencyStepSize = 100 // Hz
+)
+
+// McGroupSetupReq creates or modifies a multicast group on a device
+type McGroupSetupReq struct {
+	McGroupID      uint8
+	McAddr         types.DevAddr
+	McKeyEncrypted [16]byte
+	MinMcFCount    uint32
+	MaxMcFCount    uint32
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (r McGroupSetupReq) MarshalBinary() ([]byte, error) {
+	if r.McGroupID > maxMcGroupID {
+		return nil, errors.NewErrInvalidArgument("McGroupID", fmt.Sprintf("must be at most %d", maxMcGroupID))
+	}
+	b := make([]byte, 30)
+	b[0] = cidMcGroupSetup
+	b[1] = r.McGroupID
+	for i, v := range r.McAddr { // LSB first
+		b[5-i] = v
+	}
+	copy(b[6:22], r.McKeyEncrypted[:])
+	binary.LittleEndian.PutUint32(b[22:26], r.MinMcFCount)
+	binary.LittleEndian.PutUint32(b[26:30], r.MaxMcFCount)
+	return b, nil
+}
+
+// McClassCSessionReq starts a Class C multicast session on a device
+type McClassCSessionReq struct {
+	McGroupID      uint8
+	SessionTime    uint32 // in seconds since the GPS epoch
+	SessionTimeOut uint8  // the session lasts 2^SessionTimeOut seconds
+	DLFrequency    uint64 // in Hz
+	DataRate       uint8  // index
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (r McClassCSessionReq) MarshalBinary() ([]byte, error) {
+	if r.McGroupID > maxMcGroupID {
+		return nil, errors.NewErrInvalidArgument("McGroupID", fmt.Sprintf("must be at most %d", maxMcGroupID))
+	}
+	if r.SessionTimeOut > maxSessionTimeOut {
+		return nil, errors.NewErrInvalidArgument("SessionTimeOut", fmt.Sprintf("must be at most %d", maxSessionTimeOut))
+	}
+	frequency := r.DLFrequency / dlFrequencyStepSize
+	if frequency >= 1<<24 {
+		return nil, errors.NewErrInvalidArgument("DLFrequency", "out of range")
+	}
+	b := make([]byte, 11)
+	b[0] = cidMcClassCSession
+	b[1] = r.McGroupID
+	binary.LittleEndian.PutUint32(b[2:6], r.SessionTime)
+	b[6] = r.SessionTimeOut
+	b[7], b[8], b[9] = byte(frequency), byte(frequency>>8), byte(frequency>>16)
+	b[10] = r.DataRate
+	return b, nil
+}
+
+// McGroupSetupAns is the answer of a device to a McGroupSetupReq
+type McGroupSetupAns struct {
+	McGroupID uint8
+	IDError   bool
+}
+
+// McClassCSessionAns is the answer of a device to a McClassCSessionReq
+type McClassCSessionAns struct {
+	McGroupID        uint8
+	DataRateError    bool
+	FrequencyError   bool
+	McGroupUndefined bool
+	TimeToStart      uint32 // in seconds, only if there is no error
+}
+
+// Error returns the error that the device reported, or nil
+func (a McClassCSessionAns) Error() error {
+	switch {
+	case a.McGroupUndefined:
+		return errors.New("Multicast group undefined")
+	case a.FrequencyError:
+		return errors.New("Multicast frequency not supported")
+	case a.DataRateError:
+		return errors.New("Multicast data rate not supported")
+	}
+	return nil
+}
+
+// MulticastSetupAnswers contains the answers in an uplink message on the MulticastSetupPort
+type MulticastSetupAnswers struct {
+	McGroupSetup    []McGroupSetupAns
+	McClassCSession []McClassCSessionAns
+}
+
+// UnmarshalMulticastSetupAnswers unmarshals the answers in an uplink message on the MulticastSetupPort. Answers that
+// are not needed for FUOTA are skipped.
+func UnmarshalMulticastSetupAnswers(b []byte) (*MulticastSetupAnswers, error) {
+	answers := new(MulticastSetupAnswers)
+	for len(b) > 0 {
+		cid := b[0]
+		b = b[1:]
+		var length int
+		switch cid {
+		case cidPackageVersion:
+			length = 2
+		case cidMcGroupStatus:
+			if len(b) < 1 {
+				break
+			}
+			length = 1
+			for mask := b[0] & 0x0f; mask != 0; mask >>= 1 {
+				if mask&1 == 1 {
+					length += 5
+				}
+			}
+		case cidMcGroupSetup, cidMcGroupDelete:
+			length = 1
+		case cidMcClassCSession, cidMcClassBSession:
+			length = 1
+			if len(b) > 0 && b[0]&0x1c == 0 {
+				length = 4
+			}
+		default:
+			return answers, errors.NewErrInvalidArgument("Multicast Setup", fmt.Sprintf("unknown command 0x%02X", cid))
+		}
+		if len(b) < length || length == 0 {
+			return answers, errors.NewErrInvalidArgument("Multicast Setup", fmt.Sprintf("command 0x%02X too short", cid))
+		}
+		payload := b[:length]
+		b = b[length:]
+		switch cid {
+		case cidMcGroupSetup:
+			answers.McGroupSetup = append(answers.McGroupSetup, McGroupSetupAns{
+				McGroupID: payload[0] & 0x03,
+				IDError:   payload[0]&0x04 != 0,
+			})
+		case cidMcClassCSession:
+			ans := McClassCSessionAns{
+				McGroupID:        payload[0] & 0x03,
+				DataRateError:    payload[0]&0x04 != 0,
+				FrequencyError:   payload[0]&0x08 != 0,
+				McGroupUndefined: payload[0]&0x10 != 0,
+			}
+			if length == 4 {
+				ans.TimeToStart = uint32(payload[1]) | uint32(payload[2])<<8 | uint32(payload[3])<<16
+			}
+			answers.McClassCSession = append(answers.McClassCSession, ans)
+		}
+	}
+	return answers, nil
+}
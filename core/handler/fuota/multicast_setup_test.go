@@ -0,0 +1,91 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package fuota
+
+import (
+	"crypto/aes"
+	"testing"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestMulticastSetupCommands(t *testing.T) {
+	a := New(t)
+
+	b, err := McGroupSetupReq{
+		McGroupID:      1,
+		McAddr:         types.DevAddr{1, 2, 3, 4},
+		McKeyEncrypted: [16]byte{0xff},
+		MinMcFCount:    1,
+		MaxMcFCount:    0x0100,
+	}.MarshalBinary()
+	a.So(err, ShouldBeNil)
+	a.So(b, ShouldHaveLength, 30)
+	a.So(b[:7], ShouldResemble, []byte{0x02, 0x01, 0x04, 0x03, 0x02, 0x01, 0xff})
+	a.So(b[22:], ShouldResemble, []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00})
+
+	_, err = McGroupSetupReq{McGroupID: 4}.MarshalBinary()
+	a.So(err, ShouldNotBeNil)
+
+	b, err = McClassCSessionReq{
+		McGroupID:      1,
+		SessionTime:    0x01020304,
+		SessionTimeOut: 9,
+		DLFrequency:    869525000,
+		DataRate:       3,
+	}.MarshalBinary()
+	a.So(err, ShouldBeNil)
+	a.So(b, ShouldResemble, []byte{0x04, 0x01, 0x04, 0x03, 0x02, 0x01, 0x09, 0xd2, 0xad, 0x84, 0x03})
+
+	_, err = McClassCSessionReq{SessionTimeOut: 16}.MarshalBinary()
+	a.So(err, ShouldNotBeNil)
+
+	answers, err := UnmarshalMulticastSetupAnswers([]byte{
+		0x02, 0x01, // McGroupSetupAns for group 1
+		0x04, 0x01, 0x0a, 0x00, 0x00, // McClassCSessionAns for group 1, starting in 10 seconds
+		0x04, 0x12, // McClassCSessionAns for group 2 with undefined group
+	})
+	a.So(err, ShouldBeNil)
+	a.So(answers.McGroupSetup, ShouldResemble, []McGroupSetupAns{{McGroupID: 1}})
+	a.So(answers.McClassCSession, ShouldHaveLength, 2)
+	a.So(answers.McClassCSession[0].Error(), ShouldBeNil)
+	a.So(answers.McClassCSession[0].TimeToStart, ShouldEqual, 10)
+	a.So(answers.McClassCSession[1].McGroupID, ShouldEqual, 2)
+	a.So(answers.McClassCSession[1].Error(), ShouldNotBeNil)
+
+	_, err = UnmarshalMulticastSetupAnswers([]byte{0x04, 0x01, 0x0a})
+	a.So(err, ShouldNotBeNil)
+
+	_, err = UnmarshalMulticastSetupAnswers([]byte{0x42})
+	a.So(err, ShouldNotBeNil)
+}
+
+func TestEncryptMcKey(t *testing.T) {
+	a := New(t)
+
+	appKey := types.AppKey{1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8}
+	mcKey := types.AppKey{8, 7, 6, 5, 4, 3, 2, 1, 8, 7, 6, 5, 4, 3, 2, 1}
+
+	encrypted := EncryptMcKey(appKey, mcKey)
+	a.So(encrypted[:], ShouldNotResemble, mcKey[:])
+
+	// The device derives the McKEKey from its key and decrypts the McKey with aes128_encrypt
+	var mcRootKey, mcKEKey, decrypted [16]byte
+	block, _ := aes.NewCipher(appKey[:])
+	block.Encrypt(mcRootKey[:], make([]byte, 16))
+	block, _ = aes.NewCipher(mcRootKey[:])
+	block.Encrypt(mcKEKey[:], make([]byte, 16))
+	block, _ = aes.NewCipher(mcKEKey[:])
+	block.Encrypt(decrypted[:], encrypted[:])
+	a.So(decrypted[:], ShouldResemble, mcKey[:])
+
+	appSKey, nwkSKey := GroupSessionKeys(mcKey, types.DevAddr{1, 2, 3, 4})
+	a.So(appSKey.IsEmpty(), ShouldBeFalse)
+	a.So(nwkSKey.IsEmpty(), ShouldBeFalse)
+	a.So(appSKey[:], ShouldNotResemble, nwkSKey[:])
+
+	otherAppSKey, _ := GroupSessionKeys(mcKey, types.DevAddr{1, 2, 3, 5})
+	a.So(otherAppSKey, ShouldNotEqual, appSKey)
+}
@@ -0,0 +1,117 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package fuota
+
+import (
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/jsoncodec"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+// ApplicationIdentifier identifies an application
+type ApplicationIdentifier struct {
+	AppID string `json:"app_id"`
+}
+
+// SessionIdentifier identifies a FUOTA session
+type SessionIdentifier struct {
+	AppID     string `json:"app_id"`
+	SessionID string `json:"session_id"`
+}
+
+// SessionRequest contains the firmware, devices and multicast settings of a new FUOTA session
+type SessionRequest struct {
+	AppID     string   `json:"app_id"`
+	SessionID string   `json:"session_id"`
+	DevIDs    []string `json:"dev_ids"`
+	Firmware  []byte   `json:"firmware"`
+
+	McAddr        types.DevAddr `json:"mc_addr"`
+	McGroupID     uint8         `json:"mc_group_id,omitempty"`
+	FrequencyPlan string        `json:"frequency_plan,omitempty"` // for example EU_863_870, guessed from the frequency if empty
+	Frequency     uint64        `json:"frequency"`                // in Hz
+	DataRate      string        `json:"data_rate"`                // for example SF12BW125
+	Power         int32         `json:"power,omitempty"`          // in dBm
+	Gateways      []string      `json:"gateways,omitempty"`       // the IDs of the Gateways that send the fragments
+
+	FragSize         uint8           `json:"frag_size,omitempty"`
+	Redundancy       uint16          `json:"redundancy,omitempty"` // the number of redundancy fragments
+	FragIndex        uint8           `json:"frag_index,omitempty"`
+	Descriptor       uint32          `json:"descriptor,omitempty"`
+	StartAt          *types.JSONTime `json:"start_at,omitempty"`
+	FragmentInterval string          `json:"fragment_interval,omitempty"` // for example 5s
+}
+
+// SessionList contains the FUOTA sessions of an application
+type SessionList struct {
+	Sessions []*Session `json:"sessions"`
+}
+
+// Empty is the response of requests that do not return anything
+type Empty struct{}
+
+// FUOTAManagerServer is the server API for the FUOTAManager service
+type FUOTAManagerServer interface {
+	CreateSession(context.Context, *SessionRequest) (*Session, error)
+	GetSession(context.Context, *SessionIdentifier) (*Session, error)
+	ListSessions(context.Context, *ApplicationIdentifier) (*SessionList, error)
+	DeleteSession(context.Context, *SessionIdentifier) (*Empty, error)
+}
+
+// FUOTAManagerClient is the client API for the FUOTAManager service
+type FUOTAManagerClient interface {
+	CreateSession(ctx context.Context, in *SessionRequest, opts ...grpc.CallOption) (*Session, error)
+	GetSession(ctx context.Context, in *SessionIdentifier, opts ...grpc.CallOption) (*Session, error)
+	ListSessions(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*SessionList, error)
+	DeleteSession(ctx context.Context, in *SessionIdentifier, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type fuotaManagerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewFUOTAManagerClient returns a new FUOTAManagerClient
+func NewFUOTAManagerClient(cc *grpc.ClientConn) FUOTAManagerClient {
+	return &fuotaManagerClient{cc}
+}
+
+func (c *fuotaManagerClient) CreateSession(ctx context.Context, in *SessionRequest, opts ...grpc.CallOption) (*Session, error) {
+	out := new(Session)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.FUOTAManager/CreateSession", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fuotaManagerClient) GetSession(ctx context.Context, in *SessionIdentifier, opts ...grpc.CallOption) (*Session, error) {
+	out := new(Session)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.FUOTAManager/GetSession", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fuotaManagerClient) ListSessions(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*SessionList, error) {
+	out := new(SessionList)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.FUOTAManager/ListSessions", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fuotaManagerClient) DeleteSession(ctx context.Context, in *SessionIdentifier, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.FUOTAManager/DeleteSession", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var fuotaManagerServiceDesc = jsoncodec.ServiceDesc("handler.FUOTAManager", (*FUOTAManagerServer)(nil))
+
+// RegisterFUOTAManagerServer registers the FUOTAManager service
+func RegisterFUOTAManagerServer(s *grpc.Server, srv FUOTAManagerServer) {
+	s.RegisterService(fuotaManagerServiceDesc, srv)
+}
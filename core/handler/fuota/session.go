@@ -0,0 +1,133 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package fuota
+
+import (
+	"time"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// SessionStatus is the status of a FUOTA session
+type SessionStatus string
+
+// Statuses of a FUOTA session
+const (
+	// StatusSetup: the multicast group and fragmentation session are being set up on the devices
+	StatusSetup SessionStatus = "setup"
+	// StatusSending: the fragments are being sent to the multicast group
+	StatusSending SessionStatus = "sending"
+	// StatusSent: all fragments were sent, the devices are asked for their status
+	StatusSent SessionStatus = "sent"
+	// StatusCompleted: all participating devices received the firmware
+	StatusCompleted SessionStatus = "completed"
+	// StatusFailed: the session could not be completed
+	StatusFailed SessionStatus = "failed"
+)
+
+// IsActive returns true if the session is still in progress
+func (s SessionStatus) IsActive() bool {
+	return s == StatusSetup || s == StatusSending || s == StatusSent
+}
+
+// DeviceStatus is the progress of a device in a FUOTA session
+type DeviceStatus struct {
+	DevID              string `json:"dev_id"`
+	McGroupSetup       bool   `json:"mc_group_setup"`
+	FragSessionSetup   bool   `json:"frag_session_setup"`
+	ClassCSessionSetup bool   `json:"class_c_session_setup"`
+	FragmentsReceived  uint16 `json:"fragments_received,omitempty"`
+	MissingFragments   uint8  `json:"missing_fragments,omitempty"`
+	Done               bool   `json:"done"`
+	Error              string `json:"error,omitempty"`
+}
+
+// Ready returns true if the device answered all setup requests without errors
+func (d *DeviceStatus) Ready() bool {
+	return d.McGroupSetup && d.FragSessionSetup && d.ClassCSessionSetup && d.Error == ""
+}
+
+// Session is a firmware update over the air to a group of devices. The firmware is sent in fragments to a multicast
+// group that is set up on the devices for the session.
+type Session struct {
+	old *Session
+
+	AppID     string `redis:"app_id" json:"app_id"`
+	SessionID string `redis:"session_id" json:"session_id"`
+	GroupID   string `redis:"group_id" json:"group_id"`
+	McGroupID uint8  `redis:"mc_group_id" json:"mc_group_id"`
+
+	Firmware   []byte `redis:"-" json:"-"`
+	FragIndex  uint8  `redis:"frag_index" json:"frag_index"`
+	NbFrag     uint16 `redis:"nb_frag" json:"nb_frag"`
+	FragSize   uint8  `redis:"frag_size" json:"frag_size"`
+	Padding    uint8  `redis:"padding" json:"padding"`
+	Redundancy uint16 `redis:"redundancy" json:"redundancy"`
+	Descriptor uint32 `redis:"descriptor" json:"descriptor"`
+
+	StartAt          time.Time     `redis:"start_at" json:"start_at"`
+	FragmentInterval time.Duration `redis:"fragment_interval" json:"fragment_interval"`
+	SessionTimeOut   uint8         `redis:"session_time_out" json:"session_time_out"`
+
+	Status         SessionStatus   `redis:"status" json:"status"`
+	Error          string          `redis:"error" json:"error,omitempty"`
+	FragmentsSent  uint16          `redis:"fragments_sent" json:"fragments_sent"`
+	LastFragmentAt time.Time       `redis:"last_fragment_at" json:"last_fragment_at,omitempty"`
+	Devices        []*DeviceStatus `redis:"-" json:"devices"`
+
+	CreatedAt time.Time `redis:"created_at" json:"created_at"`
+	UpdatedAt time.Time `redis:"updated_at" json:"updated_at"`
+}
+
+// Validate the session
+func (s *Session) Validate() error {
+	if err := api.NotEmptyAndValidID(s.AppID, "Application ID"); err != nil {
+		return err
+	}
+	if err := api.NotEmptyAndValidID(s.SessionID, "Session ID"); err != nil {
+		return err
+	}
+	if s.McGroupID > maxMcGroupID {
+		return errors.NewErrInvalidArgument("McGroupID", "must be at most 3")
+	}
+	if s.FragIndex > maxFragIndex {
+		return errors.NewErrInvalidArgument("FragIndex", "must be at most 3")
+	}
+	if len(s.Devices) == 0 {
+		return errors.NewErrInvalidArgument("Devices", "can not be empty")
+	}
+	return nil
+}
+
+// TotalFragments returns the number of fragments including the redundancy fragments
+func (s *Session) TotalFragments() uint16 {
+	return s.NbFrag + s.Redundancy
+}
+
+// Device returns the status of a device in the session, or nil if the device does not participate
+func (s *Session) Device(devID string) *DeviceStatus {
+	for _, dev := range s.Devices {
+		if dev.DevID == devID {
+			return dev
+		}
+	}
+	return nil
+}
+
+// ReadyDevices returns the devices that are ready to receive fragments
+func (s *Session) ReadyDevices() (devices []*DeviceStatus) {
+	for _, dev := range s.Devices {
+		if dev.Ready() {
+			devices = append(devices, dev)
+		}
+	}
+	return
+}
+
+// StartUpdate stores the state of the session
+func (s *Session) StartUpdate() {
+	old := *s
+	s.old = &old
+}
@@ -0,0 +1,226 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package fuota
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/storage"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"gopkg.in/redis.v5"
+)
+
+// SessionRetention is the time for which a session is kept after it completed or failed
+var SessionRetention = 7 * 24 * time.Hour
+
+// Store interface for FUOTA Sessions
+type Store interface {
+	List(appID string) ([]*Session, error)
+	ListActive() ([]*Session, error)
+	Get(appID, sessionID string) (*Session, error)
+	GetFirmware(appID, sessionID string) ([]byte, error)
+	Set(new *Session, properties ...string) (err error)
+	SetDevice(appID, sessionID string, device *DeviceStatus) error
+	Delete(appID, sessionID string) error
+}
+
+const defaultRedisPrefix = "handler"
+const redisSessionPrefix = "fuota"
+const redisDevicesPrefix = "fuota-devices"
+const redisFirmwarePrefix = "fuota-firmware"
+const redisActiveKey = "fuota-active"
+
+// NewRedisSessionStore creates a new Redis-based FUOTA Session store
+// if an empty prefix is passed, a default prefix will be used.
+func NewRedisSessionStore(client *redis.Client, prefix string) Store {
+	if prefix == "" {
+		prefix = defaultRedisPrefix
+	}
+	store := storage.NewRedisMapStore(client, prefix+":"+redisSessionPrefix)
+	store.SetBase(Session{}, "")
+	return &RedisSessionStore{
+		client: client,
+		prefix: prefix,
+		store:  store,
+	}
+}
+
+// RedisSessionStore stores FUOTA Sessions in Redis.
+// - Sessions are stored as a Hash
+// - The status of the devices in a session is stored as a Hash with a field per device
+// - The firmware of a session is stored as a String
+// - The keys of the active sessions are stored as a Set
+type RedisSessionStore struct {
+	client *redis.Client
+	prefix string
+	store  *storage.RedisMapStore
+}
+
+func (s *RedisSessionStore) key(appID, sessionID string) string {
+	return fmt.Sprintf("%s:%s", appID, sessionID)
+}
+
+func (s *RedisSessionStore) sessionKey(appID, sessionID string) string {
+	return fmt.Sprintf("%s:%s:%s", s.prefix, redisSessionPrefix, s.key(appID, sessionID))
+}
+
+func (s *RedisSessionStore) devicesKey(appID, sessionID string) string {
+	return fmt.Sprintf("%s:%s:%s", s.prefix, redisDevicesPrefix, s.key(appID, sessionID))
+}
+
+func (s *RedisSessionStore) firmwareKey(appID, sessionID string) string {
+	return fmt.Sprintf("%s:%s:%s", s.prefix, redisFirmwarePrefix, s.key(appID, sessionID))
+}
+
+func (s *RedisSessionStore) activeKey() string {
+	return fmt.Sprintf("%s:%s", s.prefix, redisActiveKey)
+}
+
+// List the Sessions of an Application
+func (s *RedisSessionStore) List(appID string) ([]*Session, error) {
+	sessionsI, err := s.store.List(fmt.Sprintf("%s:*", appID), nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.withDevices(sessionsI)
+}
+
+// ListActive lists the active Sessions of all Applications
+func (s *RedisSessionStore) ListActive() ([]*Session, error) {
+	keys, err := s.client.SMembers(s.activeKey()).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sessionsI, err := s.store.GetAll(keys, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.withDevices(sessionsI)
+}
+
+func (s *RedisSessionStore) withDevices(sessionsI []interface{}) ([]*Session, error) {
+	sessions := make([]*Session, 0, len(sessionsI))
+	for _, sessionI := range sessionsI {
+		if session, ok := sessionI.(Session); ok && session.SessionID != "" {
+			if err := s.getDevices(&session); err != nil {
+				return nil, err
+			}
+			sessions = append(sessions, &session)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *RedisSessionStore) getDevices(session *Session) error {
+	devices, err := s.client.HGetAll(s.devicesKey(session.AppID, session.SessionID)).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	session.Devices = make([]*DeviceStatus, 0, len(devices))
+	for _, data := range devices {
+		device := new(DeviceStatus)
+		if err := json.Unmarshal([]byte(data), device); err != nil {
+			return err
+		}
+		session.Devices = append(session.Devices, device)
+	}
+	sort.Slice(session.Devices, func(i, j int) bool {
+		return session.Devices[i].DevID < session.Devices[j].DevID
+	})
+	return nil
+}
+
+// Get a specific Session. The Firmware of the session is not loaded.
+func (s *RedisSessionStore) Get(appID, sessionID string) (*Session, error) {
+	sessionI, err := s.store.Get(s.key(appID, sessionID))
+	if err != nil {
+		return nil, err
+	}
+	session, ok := sessionI.(Session)
+	if !ok {
+		return nil, errors.New("Database did not return a Session")
+	}
+	if err := s.getDevices(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetFirmware gets the Firmware of a specific Session
+func (s *RedisSessionStore) GetFirmware(appID, sessionID string) ([]byte, error) {
+	firmware, err := s.client.Get(s.firmwareKey(appID, sessionID)).Bytes()
+	if err == redis.Nil {
+		return nil, errors.NewErrNotFound(s.firmwareKey(appID, sessionID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return firmware, nil
+}
+
+// Set a new Session or update an existing one. The Devices are only stored for new Sessions, use SetDevice to update
+// the status of a device. When the Session is no longer active, its Firmware is deleted and the Session expires after
+// the SessionRetention.
+func (s *RedisSessionStore) Set(new *Session, properties ...string) (err error) {
+	now := time.Now()
+	new.UpdatedAt = now
+	if new.old == nil {
+		new.CreatedAt = now
+	}
+	if err := s.store.Set(s.key(new.AppID, new.SessionID), *new, properties...); err != nil {
+		return err
+	}
+	_, err = s.client.Pipelined(func(pipe *redis.Pipeline) error {
+		if new.old == nil {
+			for _, device := range new.Devices {
+				data, err := json.Marshal(device)
+				if err != nil {
+					return err
+				}
+				pipe.HSet(s.devicesKey(new.AppID, new.SessionID), device.DevID, string(data))
+			}
+			if len(new.Firmware) != 0 {
+				pipe.Set(s.firmwareKey(new.AppID, new.SessionID), new.Firmware, 0)
+			}
+		}
+		if new.Status.IsActive() {
+			pipe.SAdd(s.activeKey(), s.key(new.AppID, new.SessionID))
+			return nil
+		}
+		pipe.SRem(s.activeKey(), s.key(new.AppID, new.SessionID))
+		pipe.Del(s.firmwareKey(new.AppID, new.SessionID))
+		pipe.Expire(s.sessionKey(new.AppID, new.SessionID), SessionRetention)
+		pipe.Expire(s.devicesKey(new.AppID, new.SessionID), SessionRetention)
+		return nil
+	})
+	return err
+}
+
+// SetDevice sets the status of a device in a Session
+func (s *RedisSessionStore) SetDevice(appID, sessionID string, device *DeviceStatus) error {
+	data, err := json.Marshal(device)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(s.devicesKey(appID, sessionID), device.DevID, string(data)).Err()
+}
+
+// Delete a Session
+func (s *RedisSessionStore) Delete(appID, sessionID string) error {
+	if err := s.store.Delete(s.key(appID, sessionID)); err != nil {
+		return err
+	}
+	_, err := s.client.Pipelined(func(pipe *redis.Pipeline) error {
+		pipe.SRem(s.activeKey(), s.key(appID, sessionID))
+		pipe.Del(s.devicesKey(appID, sessionID), s.firmwareKey(appID, sessionID))
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,92 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package fuota
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestSessionStore(t *testing.T) {
+	a := New(t)
+
+	s := NewRedisSessionStore(GetRedisClient(), "handler-test-fuota-store")
+
+	_, err := s.Get("test", "firmware-v2")
+	a.So(err, ShouldNotBeNil)
+
+	err = s.Set(&Session{
+		AppID:            "test",
+		SessionID:        "firmware-v2",
+		Firmware:         []byte{1, 2, 3, 4, 5},
+		NbFrag:           3,
+		FragSize:         2,
+		Padding:          1,
+		FragmentInterval: 2 * time.Second,
+		Status:           StatusSetup,
+		Devices:          []*DeviceStatus{{DevID: "dev-1"}, {DevID: "dev-2"}},
+	})
+	a.So(err, ShouldBeNil)
+	defer s.Delete("test", "firmware-v2")
+
+	session, err := s.Get("test", "firmware-v2")
+	a.So(err, ShouldBeNil)
+	a.So(session.Firmware, ShouldBeEmpty)
+	a.So(session.FragmentInterval, ShouldEqual, 2*time.Second)
+	a.So(session.Status, ShouldEqual, StatusSetup)
+	a.So(session.Devices, ShouldHaveLength, 2)
+	a.So(session.CreatedAt.IsZero(), ShouldBeFalse)
+
+	firmware, err := s.GetFirmware("test", "firmware-v2")
+	a.So(err, ShouldBeNil)
+	a.So(firmware, ShouldResemble, []byte{1, 2, 3, 4, 5})
+
+	session.StartUpdate()
+	session.Status = StatusSending
+	err = s.Set(session, "Status")
+	a.So(err, ShouldBeNil)
+
+	status := session.Device("dev-2")
+	status.McGroupSetup = true
+	err = s.SetDevice("test", "firmware-v2", status)
+	a.So(err, ShouldBeNil)
+
+	session, err = s.Get("test", "firmware-v2")
+	a.So(err, ShouldBeNil)
+	a.So(session.Status, ShouldEqual, StatusSending)
+	a.So(session.Device("dev-1").McGroupSetup, ShouldBeFalse)
+	a.So(session.Device("dev-2").McGroupSetup, ShouldBeTrue)
+	a.So(session.Device("dev-3"), ShouldBeNil)
+
+	sessions, err := s.List("test")
+	a.So(err, ShouldBeNil)
+	a.So(sessions, ShouldHaveLength, 1)
+
+	sessions, err = s.ListActive()
+	a.So(err, ShouldBeNil)
+	a.So(sessions, ShouldHaveLength, 1)
+
+	// Finished sessions are no longer active and their firmware is deleted
+	session.StartUpdate()
+	session.Status = StatusCompleted
+	err = s.Set(session, "Status")
+	a.So(err, ShouldBeNil)
+
+	sessions, err = s.ListActive()
+	a.So(err, ShouldBeNil)
+	a.So(sessions, ShouldBeEmpty)
+
+	_, err = s.GetFirmware("test", "firmware-v2")
+	a.So(err, ShouldNotBeNil)
+
+	err = s.Delete("test", "firmware-v2")
+	a.So(err, ShouldBeNil)
+
+	sessions, err = s.List("test")
+	a.So(err, ShouldBeNil)
+	a.So(sessions, ShouldBeEmpty)
+}
@@ -0,0 +1,138 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/handler/fuota"
+	"github.com/TheThingsNetwork/ttn/core/handler/multicast"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestSessionTimeOut(t *testing.T) {
+	a := New(t)
+
+	timeOut, err := sessionTimeOut(time.Second)
+	a.So(err, ShouldBeNil)
+	a.So(timeOut, ShouldEqual, 0)
+
+	timeOut, err = sessionTimeOut(100 * time.Second)
+	a.So(err, ShouldBeNil)
+	a.So(timeOut, ShouldEqual, 7)
+
+	_, err = sessionTimeOut(24 * time.Hour)
+	a.So(err, ShouldNotBeNil)
+}
+
+func TestFUOTASession(t *testing.T) {
+	a := New(t)
+
+	ns := &mockMulticast{}
+	h := &handler{
+		Component:    &component.Component{Ctx: GetLogger(t, "TestFUOTASession")},
+		devices:      device.NewRedisDeviceStore(GetRedisClient(), "handler-test-fuota-session"),
+		groups:       multicast.NewRedisGroupStore(GetRedisClient(), "handler-test-fuota-session"),
+		sessions:     fuota.NewRedisSessionStore(GetRedisClient(), "handler-test-fuota-session"),
+		ttnMulticast: ns,
+		qEvent:       make(chan *types.DeviceEvent, 10),
+	}
+	h.InitStatus()
+
+	for _, devID := range []string{"dev-1", "dev-2"} {
+		h.devices.Set(&device.Device{AppID: "app1", DevID: devID})
+		defer h.devices.Delete("app1", devID)
+	}
+	h.groups.Set(&multicast.Group{
+		AppID:     "app1",
+		GroupID:   "firmware-v2",
+		McAddr:    types.DevAddr{1, 2, 3, 4},
+		McAppSKey: types.AppSKey{1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8},
+	})
+	defer h.groups.Delete("app1", "firmware-v2")
+
+	start := time.Now()
+	h.sessions.Set(&fuota.Session{
+		AppID:            "app1",
+		SessionID:        "firmware-v2",
+		GroupID:          "firmware-v2",
+		McGroupID:        1,
+		FragIndex:        1,
+		Firmware:         make([]byte, 100),
+		NbFrag:           3,
+		FragSize:         48,
+		Padding:          44,
+		Redundancy:       1,
+		StartAt:          start,
+		FragmentInterval: time.Second,
+		Status:           fuota.StatusSetup,
+		Devices:          []*fuota.DeviceStatus{{DevID: "dev-1"}, {DevID: "dev-2"}},
+	})
+	defer h.sessions.Delete("app1", "firmware-v2")
+
+	uplink := func(devID string, fPort uint8, payload []byte) {
+		err := h.HandleFUOTAUplink(GetLogger(t, "TestFUOTASession"), nil, &types.UplinkMessage{
+			AppID:      "app1",
+			DevID:      devID,
+			FPort:      fPort,
+			PayloadRaw: payload,
+		}, &device.Device{AppID: "app1", DevID: devID})
+		a.So(err, ShouldBeNil)
+	}
+
+	// dev-1 accepts the setup, dev-2 does not support the fragmentation session
+	uplink("dev-1", fuota.MulticastSetupPort, []byte{0x02, 0x01, 0x04, 0x01, 0x0a, 0x00, 0x00})
+	uplink("dev-1", fuota.FragmentationPort, []byte{0x02, 0x40})
+	uplink("dev-2", fuota.MulticastSetupPort, []byte{0x02, 0x01})
+	uplink("dev-2", fuota.FragmentationPort, []byte{0x02, 0x42})
+	uplink("dev-2", 1, []byte{0x02, 0x40}) // other port
+
+	session, _ := h.sessions.Get("app1", "firmware-v2")
+	a.So(session.Device("dev-1").Ready(), ShouldBeTrue)
+	a.So(session.Device("dev-2").Ready(), ShouldBeFalse)
+	a.So(session.Device("dev-2").Error, ShouldNotBeEmpty)
+
+	h.processFUOTASessions(start)
+	session, _ = h.sessions.Get("app1", "firmware-v2")
+	a.So(session.Status, ShouldEqual, fuota.StatusSending)
+
+	for i := 1; i <= 4; i++ {
+		h.processFUOTASessions(start.Add(time.Duration(i) * time.Second))
+		a.So(ns.downlink.FCnt, ShouldEqual, i-1)
+	}
+	h.processFUOTASessions(start.Add(4 * time.Second)) // nothing left to send
+	a.So(ns.downlink.FCnt, ShouldEqual, 3)
+
+	session, _ = h.sessions.Get("app1", "firmware-v2")
+	a.So(session.Status, ShouldEqual, fuota.StatusSent)
+	a.So(session.FragmentsSent, ShouldEqual, 4)
+
+	// The devices that participate are asked for their status
+	queue, _ := h.devices.DownlinkQueue("app1", "dev-1")
+	length, _ := queue.Length()
+	a.So(length, ShouldEqual, 1)
+	queue, _ = h.devices.DownlinkQueue("app1", "dev-2")
+	length, _ = queue.Length()
+	a.So(length, ShouldEqual, 0)
+
+	h.processFUOTASessions(start.Add(5 * time.Second))
+	session, _ = h.sessions.Get("app1", "firmware-v2")
+	a.So(session.Status, ShouldEqual, fuota.StatusSent)
+
+	uplink("dev-1", fuota.FragmentationPort, []byte{0x01, 0x03, 0x40, 0x00, 0x00})
+
+	h.processFUOTASessions(start.Add(6 * time.Second))
+	session, _ = h.sessions.Get("app1", "firmware-v2")
+	a.So(session.Status, ShouldEqual, fuota.StatusCompleted)
+	a.So(session.Device("dev-1").FragmentsReceived, ShouldEqual, 3)
+
+	// Completed sessions are no longer processed
+	active, _ := h.sessions.ListActive()
+	a.So(active, ShouldBeEmpty)
+}
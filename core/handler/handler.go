@@ -5,6 +5,7 @@ package handler
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 
 	pb_broker "github.com/TheThingsNetwork/api/broker"
@@ -15,7 +16,13 @@ import (
 	"github.com/TheThingsNetwork/ttn/core/component"
 	"github.com/TheThingsNetwork/ttn/core/handler/application"
 	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/handler/fuota"
+	"github.com/TheThingsNetwork/ttn/core/handler/multicast"
+	"github.com/TheThingsNetwork/ttn/core/handler/scheduled"
+	"github.com/TheThingsNetwork/ttn/core/handler/timeseries"
+	ns_multicast "github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
 	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/kafka"
 	"github.com/TheThingsNetwork/ttn/mqtt"
 	"google.golang.org/grpc"
 	"gopkg.in/redis.v5"
@@ -29,12 +36,22 @@ type Handler interface {
 	WithMQTT(username, password string, brokers ...string) Handler
 	WithMQTTFields(enabled bool) Handler
 	WithAMQP(username, password, host, exchange string) Handler
+	WithAMQPDeadLetterExchange(exchange string) Handler
+	WithKafka(brokers []string, topics kafka.Topics) Handler
 	WithDeviceAttributes(attribute ...string) Handler
+	WithConfirmedDownlinkRetries(retries int) Handler
+	WithJoinServers(keks map[string][]byte, servers ...JoinServer) Handler
+	WithWebhooks() Handler
+	WebhookHandler() http.Handler
+	WithUplinkStorage(store timeseries.Store) Handler
+	UplinkStorageHandler() http.Handler
 
 	HandleUplink(uplink *pb_broker.DeduplicatedUplinkMessage) error
 	HandleActivationChallenge(challenge *pb_broker.ActivationChallengeRequest) (*pb_broker.ActivationChallengeResponse, error)
 	HandleActivation(activation *pb_broker.DeduplicatedDeviceActivationRequest) (*pb.DeviceActivationResponse, error)
 	EnqueueDownlink(appDownlink *types.DownlinkMessage) error
+	HandleDownlinkFallback(downlink *pb_broker.DownlinkMessage) error
+	EnqueueMulticastDownlink(appDownlink *types.MulticastDownlinkMessage) (*multicast.DownlinkResponse, error)
 }
 
 // Timeout for publishing events to prevent blocking critical path.
@@ -45,9 +62,15 @@ func NewRedisHandler(client *redis.Client, ttnBrokerID string) Handler {
 	return &handler{
 		devices:      device.NewRedisDeviceStore(client, "handler"),
 		applications: application.NewRedisApplicationStore(client, "handler"),
+		revisions:    application.NewRedisRevisionStore(client, "handler"),
+		groups:       multicast.NewRedisGroupStore(client, "handler"),
+		sessions:     fuota.NewRedisSessionStore(client, "handler"),
+		scheduled:    scheduled.NewRedisDownlinkStore(client, "handler"),
 		ttnBrokerID:  ttnBrokerID,
 		qUp:          make(chan *types.UplinkMessage),
 		qEvent:       make(chan *types.DeviceEvent),
+
+		confirmedDownlinkRetries: DefaultConfirmedDownlinkRetries,
 	}
 }
 
@@ -56,6 +79,10 @@ type handler struct {
 
 	devices      device.Store
 	applications application.Store
+	revisions    application.RevisionStore
+	groups       multicast.Store
+	sessions     fuota.Store
+	scheduled    scheduled.Store
 
 	ttnBrokerID      string
 	ttnBrokerConn    *grpc.ClientConn
@@ -63,6 +90,9 @@ type handler struct {
 	ttnBrokerManager pb_broker.BrokerManagerClient
 	ttnDeviceManager pb_lorawan.DeviceManagerClient
 
+	ttnMulticastManager ns_multicast.MulticastManagerClient
+	ttnMulticast        ns_multicast.MulticastClient
+
 	downlink chan *pb_broker.DownlinkMessage
 
 	mqttClient        mqtt.Client
@@ -83,6 +113,31 @@ type handler struct {
 	amqpUp       chan *types.UplinkMessage
 	amqpEvent    chan *types.DeviceEvent
 
+	amqpDeadLetterExchange string
+
+	kafkaClient     kafka.Client
+	kafkaSubscriber kafka.Subscriber
+	kafkaPublisher  kafka.Publisher
+	kafkaBrokers    []string
+	kafkaTopics     kafka.Topics
+	kafkaEnabled    bool
+	kafkaUp         chan *types.UplinkMessage
+	kafkaEvent      chan *types.DeviceEvent
+
+	webhooksEnabled bool
+	webhookClient   *http.Client
+	webhookQueue    chan *webhookMessage
+	webhookRetries  chan *webhookDelivery
+	webhookCache    webhookCache
+
+	uplinkStore   timeseries.Store
+	uplinkStoreUp chan *types.UplinkMessage
+
+	joinServers    []JoinServer
+	joinServerKEKs map[string][]byte
+
+	confirmedDownlinkRetries int
+
 	qUp    chan *types.UplinkMessage
 	qEvent chan *types.DeviceEvent
 
@@ -117,11 +172,28 @@ func (h *handler) WithAMQP(username, password, host, exchange string) Handler {
 	return h
 }
 
+func (h *handler) WithAMQPDeadLetterExchange(exchange string) Handler {
+	h.amqpDeadLetterExchange = exchange
+	return h
+}
+
+func (h *handler) WithKafka(brokers []string, topics kafka.Topics) Handler {
+	h.kafkaBrokers = brokers
+	h.kafkaTopics = topics
+	h.kafkaEnabled = true
+	return h
+}
+
 func (h *handler) WithDeviceAttributes(a ...string) Handler {
 	h.devices.AddBuiltinAttribute(a...)
 	return h
 }
 
+func (h *handler) WithConfirmedDownlinkRetries(retries int) Handler {
+	h.confirmedDownlinkRetries = retries
+	return h
+}
+
 func (h *handler) Init(c *component.Component) error {
 	h.Component = c
 	h.InitStatus()
@@ -153,6 +225,21 @@ func (h *handler) Init(c *component.Component) error {
 		}
 	}
 
+	if h.kafkaEnabled {
+		err = h.HandleKafka(h.kafkaBrokers, h.kafkaTopics)
+		if err != nil {
+			return err
+		}
+	}
+
+	if h.webhooksEnabled {
+		h.HandleWebhooks()
+	}
+
+	if h.uplinkStore != nil {
+		h.HandleUplinkStorage()
+	}
+
 	go func() {
 		for {
 			select {
@@ -163,6 +250,15 @@ func (h *handler) Init(c *component.Component) error {
 				if h.amqpEnabled {
 					h.amqpUp <- up
 				}
+				if h.kafkaEnabled {
+					h.kafkaUp <- up
+				}
+				if h.webhooksEnabled {
+					h.enqueueWebhookMessage(&webhookMessage{appID: up.AppID, up: up})
+				}
+				if h.uplinkStore != nil {
+					h.uplinkStoreUp <- up
+				}
 			case event := <-h.qEvent:
 				if h.mqttEnabled {
 					h.mqttEvent <- event
@@ -170,6 +266,12 @@ func (h *handler) Init(c *component.Component) error {
 				if h.amqpEnabled {
 					h.amqpEvent <- event
 				}
+				if h.kafkaEnabled {
+					h.kafkaEvent <- event
+				}
+				if h.webhooksEnabled {
+					h.enqueueWebhookMessage(&webhookMessage{appID: event.AppID, event: event})
+				}
 			}
 		}
 	}()
@@ -179,6 +281,14 @@ func (h *handler) Init(c *component.Component) error {
 		return err
 	}
 
+	if h.sessions != nil {
+		go h.runFUOTASessions()
+	}
+
+	if h.scheduled != nil {
+		go h.runScheduledDownlinks()
+	}
+
 	h.Component.SetStatus(component.StatusHealthy)
 	// if h.Component.Monitor != nil {
 	// 	h.monitorStream = h.Component.Monitor.HandlerClient(h.Context, grpc.PerRPCCredentials(auth.WithStaticToken(h.AccessToken)))
@@ -194,6 +304,16 @@ func (h *handler) Shutdown() {
 	if h.amqpEnabled {
 		h.amqpClient.Disconnect()
 	}
+	if h.kafkaEnabled {
+		// The consumer and producer use the client, so they are closed first
+		if h.kafkaSubscriber != nil {
+			h.kafkaSubscriber.Close()
+		}
+		if h.kafkaPublisher != nil {
+			h.kafkaPublisher.Close()
+		}
+		h.kafkaClient.Disconnect()
+	}
 }
 
 func (h *handler) associateBroker() error {
@@ -209,6 +329,8 @@ func (h *handler) associateBroker() error {
 	h.ttnBroker = pb_broker.NewBrokerClient(conn)
 	h.ttnBrokerManager = pb_broker.NewBrokerManagerClient(conn)
 	h.ttnDeviceManager = pb_lorawan.NewDeviceManagerClient(conn)
+	h.ttnMulticastManager = ns_multicast.NewMulticastManagerClient(conn)
+	h.ttnMulticast = ns_multicast.NewMulticastClient(conn)
 
 	h.downlink = make(chan *pb_broker.DownlinkMessage)
 
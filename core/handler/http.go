@@ -0,0 +1,58 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/TheThingsNetwork/go-account-lib/claims"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+func writeHTTPError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch errors.GetErrType(err) {
+	case errors.InvalidArgument:
+		status = http.StatusBadRequest
+	case errors.NotFound:
+		status = http.StatusNotFound
+	case errors.PermissionDenied:
+		status = http.StatusForbidden
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func writeHTTPJSON(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// validateHTTPAuth validates the token or access key in the Authorization header of an HTTP request
+func (h *handler) validateHTTPAuth(r *http.Request, appID string, right types.Right) error {
+	authorization := r.Header.Get("Authorization")
+	var token string
+	switch {
+	case len(authorization) >= 7 && strings.ToLower(authorization[0:7]) == "bearer ":
+		token = authorization[7:]
+	case len(authorization) >= 4 && strings.ToLower(authorization[0:4]) == "key ":
+		var err error
+		if token, err = h.Component.ExchangeAppKeyForToken(appID, authorization[4:]); err != nil {
+			return errors.NewErrPermissionDenied(err.Error())
+		}
+	default:
+		return errors.NewErrPermissionDenied("Neither token nor key present")
+	}
+	if h.Component.TokenKeyProvider == nil {
+		return errors.NewErrInternal("No token provider configured")
+	}
+	claims, err := claims.FromToken(h.Component.TokenKeyProvider, token)
+	if err != nil {
+		return errors.NewErrPermissionDenied(err.Error())
+	}
+	return checkAppRights(claims, appID, right)
+}
@@ -0,0 +1,135 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	pb_broker "github.com/TheThingsNetwork/api/broker"
+	"github.com/TheThingsNetwork/api/trace"
+	"github.com/TheThingsNetwork/ttn/core/backend"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/brocaar/lorawan"
+)
+
+// JoinServer is an external Join Server that holds the root keys of devices with an AppEUI (JoinEUI) in a range
+type JoinServer struct {
+	From  types.AppEUI
+	To    types.AppEUI
+	URL   string
+	Token string
+}
+
+// ParseJoinServer parses a Join Server in the format AppEUI=URL or FromAppEUI-ToAppEUI=URL
+func ParseJoinServer(str string) (js JoinServer, err error) {
+	parts := strings.SplitN(str, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return js, errors.NewErrInvalidArgument("Join Server", "expected format AppEUI=URL or FromAppEUI-ToAppEUI=URL")
+	}
+	euis := strings.SplitN(parts[0], "-", 2)
+	if js.From, err = types.ParseAppEUI(euis[0]); err != nil {
+		return js, errors.NewErrInvalidArgument("Join Server", err.Error())
+	}
+	js.To = js.From
+	if len(euis) == 2 {
+		if js.To, err = types.ParseAppEUI(euis[1]); err != nil {
+			return js, errors.NewErrInvalidArgument("Join Server", err.Error())
+		}
+	}
+	if bytes.Compare(js.From.Bytes(), js.To.Bytes()) > 0 {
+		return js, errors.NewErrInvalidArgument("Join Server", "invalid AppEUI range")
+	}
+	js.URL = parts[1]
+	return js, nil
+}
+
+// Contains returns true if the AppEUI is in the range of the Join Server
+func (js JoinServer) Contains(appEUI types.AppEUI) bool {
+	return bytes.Compare(js.From.Bytes(), appEUI.Bytes()) <= 0 && bytes.Compare(appEUI.Bytes(), js.To.Bytes()) <= 0
+}
+
+func (h *handler) WithJoinServers(keks map[string][]byte, servers ...JoinServer) Handler {
+	h.joinServerKEKs = keks
+	h.joinServers = append(h.joinServers, servers...)
+	return h
+}
+
+// getJoinServer returns the external Join Server for the device, or nil if the Handler holds the AppKey
+func (h *handler) getJoinServer(dev *device.Device) *JoinServer {
+	if !dev.AppKey.IsEmpty() {
+		return nil
+	}
+	for i, js := range h.joinServers {
+		if js.Contains(dev.AppEUI) {
+			return &h.joinServers[i]
+		}
+	}
+	return nil
+}
+
+// joinWithJoinServer sends the join-request to the external Join Server and returns the (encrypted) join-accept and
+// the session keys from the JoinAns
+func (h *handler) joinWithJoinServer(js *JoinServer, dev *device.Device, activation *pb_broker.DeduplicatedDeviceActivationRequest, joinAccept *lorawan.JoinAcceptPayload) (payload []byte, appSKey types.AppSKey, nwkSKey types.NwkSKey, err error) {
+	dlSettings, err := joinAccept.DLSettings.MarshalBinary()
+	if err != nil {
+		return nil, appSKey, nwkSKey, err
+	}
+	req := &backend.JoinReqMessage{
+		MACVersion: "1.0.2",
+		PHYPayload: activation.Payload,
+		DevEUI:     activation.DevEUI,
+		DevAddr:    types.DevAddr(joinAccept.DevAddr),
+		DLSettings: dlSettings,
+		RxDelay:    int(joinAccept.RXDelay),
+	}
+	if joinAccept.CFList != nil {
+		if req.CFList, err = joinAccept.CFList.MarshalBinary(); err != nil {
+			return nil, appSKey, nwkSKey, err
+		}
+	}
+
+	client := &backend.Client{
+		SenderID:   fmt.Sprintf("%X", joinAccept.NetID[:]),
+		ReceiverID: dev.AppEUI.String(),
+		URL:        js.URL,
+		Token:      js.Token,
+	}
+	activation.Trace = activation.Trace.WithEvent(trace.ForwardEvent, "join server", js.URL)
+	ans := new(backend.JoinAnsMessage)
+	if err = client.Do(backend.JoinReq, req, ans); err != nil {
+		return nil, appSKey, nwkSKey, errors.Wrap(err, "Join Server did not handle join-request")
+	}
+	if ans.Result.ResultCode == backend.ResultMICFailed {
+		return nil, appSKey, nwkSKey, errors.NewErrNotFound("device that validates MIC")
+	}
+	if err = backend.CheckResult(ans.Result); err != nil {
+		return nil, appSKey, nwkSKey, errors.Wrap(err, "Join Server did not accept join-request")
+	}
+	if len(ans.PHYPayload) == 0 {
+		return nil, appSKey, nwkSKey, errors.NewErrInvalidArgument("JoinAns", "does not contain PHYPayload")
+	}
+
+	key, err := backend.UnwrapKeyEnvelope(h.joinServerKEKs, ans.AppSKey)
+	if err != nil {
+		return nil, appSKey, nwkSKey, errors.Wrap(err, "Could not unwrap AppSKey")
+	}
+	if len(key) != len(appSKey) {
+		return nil, appSKey, nwkSKey, errors.NewErrInvalidArgument("AppSKey", "invalid length")
+	}
+	copy(appSKey[:], key)
+	key, err = backend.UnwrapKeyEnvelope(h.joinServerKEKs, ans.NwkSKey)
+	if err != nil {
+		return nil, appSKey, nwkSKey, errors.Wrap(err, "Could not unwrap NwkSKey")
+	}
+	if len(key) != len(nwkSKey) {
+		return nil, appSKey, nwkSKey, errors.NewErrInvalidArgument("NwkSKey", "invalid length")
+	}
+	copy(nwkSKey[:], key)
+
+	return ans.PHYPayload, appSKey, nwkSKey, nil
+}
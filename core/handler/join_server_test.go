@@ -0,0 +1,191 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	pb_broker "github.com/TheThingsNetwork/api/broker"
+	pb_protocol "github.com/TheThingsNetwork/api/protocol"
+	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
+	"github.com/TheThingsNetwork/ttn/core/backend"
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	"github.com/brocaar/lorawan"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestParseJoinServer(t *testing.T) {
+	a := New(t)
+
+	js, err := ParseJoinServer("70B3D57ED0000000-70B3D57ED000FFFF=https://js.example.com")
+	a.So(err, ShouldBeNil)
+	a.So(js.URL, ShouldEqual, "https://js.example.com")
+	a.So(js.Contains(types.AppEUI{0x70, 0xB3, 0xD5, 0x7E, 0xD0, 0x00, 0x12, 0x34}), ShouldBeTrue)
+	a.So(js.Contains(types.AppEUI{0x70, 0xB3, 0xD5, 0x7E, 0xD0, 0x01, 0x00, 0x00}), ShouldBeFalse)
+
+	js, err = ParseJoinServer("70B3D57ED0000000=https://js.example.com")
+	a.So(err, ShouldBeNil)
+	a.So(js.From, ShouldEqual, js.To)
+
+	_, err = ParseJoinServer("70B3D57ED000FFFF-70B3D57ED0000000=https://js.example.com")
+	a.So(err, ShouldNotBeNil)
+
+	_, err = ParseJoinServer("70B3D57ED0000000")
+	a.So(err, ShouldNotBeNil)
+}
+
+func buildJoinServerTestActivation(appEUI types.AppEUI, devEUI types.DevEUI, appKey types.AppKey, devNonce [2]byte) *pb_broker.DeduplicatedDeviceActivationRequest {
+	devAddr := types.DevAddr{0x26, 0x01, 0x02, 0x03}
+	req := &pb_broker.DeduplicatedDeviceActivationRequest{
+		AppID:  "app",
+		DevID:  "dev",
+		AppEUI: appEUI,
+		DevEUI: devEUI,
+		ActivationMetadata: &pb_protocol.ActivationMetadata{Protocol: &pb_protocol.ActivationMetadata_LoRaWAN{LoRaWAN: &pb_lorawan.ActivationMetadata{
+			AppEUI:  appEUI,
+			DevEUI:  devEUI,
+			DevAddr: &devAddr,
+		}}},
+	}
+
+	req.ResponseTemplate = new(pb_broker.DeviceActivationResponse)
+	req.ResponseTemplate.Message = new(pb_protocol.Message)
+	res := req.ResponseTemplate.Message.InitLoRaWAN()
+	res.MType = pb_lorawan.MType_JOIN_ACCEPT
+	res.Payload = &pb_lorawan.Message_JoinAcceptPayload{JoinAcceptPayload: &pb_lorawan.JoinAcceptPayload{
+		NetID:   types.NetID{0x00, 0x00, 0x13},
+		DevAddr: devAddr,
+	}}
+	req.ResponseTemplate.Payload = res.PHYPayloadBytes()
+	req.ResponseTemplate.DownlinkOption = new(pb_broker.DownlinkOption)
+
+	req.Message = new(pb_protocol.Message)
+	msg := req.Message.InitLoRaWAN()
+	msg.MType = pb_lorawan.MType_JOIN_REQUEST
+	msg.Payload = &pb_lorawan.Message_JoinRequestPayload{JoinRequestPayload: &pb_lorawan.JoinRequestPayload{
+		AppEUI:   appEUI,
+		DevEUI:   devEUI,
+		DevNonce: types.DevNonce(devNonce),
+	}}
+	phy := msg.PHYPayload()
+	phy.SetMIC(lorawan.AES128Key(appKey))
+	req.Payload, _ = phy.MarshalBinary()
+
+	return req
+}
+
+func TestJoinWithJoinServer(t *testing.T) {
+	a := New(t)
+
+	appEUI, devEUI := types.AppEUI{0x70, 0xB3, 0xD5, 0x7E, 0xD0, 0x00, 0x00, 0x01}, types.DevEUI{1, 2, 3, 4, 5, 6, 7, 8}
+	appKey := types.AppKey{1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8}
+	kek := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+	mock := backend.NewMockJoinServer()
+	mock.KEKLabel, mock.KEK = "ttn", kek
+	mock.SetAppKey(devEUI, appKey)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	h := &handler{}
+	h.WithJoinServers(map[string][]byte{"ttn": kek}, JoinServer{From: appEUI, To: appEUI, URL: server.URL})
+
+	dev := &device.Device{AppID: "app", DevID: "dev", AppEUI: appEUI, DevEUI: devEUI}
+	js := h.getJoinServer(dev)
+	a.So(js, ShouldNotBeNil)
+
+	req := buildJoinServerTestActivation(appEUI, devEUI, appKey, [2]byte{1, 2})
+	joinAccept := &lorawan.JoinAcceptPayload{NetID: lorawan.NetID{0x00, 0x00, 0x13}, DevAddr: lorawan.DevAddr{0x26, 0x01, 0x02, 0x03}}
+	payload, appSKey, nwkSKey, err := h.joinWithJoinServer(js, dev, req, joinAccept)
+	a.So(err, ShouldBeNil)
+	a.So(mock.Joins(), ShouldEqual, 1)
+	a.So(appSKey.IsEmpty(), ShouldBeFalse)
+	a.So(nwkSKey.IsEmpty(), ShouldBeFalse)
+
+	// The device can decrypt the join-accept with its AppKey
+	var resPHY lorawan.PHYPayload
+	a.So(resPHY.UnmarshalBinary(payload), ShouldBeNil)
+	a.So(resPHY.DecryptJoinAcceptPayload(lorawan.AES128Key(appKey)), ShouldBeNil)
+	ok, err := resPHY.ValidateMIC(lorawan.AES128Key(appKey))
+	a.So(err, ShouldBeNil)
+	a.So(ok, ShouldBeTrue)
+	a.So(resPHY.MACPayload.(*lorawan.JoinAcceptPayload).DevAddr, ShouldEqual, joinAccept.DevAddr)
+
+	// Unknown KEK
+	h.joinServerKEKs = nil
+	_, _, _, err = h.joinWithJoinServer(js, dev, req, joinAccept)
+	a.So(err, ShouldNotBeNil)
+
+	// Unknown device
+	dev.DevEUI = types.DevEUI{8, 7, 6, 5, 4, 3, 2, 1}
+	req.DevEUI = dev.DevEUI
+	_, _, _, err = h.joinWithJoinServer(js, dev, req, joinAccept)
+	a.So(err, ShouldNotBeNil)
+
+	// Device with AppKey in the Handler
+	dev.AppKey = appKey
+	a.So(h.getJoinServer(dev), ShouldBeNil)
+}
+
+func TestHandleActivationWithJoinServer(t *testing.T) {
+	a := New(t)
+
+	appEUI, devEUI := types.AppEUI{0x70, 0xB3, 0xD5, 0x7E, 0xD0, 0x00, 0x00, 0x02}, types.DevEUI{1, 2, 3, 4, 5, 6, 7, 9}
+	appKey := types.AppKey{1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8}
+
+	mock := backend.NewMockJoinServer()
+	mock.SetAppKey(devEUI, appKey)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	h := &handler{
+		Component:    &component.Component{Ctx: GetLogger(t, "TestHandleActivationWithJoinServer")},
+		applications: application.NewRedisApplicationStore(GetRedisClient(), "handler-test-activation-join-server"),
+		devices:      device.NewRedisDeviceStore(GetRedisClient(), "handler-test-activation-join-server"),
+		qEvent:       make(chan *types.DeviceEvent, 10),
+	}
+	h.InitStatus()
+	h.WithJoinServers(nil, JoinServer{From: appEUI, To: appEUI, URL: server.URL})
+
+	dev := &device.Device{AppID: "app", DevID: "dev", AppEUI: appEUI, DevEUI: devEUI}
+	h.devices.Set(dev)
+	defer func() { h.devices.Delete("app", "dev") }()
+
+	// The challenge is delegated to the Join Server
+	res, err := h.HandleActivationChallenge(&pb_broker.ActivationChallengeRequest{AppID: "app", DevID: "dev"})
+	a.So(err, ShouldBeNil)
+	a.So(res.Payload, ShouldBeEmpty)
+
+	req := buildJoinServerTestActivation(appEUI, devEUI, appKey, [2]byte{1, 2})
+	activation, err := h.HandleActivation(req)
+	a.So(err, ShouldBeNil)
+	a.So(activation.Payload, ShouldNotBeEmpty)
+	a.So(mock.Joins(), ShouldEqual, 1)
+
+	dev, _ = h.devices.Get("app", "dev")
+	a.So(dev.AppSKey.IsEmpty(), ShouldBeFalse)
+	a.So(dev.UsedDevNonces, ShouldHaveLength, 1)
+	a.So(h.qEvent, ShouldHaveLength, 1)
+	a.So((<-h.qEvent).Event, ShouldEqual, types.ActivationEvent)
+
+	// DevNonce Re-use
+	_, err = h.HandleActivation(buildJoinServerTestActivation(appEUI, devEUI, appKey, [2]byte{1, 2}))
+	a.So(err, ShouldNotBeNil)
+
+	// Wrong MIC is rejected by the Join Server
+	_, err = h.HandleActivation(buildJoinServerTestActivation(appEUI, devEUI, types.AppKey{}, [2]byte{1, 3}))
+	a.So(err, ShouldHaveSameTypeAs, &errors.ErrNotFound{})
+	a.So(mock.Joins(), ShouldEqual, 1)
+
+	// Rejected join-requests only publish activation errors
+	a.So(h.qEvent, ShouldHaveLength, 2)
+	a.So((<-h.qEvent).Event, ShouldEqual, types.ActivationErrorEvent)
+	a.So((<-h.qEvent).Event, ShouldEqual, types.ActivationErrorEvent)
+}
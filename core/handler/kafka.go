@@ -0,0 +1,109 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/kafka"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// KafkaBufferSize indicates the size for uplink channel buffers
+var KafkaBufferSize = 10
+
+func (h *handler) HandleKafka(brokers []string, topics kafka.Topics) error {
+	h.kafkaClient = kafka.NewClient(h.Ctx, brokers...)
+
+	err := h.kafkaClient.Connect()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			h.kafkaClient.Disconnect()
+		}
+	}()
+
+	h.kafkaUp = make(chan *types.UplinkMessage, KafkaBufferSize)
+	h.kafkaEvent = make(chan *types.DeviceEvent, KafkaBufferSize)
+
+	ctx := h.Ctx.WithField("Protocol", "Kafka")
+
+	if topics.Downlink != "" {
+		subscriber := h.kafkaClient.NewSubscriber(topics.Downlink, topics.DownlinkGroup)
+		err = subscriber.Open()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				subscriber.Close()
+			}
+		}()
+		err = subscriber.ConsumeDownlink(func(_ kafka.Subscriber, appID, devID string, req types.DownlinkMessage) error {
+			err := h.EnqueueDownlink(&req)
+			switch {
+			case err == nil:
+				return nil
+			case errors.IsNotFound(err), errors.IsInvalidArgument(err):
+				// Downlinks that can never be enqueued are dropped instead of retried
+				ctx.WithFields(ttnlog.Fields{
+					"AppID": appID,
+					"DevID": devID,
+				}).WithError(err).Warn("Could not enqueue Downlink")
+				return nil
+			default:
+				return err
+			}
+		})
+		if err != nil {
+			return err
+		}
+		h.kafkaSubscriber = subscriber
+	}
+
+	publisher := h.kafkaClient.NewPublisher(topics)
+	err = publisher.Open()
+	if err != nil {
+		ctx.WithError(err).Error("Could not open publisher")
+		return err
+	}
+	h.kafkaPublisher = publisher
+
+	go func() {
+		for up := range h.kafkaUp {
+			ctx := ctx.WithFields(ttnlog.Fields{
+				"DevID": up.DevID,
+				"AppID": up.AppID,
+			})
+			ctx.Debug("Publish Uplink")
+			if err := publisher.PublishUplink(*up); err != nil {
+				ctx.WithError(err).Warn("Could not publish Uplink")
+			}
+		}
+	}()
+
+	go func() {
+		for event := range h.kafkaEvent {
+			ctx := ctx.WithFields(ttnlog.Fields{
+				"DevID": event.DevID,
+				"AppID": event.AppID,
+				"Event": event.Event,
+			})
+			ctx.Debug("Publish Event")
+			if event.DevID == "" {
+				if err := publisher.PublishAppEvent(event.AppID, event.Event, event.Data); err != nil {
+					ctx.WithError(err).Warn("Could not publish App Event")
+				}
+			} else {
+				if err := publisher.PublishDeviceEvent(event.AppID, event.DevID, event.Event, event.Data); err != nil {
+					ctx.WithError(err).Warn("Could not publish Device Event")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/kafka"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestHandleKafka(t *testing.T) {
+	a := New(t)
+
+	topics := kafka.DefaultTopics
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	metadata := sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+	for _, topic := range []string{topics.Uplink, topics.Activations, topics.Events, topics.Downlink} {
+		metadata.SetLeader(topic, 0, broker.BrokerID())
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": metadata,
+		"ProduceRequest":  sarama.NewMockProduceResponse(t).SetVersion(2),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).SetVersion(1).
+			SetOffset(topics.Downlink, 0, sarama.OffsetNewest, 0).
+			SetOffset(topics.Downlink, 0, sarama.OffsetOldest, 0),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 1).SetVersion(3).
+			SetMessage(topics.Downlink, 0, 0, sarama.StringEncoder(`{"app_id":"handler-kafka-app1","dev_id":"handler-kafka-dev1","payload_raw":"qrw="}`)).
+			SetHighWaterMark(topics.Downlink, 0, 1),
+		// The handler is the only member of the consumer group of the downlink topic
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, topics.DownlinkGroup, broker),
+		"JoinGroupRequest": sarama.NewMockWrapper(&sarama.JoinGroupResponse{
+			GenerationId: 1, GroupProtocol: "range", LeaderId: "leader", MemberId: "member",
+		}),
+		"SyncGroupRequest": sarama.NewMockWrapper(&sarama.SyncGroupResponse{
+			// version 0, 1 topic: the downlink topic with 1 partition: 0, no user data
+			MemberAssignment: append(append([]byte{0, 0, 0, 0, 0, 1, 0, byte(len(topics.Downlink))}, topics.Downlink...), 0, 0, 0, 1, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff),
+		}),
+		"HeartbeatRequest":  sarama.NewMockWrapper(&sarama.HeartbeatResponse{}),
+		"LeaveGroupRequest": sarama.NewMockWrapper(&sarama.LeaveGroupResponse{}),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(topics.DownlinkGroup, topics.Downlink, 0, sarama.OffsetNewest, "", sarama.ErrNoError),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	})
+
+	appID := "handler-kafka-app1"
+	devID := "handler-kafka-dev1"
+	h := &handler{
+		Component: &component.Component{Ctx: GetLogger(t, "TestHandleKafka")},
+		devices:   device.NewRedisDeviceStore(GetRedisClient(), "handler-test-handle-kafka"),
+	}
+	h.WithKafka([]string{broker.Addr()}, topics)
+	h.devices.Set(&device.Device{
+		AppID: appID,
+		DevID: devID,
+	})
+	defer func() {
+		h.devices.Delete(appID, devID)
+	}()
+	err := h.HandleKafka(h.kafkaBrokers, h.kafkaTopics)
+	a.So(err, ShouldBeNil)
+
+	<-time.After(100 * time.Millisecond)
+	q, _ := h.devices.DownlinkQueue(appID, devID)
+	downlink, _ := q.Next()
+	a.So(downlink, ShouldNotBeNil)
+	a.So(downlink.PayloadRaw, ShouldResemble, []byte{0xAA, 0xBC})
+
+	produced := func() (n int) {
+		for _, rr := range broker.History() {
+			if _, ok := rr.Request.(*sarama.ProduceRequest); ok {
+				n++
+			}
+		}
+		return
+	}
+
+	h.kafkaUp <- &types.UplinkMessage{AppID: appID, DevID: devID, PayloadRaw: []byte{0xAA, 0xBC}}
+	h.kafkaEvent <- &types.DeviceEvent{AppID: appID, DevID: devID, Event: types.ActivationEvent}
+	<-time.After(100 * time.Millisecond)
+	a.So(produced(), ShouldEqual, 2)
+
+	// Messages that are published after the shutdown are dropped
+	h.Shutdown()
+	a.So(h.kafkaClient.IsConnected(), ShouldBeFalse)
+	h.kafkaUp <- &types.UplinkMessage{AppID: appID, DevID: devID, PayloadRaw: []byte{0xAA, 0xBC}}
+	<-time.After(100 * time.Millisecond)
+	a.So(produced(), ShouldEqual, 2)
+}
@@ -19,7 +19,12 @@ import (
 	ttnlog "github.com/TheThingsNetwork/go-utils/log"
 	"github.com/TheThingsNetwork/ttn/api/ratelimit"
 	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/binarystruct"
+	"github.com/TheThingsNetwork/ttn/core/handler/clocksync"
 	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/handler/fuota"
+	"github.com/TheThingsNetwork/ttn/core/handler/multicast"
+	"github.com/TheThingsNetwork/ttn/core/handler/timeseries"
 	"github.com/TheThingsNetwork/ttn/core/storage"
 	"github.com/TheThingsNetwork/ttn/core/types"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
@@ -508,7 +513,13 @@ func (h *handlerManager) SetApplication(ctx context.Context, in *pb_handler.Appl
 		app.PayloadFormat = application.PayloadFormatCustom
 	}
 
-	err = h.handler.applications.Set(app)
+	if app.PayloadFormat == application.PayloadFormatStruct {
+		if _, err := binarystruct.Parse(app.CustomDecoder); err != nil {
+			return nil, err
+		}
+	}
+
+	err = h.handler.setApplication(app, author(claims))
 	if err != nil {
 		return nil, err
 	}
@@ -557,6 +568,13 @@ func (h *handlerManager) DeleteApplication(ctx context.Context, in *pb_handler.A
 		return nil, err
 	}
 
+	if h.handler.revisions != nil {
+		err = h.handler.revisions.DeleteAll(in.AppID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err = h.handler.Discovery.RemoveAppID(in.AppID, token)
 	if err != nil {
 		h.handler.Ctx.WithField("AppID", in.AppID).WithError(errors.FromGRPCError(err)).Warn("Could not unregister Application from Discovery")
@@ -610,4 +628,10 @@ func (h *handler) RegisterManager(s *grpc.Server) {
 	pb_handler.RegisterHandlerManagerServer(s, server)
 	pb_handler.RegisterApplicationManagerServer(s, server)
 	pb_lorawan.RegisterDevAddrManagerServer(s, server)
+	timeseries.RegisterUplinkStorageManagerServer(s, server)
+	device.RegisterDownlinkQueueManagerServer(s, server)
+	multicast.RegisterMulticastManagerServer(s, server)
+	fuota.RegisterFUOTAManagerServer(s, server)
+	clocksync.RegisterClockSyncManagerServer(s, server)
+	application.RegisterPayloadFormatterManagerServer(s, server)
 }
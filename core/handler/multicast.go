@@ -0,0 +1,251 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"time"
+
+	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
+	"github.com/TheThingsNetwork/go-account-lib/rights"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/multicast"
+	ns_multicast "github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+)
+
+func (h *handler) EnqueueMulticastDownlink(appDownlink *types.MulticastDownlinkMessage) (res *multicast.DownlinkResponse, err error) {
+	ctx := h.Ctx.WithFields(ttnlog.Fields{
+		"AppID":   appDownlink.AppID,
+		"GroupID": appDownlink.GroupID,
+	})
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			ctx.WithError(err).Warn("Could not send multicast downlink")
+		} else {
+			ctx.WithField("Duration", time.Now().Sub(start)).Info("Sent multicast downlink")
+		}
+	}()
+
+	if len(appDownlink.PayloadRaw) == 0 {
+		return nil, errors.NewErrInvalidArgument("Downlink Payload", "empty")
+	}
+	if appDownlink.FPort == 0 || appDownlink.FPort > 223 {
+		return nil, errors.NewErrInvalidArgument("Downlink FPort", "must be between 1 and 223")
+	}
+
+	group, err := h.groups.Get(appDownlink.AppID, appDownlink.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	group.StartUpdate()
+	fCnt := group.FCnt
+
+	// The payload is encrypted once for all devices in the group, the NetworkServer sets the MIC
+	phyPayload := pb_lorawan.Message{
+		MHDR: pb_lorawan.MHDR{MType: pb_lorawan.MType_UNCONFIRMED_DOWN, Major: pb_lorawan.Major_LORAWAN_R1},
+		Payload: &pb_lorawan.Message_MACPayload{MACPayload: &pb_lorawan.MACPayload{
+			FHDR:       pb_lorawan.FHDR{DevAddr: group.McAddr, FCnt: fCnt},
+			FPort:      int32(appDownlink.FPort),
+			FRMPayload: appDownlink.PayloadRaw,
+		}},
+	}
+	if err = phyPayload.EncryptFRMPayload(group.McAppSKey); err != nil {
+		return nil, err
+	}
+
+	// The FCnt is used up even if the downlink fails, so that it is never used twice
+	group.FCnt++
+	if err = h.groups.Set(group, "FCnt"); err != nil {
+		return nil, err
+	}
+
+	downlink, err := h.ttnMulticast.Downlink(h.GetContext(""), &ns_multicast.DownlinkMessage{
+		AppID:   group.AppID,
+		GroupID: group.GroupID,
+		Payload: phyPayload.PHYPayloadBytes(),
+		FCnt:    fCnt,
+	})
+	if err != nil {
+		return nil, errors.Wrap(errors.FromGRPCError(err), "Broker did not send multicast downlink")
+	}
+
+	h.status.downlink.Mark(1)
+
+	return &multicast.DownlinkResponse{FCnt: fCnt, Gateways: downlink.Gateways}, nil
+}
+
+func (h *handlerManager) GetGroup(ctx context.Context, in *multicast.GroupIdentifier) (*multicast.GroupSettings, error) {
+	if in.AppID == "" || in.GroupID == "" {
+		return nil, errors.NewErrInvalidArgument("Group Identifier", "must contain AppID and GroupID")
+	}
+	ctx, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	token, _ := ttnctx.TokenFromIncomingContext(ctx)
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+
+	group, err := h.handler.groups.Get(in.AppID, in.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	nsGroup, err := h.handler.ttnMulticastManager.GetGroup(ttnctx.OutgoingContextWithToken(ctx, token), &ns_multicast.GroupIdentifier{
+		AppID:   in.AppID,
+		GroupID: in.GroupID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(errors.FromGRPCError(err), "Broker did not return group")
+	}
+
+	return &multicast.GroupSettings{
+		AppID:     group.AppID,
+		GroupID:   group.GroupID,
+		McAddr:    group.McAddr,
+		McNwkSKey: nsGroup.McNwkSKey,
+		McAppSKey: group.McAppSKey,
+		FCnt:      group.FCnt,
+		Frequency: nsGroup.Frequency,
+		DataRate:  nsGroup.DataRate,
+		Power:     nsGroup.Power,
+		Gateways:  nsGroup.Gateways,
+	}, nil
+}
+
+func (h *handlerManager) ListGroups(ctx context.Context, in *multicast.ApplicationIdentifier) (*multicast.GroupList, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+
+	groups, err := h.handler.groups.List(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	res := &multicast.GroupList{Groups: make([]*multicast.GroupSettings, 0, len(groups))}
+	for _, group := range groups {
+		res.Groups = append(res.Groups, &multicast.GroupSettings{
+			AppID:   group.AppID,
+			GroupID: group.GroupID,
+			McAddr:  group.McAddr,
+			FCnt:    group.FCnt,
+		})
+	}
+	return res, nil
+}
+
+func (h *handlerManager) SetGroup(ctx context.Context, in *multicast.GroupSettings) (*multicast.Empty, error) {
+	nsGroup := &ns_multicast.Group{
+		AppID:     in.AppID,
+		GroupID:   in.GroupID,
+		McAddr:    in.McAddr,
+		McNwkSKey: in.McNwkSKey,
+		FCntDown:  in.FCnt,
+		Frequency: in.Frequency,
+		DataRate:  in.DataRate,
+		Power:     in.Power,
+		Gateways:  in.Gateways,
+	}
+	if err := nsGroup.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Invalid Group")
+	}
+	if in.McAppSKey.IsEmpty() {
+		return nil, errors.Wrap(errors.NewErrInvalidArgument("McAppSKey", "can not be empty"), "Invalid Group")
+	}
+
+	ctx, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	token, _ := ttnctx.TokenFromIncomingContext(ctx)
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+
+	if _, err := h.handler.applications.Get(in.AppID); err != nil {
+		return nil, errors.Wrap(err, "Application not registered to this Handler")
+	}
+
+	if err := h.handler.setGroup(ttnctx.OutgoingContextWithToken(ctx, token), nsGroup, in.McAppSKey); err != nil {
+		return nil, err
+	}
+	return &multicast.Empty{}, nil
+}
+
+// setGroup sets the network session and transmission settings of a multicast group on the NetworkServer and stores
+// the application session of the group
+func (h *handler) setGroup(ctx context.Context, nsGroup *ns_multicast.Group, mcAppSKey types.AppSKey) error {
+	group, err := h.groups.Get(nsGroup.AppID, nsGroup.GroupID)
+	if err != nil && errors.GetErrType(err) != errors.NotFound {
+		return err
+	}
+	if group == nil {
+		group = &multicast.Group{AppID: nsGroup.AppID, GroupID: nsGroup.GroupID}
+	} else {
+		group.StartUpdate()
+	}
+	group.McAddr = nsGroup.McAddr
+	group.McAppSKey = mcAppSKey
+	group.FCnt = nsGroup.FCntDown
+
+	if _, err = h.ttnMulticastManager.SetGroup(ctx, nsGroup); err != nil {
+		return errors.Wrap(errors.FromGRPCError(err), "Broker did not set group")
+	}
+
+	return h.groups.Set(group)
+}
+
+func (h *handlerManager) DeleteGroup(ctx context.Context, in *multicast.GroupIdentifier) (*multicast.Empty, error) {
+	if in.AppID == "" || in.GroupID == "" {
+		return nil, errors.NewErrInvalidArgument("Group Identifier", "must contain AppID and GroupID")
+	}
+	ctx, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	token, _ := ttnctx.TokenFromIncomingContext(ctx)
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+
+	if _, err := h.handler.groups.Get(in.AppID, in.GroupID); err != nil {
+		return nil, err
+	}
+	_, err = h.handler.ttnMulticastManager.DeleteGroup(ttnctx.OutgoingContextWithToken(ctx, token), &ns_multicast.GroupIdentifier{
+		AppID:   in.AppID,
+		GroupID: in.GroupID,
+	})
+	if err != nil && errors.GetErrType(errors.FromGRPCError(err)) != errors.NotFound {
+		return nil, errors.Wrap(errors.FromGRPCError(err), "Broker did not delete group")
+	}
+	if err := h.handler.groups.Delete(in.AppID, in.GroupID); err != nil {
+		return nil, err
+	}
+	return &multicast.Empty{}, nil
+}
+
+func (h *handlerManager) SendDownlink(ctx context.Context, in *types.MulticastDownlinkMessage) (*multicast.DownlinkResponse, error) {
+	if in.AppID == "" || in.GroupID == "" {
+		return nil, errors.NewErrInvalidArgument("Multicast Downlink", "must contain AppID and GroupID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.WriteDownlink); err != nil {
+		return nil, err
+	}
+	return h.handler.EnqueueMulticastDownlink(in)
+}
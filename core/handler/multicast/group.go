@@ -0,0 +1,51 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package multicast contains the multicast groups of the Handler
+package multicast
+
+import (
+	"time"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// Group is a multicast group. The Handler keeps the application session of the group, the network session and the
+// transmission settings are kept by the NetworkServer.
+type Group struct {
+	old *Group
+
+	AppID     string        `redis:"app_id"`
+	GroupID   string        `redis:"group_id"`
+	McAddr    types.DevAddr `redis:"mc_addr"`
+	McAppSKey types.AppSKey `redis:"mc_app_s_key"`
+	FCnt      uint32        `redis:"f_cnt"` // the FCnt of the next downlink
+
+	CreatedAt time.Time `redis:"created_at"`
+	UpdatedAt time.Time `redis:"updated_at"`
+}
+
+// Validate the group
+func (g *Group) Validate() error {
+	if err := api.NotEmptyAndValidID(g.AppID, "Application ID"); err != nil {
+		return err
+	}
+	if err := api.NotEmptyAndValidID(g.GroupID, "Group ID"); err != nil {
+		return err
+	}
+	if g.McAddr.IsEmpty() {
+		return errors.NewErrInvalidArgument("McAddr", "can not be empty")
+	}
+	if g.McAppSKey.IsEmpty() {
+		return errors.NewErrInvalidArgument("McAppSKey", "can not be empty")
+	}
+	return nil
+}
+
+// StartUpdate stores the state of the group
+func (g *Group) StartUpdate() {
+	old := *g
+	g.old = &old
+}
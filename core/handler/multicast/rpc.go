@@ -0,0 +1,125 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package multicast
+
+import (
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/jsoncodec"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+// ApplicationIdentifier identifies an application
+type ApplicationIdentifier struct {
+	AppID string `json:"app_id"`
+}
+
+// GroupIdentifier identifies a multicast group
+type GroupIdentifier struct {
+	AppID   string `json:"app_id"`
+	GroupID string `json:"group_id"`
+}
+
+// GroupSettings contains the application session, network session and transmission settings of a multicast group
+type GroupSettings struct {
+	AppID     string        `json:"app_id"`
+	GroupID   string        `json:"group_id"`
+	McAddr    types.DevAddr `json:"mc_addr"`
+	McNwkSKey types.NwkSKey `json:"mc_nwk_s_key,omitempty"`
+	McAppSKey types.AppSKey `json:"mc_app_s_key,omitempty"`
+	FCnt      uint32        `json:"f_cnt"`
+
+	Frequency uint64   `json:"frequency,omitempty"` // in Hz
+	DataRate  string   `json:"data_rate,omitempty"` // for example SF12BW125
+	Power     int32    `json:"power,omitempty"`     // in dBm
+	Gateways  []string `json:"gateways,omitempty"`  // the IDs of the Gateways that send the downlinks
+}
+
+// GroupList contains the multicast groups of an application, without keys and transmission settings
+type GroupList struct {
+	Groups []*GroupSettings `json:"groups"`
+}
+
+// DownlinkResponse is the response to a multicast downlink
+type DownlinkResponse struct {
+	FCnt     uint32   `json:"f_cnt"`
+	Gateways []string `json:"gateways"` // the IDs of the Gateways that the downlink was sent to
+}
+
+// Empty is the response of requests that do not return anything
+type Empty struct{}
+
+// MulticastManagerServer is the server API for the MulticastManager service
+type MulticastManagerServer interface {
+	GetGroup(context.Context, *GroupIdentifier) (*GroupSettings, error)
+	ListGroups(context.Context, *ApplicationIdentifier) (*GroupList, error)
+	SetGroup(context.Context, *GroupSettings) (*Empty, error)
+	DeleteGroup(context.Context, *GroupIdentifier) (*Empty, error)
+	SendDownlink(context.Context, *types.MulticastDownlinkMessage) (*DownlinkResponse, error)
+}
+
+// MulticastManagerClient is the client API for the MulticastManager service
+type MulticastManagerClient interface {
+	GetGroup(ctx context.Context, in *GroupIdentifier, opts ...grpc.CallOption) (*GroupSettings, error)
+	ListGroups(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*GroupList, error)
+	SetGroup(ctx context.Context, in *GroupSettings, opts ...grpc.CallOption) (*Empty, error)
+	DeleteGroup(ctx context.Context, in *GroupIdentifier, opts ...grpc.CallOption) (*Empty, error)
+	SendDownlink(ctx context.Context, in *types.MulticastDownlinkMessage, opts ...grpc.CallOption) (*DownlinkResponse, error)
+}
+
+type multicastManagerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewMulticastManagerClient returns a new MulticastManagerClient
+func NewMulticastManagerClient(cc *grpc.ClientConn) MulticastManagerClient {
+	return &multicastManagerClient{cc}
+}
+
+func (c *multicastManagerClient) GetGroup(ctx context.Context, in *GroupIdentifier, opts ...grpc.CallOption) (*GroupSettings, error) {
+	out := new(GroupSettings)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.MulticastManager/GetGroup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *multicastManagerClient) ListGroups(ctx context.Context, in *ApplicationIdentifier, opts ...grpc.CallOption) (*GroupList, error) {
+	out := new(GroupList)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.MulticastManager/ListGroups", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *multicastManagerClient) SetGroup(ctx context.Context, in *GroupSettings, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.MulticastManager/SetGroup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *multicastManagerClient) DeleteGroup(ctx context.Context, in *GroupIdentifier, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.MulticastManager/DeleteGroup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *multicastManagerClient) SendDownlink(ctx context.Context, in *types.MulticastDownlinkMessage, opts ...grpc.CallOption) (*DownlinkResponse, error) {
+	out := new(DownlinkResponse)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.MulticastManager/SendDownlink", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var multicastManagerServiceDesc = jsoncodec.ServiceDesc("handler.MulticastManager", (*MulticastManagerServer)(nil))
+
+// RegisterMulticastManagerServer registers the MulticastManager service
+func RegisterMulticastManagerServer(s *grpc.Server, srv MulticastManagerServer) {
+	s.RegisterService(multicastManagerServiceDesc, srv)
+}
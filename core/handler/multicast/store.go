@@ -0,0 +1,80 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package multicast
+
+import (
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/multicast"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"gopkg.in/redis.v5"
+)
+
+// Store interface for multicast Groups
+type Store interface {
+	List(appID string) ([]*Group, error)
+	Get(appID, groupID string) (*Group, error)
+	Set(new *Group, properties ...string) (err error)
+	Delete(appID, groupID string) error
+}
+
+const defaultRedisPrefix = "handler"
+
+// NewRedisGroupStore creates a new Redis-based multicast Group store
+// if an empty prefix is passed, a default prefix will be used.
+func NewRedisGroupStore(client *redis.Client, prefix string) Store {
+	if prefix == "" {
+		prefix = defaultRedisPrefix
+	}
+	return &RedisGroupStore{
+		store: multicast.NewRedisGroupStore(client, prefix, Group{}),
+	}
+}
+
+// RedisGroupStore stores multicast Groups in Redis, see multicast.RedisGroupStore
+type RedisGroupStore struct {
+	store *multicast.RedisGroupStore
+}
+
+// List the Groups of an Application
+func (s *RedisGroupStore) List(appID string) ([]*Group, error) {
+	groupsI, err := s.store.List(appID)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*Group, 0, len(groupsI))
+	for _, groupI := range groupsI {
+		if group, ok := groupI.(Group); ok {
+			groups = append(groups, &group)
+		}
+	}
+	return groups, nil
+}
+
+// Get a specific Group
+func (s *RedisGroupStore) Get(appID, groupID string) (*Group, error) {
+	groupI, err := s.store.Get(appID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group, ok := groupI.(Group); ok {
+		return &group, nil
+	}
+	return nil, errors.New("Database did not return a Group")
+}
+
+// Set a new Group or update an existing one
+func (s *RedisGroupStore) Set(new *Group, properties ...string) (err error) {
+	now := time.Now()
+	new.UpdatedAt = now
+	if new.old == nil {
+		new.CreatedAt = now
+	}
+	return s.store.Set(new.AppID, new.GroupID, *new, properties...)
+}
+
+// Delete a Group
+func (s *RedisGroupStore) Delete(appID, groupID string) error {
+	return s.store.Delete(appID, groupID)
+}
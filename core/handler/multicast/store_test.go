@@ -0,0 +1,58 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package multicast
+
+import (
+	"testing"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestGroupStore(t *testing.T) {
+	a := New(t)
+
+	s := NewRedisGroupStore(GetRedisClient(), "handler-test-multicast-store")
+
+	_, err := s.Get("test", "lights")
+	a.So(err, ShouldNotBeNil)
+
+	err = s.Set(&Group{
+		AppID:     "test",
+		GroupID:   "lights",
+		McAddr:    types.DevAddr{1, 2, 3, 4},
+		McAppSKey: types.AppSKey{1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8},
+		FCnt:      42,
+	})
+	a.So(err, ShouldBeNil)
+	defer s.Delete("test", "lights")
+
+	group, err := s.Get("test", "lights")
+	a.So(err, ShouldBeNil)
+	a.So(group.McAddr, ShouldEqual, types.DevAddr{1, 2, 3, 4})
+	a.So(group.FCnt, ShouldEqual, 42)
+	a.So(group.CreatedAt.IsZero(), ShouldBeFalse)
+
+	group.StartUpdate()
+	group.FCnt++
+	err = s.Set(group, "FCnt")
+	a.So(err, ShouldBeNil)
+
+	group, err = s.Get("test", "lights")
+	a.So(err, ShouldBeNil)
+	a.So(group.FCnt, ShouldEqual, 43)
+	a.So(group.McAppSKey, ShouldEqual, types.AppSKey{1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8})
+
+	groups, err := s.List("test")
+	a.So(err, ShouldBeNil)
+	a.So(groups, ShouldHaveLength, 1)
+
+	err = s.Delete("test", "lights")
+	a.So(err, ShouldBeNil)
+
+	groups, err = s.List("test")
+	a.So(err, ShouldBeNil)
+	a.So(groups, ShouldBeEmpty)
+}
@@ -0,0 +1,77 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"testing"
+
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/handler/multicast"
+	ns_multicast "github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	"github.com/brocaar/lorawan"
+	. "github.com/smartystreets/assertions"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+type mockMulticast struct {
+	downlink *ns_multicast.DownlinkMessage
+}
+
+func (m *mockMulticast) Downlink(ctx context.Context, in *ns_multicast.DownlinkMessage, opts ...grpc.CallOption) (*ns_multicast.DownlinkMessage, error) {
+	m.downlink = in
+	out := *in
+	out.Gateways = []string{"gtw-1"}
+	return &out, nil
+}
+
+func TestEnqueueMulticastDownlink(t *testing.T) {
+	a := New(t)
+
+	ns := &mockMulticast{}
+	h := &handler{
+		Component:    &component.Component{Ctx: GetLogger(t, "TestEnqueueMulticastDownlink")},
+		groups:       multicast.NewRedisGroupStore(GetRedisClient(), "handler-test-enqueue-multicast-downlink"),
+		ttnMulticast: ns,
+	}
+	h.InitStatus()
+
+	appSKey := types.AppSKey{1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8}
+	msg := &types.MulticastDownlinkMessage{AppID: "app1", GroupID: "lights", FPort: 10, PayloadRaw: []byte{0xaa, 0xbb}}
+
+	_, err := h.EnqueueMulticastDownlink(msg)
+	a.So(err, ShouldNotBeNil)
+
+	h.groups.Set(&multicast.Group{
+		AppID:     "app1",
+		GroupID:   "lights",
+		McAddr:    types.DevAddr{1, 2, 3, 4},
+		McAppSKey: appSKey,
+		FCnt:      0x10001,
+	})
+	defer h.groups.Delete("app1", "lights")
+
+	res, err := h.EnqueueMulticastDownlink(&types.MulticastDownlinkMessage{AppID: "app1", GroupID: "lights", PayloadRaw: []byte{0xaa}})
+	a.So(err, ShouldNotBeNil) // no FPort
+
+	res, err = h.EnqueueMulticastDownlink(msg)
+	a.So(err, ShouldBeNil)
+	a.So(res.FCnt, ShouldEqual, 0x10001)
+	a.So(res.Gateways, ShouldResemble, []string{"gtw-1"})
+	a.So(ns.downlink.FCnt, ShouldEqual, 0x10001)
+
+	var phy lorawan.PHYPayload
+	a.So(phy.UnmarshalBinary(ns.downlink.Payload), ShouldBeNil)
+	a.So(phy.MHDR.MType, ShouldEqual, lorawan.UnconfirmedDataDown)
+	macPayload := phy.MACPayload.(*lorawan.MACPayload)
+	a.So(macPayload.FHDR.DevAddr, ShouldEqual, lorawan.DevAddr{1, 2, 3, 4})
+	macPayload.FHDR.FCnt = ns.downlink.FCnt
+	a.So(phy.DecryptFRMPayload(lorawan.AES128Key(appSKey)), ShouldBeNil)
+	a.So(macPayload.FRMPayload[0].(*lorawan.DataPayload).Bytes, ShouldResemble, []byte{0xaa, 0xbb})
+
+	group, _ := h.groups.Get("app1", "lights")
+	a.So(group.FCnt, ShouldEqual, 0x10002)
+}
@@ -1,7 +1,7 @@
 // Copyright © 2017 The Things Network
 // Use of this source code is governed by the MIT license that can be found in the LICENSE file.
 
-package handler
+package payload
 
 import (
 	"fmt"
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	pb_handler "github.com/TheThingsNetwork/api/handler"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
 	"github.com/TheThingsNetwork/ttn/core/handler/functions"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 )
@@ -27,27 +28,66 @@ type CustomUplinkFunctions struct {
 
 	// Logger is the logger that will be used to store logs
 	Logger functions.Logger
+
+	// Runtime is the JavaScript runtime that runs the functions. If it is nil, the default runtime is used.
+	Runtime functions.Runtime
+
+	// Metadata is the metadata of the uplink message. It is passed read-only to the Decoder, Converter and Validator
+	// functions as third argument.
+	Metadata *application.UplinkMetadata
+
+	// State is the JSON-encoded decoder state of the device. If it is not nil, the state is passed to the Decoder
+	// function as fourth argument, and the changes that the Decoder makes to it are stored back.
+	State *string
 }
 
 // timeOut is the maximum allowed time a payload function is allowed to run
 var timeOut = 100 * time.Millisecond
 
+// readOnlyMetadata is a JavaScript expression that parses the JSON-encoded metadata and makes it read-only
+const readOnlyMetadata = `(function freeze(o) {
+	Object.getOwnPropertyNames(o).forEach(function (k) {
+		if (typeof o[k] === "object" && o[k] !== null) {
+			freeze(o[k]);
+		}
+	});
+	return Object.freeze(o);
+})(JSON.parse(metadata))`
+
+// runCode runs the code of a payload function in the runtime
+func runCode(runtime functions.Runtime, name, code string, env map[string]interface{}, logger functions.Logger) (interface{}, error) {
+	if runtime == nil {
+		return functions.RunCode(name, code, env, timeOut, logger)
+	}
+	return runtime.RunCode(name, code, env, timeOut, logger)
+}
+
 // decode decodes the payload using the Decoder function into a map
 func (f *CustomUplinkFunctions) decode(payload []byte, port uint8) (map[string]interface{}, error) {
 	if f.Decoder == "" {
 		return nil, nil
 	}
 
+	if f.State != nil {
+		return f.decodeWithState(payload, port)
+	}
+
+	metadata, err := f.Metadata.JSON()
+	if err != nil {
+		return nil, err
+	}
+
 	env := map[string]interface{}{
-		"payload": payload,
-		"port":    port,
+		"payload":  payload,
+		"port":     port,
+		"metadata": metadata,
 	}
 	code := fmt.Sprintf(`
 		%s;
-		Decoder(payload.slice(0), port);
-	`, f.Decoder)
+		Decoder(payload.slice(0), port, %s);
+	`, f.Decoder, readOnlyMetadata)
 
-	value, err := functions.RunCode("Decoder", code, env, timeOut, f.Logger)
+	value, err := runCode(f.Runtime, "Decoder", code, env, f.Logger)
 	if err != nil {
 		return nil, err
 	}
@@ -67,17 +107,23 @@ func (f *CustomUplinkFunctions) convert(fields map[string]interface{}, port uint
 		return fields, nil
 	}
 
+	metadata, err := f.Metadata.JSON()
+	if err != nil {
+		return nil, err
+	}
+
 	env := map[string]interface{}{
-		"fields": fields,
-		"port":   port,
+		"fields":   fields,
+		"port":     port,
+		"metadata": metadata,
 	}
 
 	code := fmt.Sprintf(`
 		%s;
-		Converter(fields, port)
-	`, f.Converter)
+		Converter(fields, port, %s)
+	`, f.Converter, readOnlyMetadata)
 
-	value, err := functions.RunCode("Converter", code, env, timeOut, f.Logger)
+	value, err := runCode(f.Runtime, "Converter", code, env, f.Logger)
 	if err != nil {
 		return nil, err
 	}
@@ -97,16 +143,22 @@ func (f *CustomUplinkFunctions) validate(fields map[string]interface{}, port uin
 		return true, nil
 	}
 
+	metadata, err := f.Metadata.JSON()
+	if err != nil {
+		return false, err
+	}
+
 	env := map[string]interface{}{
-		"fields": fields,
-		"port":   port,
+		"fields":   fields,
+		"port":     port,
+		"metadata": metadata,
 	}
 	code := fmt.Sprintf(`
 		%s;
-		Validator(fields, port)
-	`, f.Validator)
+		Validator(fields, port, %s)
+	`, f.Validator, readOnlyMetadata)
 
-	value, err := functions.RunCode("Validator", code, env, timeOut, f.Logger)
+	value, err := runCode(f.Runtime, "Validator", code, env, f.Logger)
 	if err != nil {
 		return false, err
 	}
@@ -147,6 +199,9 @@ type CustomDownlinkFunctions struct {
 
 	// Logger is the logger that will be used to store logs
 	Logger functions.Logger
+
+	// Runtime is the JavaScript runtime that runs the functions. If it is nil, the default runtime is used.
+	Runtime functions.Runtime
 }
 
 // encode encodes the map into a byte slice using the encoder payload function
@@ -165,7 +220,7 @@ func (f *CustomDownlinkFunctions) encode(payload map[string]interface{}, port ui
 		Encoder(payload, port)
 	`, f.Encoder)
 
-	value, err := functions.RunCode("Encoder", code, env, timeOut, f.Logger)
+	value, err := runCode(f.Runtime, "Encoder", code, env, f.Logger)
 	if err != nil {
 		return nil, err
 	}
@@ -1,13 +1,14 @@
 // Copyright © 2017 The Things Network
 // Use of this source code is governed by the MIT license that can be found in the LICENSE file.
 
-package handler
+package payload
 
 import (
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
 	. "github.com/smartystreets/assertions"
 )
 
@@ -105,6 +106,61 @@ func TestCustomDecodeUplink(t *testing.T) {
 	a.So(data["humidity"], ShouldEqual, 110)
 }
 
+func TestCustomDecodeUplinkMetadata(t *testing.T) {
+	a := New(t)
+
+	uplink := &CustomUplinkFunctions{
+		Decoder: `function Decoder (payload, port, metadata) {
+	return {
+		value: payload[0],
+		counter: metadata.counter,
+		model: metadata.attributes.model,
+	}
+}`,
+		Converter: `function Converter (data, port, metadata) {
+	data.value *= metadata.attributes.scale;
+	return data;
+}`,
+		Validator: `function Validator (data, port, metadata) {
+	return metadata.gateways > 0 && metadata.data_rate === "SF7BW125";
+}`,
+		Metadata: &application.UplinkMetadata{
+			AppID:      "app",
+			FCnt:       42,
+			Time:       time.Date(2017, 9, 1, 12, 0, 0, 0, time.UTC),
+			DataRate:   "SF7BW125",
+			Gateways:   2,
+			Attributes: map[string]string{"model": "v2", "scale": "2"},
+		},
+	}
+
+	data, valid, err := uplink.Decode([]byte{21}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(data["value"], ShouldEqual, 42)
+	a.So(data["counter"], ShouldEqual, 42)
+	a.So(data["model"], ShouldEqual, "v2")
+
+	// The metadata is read-only
+	uplink.Converter = `function Converter (data, port, metadata) {
+	"use strict";
+	metadata.attributes.scale = 3;
+	return data;
+}`
+	_, _, err = uplink.Decode([]byte{21}, 1)
+	a.So(err, ShouldNotBeNil)
+
+	// Functions without metadata get empty metadata
+	uplink.Metadata = nil
+	uplink.Converter = ""
+	uplink.Validator = `function Validator (data, port, metadata) {
+	return metadata.counter === 0 && Object.keys(metadata.attributes).length === 0;
+}`
+	_, valid, err = uplink.Decode([]byte{21}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+}
+
 func TestCustomDecodeInvalidUplinkFunction(t *testing.T) {
 	a := New(t)
 
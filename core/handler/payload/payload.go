@@ -0,0 +1,87 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package payload decodes and encodes payload with the payload formatters of applications and devices
+package payload
+
+import (
+	pb_handler "github.com/TheThingsNetwork/api/handler"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/binarystruct"
+	"github.com/TheThingsNetwork/ttn/core/handler/cayennelpp"
+	"github.com/TheThingsNetwork/ttn/core/handler/functions"
+	"github.com/TheThingsNetwork/ttn/core/handler/protobuf"
+)
+
+// Decoder decodes raw payload to fields
+type Decoder interface {
+	Decode(payload []byte, fPort uint8) (map[string]interface{}, bool, error)
+	Log() []*pb_handler.LogEntry
+}
+
+// Encoder encodes fields to raw payload
+type Encoder interface {
+	Encode(fields map[string]interface{}, fPort uint8) ([]byte, bool, error)
+	Log() []*pb_handler.LogEntry
+}
+
+// NewDecoder returns the decoder of the payload formatter, or nil if the payload format is not supported. The payload
+// functions run in the runtime and get the metadata. If the payload formatter has DecoderState enabled, the state is
+// passed to the Decoder function. A nil state is replaced by an empty state.
+func NewDecoder(runtime functions.Runtime, formatter *application.PayloadFormatter, metadata *application.UplinkMetadata, state *string, logger functions.Logger) Decoder {
+	switch formatter.PayloadFormat {
+	case application.PayloadFormatCustom:
+		decoder := &CustomUplinkFunctions{
+			Decoder:   formatter.CustomDecoder,
+			Converter: formatter.CustomConverter,
+			Validator: formatter.CustomValidator,
+			Logger:    logger,
+			Runtime:   runtime,
+			Metadata:  metadata,
+		}
+		if formatter.DecoderState {
+			if state == nil {
+				state = new(string)
+			}
+			decoder.State = state
+		}
+		return decoder
+	case application.PayloadFormatCayenneLPP:
+		return &cayennelpp.Decoder{}
+	case application.PayloadFormatCayenneLPPPacked:
+		return &cayennelpp.Decoder{Packed: true}
+	case application.PayloadFormatProtobuf:
+		return &protobuf.Decoder{
+			DescriptorSet: formatter.ProtobufDescriptorSet,
+			Messages:      formatter.ProtobufMessages,
+		}
+	case application.PayloadFormatStruct:
+		return &binarystruct.Decoder{Layout: formatter.CustomDecoder}
+	}
+	return nil
+}
+
+// NewEncoder returns the encoder of the payload formatter, or nil if the payload format is not supported. The payload
+// functions run in the runtime.
+func NewEncoder(runtime functions.Runtime, formatter *application.PayloadFormatter, logger functions.Logger) Encoder {
+	switch formatter.PayloadFormat {
+	case application.PayloadFormatCustom:
+		return &CustomDownlinkFunctions{
+			Encoder: formatter.CustomEncoder,
+			Logger:  logger,
+			Runtime: runtime,
+		}
+	case application.PayloadFormatCayenneLPP:
+		return &cayennelpp.Encoder{}
+	case application.PayloadFormatCayenneLPPPacked:
+		return &cayennelpp.Encoder{Packed: true}
+	case application.PayloadFormatProtobuf:
+		return &protobuf.Encoder{
+			DescriptorSet: formatter.ProtobufDescriptorSet,
+			Messages:      formatter.ProtobufMessages,
+		}
+	case application.PayloadFormatStruct:
+		return &binarystruct.Encoder{Layout: formatter.CustomDecoder}
+	}
+	return nil
+}
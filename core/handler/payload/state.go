@@ -0,0 +1,97 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"fmt"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// MaxDecoderStateSize is the maximum size of the JSON-encoded decoder state of a device
+var MaxDecoderStateSize = 4096
+
+// stateHelpers are the JavaScript helpers that are available to a Decoder function with state.
+//
+// reassemble(state, key, id, index, count, bytes) stores the bytes as frame index of count frames of message id under
+// the key in the state. When all frames of the message are received, it removes them from the state and returns the
+// reassembled payload, otherwise it returns null. A frame with index 0 or with a different id or count starts a new
+// message, so the frames of a previous message that was not completed are discarded.
+const stateHelpers = `
+	function reassemble(state, key, id, index, count, bytes) {
+		if (id === undefined || id === null) {
+			throw new Error("reassemble requires the id of the message");
+		}
+		if (typeof index !== "number" || typeof count !== "number" || index < 0 || index >= count) {
+			throw new Error("reassemble requires an index lower than the count");
+		}
+		var frames = state[key];
+		if (!frames || frames.id !== id || frames.count !== count || index === 0) {
+			frames = { id: id, count: count, parts: {} };
+		}
+		frames.parts[index] = Array.prototype.slice.call(bytes);
+		var payload = [];
+		for (var i = 0; i < count; i++) {
+			if (!frames.parts[i]) {
+				state[key] = frames;
+				return null;
+			}
+			payload = payload.concat(frames.parts[i]);
+		}
+		delete state[key];
+		return payload;
+	}
+`
+
+// decodeWithState decodes the payload using the Decoder function with the decoder state of the device. The state is
+// only updated if the Decoder function succeeds.
+func (f *CustomUplinkFunctions) decodeWithState(payload []byte, port uint8) (map[string]interface{}, error) {
+	state := *f.State
+	if state == "" {
+		state = "{}"
+	}
+
+	metadata, err := f.Metadata.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	env := map[string]interface{}{
+		"payload":  payload,
+		"port":     port,
+		"state":    state,
+		"metadata": metadata,
+	}
+	code := fmt.Sprintf(`
+		%s;
+		%s;
+		(function (state) {
+			var fields = Decoder(payload.slice(0), port, %s, state);
+			return { fields: fields, state: JSON.stringify(state) };
+		})(JSON.parse(state));
+	`, stateHelpers, f.Decoder, readOnlyMetadata)
+
+	value, err := runCode(f.Runtime, "Decoder", code, env, f.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	result, _ := value.(map[string]interface{})
+	m, ok := result["fields"].(map[string]interface{})
+	if !ok {
+		return nil, errors.NewErrInvalidArgument("Decoder", "does not return an object")
+	}
+	newState, ok := result["state"].(string)
+	if !ok {
+		return nil, errors.NewErrInvalidArgument("Decoder State", "must be an object")
+	}
+	if len(newState) > MaxDecoderStateSize {
+		// The state is reset, so that the next uplink messages do not fail as well
+		*f.State = ""
+		return nil, errors.NewErrInvalidArgument("Decoder State", fmt.Sprintf("can not be larger than %d bytes and was reset", MaxDecoderStateSize))
+	}
+	*f.State = newState
+
+	return m, nil
+}
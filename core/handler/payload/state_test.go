@@ -0,0 +1,132 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/functions"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestCustomDecodeWithState(t *testing.T) {
+	a := New(t)
+
+	state := ""
+	functions := &CustomUplinkFunctions{
+		Decoder: `function Decoder (payload, port, metadata, state) {
+  var value = (state.last || 0) + payload[0];
+  state.last = value;
+  return { value: value };
+}`,
+		State: &state,
+	}
+
+	m, err := functions.decode([]byte{10}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(m["value"], ShouldEqual, 10)
+	a.So(state, ShouldEqual, `{"last":10}`)
+
+	m, err = functions.decode([]byte{5}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(m["value"], ShouldEqual, 15)
+	a.So(state, ShouldEqual, `{"last":15}`)
+
+	// The state is not updated if the Decoder fails
+	functions.Decoder = `function Decoder (payload, port, metadata, state) { state.last = 0; throw new Error("fail"); }`
+	_, err = functions.decode([]byte{5}, 1)
+	a.So(err, ShouldNotBeNil)
+	a.So(state, ShouldEqual, `{"last":15}`)
+
+	// The state can not be too large
+	functions.Decoder = `function Decoder (payload, port, metadata, state) { state.big = new Array(5000).join("x"); return {}; }`
+	_, err = functions.decode([]byte{5}, 1)
+	a.So(err, ShouldNotBeNil)
+	a.So(state, ShouldEqual, "")
+}
+
+func TestCustomDecodeReassemble(t *testing.T) {
+	a := New(t)
+
+	state := ""
+	functions := &CustomUplinkFunctions{
+		Decoder: `function Decoder (payload, port, metadata, state) {
+  var message = reassemble(state, "message", payload[0], payload[1] >> 4, payload[1] & 0x0F, payload.slice(2));
+  if (message === null) {
+    return { complete: false };
+  }
+  return { complete: true, message: message };
+}`,
+		State: &state,
+	}
+
+	m, err := functions.decode([]byte{0x01, 0x03, 0x00}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(m["complete"], ShouldBeFalse)
+
+	m, err = functions.decode([]byte{0x01, 0x23, 0x05}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(m["complete"], ShouldBeFalse)
+
+	m, err = functions.decode([]byte{0x01, 0x13, 0x01, 0x02}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(m["complete"], ShouldBeTrue)
+	a.So(m["message"], ShouldResemble, []interface{}{int64(0), int64(1), int64(2), int64(5)})
+	a.So(state, ShouldEqual, `{}`)
+
+	// Frames of a message with a different id are not mixed
+	m, err = functions.decode([]byte{0x02, 0x02, 0x0a}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(m["complete"], ShouldBeFalse)
+	m, err = functions.decode([]byte{0x03, 0x12, 0x0b}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(m["complete"], ShouldBeFalse)
+
+	// A frame with index 0 starts a new message
+	m, err = functions.decode([]byte{0x03, 0x02, 0x0c}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(m["complete"], ShouldBeFalse)
+	m, err = functions.decode([]byte{0x03, 0x12, 0x0d}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(m["complete"], ShouldBeTrue)
+	a.So(m["message"], ShouldResemble, []interface{}{int64(0x0c), int64(0x0d)})
+
+	// The id of the message is required
+	functions.Decoder = `function Decoder (payload, port, metadata, state) { return { message: reassemble(state, "message", undefined, 0, 1, payload) }; }`
+	_, err = functions.decode([]byte{0x01}, 1)
+	a.So(err, ShouldNotBeNil)
+}
+
+func TestNewDecoderWithState(t *testing.T) {
+	a := New(t)
+
+	formatter := &application.PayloadFormatter{
+		PayloadFormat: application.PayloadFormatCustom,
+		CustomDecoder: `function Decoder (payload, port, metadata, state) { state.count = (state.count || 0) + 1; return { count: state.count }; }`,
+	}
+
+	// Without DecoderState, the Decoder does not get a state
+	decoder := NewDecoder(functions.Isolated, formatter, nil, nil, nil)
+	_, _, err := decoder.Decode([]byte{1}, 1)
+	a.So(err, ShouldNotBeNil)
+
+	formatter.DecoderState = true
+	state := ""
+	decoder = NewDecoder(functions.Isolated, formatter, nil, &state, nil)
+	fields, valid, err := decoder.Decode([]byte{1}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(fields["count"], ShouldEqual, 1)
+	a.So(strings.Contains(state, `"count":1`), ShouldBeTrue)
+
+	// Test vectors share the state
+	results := RunTestVectors("app", formatter, []application.TestVector{
+		{FPort: 1, Payload: "01", Fields: map[string]interface{}{"count": 1}},
+		{FPort: 1, Payload: "01", Fields: map[string]interface{}{"count": 2}},
+	})
+	a.So(results[0], ShouldBeNil)
+	a.So(results[1], ShouldBeNil)
+}
@@ -0,0 +1,82 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/functions"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// RunTestVectors runs the test vectors against the payload formatter. The result contains the error of each test
+// vector, which is nil if the test vector passed. If the payload formatter has DecoderState enabled, the uplink test
+// vectors share the decoder state in the order that they are given, starting from an empty state. The test vectors do
+// not count towards the quota of the application.
+func RunTestVectors(appID string, formatter *application.PayloadFormatter, vectors []application.TestVector) []error {
+	results := make([]error, len(vectors))
+	state := new(string)
+	for i, vector := range vectors {
+		results[i] = runTestVector(appID, formatter, state, vector)
+	}
+	return results
+}
+
+func runTestVector(appID string, formatter *application.PayloadFormatter, state *string, vector application.TestVector) error {
+	if err := vector.Validate(); err != nil {
+		return err
+	}
+	if formatter == nil {
+		return errors.NewErrInvalidArgument("Payload Formatter", "can not be empty")
+	}
+	payload, _ := vector.PayloadBytes()
+
+	if vector.Downlink {
+		encoder := NewEncoder(functions.Isolated, formatter, functions.Ignore)
+		if encoder == nil {
+			return errors.NewErrInvalidArgument("Payload Format", "does not encode payloads")
+		}
+		actual, _, err := encoder.Encode(vector.Fields, vector.FPort)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(actual, payload) {
+			return errors.New(fmt.Sprintf("expected payload %X, got %X", payload, actual))
+		}
+		return nil
+	}
+
+	metadata := vector.Metadata
+	if metadata == nil {
+		metadata = &application.UplinkMetadata{AppID: appID}
+	}
+	decoder := NewDecoder(functions.Isolated, formatter, metadata, state, functions.Ignore)
+	if decoder == nil {
+		return errors.NewErrInvalidArgument("Payload Format", "does not decode payloads")
+	}
+	fields, valid, err := decoder.Decode(payload, vector.FPort)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errors.New("payload validator function returned false")
+	}
+	// The fields are compared by their JSON representation, so that numbers of different types are equal
+	expected, _ := json.Marshal(vector.Fields)
+	actual, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	var expectedFields, actualFields interface{}
+	json.Unmarshal(expected, &expectedFields)
+	json.Unmarshal(actual, &actualFields)
+	if !reflect.DeepEqual(expectedFields, actualFields) {
+		return errors.New(fmt.Sprintf("expected fields %s, got %s", expected, actual))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package payload
+
+import (
+	"testing"
+
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestRunTestVectors(t *testing.T) {
+	a := New(t)
+
+	custom := &application.PayloadFormatter{
+		PayloadFormat: application.PayloadFormatCustom,
+		CustomDecoder: `function Decoder (bytes, port) { return { led: bytes[0] == 1, port: port }; }`,
+		CustomEncoder: `function Encoder (fields, port) { return [ fields.led ? 1 : 0 ]; }`,
+	}
+	results := RunTestVectors("app", custom, []application.TestVector{
+		{FPort: 1, Payload: "01", Fields: map[string]interface{}{"led": true, "port": 1}},
+		{FPort: 2, Payload: "00", Fields: map[string]interface{}{"led": true, "port": 2}},
+		{Downlink: true, FPort: 1, Payload: "01", Fields: map[string]interface{}{"led": true}},
+		{Downlink: true, FPort: 1, Payload: "00", Fields: map[string]interface{}{"led": true}},
+		{FPort: 1, Payload: "zz"},
+	})
+	a.So(results, ShouldHaveLength, 5)
+	a.So(results[0], ShouldBeNil)
+	a.So(results[1], ShouldNotBeNil)
+	a.So(results[2], ShouldBeNil)
+	a.So(results[3], ShouldNotBeNil)
+	a.So(results[4], ShouldNotBeNil)
+
+	lpp := &application.PayloadFormatter{PayloadFormat: application.PayloadFormatCayenneLPP}
+	results = RunTestVectors("app", lpp, []application.TestVector{
+		{FPort: 1, Payload: "01 67 01 10", Fields: map[string]interface{}{"temperature_1": 27.2}},
+		{Downlink: true, FPort: 1, Payload: "02 EC 45", Fields: map[string]interface{}{"value_2": -50.51}},
+	})
+	a.So(results[0], ShouldBeNil)
+	a.So(results[1], ShouldBeNil)
+
+	packed := &application.PayloadFormatter{PayloadFormat: application.PayloadFormatCayenneLPPPacked}
+	results = RunTestVectors("app", packed, []application.TestVector{
+		{FPort: 2, Payload: "67 00 D7 68 63", Fields: map[string]interface{}{"temperature_0": 21.5, "relative_humidity_1": 49.5}},
+		{Downlink: true, FPort: 2, Payload: "67 00 D7", Fields: map[string]interface{}{"temperature_0": 21.5}},
+	})
+	a.So(results[0], ShouldBeNil)
+	a.So(results[1], ShouldBeNil)
+
+	withMetadata := &application.PayloadFormatter{
+		PayloadFormat: application.PayloadFormatCustom,
+		CustomDecoder: `function Decoder (bytes, port, metadata) { return { model: metadata.attributes.model || null }; }`,
+	}
+	results = RunTestVectors("app", withMetadata, []application.TestVector{
+		{FPort: 1, Payload: "01", Fields: map[string]interface{}{"model": nil}},
+		{FPort: 1, Payload: "01", Fields: map[string]interface{}{"model": "v2"}, Metadata: &application.UplinkMetadata{
+			Attributes: map[string]string{"model": "v2"},
+		}},
+	})
+	a.So(results[0], ShouldBeNil)
+	a.So(results[1], ShouldBeNil)
+
+	a.So(RunTestVectors("app", nil, []application.TestVector{{FPort: 1, Payload: "01"}})[0], ShouldNotBeNil)
+}
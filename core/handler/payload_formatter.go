@@ -0,0 +1,296 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"github.com/TheThingsNetwork/go-account-lib/claims"
+	"github.com/TheThingsNetwork/go-account-lib/rights"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+)
+
+func (h *handlerManager) GetApplicationPayloadFormatter(ctx context.Context, in *application.ApplicationIdentifier) (*application.ApplicationPayloadFormatter, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	app, err := h.handler.applications.Get(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	return &application.ApplicationPayloadFormatter{
+		AppID:            app.AppID,
+		PayloadFormatter: app.GetPayloadFormatter(),
+		Revision:         app.PayloadFormatterRevision,
+	}, nil
+}
+
+func (h *handlerManager) SetApplicationPayloadFormatter(ctx context.Context, in *application.ApplicationPayloadFormatter) (*application.Empty, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	if in.PayloadFormatter == nil {
+		return nil, errors.NewErrInvalidArgument("Payload Formatter", "can not be empty")
+	}
+	if err := in.PayloadFormatter.Validate(); err != nil {
+		return nil, err
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	app, err := h.handler.applications.Get(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	app.StartUpdate()
+	app.SetPayloadFormatter(in.PayloadFormatter)
+	if err := h.handler.setApplication(app, author(claims)); err != nil {
+		return nil, err
+	}
+	return &application.Empty{}, nil
+}
+
+// author returns the user or client of the claims, which is recorded as author of payload formatter revisions
+func author(claims *claims.Claims) string {
+	if claims.Username != "" {
+		return claims.Username
+	}
+	if claims.Client != "" {
+		return claims.Client
+	}
+	return claims.Subject
+}
+
+// setApplication stores the application. If its payload formatter changed, the test vectors of the application are
+// run against it and a new payload formatter revision is stored. A payload formatter that was set before revisions
+// were kept is stored as the first revision.
+func (h *handler) setApplication(app *application.Application, author string) error {
+	if !app.PayloadFormatterChanged() {
+		return h.applications.Set(app)
+	}
+	if err := checkTestVectors(app); err != nil {
+		return err
+	}
+	if h.revisions == nil {
+		return h.applications.Set(app)
+	}
+	var revisions []*application.PayloadFormatterRevision
+	if previous := app.PreviousPayloadFormatter(); previous != nil {
+		revision, err := h.revisions.Next(app.AppID)
+		if err != nil {
+			return err
+		}
+		revisions = append(revisions, &application.PayloadFormatterRevision{
+			AppID:            app.AppID,
+			Revision:         revision,
+			Hash:             previous.Hash(),
+			PayloadFormatter: previous,
+		})
+	}
+	revision, err := h.revisions.Next(app.AppID)
+	if err != nil {
+		return err
+	}
+	revisions = append(revisions, app.NewPayloadFormatterRevision(revision, author))
+	if err := h.applications.Set(app); err != nil {
+		return err
+	}
+	for _, revision := range revisions {
+		if err := h.revisions.Add(revision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *handlerManager) GetPayloadFormatterHistory(ctx context.Context, in *application.ApplicationIdentifier) (*application.PayloadFormatterHistory, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	if _, err := h.handler.applications.Get(in.AppID); err != nil {
+		return nil, err
+	}
+	revisions, err := h.handler.revisions.List(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	return &application.PayloadFormatterHistory{
+		AppID:     in.AppID,
+		Revisions: revisions,
+	}, nil
+}
+
+func (h *handlerManager) RollbackPayloadFormatter(ctx context.Context, in *application.PayloadFormatterRevisionIdentifier) (*application.PayloadFormatterRevisionIdentifier, error) {
+	if in.AppID == "" || in.Revision == 0 {
+		return nil, errors.NewErrInvalidArgument("Revision Identifier", "must contain AppID and Revision")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	app, err := h.handler.applications.Get(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := h.handler.revisions.Get(in.AppID, in.Revision)
+	if err != nil {
+		return nil, err
+	}
+	if revision.PayloadFormatter == nil {
+		return nil, errors.NewErrInternal("Revision does not contain a payload formatter")
+	}
+	// The rollback is recorded as a new revision with the content of the old revision
+	app.StartUpdate()
+	app.SetPayloadFormatter(revision.PayloadFormatter)
+	if err := h.handler.setApplication(app, author(claims)); err != nil {
+		return nil, err
+	}
+	return &application.PayloadFormatterRevisionIdentifier{
+		AppID:    app.AppID,
+		Revision: app.PayloadFormatterRevision,
+	}, nil
+}
+
+func (h *handlerManager) GetDevicePayloadFormatter(ctx context.Context, in *application.DeviceIdentifier) (*application.DevicePayloadFormatter, error) {
+	if in.AppID == "" || in.DevID == "" {
+		return nil, errors.NewErrInvalidArgument("Device Identifier", "must contain AppID and DevID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+	dev, err := h.handler.devices.Get(in.AppID, in.DevID)
+	if err != nil {
+		return nil, err
+	}
+	return &application.DevicePayloadFormatter{
+		AppID:            dev.AppID,
+		DevID:            dev.DevID,
+		PayloadFormatter: dev.PayloadFormatter,
+	}, nil
+}
+
+func (h *handlerManager) SetDevicePayloadFormatter(ctx context.Context, in *application.DevicePayloadFormatter) (*application.Empty, error) {
+	if in.AppID == "" || in.DevID == "" {
+		return nil, errors.NewErrInvalidArgument("Device Identifier", "must contain AppID and DevID")
+	}
+	if in.PayloadFormatter != nil {
+		if err := in.PayloadFormatter.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+	dev, err := h.handler.devices.Get(in.AppID, in.DevID)
+	if err != nil {
+		return nil, err
+	}
+	dev.StartUpdate()
+	dev.PayloadFormatter = in.PayloadFormatter
+	if err := h.handler.devices.Set(dev, "PayloadFormatter"); err != nil {
+		return nil, err
+	}
+	return &application.Empty{}, nil
+}
+
+func (h *handlerManager) ResetDecoderState(ctx context.Context, in *application.DeviceIdentifier) (*application.Empty, error) {
+	if in.AppID == "" || in.DevID == "" {
+		return nil, errors.NewErrInvalidArgument("Device Identifier", "must contain AppID and DevID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.Devices); err != nil {
+		return nil, err
+	}
+	dev, err := h.handler.devices.Get(in.AppID, in.DevID)
+	if err != nil {
+		return nil, err
+	}
+	dev.StartUpdate()
+	dev.DecoderState = ""
+	if err := h.handler.devices.Set(dev, "DecoderState"); err != nil {
+		return nil, err
+	}
+	return &application.Empty{}, nil
+}
+
+func (h *handlerManager) GetAttributePayloadFormatters(ctx context.Context, in *application.ApplicationIdentifier) (*application.AttributePayloadFormatters, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	app, err := h.handler.applications.Get(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	return &application.AttributePayloadFormatters{
+		AppID:      app.AppID,
+		Formatters: app.AttributePayloadFormatters,
+	}, nil
+}
+
+func (h *handlerManager) SetAttributePayloadFormatters(ctx context.Context, in *application.AttributePayloadFormatters) (*application.Empty, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	for i := range in.Formatters {
+		if err := in.Formatters[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	app, err := h.handler.applications.Get(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	app.StartUpdate()
+	app.AttributePayloadFormatters = in.Formatters
+	if err := h.handler.applications.Set(app, "AttributePayloadFormatters"); err != nil {
+		return nil, err
+	}
+	return &application.Empty{}, nil
+}
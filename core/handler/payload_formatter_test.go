@@ -0,0 +1,65 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"testing"
+
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestSetApplicationRevisions(t *testing.T) {
+	a := New(t)
+	appID := "app1"
+	h := &handler{
+		Component:    &component.Component{Ctx: GetLogger(t, "TestSetApplicationRevisions")},
+		applications: application.NewRedisApplicationStore(GetRedisClient(), "handler-test-set-application-revisions"),
+		revisions:    application.NewRedisRevisionStore(GetRedisClient(), "handler-test-set-application-revisions"),
+	}
+	defer h.applications.Delete(appID)
+	defer h.revisions.DeleteAll(appID)
+
+	// The payload formatter was set before revisions were kept
+	h.applications.Set(&application.Application{
+		AppID:         appID,
+		PayloadFormat: application.PayloadFormatCustom,
+		CustomDecoder: `function Decoder (bytes) { return {}; }`,
+	})
+
+	app, _ := h.applications.Get(appID)
+	app.StartUpdate()
+	app.PayloadFormat = application.PayloadFormatCayenneLPP
+	app.CustomDecoder = ""
+	err := h.setApplication(app, "alice")
+	a.So(err, ShouldBeNil)
+
+	revisions, _ := h.revisions.List(appID)
+	a.So(revisions, ShouldHaveLength, 2)
+	a.So(revisions[0].Revision, ShouldEqual, 1)
+	a.So(revisions[0].Author, ShouldBeEmpty)
+	a.So(revisions[0].PayloadFormatter.PayloadFormat, ShouldEqual, application.PayloadFormatCustom)
+	a.So(revisions[1].Revision, ShouldEqual, 2)
+	a.So(revisions[1].Author, ShouldEqual, "alice")
+	a.So(revisions[1].PayloadFormatter.PayloadFormat, ShouldEqual, application.PayloadFormatCayenneLPP)
+
+	// A concurrent update of an application that was read before the last revision does not reuse its number
+	stale := *app
+	stale.StartUpdate()
+	stale.PayloadFormat = application.PayloadFormatProtobuf
+	app.StartUpdate()
+	app.PayloadFormat = application.PayloadFormatCustom
+	a.So(h.setApplication(app, "alice"), ShouldBeNil)
+	a.So(h.setApplication(&stale, "bob"), ShouldBeNil)
+
+	revisions, _ = h.revisions.List(appID)
+	a.So(revisions, ShouldHaveLength, 4)
+	a.So(revisions[2].Author, ShouldEqual, "alice")
+	a.So(revisions[3].Author, ShouldEqual, "bob")
+
+	app, _ = h.applications.Get(appID)
+	a.So(app.PayloadFormatterRevision, ShouldEqual, 4)
+}
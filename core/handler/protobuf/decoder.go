@@ -0,0 +1,59 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package protobuf
+
+import (
+	"encoding/json"
+
+	pb_handler "github.com/TheThingsNetwork/api/handler"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Decoder is a Protocol Buffers PayloadDecoder
+type Decoder struct {
+	// DescriptorSet is the serialized FileDescriptorSet that contains the messages
+	DescriptorSet []byte
+	// Messages are the full names of the messages per FPort
+	Messages map[uint8]string
+}
+
+// Decode decodes the Protocol Buffers payload to fields. The fields are named and formatted according to the JSON
+// mapping of Protocol Buffers. Payload on ports without message is not decoded.
+func (d *Decoder) Decode(payload []byte, fPort uint8) (map[string]interface{}, bool, error) {
+	name, ok := d.Messages[fPort]
+	if !ok {
+		return nil, true, nil
+	}
+	files, err := Files(d.DescriptorSet)
+	if err != nil {
+		return nil, false, err
+	}
+	desc, err := Message(files, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	msg := dynamicpb.NewMessage(desc)
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, false, errors.NewErrInvalidArgument("Payload", err.Error())
+	}
+	marshaled, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(marshaled, &fields); err != nil {
+		return nil, false, err
+	}
+	return fields, true, nil
+}
+
+// Log returns the log
+func (d *Decoder) Log() []*pb_handler.LogEntry {
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package protobuf
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/bluele/gcache"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// descriptorCacheSize is the number of parsed descriptor sets that is kept in memory
+const descriptorCacheSize = 128
+
+var descriptorCache = gcache.New(descriptorCacheSize).LRU().Build()
+
+// Files parses a serialized FileDescriptorSet, as generated by protoc with --descriptor_set_out and
+// --include_imports. Parsed descriptor sets are cached.
+func Files(descriptorSet []byte) (*protoregistry.Files, error) {
+	key := sha256.Sum256(descriptorSet)
+	if files, err := descriptorCache.Get(key); err == nil {
+		return files.(*protoregistry.Files), nil
+	}
+
+	set := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(descriptorSet, set); err != nil {
+		return nil, errors.NewErrInvalidArgument("Protobuf Descriptor Set", err.Error())
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, errors.NewErrInvalidArgument("Protobuf Descriptor Set", err.Error())
+	}
+
+	descriptorCache.Set(key, files)
+	return files, nil
+}
+
+// Message returns the descriptor of the message with the given full name
+func Message(files *protoregistry.Files, name string) (protoreflect.MessageDescriptor, error) {
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, errors.NewErrNotFound(fmt.Sprintf("Protobuf message %s", name))
+	}
+	msg, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, errors.NewErrInvalidArgument("Protobuf Message", fmt.Sprintf("%s is not a message", name))
+	}
+	return msg, nil
+}
+
+// Validate checks that the descriptor set can be parsed and that it contains the messages
+func Validate(descriptorSet []byte, messages map[uint8]string) error {
+	if len(descriptorSet) == 0 {
+		return errors.NewErrInvalidArgument("Protobuf Descriptor Set", "can not be empty")
+	}
+	if len(messages) == 0 {
+		return errors.NewErrInvalidArgument("Protobuf Messages", "can not be empty")
+	}
+	files, err := Files(descriptorSet)
+	if err != nil {
+		return err
+	}
+	for port, name := range messages {
+		if port == 0 {
+			return errors.NewErrInvalidArgument("Protobuf Messages", "FPort 0 is reserved for MAC commands")
+		}
+		if _, err := Message(files, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package protobuf
+
+import (
+	"encoding/json"
+	"fmt"
+
+	pb_handler "github.com/TheThingsNetwork/api/handler"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Encoder is a Protocol Buffers PayloadEncoder
+type Encoder struct {
+	// DescriptorSet is the serialized FileDescriptorSet that contains the messages
+	DescriptorSet []byte
+	// Messages are the full names of the messages per FPort
+	Messages map[uint8]string
+}
+
+// Encode encodes the fields to the Protocol Buffers message of the FPort. The fields are read according to the JSON
+// mapping of Protocol Buffers.
+func (e *Encoder) Encode(fields map[string]interface{}, fPort uint8) ([]byte, bool, error) {
+	name, ok := e.Messages[fPort]
+	if !ok {
+		return nil, false, errors.NewErrInvalidArgument("FPort", fmt.Sprintf("no Protobuf message for port %d", fPort))
+	}
+	files, err := Files(e.DescriptorSet)
+	if err != nil {
+		return nil, false, err
+	}
+	desc, err := Message(files, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	marshaled, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false, err
+	}
+	msg := dynamicpb.NewMessage(desc)
+	if err := protojson.Unmarshal(marshaled, msg); err != nil {
+		return nil, false, errors.NewErrInvalidArgument("Fields", err.Error())
+	}
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, false, err
+	}
+	return payload, true, nil
+}
+
+// Log returns the log
+func (e *Encoder) Log() []*pb_handler.LogEntry {
+	return nil
+}
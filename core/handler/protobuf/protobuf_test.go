@@ -0,0 +1,140 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package protobuf
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/assertions"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// buildDescriptorSet builds the descriptor set of:
+//
+//	syntax = "proto3";
+//	package test;
+//	message Measurement {
+//	  float temperature = 1;
+//	  uint32 humidity = 2;
+//	  bool alarm = 3;
+//	}
+//	message Config { uint32 interval = 1; }
+func buildDescriptorSet(t *testing.T) []byte {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+	}
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("test.proto"),
+			Package: proto.String("test"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Measurement"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("temperature", 1, descriptorpb.FieldDescriptorProto_TYPE_FLOAT),
+						field("humidity", 2, descriptorpb.FieldDescriptorProto_TYPE_UINT32),
+						field("alarm", 3, descriptorpb.FieldDescriptorProto_TYPE_BOOL),
+					},
+				},
+				{
+					Name: proto.String("Config"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("interval", 1, descriptorpb.FieldDescriptorProto_TYPE_UINT32),
+					},
+				},
+			},
+		}},
+	}
+	b, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestValidate(t *testing.T) {
+	a := New(t)
+	descriptorSet := buildDescriptorSet(t)
+
+	a.So(Validate(descriptorSet, map[uint8]string{1: "test.Measurement", 2: "test.Config"}), ShouldBeNil)
+	a.So(Validate(nil, map[uint8]string{1: "test.Measurement"}), ShouldNotBeNil)
+	a.So(Validate(descriptorSet, nil), ShouldNotBeNil)
+	a.So(Validate([]byte{0xff, 0xff}, map[uint8]string{1: "test.Measurement"}), ShouldNotBeNil)
+	a.So(Validate(descriptorSet, map[uint8]string{1: "test.Unknown"}), ShouldNotBeNil)
+	a.So(Validate(descriptorSet, map[uint8]string{0: "test.Measurement"}), ShouldNotBeNil)
+	a.So(Validate(descriptorSet, map[uint8]string{1: "test"}), ShouldNotBeNil)
+}
+
+func TestDecode(t *testing.T) {
+	a := New(t)
+	decoder := &Decoder{
+		DescriptorSet: buildDescriptorSet(t),
+		Messages:      map[uint8]string{1: "test.Measurement"},
+	}
+
+	// temperature: 21.5, humidity: 60, alarm: true
+	fields, valid, err := decoder.Decode([]byte{0x0d, 0x00, 0x00, 0xac, 0x41, 0x10, 0x3c, 0x18, 0x01}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(fields, ShouldResemble, map[string]interface{}{
+		"temperature": 21.5,
+		"humidity":    60.0,
+		"alarm":       true,
+	})
+
+	// Unpopulated fields
+	fields, _, err = decoder.Decode([]byte{}, 1)
+	a.So(err, ShouldBeNil)
+	a.So(fields, ShouldResemble, map[string]interface{}{
+		"temperature": 0.0,
+		"humidity":    0.0,
+		"alarm":       false,
+	})
+
+	// Port without message
+	fields, valid, err = decoder.Decode([]byte{0x01}, 2)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(fields, ShouldBeNil)
+
+	// Invalid payload
+	_, _, err = decoder.Decode([]byte{0x0d, 0x00}, 1)
+	a.So(err, ShouldNotBeNil)
+}
+
+func TestEncode(t *testing.T) {
+	a := New(t)
+	descriptorSet := buildDescriptorSet(t)
+	encoder := &Encoder{
+		DescriptorSet: descriptorSet,
+		Messages:      map[uint8]string{1: "test.Measurement", 2: "test.Config"},
+	}
+
+	payload, valid, err := encoder.Encode(map[string]interface{}{"interval": 300}, 2)
+	a.So(err, ShouldBeNil)
+	a.So(valid, ShouldBeTrue)
+	a.So(payload, ShouldResemble, []byte{0x08, 0xac, 0x02})
+
+	_, _, err = encoder.Encode(map[string]interface{}{"interval": 300}, 3)
+	a.So(err, ShouldNotBeNil)
+
+	_, _, err = encoder.Encode(map[string]interface{}{"unknown": 300}, 2)
+	a.So(err, ShouldNotBeNil)
+
+	// Round trip
+	fields := map[string]interface{}{"temperature": 21.5, "humidity": 60.0, "alarm": true}
+	payload, _, err = encoder.Encode(fields, 1)
+	a.So(err, ShouldBeNil)
+	decoded, _, err := (&Decoder{DescriptorSet: descriptorSet, Messages: encoder.Messages}).Decode(payload, 1)
+	a.So(err, ShouldBeNil)
+	a.So(decoded, ShouldResemble, fields)
+}
@@ -0,0 +1,85 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package scheduled stores downlink messages that are added to the downlink queue of a device at a later time
+package scheduled
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/cron"
+)
+
+// Downlink is a downlink message that is added to the downlink queue of a device at NextAt. If the downlink has a
+// Recurrence, it is rescheduled after it was added to the queue.
+type Downlink struct {
+	old *Downlink
+
+	AppID      string                 `redis:"app_id"`
+	DevID      string                 `redis:"dev_id"`
+	ID         string                 `redis:"id"`
+	Message    *types.DownlinkMessage `redis:"message"`
+	Recurrence string                 `redis:"recurrence"`
+	NextAt     time.Time              `redis:"next_at"`
+
+	// Attempts is the number of failed attempts to add the current occurrence to the queue, which is retried at RetryAt
+	Attempts int       `redis:"attempts"`
+	RetryAt  time.Time `redis:"retry_at"`
+
+	CreatedAt time.Time `redis:"created_at"`
+	UpdatedAt time.Time `redis:"updated_at"`
+}
+
+// StartUpdate stores the state of the downlink
+func (d *Downlink) StartUpdate() {
+	old := *d
+	d.old = &old
+}
+
+// Due returns true if the downlink needs to be added to the queue at the given time
+func (d *Downlink) Due(t time.Time) bool {
+	return !d.NextAt.After(t) && !d.RetryAt.After(t)
+}
+
+// Reschedule sets NextAt to the next occurrence of the Recurrence after the given time. It returns false if the
+// downlink does not recur.
+func (d *Downlink) Reschedule(t time.Time) (bool, error) {
+	d.Attempts, d.RetryAt = 0, time.Time{}
+	if d.Recurrence == "" {
+		return false, nil
+	}
+	schedule, err := cron.Parse(d.Recurrence)
+	if err != nil {
+		return false, err
+	}
+	next := schedule.Next(t.UTC())
+	if next.IsZero() {
+		return false, nil
+	}
+	d.NextAt = next
+	return true, nil
+}
+
+// Occurrence returns the downlink message for the current occurrence. Occurrences of recurring downlinks get an ID
+// that is derived from the ID of the scheduled downlink.
+func (d *Downlink) Occurrence() *types.DownlinkMessage {
+	msg := *d.Message
+	msg.AppID, msg.DevID = d.AppID, d.DevID
+	msg.ID = d.ID
+	if d.Recurrence != "" {
+		msg.ID = fmt.Sprintf("%s.%d", d.ID, d.NextAt.Unix())
+	}
+	return &msg
+}
+
+// ToDownlinkMessage returns the downlink message as it was scheduled
+func (d *Downlink) ToDownlinkMessage() *types.DownlinkMessage {
+	msg := *d.Message
+	msg.ID = d.ID
+	at := types.JSONTime(d.NextAt)
+	msg.At = &at
+	msg.Recurrence = d.Recurrence
+	return &msg
+}
@@ -0,0 +1,140 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package scheduled
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/storage"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"gopkg.in/redis.v5"
+)
+
+// Store interface for scheduled Downlinks
+type Store interface {
+	List(appID, devID string) ([]*Downlink, error)
+	ListDue(t time.Time) ([]*Downlink, error)
+	Get(appID, devID, id string) (*Downlink, error)
+	Set(new *Downlink, properties ...string) (err error)
+	Delete(appID, devID, id string) error
+}
+
+const defaultRedisPrefix = "handler"
+const redisDownlinkPrefix = "scheduled-downlink"
+const redisDueKey = "scheduled-downlink-due"
+
+// NewRedisDownlinkStore creates a new Redis-based scheduled Downlink store
+// if an empty prefix is passed, a default prefix will be used.
+func NewRedisDownlinkStore(client *redis.Client, prefix string) Store {
+	if prefix == "" {
+		prefix = defaultRedisPrefix
+	}
+	store := storage.NewRedisMapStore(client, prefix+":"+redisDownlinkPrefix)
+	store.SetBase(Downlink{}, "")
+	return &RedisDownlinkStore{
+		client: client,
+		prefix: prefix,
+		store:  store,
+	}
+}
+
+// RedisDownlinkStore stores scheduled Downlinks in Redis.
+// - Downlinks are stored as a Hash
+// - The keys of the Downlinks are stored as a Sorted Set, scored by the time at which they are due
+type RedisDownlinkStore struct {
+	client *redis.Client
+	prefix string
+	store  *storage.RedisMapStore
+}
+
+func (s *RedisDownlinkStore) key(appID, devID, id string) string {
+	return fmt.Sprintf("%s:%s:%s", appID, devID, id)
+}
+
+func (s *RedisDownlinkStore) dueKey() string {
+	return fmt.Sprintf("%s:%s", s.prefix, redisDueKey)
+}
+
+// List the scheduled Downlinks of a Device
+func (s *RedisDownlinkStore) List(appID, devID string) ([]*Downlink, error) {
+	return s.list(fmt.Sprintf("%s:%s:*", appID, devID))
+}
+
+// ListDue lists the scheduled Downlinks of all Devices that are due at the given time
+func (s *RedisDownlinkStore) ListDue(t time.Time) ([]*Downlink, error) {
+	keys, err := s.client.ZRangeByScore(s.dueKey(), redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprint(t.Unix()),
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	downlinksI, err := s.store.GetAll(keys, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.downlinks(downlinksI), nil
+}
+
+func (s *RedisDownlinkStore) list(selector string) ([]*Downlink, error) {
+	downlinksI, err := s.store.List(selector, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.downlinks(downlinksI), nil
+}
+
+func (s *RedisDownlinkStore) downlinks(downlinksI []interface{}) []*Downlink {
+	downlinks := make([]*Downlink, 0, len(downlinksI))
+	for _, downlinkI := range downlinksI {
+		if downlink, ok := downlinkI.(Downlink); ok && downlink.ID != "" {
+			downlinks = append(downlinks, &downlink)
+		}
+	}
+	return downlinks
+}
+
+// Get a specific scheduled Downlink
+func (s *RedisDownlinkStore) Get(appID, devID, id string) (*Downlink, error) {
+	downlinkI, err := s.store.Get(s.key(appID, devID, id))
+	if err != nil {
+		return nil, err
+	}
+	if downlink, ok := downlinkI.(Downlink); ok {
+		return &downlink, nil
+	}
+	return nil, errors.New("Database did not return a Downlink")
+}
+
+// Set a new scheduled Downlink or update an existing one
+func (s *RedisDownlinkStore) Set(new *Downlink, properties ...string) (err error) {
+	now := time.Now()
+	new.UpdatedAt = now
+	if new.old == nil {
+		new.CreatedAt = now
+	}
+	if err := s.store.Set(s.key(new.AppID, new.DevID, new.ID), *new, properties...); err != nil {
+		return err
+	}
+	due := new.NextAt
+	if new.RetryAt.After(due) {
+		due = new.RetryAt
+	}
+	return s.client.ZAdd(s.dueKey(), redis.Z{
+		Score:  float64(due.Unix()),
+		Member: s.key(new.AppID, new.DevID, new.ID),
+	}).Err()
+}
+
+// Delete a scheduled Downlink
+func (s *RedisDownlinkStore) Delete(appID, devID, id string) error {
+	if err := s.store.Delete(s.key(appID, devID, id)); err != nil {
+		return err
+	}
+	return s.client.ZRem(s.dueKey(), s.key(appID, devID, id)).Err()
+}
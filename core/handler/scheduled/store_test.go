@@ -0,0 +1,96 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package scheduled
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestDownlinkStore(t *testing.T) {
+	a := New(t)
+
+	s := NewRedisDownlinkStore(GetRedisClient(), "handler-test-scheduled-store")
+
+	_, err := s.Get("test", "dev", "refresh")
+	a.So(err, ShouldNotBeNil)
+
+	nextAt := time.Date(2017, time.September, 2, 0, 0, 0, 0, time.UTC)
+	err = s.Set(&Downlink{
+		AppID:      "test",
+		DevID:      "dev",
+		ID:         "refresh",
+		Message:    &types.DownlinkMessage{FPort: 1, PayloadRaw: []byte{1, 2, 3}},
+		Recurrence: "@daily",
+		NextAt:     nextAt,
+	})
+	a.So(err, ShouldBeNil)
+	defer s.Delete("test", "dev", "refresh")
+
+	downlink, err := s.Get("test", "dev", "refresh")
+	a.So(err, ShouldBeNil)
+	a.So(downlink.Message.PayloadRaw, ShouldResemble, []byte{1, 2, 3})
+	a.So(downlink.NextAt.Equal(nextAt), ShouldBeTrue)
+	a.So(downlink.CreatedAt.IsZero(), ShouldBeFalse)
+
+	a.So(downlink.Due(nextAt.Add(-time.Second)), ShouldBeFalse)
+	a.So(downlink.Due(nextAt), ShouldBeTrue)
+
+	occurrence := downlink.Occurrence()
+	a.So(occurrence.AppID, ShouldEqual, "test")
+	a.So(occurrence.ID, ShouldEqual, "refresh.1504310400")
+
+	downlink.StartUpdate()
+	recurs, err := downlink.Reschedule(nextAt.Add(time.Minute))
+	a.So(err, ShouldBeNil)
+	a.So(recurs, ShouldBeTrue)
+	err = s.Set(downlink, "NextAt")
+	a.So(err, ShouldBeNil)
+
+	downlink, err = s.Get("test", "dev", "refresh")
+	a.So(err, ShouldBeNil)
+	a.So(downlink.NextAt.Equal(nextAt.Add(24*time.Hour)), ShouldBeTrue)
+
+	downlinks, err := s.List("test", "dev")
+	a.So(err, ShouldBeNil)
+	a.So(downlinks, ShouldHaveLength, 1)
+
+	// Only the downlinks that are due are listed
+	downlinks, err = s.ListDue(nextAt.Add(time.Hour))
+	a.So(err, ShouldBeNil)
+	a.So(downlinks, ShouldBeEmpty)
+
+	downlinks, err = s.ListDue(nextAt.Add(24 * time.Hour))
+	a.So(err, ShouldBeNil)
+	a.So(downlinks, ShouldHaveLength, 1)
+
+	// Downlinks that are retried are due at RetryAt
+	downlink.StartUpdate()
+	downlink.RetryAt = nextAt.Add(25 * time.Hour)
+	err = s.Set(downlink, "RetryAt")
+	a.So(err, ShouldBeNil)
+
+	downlinks, err = s.ListDue(nextAt.Add(24 * time.Hour))
+	a.So(err, ShouldBeNil)
+	a.So(downlinks, ShouldBeEmpty)
+
+	downlinks, err = s.ListDue(nextAt.Add(25 * time.Hour))
+	a.So(err, ShouldBeNil)
+	a.So(downlinks, ShouldHaveLength, 1)
+
+	err = s.Delete("test", "dev", "refresh")
+	a.So(err, ShouldBeNil)
+
+	downlinks, err = s.List("test", "dev")
+	a.So(err, ShouldBeNil)
+	a.So(downlinks, ShouldBeEmpty)
+
+	downlinks, err = s.ListDue(nextAt.Add(25 * time.Hour))
+	a.So(err, ShouldBeNil)
+	a.So(downlinks, ShouldBeEmpty)
+}
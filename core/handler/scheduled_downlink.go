@@ -0,0 +1,142 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"time"
+
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/scheduled"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/cron"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// DownlinkSchedulerInterval is the interval in which the Handler adds scheduled downlinks to the downlink queues
+var DownlinkSchedulerInterval = 10 * time.Second
+
+// ScheduledDownlinkRetries is the number of times the Handler retries to add an occurrence of a scheduled downlink to
+// the downlink queue after a temporary error. The delay between the attempts doubles with every attempt.
+var ScheduledDownlinkRetries = 5
+
+// scheduleDownlink stores a downlink message that has a time or recurrence, so that it is added to the downlink queue
+// of the device later
+func (h *handler) scheduleDownlink(ctx ttnlog.Interface, appDownlink *types.DownlinkMessage) error {
+	if h.scheduled == nil {
+		return errors.NewErrInternal("Scheduled downlinks are not enabled")
+	}
+
+	downlink := &scheduled.Downlink{
+		AppID:      appDownlink.AppID,
+		DevID:      appDownlink.DevID,
+		ID:         appDownlink.ID,
+		Recurrence: appDownlink.Recurrence,
+	}
+
+	if appDownlink.At != nil {
+		downlink.NextAt = time.Time(*appDownlink.At).UTC()
+	} else {
+		schedule, err := cron.Parse(appDownlink.Recurrence)
+		if err != nil {
+			return err
+		}
+		downlink.NextAt = schedule.Next(time.Now().UTC())
+		if downlink.NextAt.IsZero() {
+			return errors.NewErrInvalidArgument("Downlink Recurrence", "never occurs")
+		}
+	}
+	if downlink.Recurrence != "" {
+		if _, err := cron.Parse(downlink.Recurrence); err != nil {
+			return err
+		}
+	}
+
+	// The scheduled message is used as template for the message that is added to the queue
+	msg := *appDownlink
+	msg.ID, msg.AppID, msg.DevID = "", "", ""
+	msg.At, msg.Recurrence = nil, ""
+	downlink.Message = &msg
+
+	if err := h.scheduled.Set(downlink); err != nil {
+		return err
+	}
+
+	ctx.WithFields(ttnlog.Fields{
+		"DownlinkID": downlink.ID,
+		"NextAt":     downlink.NextAt,
+		"Recurrence": downlink.Recurrence,
+	}).Debug("Scheduled downlink")
+
+	select {
+	case h.qEvent <- &types.DeviceEvent{
+		AppID: downlink.AppID,
+		DevID: downlink.DevID,
+		Event: types.DownlinkScheduledEvent,
+		Data: types.DownlinkEventData{
+			Message: downlink.ToDownlinkMessage(),
+		},
+	}:
+	case <-time.After(eventPublishTimeout):
+		ctx.Warnf("Could not emit %q event", types.DownlinkScheduledEvent)
+	}
+	return nil
+}
+
+func (h *handler) runScheduledDownlinks() {
+	for now := range time.Tick(DownlinkSchedulerInterval) {
+		h.processScheduledDownlinks(now)
+	}
+}
+
+// processScheduledDownlinks adds the scheduled downlinks that are due to the downlink queues of the devices
+func (h *handler) processScheduledDownlinks(now time.Time) {
+	downlinks, err := h.scheduled.ListDue(now)
+	if err != nil {
+		h.Ctx.WithError(err).Warn("Could not list due scheduled downlinks")
+		return
+	}
+	for _, downlink := range downlinks {
+		if !downlink.Due(now) {
+			continue
+		}
+		ctx := h.Ctx.WithFields(ttnlog.Fields{
+			"AppID":      downlink.AppID,
+			"DevID":      downlink.DevID,
+			"DownlinkID": downlink.ID,
+		})
+		downlink.StartUpdate()
+		if err := h.processScheduledDownlink(ctx, downlink, now); err != nil {
+			ctx.WithError(err).Warn("Could not process scheduled downlink")
+		}
+	}
+}
+
+func (h *handler) processScheduledDownlink(ctx ttnlog.Interface, downlink *scheduled.Downlink, now time.Time) error {
+	err := h.EnqueueDownlink(downlink.Occurrence())
+	switch {
+	case errors.IsNotFound(err):
+		ctx.Debug("Device of scheduled downlink not found, deleting")
+		return h.scheduled.Delete(downlink.AppID, downlink.DevID, downlink.ID)
+	case errors.IsInvalidArgument(err):
+		// Retrying will not help, so the occurrence is skipped
+		ctx.WithError(err).Warn("Scheduled downlink is invalid, skipping occurrence")
+	case err != nil && downlink.Attempts < ScheduledDownlinkRetries:
+		downlink.RetryAt = now.Add(DownlinkSchedulerInterval << uint(downlink.Attempts))
+		downlink.Attempts++
+		ctx.WithError(err).WithField("RetryAt", downlink.RetryAt).Debug("Could not enqueue scheduled downlink, retrying")
+		return h.scheduled.Set(downlink, "Attempts", "RetryAt")
+	case err != nil:
+		ctx.WithError(err).Warn("Could not enqueue scheduled downlink, skipping occurrence")
+	}
+
+	// Missed occurrences are skipped, the downlink is rescheduled after the current time
+	recurs, err := downlink.Reschedule(now)
+	if err != nil {
+		return err
+	}
+	if !recurs {
+		return h.scheduled.Delete(downlink.AppID, downlink.DevID, downlink.ID)
+	}
+	return h.scheduled.Set(downlink, "NextAt", "Attempts", "RetryAt")
+}
@@ -0,0 +1,131 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/handler/scheduled"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestScheduledDownlinks(t *testing.T) {
+	a := New(t)
+	appID := "app1"
+	devID := "dev1"
+	h := &handler{
+		Component: &component.Component{Ctx: GetLogger(t, "TestScheduledDownlinks")},
+		devices:   device.NewRedisDeviceStore(GetRedisClient(), "handler-test-scheduled-downlinks"),
+		scheduled: scheduled.NewRedisDownlinkStore(GetRedisClient(), "handler-test-scheduled-downlinks"),
+		qEvent:    make(chan *types.DeviceEvent, 10),
+	}
+	h.devices.Set(&device.Device{AppID: appID, DevID: devID})
+	defer h.devices.Delete(appID, devID)
+	queue, _ := h.devices.DownlinkQueue(appID, devID)
+
+	err := h.EnqueueDownlink(&types.DownlinkMessage{
+		AppID:      appID,
+		DevID:      devID,
+		PayloadRaw: []byte{0x01},
+		Recurrence: "every day",
+	})
+	a.So(err, ShouldNotBeNil)
+
+	at := types.JSONTime(time.Now().Add(time.Hour))
+
+	// The schedule type is validated before the downlink is scheduled
+	err = h.EnqueueDownlink(&types.DownlinkMessage{
+		AppID:      appID,
+		DevID:      devID,
+		PayloadRaw: []byte{0x01},
+		Schedule:   "bogus",
+		At:         &at,
+	})
+	a.So(err, ShouldNotBeNil)
+
+	err = h.EnqueueDownlink(&types.DownlinkMessage{
+		ID:         "once",
+		AppID:      appID,
+		DevID:      devID,
+		PayloadRaw: []byte{0x01},
+		Schedule:   types.ScheduleLast,
+		TTL:        "1m",
+		At:         &at,
+	})
+	a.So(err, ShouldBeNil)
+	defer h.scheduled.Delete(appID, devID, "once")
+
+	err = h.EnqueueDownlink(&types.DownlinkMessage{
+		ID:         "daily",
+		AppID:      appID,
+		DevID:      devID,
+		PayloadRaw: []byte{0x02},
+		Schedule:   types.ScheduleLast,
+		Recurrence: "@daily",
+	})
+	a.So(err, ShouldBeNil)
+	defer h.scheduled.Delete(appID, devID, "daily")
+
+	qLen, _ := queue.Length()
+	a.So(qLen, ShouldEqual, 0)
+
+	downlinks, _ := h.scheduled.List(appID, devID)
+	a.So(downlinks, ShouldHaveLength, 2)
+
+	// Nothing is due yet
+	h.processScheduledDownlinks(time.Now())
+	qLen, _ = queue.Length()
+	a.So(qLen, ShouldEqual, 0)
+
+	later := time.Now().Add(25 * time.Hour)
+	h.processScheduledDownlinks(later)
+	queued, _ := queue.List()
+	a.So(queued, ShouldHaveLength, 2)
+
+	for _, msg := range queued {
+		a.So(msg.At, ShouldBeNil)
+		a.So(msg.Recurrence, ShouldBeEmpty)
+		if msg.ID == "once" {
+			a.So(msg.ExpiresAt, ShouldNotBeNil) // The TTL starts when the message is added to the queue
+		}
+	}
+
+	// The one-time downlink is removed, the daily downlink is rescheduled
+	_, err = h.scheduled.Get(appID, devID, "once")
+	a.So(err, ShouldNotBeNil)
+	daily, err := h.scheduled.Get(appID, devID, "daily")
+	a.So(err, ShouldBeNil)
+	a.So(daily.NextAt.After(later), ShouldBeTrue)
+
+	// Occurrences that can not be added to the queue are skipped instead of retried
+	invalid := &scheduled.Downlink{
+		AppID:      appID,
+		DevID:      devID,
+		ID:         "invalid",
+		Message:    &types.DownlinkMessage{PayloadRaw: []byte{0x03}, Schedule: "bogus"},
+		Recurrence: "@daily",
+		NextAt:     later,
+	}
+	h.scheduled.Set(invalid)
+	defer h.scheduled.Delete(appID, devID, "invalid")
+	h.processScheduledDownlinks(later)
+	invalid, err = h.scheduled.Get(appID, devID, "invalid")
+	a.So(err, ShouldBeNil)
+	a.So(invalid.NextAt.After(later), ShouldBeTrue)
+	a.So(invalid.Attempts, ShouldEqual, 0)
+	queued, _ = queue.List()
+	a.So(queued, ShouldHaveLength, 2)
+	h.scheduled.Delete(appID, devID, "invalid")
+
+	// Scheduled downlinks can be deleted with the downlink queue
+	err = h.deleteDownlink(appID, devID, "daily")
+	a.So(err, ShouldBeNil)
+	downlinks, _ = h.scheduled.List(appID, devID)
+	a.So(downlinks, ShouldBeEmpty)
+}
@@ -6,7 +6,9 @@ package handler
 import (
 	pb_broker "github.com/TheThingsNetwork/api/broker"
 	pb "github.com/TheThingsNetwork/api/handler"
+	"github.com/TheThingsNetwork/ttn/core/handler/fallback"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
+	gogo "github.com/gogo/protobuf/types"
 	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
 	"google.golang.org/grpc"
 )
@@ -45,8 +47,21 @@ func (h *handlerRPC) Activate(ctx context.Context, activation *pb_broker.Dedupli
 	return res, nil
 }
 
-// RegisterRPC registers this handler as a HandlerServer (github.com/TheThingsNetwork/api/handler)
+func (h *handlerRPC) Report(ctx context.Context, downlink *pb_broker.DownlinkMessage) (*gogo.Empty, error) {
+	_, err := h.handler.ValidateNetworkContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.handler.HandleDownlinkFallback(downlink); err != nil {
+		return nil, err
+	}
+	return &gogo.Empty{}, nil
+}
+
+// RegisterRPC registers this handler as a HandlerServer (github.com/TheThingsNetwork/api/handler) and as a
+// DownlinkFallbackServer
 func (h *handler) RegisterRPC(s *grpc.Server) {
 	server := &handlerRPC{h}
 	pb.RegisterHandlerServer(s, server)
+	fallback.RegisterDownlinkFallbackServer(s, server)
 }
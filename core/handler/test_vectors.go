@@ -0,0 +1,76 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/TheThingsNetwork/go-account-lib/rights"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/payload"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+)
+
+// checkTestVectors runs the test vectors of the application against its payload formatter
+func checkTestVectors(app *application.Application) error {
+	for i, err := range payload.RunTestVectors(app.AppID, app.GetPayloadFormatter(), app.TestVectors) {
+		if err != nil {
+			return errors.NewErrInvalidArgument("Payload Formatter", fmt.Sprintf("test vector %s failed: %s", app.TestVectors[i], err))
+		}
+	}
+	return nil
+}
+
+func (h *handlerManager) GetTestVectors(ctx context.Context, in *application.ApplicationIdentifier) (*application.ApplicationTestVectors, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	app, err := h.handler.applications.Get(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	return &application.ApplicationTestVectors{
+		AppID:       app.AppID,
+		TestVectors: app.TestVectors,
+	}, nil
+}
+
+func (h *handlerManager) SetTestVectors(ctx context.Context, in *application.ApplicationTestVectors) (*application.Empty, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Application Identifier", "must contain AppID")
+	}
+	for _, vector := range in.TestVectors {
+		if err := vector.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.AppSettings); err != nil {
+		return nil, err
+	}
+	app, err := h.handler.applications.Get(in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	app.StartUpdate()
+	app.TestVectors = in.TestVectors
+	if err := checkTestVectors(app); err != nil {
+		return nil, err
+	}
+	if err := h.handler.applications.Set(app, "TestVectors"); err != nil {
+		return nil, err
+	}
+	return &application.Empty{}, nil
+}
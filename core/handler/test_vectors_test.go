@@ -0,0 +1,27 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"testing"
+
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestCheckTestVectors(t *testing.T) {
+	a := New(t)
+
+	app := &application.Application{
+		AppID:         "app",
+		PayloadFormat: application.PayloadFormatCustom,
+		CustomDecoder: `function Decoder (bytes, port) { return { led: bytes[0] == 1, port: port }; }`,
+		TestVectors: []application.TestVector{
+			{FPort: 1, Payload: "01", Fields: map[string]interface{}{"led": true, "port": 1}},
+		},
+	}
+	a.So(checkTestVectors(app), ShouldBeNil)
+	app.CustomDecoder = `function Decoder (bytes, port) { return { led: false }; }`
+	a.So(checkTestVectors(app), ShouldNotBeNil)
+}
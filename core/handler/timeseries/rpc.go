@@ -0,0 +1,74 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package timeseries
+
+import (
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/jsoncodec"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+// GetUplinksRequest requests the stored uplink messages of an application, or of a device if DevID is set. From and To
+// are Unix nanoseconds; Last is the maximum number of (most recent) uplink messages.
+type GetUplinksRequest struct {
+	AppID string `json:"app_id"`
+	DevID string `json:"dev_id,omitempty"`
+	Last  int    `json:"last,omitempty"`
+	From  int64  `json:"from,omitempty"`
+	To    int64  `json:"to,omitempty"`
+}
+
+// Query returns the query of the request
+func (r *GetUplinksRequest) Query() (query Query) {
+	query.Limit = r.Last
+	if r.From != 0 {
+		query.From = time.Unix(0, r.From)
+	}
+	if r.To != 0 {
+		query.To = time.Unix(0, r.To)
+	}
+	return
+}
+
+// GetUplinksResponse contains the stored uplink messages in chronological order
+type GetUplinksResponse struct {
+	Uplinks []*types.UplinkMessage `json:"uplinks"`
+}
+
+// UplinkStorageManagerServer is the server API for the UplinkStorageManager service
+type UplinkStorageManagerServer interface {
+	GetUplinks(context.Context, *GetUplinksRequest) (*GetUplinksResponse, error)
+}
+
+// UplinkStorageManagerClient is the client API for the UplinkStorageManager service
+type UplinkStorageManagerClient interface {
+	GetUplinks(ctx context.Context, in *GetUplinksRequest, opts ...grpc.CallOption) (*GetUplinksResponse, error)
+}
+
+type uplinkStorageManagerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewUplinkStorageManagerClient returns a new UplinkStorageManagerClient
+func NewUplinkStorageManagerClient(cc *grpc.ClientConn) UplinkStorageManagerClient {
+	return &uplinkStorageManagerClient{cc}
+}
+
+func (c *uplinkStorageManagerClient) GetUplinks(ctx context.Context, in *GetUplinksRequest, opts ...grpc.CallOption) (*GetUplinksResponse, error) {
+	out := new(GetUplinksResponse)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/handler.UplinkStorageManager/GetUplinks", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var uplinkStorageManagerServiceDesc = jsoncodec.ServiceDesc("handler.UplinkStorageManager", (*UplinkStorageManagerServer)(nil))
+
+// RegisterUplinkStorageManagerServer registers the UplinkStorageManager service
+func RegisterUplinkStorageManagerServer(s *grpc.Server, srv UplinkStorageManagerServer) {
+	s.RegisterService(uplinkStorageManagerServiceDesc, srv)
+}
@@ -0,0 +1,139 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package timeseries implements the storage of uplink messages of the Handler. Uplink messages are stored per
+// application and per device in Redis sorted sets, scored by their time, and removed after the retention period.
+package timeseries
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"gopkg.in/redis.v5"
+)
+
+// DefaultRetention is the default time that uplink messages are stored
+var DefaultRetention = 7 * 24 * time.Hour
+
+// MaxLimit is the maximum number of uplink messages that can be requested at once
+var MaxLimit = 1000
+
+// Query for uplink messages
+type Query struct {
+	// From and To are the (inclusive) time range of the query. If zero, the range is not bounded.
+	From, To time.Time
+	// Limit is the maximum number of uplink messages that is returned. The most recent uplink messages are returned if
+	// the query matches more uplink messages.
+	Limit int
+}
+
+// Store stores uplink messages
+type Store interface {
+	// Add an uplink message
+	Add(msg *types.UplinkMessage) error
+	// Get the uplink messages of an application, or of a device if devID is not empty, in chronological order
+	Get(appID, devID string, query Query) ([]*types.UplinkMessage, error)
+}
+
+// NewRedisStore creates a new Redis-based uplink store
+func NewRedisStore(client *redis.Client, prefix string, retention time.Duration) Store {
+	if !strings.HasSuffix(prefix, ":") {
+		prefix += ":"
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &RedisStore{
+		client:    client,
+		prefix:    prefix,
+		retention: retention,
+	}
+}
+
+// RedisStore stores the uplink messages in Redis sorted sets
+type RedisStore struct {
+	client    *redis.Client
+	prefix    string
+	retention time.Duration
+}
+
+func (s *RedisStore) key(appID, devID string) string {
+	if devID == "" {
+		return fmt.Sprintf("%sapp:%s", s.prefix, appID)
+	}
+	return fmt.Sprintf("%sdev:%s:%s", s.prefix, appID, devID)
+}
+
+func score(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// Add implements the Store interface
+func (s *RedisStore) Add(msg *types.UplinkMessage) error {
+	if msg.AppID == "" || msg.DevID == "" {
+		return errors.NewErrInvalidArgument("Uplink", "must contain AppID and DevID")
+	}
+	t := time.Time(msg.Metadata.Time)
+	if t.IsZero() {
+		t = time.Now()
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	expired := score(time.Now().Add(-1 * s.retention))
+	pipe := s.client.Pipeline()
+	defer pipe.Close()
+	for _, key := range []string{s.key(msg.AppID, ""), s.key(msg.AppID, msg.DevID)} {
+		pipe.ZAdd(key, redis.Z{Score: float64(t.UnixNano()), Member: data})
+		pipe.ZRemRangeByScore(key, "-inf", "("+expired)
+		pipe.Expire(key, s.retention)
+	}
+	_, err = pipe.Exec()
+	return err
+}
+
+// Get implements the Store interface
+func (s *RedisStore) Get(appID, devID string, query Query) ([]*types.UplinkMessage, error) {
+	if appID == "" {
+		return nil, errors.NewErrInvalidArgument("Query", "must contain AppID")
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return nil, errors.NewErrInvalidArgument("Time range", "end before start")
+	}
+	if query.Limit < 0 {
+		return nil, errors.NewErrInvalidArgument("Limit", "can not be negative")
+	}
+	if query.Limit == 0 || query.Limit > MaxLimit {
+		query.Limit = MaxLimit
+	}
+	min, max := "-inf", "+inf"
+	if !query.From.IsZero() {
+		min = score(query.From)
+	}
+	if !query.To.IsZero() {
+		max = score(query.To)
+	}
+	res, err := s.client.ZRevRangeByScore(s.key(appID, devID), redis.ZRangeBy{
+		Min:   min,
+		Max:   max,
+		Count: int64(query.Limit),
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	uplinks := make([]*types.UplinkMessage, len(res))
+	for i, data := range res {
+		uplink := new(types.UplinkMessage)
+		if err := json.Unmarshal([]byte(data), uplink); err != nil {
+			return nil, err
+		}
+		uplinks[len(res)-1-i] = uplink
+	}
+	return uplinks, nil
+}
@@ -0,0 +1,80 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package timeseries
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestRedisStore(t *testing.T) {
+	a := New(t)
+
+	client := GetRedisClient()
+	s := NewRedisStore(client, "test-timeseries", time.Hour)
+	defer func() {
+		keys, _ := client.Keys("test-timeseries:*").Result()
+		for _, key := range keys {
+			client.Del(key)
+		}
+	}()
+
+	now := time.Now()
+	uplink := func(devID string, fCnt uint32, t time.Time) *types.UplinkMessage {
+		return &types.UplinkMessage{
+			AppID:         "app",
+			DevID:         devID,
+			FCnt:          fCnt,
+			PayloadRaw:    []byte{byte(fCnt)},
+			PayloadFields: map[string]interface{}{"counter": float64(fCnt)},
+			Metadata:      types.Metadata{Time: types.JSONTime(t)},
+		}
+	}
+
+	a.So(s.Add(&types.UplinkMessage{AppID: "app"}), ShouldNotBeNil)
+	a.So(s.Add(uplink("dev-1", 1, now.Add(-2*time.Hour))), ShouldBeNil) // Expired
+	a.So(s.Add(uplink("dev-1", 2, now.Add(-3*time.Minute))), ShouldBeNil)
+	a.So(s.Add(uplink("dev-2", 1, now.Add(-2*time.Minute))), ShouldBeNil)
+	a.So(s.Add(uplink("dev-1", 3, now.Add(-1*time.Minute))), ShouldBeNil)
+
+	uplinks, err := s.Get("app", "", Query{})
+	a.So(err, ShouldBeNil)
+	a.So(uplinks, ShouldHaveLength, 3)
+	a.So(uplinks[0].DevID, ShouldEqual, "dev-1")
+	a.So(uplinks[0].FCnt, ShouldEqual, 2)
+	a.So(uplinks[0].PayloadFields["counter"], ShouldEqual, 2)
+	a.So(uplinks[1].DevID, ShouldEqual, "dev-2")
+	a.So(uplinks[2].FCnt, ShouldEqual, 3)
+
+	// Last N
+	uplinks, err = s.Get("app", "", Query{Limit: 2})
+	a.So(err, ShouldBeNil)
+	a.So(uplinks, ShouldHaveLength, 2)
+	a.So(uplinks[0].DevID, ShouldEqual, "dev-2")
+	a.So(uplinks[1].FCnt, ShouldEqual, 3)
+
+	// Device
+	uplinks, err = s.Get("app", "dev-1", Query{})
+	a.So(err, ShouldBeNil)
+	a.So(uplinks, ShouldHaveLength, 2)
+
+	// Time range
+	uplinks, err = s.Get("app", "", Query{From: now.Add(-150 * time.Second), To: now.Add(-90 * time.Second)})
+	a.So(err, ShouldBeNil)
+	a.So(uplinks, ShouldHaveLength, 1)
+	a.So(uplinks[0].DevID, ShouldEqual, "dev-2")
+
+	_, err = s.Get("app", "", Query{From: now, To: now.Add(-1 * time.Minute)})
+	a.So(err, ShouldNotBeNil)
+	_, err = s.Get("", "", Query{})
+	a.So(err, ShouldNotBeNil)
+
+	uplinks, err = s.Get("other-app", "", Query{})
+	a.So(err, ShouldBeNil)
+	a.So(uplinks, ShouldBeEmpty)
+}
@@ -20,3 +20,7 @@ type DownlinkProcessor func(ctx ttnlog.Interface, appDown *types.DownlinkMessage
 
 // ErrNotNeeded indicates that the processing of a message should be aborted
 var ErrNotNeeded = errors.New("Further processing not needed")
+
+// ErrNotPublished indicates that the message was handled by the Handler, so that further processing is not needed and
+// the message is not published to the application
+var ErrNotPublished = errors.New("Message handled by the Handler")
@@ -65,7 +65,9 @@ func (h *handler) HandleUplink(uplink *pb_broker.DeduplicatedUplinkMessage) (err
 	// Get Uplink Processors
 	processors := []UplinkProcessor{
 		h.ConvertFromLoRaWAN,
+		h.HandleFUOTAUplink,
 		h.ConvertMetadata,
+		h.HandleClockSync,
 		h.ConvertFieldsUp,
 	}
 
@@ -73,11 +75,16 @@ func (h *handler) HandleUplink(uplink *pb_broker.DeduplicatedUplinkMessage) (err
 	uplink.Trace = uplink.Trace.WithEvent("process uplink")
 
 	// Run Uplink Processors
+	publish := true
 	for _, processor := range processors {
 		err = processor(ctx, uplink, appUplink, dev)
 		if err == ErrNotNeeded {
 			err = nil
 			return nil
+		} else if err == ErrNotPublished {
+			err = nil
+			publish = false
+			break
 		} else if err != nil {
 			return err
 		}
@@ -90,7 +97,9 @@ func (h *handler) HandleUplink(uplink *pb_broker.DeduplicatedUplinkMessage) (err
 	dev.StartUpdate()
 
 	// Publish Uplink
-	h.qUp <- appUplink
+	if publish {
+		h.qUp <- appUplink
+	}
 
 	noDownlinkErrEvent := &types.DeviceEvent{
 		AppID: appID,
@@ -99,6 +108,15 @@ func (h *handler) HandleUplink(uplink *pb_broker.DeduplicatedUplinkMessage) (err
 		Data:  types.ErrorEventData{Error: "No gateways available for downlink"},
 	}
 
+	if dev.CurrentDownlink != nil && dev.CurrentDownlink.Expired(time.Now()) {
+		h.emitExpiredDownlink(ctx, appID, devID, dev.CurrentDownlink)
+		dev.CurrentDownlink = nil
+		if err = h.devices.Set(dev, "CurrentDownlink"); err != nil {
+			return err
+		}
+		dev.StartUpdate()
+	}
+
 	if dev.CurrentDownlink == nil {
 		<-time.After(ResponseDeadline)
 
@@ -109,11 +127,12 @@ func (h *handler) HandleUplink(uplink *pb_broker.DeduplicatedUplinkMessage) (err
 
 		if len, _ := queue.Length(); len > 0 {
 			if uplink.ResponseTemplate != nil {
-				next, err := queue.Next()
+				next, err := h.nextDownlink(ctx, appID, devID, queue)
 				if err != nil {
 					return err
 				}
 				dev.CurrentDownlink = next
+				dev.CurrentDownlinkAttempts = 0
 			} else {
 				select {
 				case h.qEvent <- noDownlinkErrEvent:
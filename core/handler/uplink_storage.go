@@ -0,0 +1,123 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheThingsNetwork/go-account-lib/rights"
+	"github.com/TheThingsNetwork/ttn/core/handler/timeseries"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+)
+
+// UplinkStorageBufferSize indicates the size for the uplink storage channel buffer
+var UplinkStorageBufferSize = 100
+
+func (h *handler) WithUplinkStorage(store timeseries.Store) Handler {
+	h.uplinkStore = store
+	return h
+}
+
+// HandleUplinkStorage starts storing uplink messages in the uplink store
+func (h *handler) HandleUplinkStorage() {
+	h.uplinkStoreUp = make(chan *types.UplinkMessage, UplinkStorageBufferSize)
+	go func() {
+		for up := range h.uplinkStoreUp {
+			if err := h.uplinkStore.Add(up); err != nil {
+				h.Ctx.WithField("AppID", up.AppID).WithField("DevID", up.DevID).WithError(err).Warn("Could not store uplink")
+			}
+		}
+	}()
+}
+
+func (h *handlerManager) GetUplinks(ctx context.Context, in *timeseries.GetUplinksRequest) (*timeseries.GetUplinksResponse, error) {
+	if in.AppID == "" {
+		return nil, errors.NewErrInvalidArgument("Uplinks Request", "must contain AppID")
+	}
+	_, claims, err := h.validateTTNAuthAppContext(ctx, in.AppID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppRights(claims, in.AppID, rights.ReadUplink); err != nil {
+		return nil, err
+	}
+	if h.handler.uplinkStore == nil {
+		return nil, errors.NewErrInternal("Uplink storage is not enabled on this Handler")
+	}
+	uplinks, err := h.handler.uplinkStore.Get(in.AppID, in.DevID, in.Query())
+	if err != nil {
+		return nil, err
+	}
+	return &timeseries.GetUplinksResponse{Uplinks: uplinks}, nil
+}
+
+// UplinkStorageHandler returns the HTTP handler for querying stored uplink messages:
+//
+//	GET /uplinks/{app_id}          returns the uplink messages of the application
+//	GET /uplinks/{app_id}/{dev_id} returns the uplink messages of the device
+//
+// The optional query parameters "last" (number of most recent messages), "from" and "to" (RFC3339 timestamps) filter
+// the uplink messages. Requests are authenticated by a token or access key of the application in the Authorization
+// header.
+func (h *handler) UplinkStorageHandler() http.Handler {
+	return http.HandlerFunc(h.serveUplinkStorageHTTP)
+}
+
+func (h *handler) serveUplinkStorageHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/uplinks"), "/"), "/")
+	if r.Method != http.MethodGet || parts[0] == "" || len(parts) > 2 {
+		writeHTTPError(w, errors.NewErrNotFound(r.URL.Path))
+		return
+	}
+	appID := parts[0]
+	var devID string
+	if len(parts) == 2 {
+		devID = parts[1]
+	}
+	if err := h.getUplinks(w, r, appID, devID); err != nil {
+		writeHTTPError(w, err)
+	}
+}
+
+func (h *handler) getUplinks(w http.ResponseWriter, r *http.Request, appID, devID string) error {
+	if err := h.validateHTTPAuth(r, appID, rights.ReadUplink); err != nil {
+		return err
+	}
+	var query timeseries.Query
+	values := r.URL.Query()
+	if last := values.Get("last"); last != "" {
+		limit, err := strconv.Atoi(last)
+		if err != nil {
+			return errors.NewErrInvalidArgument("last", err.Error())
+		}
+		query.Limit = limit
+	}
+	var err error
+	if query.From, err = parseTimeParam(values.Get("from")); err != nil {
+		return errors.NewErrInvalidArgument("from", err.Error())
+	}
+	if query.To, err = parseTimeParam(values.Get("to")); err != nil {
+		return errors.NewErrInvalidArgument("to", err.Error())
+	}
+	if h.uplinkStore == nil {
+		return errors.NewErrNotFound("Uplink storage")
+	}
+	uplinks, err := h.uplinkStore.Get(appID, devID, query)
+	if err != nil {
+		return err
+	}
+	return writeHTTPJSON(w, http.StatusOK, uplinks)
+}
+
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
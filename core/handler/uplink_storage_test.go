@@ -0,0 +1,72 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/handler/timeseries"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+	"golang.org/x/net/context"
+)
+
+func TestHandleUplinkStorage(t *testing.T) {
+	a := New(t)
+
+	client := GetRedisClient()
+	defer func() {
+		keys, _ := client.Keys("handler-test-uplink-storage:*").Result()
+		for _, key := range keys {
+			client.Del(key)
+		}
+	}()
+
+	appID, devID := "uplink-storage-app", "dev"
+	h := &handler{
+		Component: &component.Component{Ctx: GetLogger(t, "TestHandleUplinkStorage")},
+	}
+	h.WithUplinkStorage(timeseries.NewRedisStore(client, "handler-test-uplink-storage", time.Hour))
+	h.HandleUplinkStorage()
+
+	h.uplinkStoreUp <- &types.UplinkMessage{AppID: appID, DevID: devID, FCnt: 1, PayloadRaw: []byte{0x01}}
+	h.uplinkStoreUp <- &types.UplinkMessage{AppID: appID, DevID: devID, FCnt: 2, PayloadRaw: []byte{0x02}}
+	<-time.After(50 * time.Millisecond)
+
+	uplinks, err := h.uplinkStore.Get(appID, devID, timeseries.Query{Limit: 1})
+	a.So(err, ShouldBeNil)
+	a.So(uplinks, ShouldHaveLength, 1)
+	a.So(uplinks[0].FCnt, ShouldEqual, 2)
+
+	// The RPC requires authentication
+	m := &handlerManager{handler: h}
+	_, err = m.GetUplinks(context.Background(), &timeseries.GetUplinksRequest{})
+	a.So(err, ShouldNotBeNil)
+	_, err = m.GetUplinks(context.Background(), &timeseries.GetUplinksRequest{AppID: appID})
+	a.So(err, ShouldNotBeNil)
+
+	server := httptest.NewServer(h.UplinkStorageHandler())
+	defer server.Close()
+
+	do := func(method, path string) int {
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	a.So(do("GET", "/uplinks/"+appID), ShouldEqual, http.StatusForbidden)
+	a.So(do("GET", "/uplinks/"+appID+"/"+devID+"?last=1"), ShouldEqual, http.StatusForbidden)
+	a.So(do("GET", "/uplinks/"), ShouldEqual, http.StatusNotFound)
+	a.So(do("GET", "/uplinks/"+appID+"/"+devID+"/other"), ShouldEqual, http.StatusNotFound)
+	a.So(do("POST", "/uplinks/"+appID), ShouldEqual, http.StatusNotFound)
+}
@@ -189,4 +189,26 @@ func TestHandleUplink(t *testing.T) {
 	a.So(next.PayloadRaw, ShouldResemble, []byte{0x12, 0x34})
 	a.So(dev.CurrentDownlink, ShouldNotBeNil)
 	a.So(dev.CurrentDownlink.PayloadRaw, ShouldResemble, []byte{0xaa, 0xbc})
+
+	expired := types.JSONTime(time.Now().Add(-1 * time.Minute))
+	dev.StartUpdate()
+	dev.CurrentDownlink = &types.DownlinkMessage{PayloadRaw: []byte{0xaa, 0xbc}, Confirmed: true, ExpiresAt: &expired}
+
+	// Test Uplink, expired downlink is cleared without downlink option
+	{
+		h.devices.Set(dev)
+		wg.Add(1)
+		go func() {
+			<-h.qUp
+			wg.Done()
+		}()
+		uplink := getUplink()
+		uplink.ResponseTemplate = nil
+		err = h.HandleUplink(uplink)
+		a.So(err, ShouldBeNil)
+		wg.WaitFor(50 * time.Millisecond)
+	}
+
+	dev, _ = h.devices.Get(appID, devID)
+	a.So(dev.CurrentDownlink, ShouldBeNil)
 }
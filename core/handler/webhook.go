@@ -0,0 +1,387 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TheThingsNetwork/go-account-lib/rights"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/go-utils/random"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/backoff"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// WebhookBufferSize indicates the size of the queue of messages for webhooks. Messages are dropped if the queue is full.
+var WebhookBufferSize = 1000
+
+// WebhookWorkers indicates the number of workers that deliver messages to webhooks
+var WebhookWorkers = 4
+
+// WebhookRetries indicates how often the delivery of a message to a webhook is retried
+var WebhookRetries = 5
+
+// WebhookBackoff is the backoff configuration for retrying the delivery of messages to webhooks
+var WebhookBackoff = backoff.Config{
+	MaxDelay:  30 * time.Second,
+	BaseDelay: 500 * time.Millisecond,
+	Factor:    1.6,
+	Jitter:    0.2,
+}
+
+// WebhookTimeout is the timeout of requests to webhooks
+var WebhookTimeout = 5 * time.Second
+
+// WebhookCacheTTL indicates how long the webhooks of an application are cached
+var WebhookCacheTTL = 10 * time.Second
+
+// WebhookEvent is the body of event messages that are sent to webhooks
+type WebhookEvent struct {
+	AppID string          `json:"app_id"`
+	DevID string          `json:"dev_id,omitempty"`
+	Event types.EventType `json:"event"`
+	Data  interface{}     `json:"data,omitempty"`
+}
+
+type webhookMessage struct {
+	appID string
+	up    *types.UplinkMessage
+	event *types.DeviceEvent
+}
+
+// webhookDelivery is the delivery of a message to a single webhook
+type webhookDelivery struct {
+	ctx     ttnlog.Interface
+	webhook application.Webhook
+	path    string
+	data    []byte
+	retry   int
+}
+
+type cachedWebhooks struct {
+	webhooks []application.Webhook
+	expires  time.Time
+}
+
+// webhookCache caches the webhooks of applications, so that the application does not have to be loaded for every message
+type webhookCache struct {
+	sync.Mutex
+	apps map[string]*cachedWebhooks
+}
+
+func (c *webhookCache) get(appID string) ([]application.Webhook, bool) {
+	c.Lock()
+	defer c.Unlock()
+	cached, ok := c.apps[appID]
+	if !ok || time.Now().After(cached.expires) {
+		return nil, false
+	}
+	return cached.webhooks, true
+}
+
+func (c *webhookCache) set(appID string, webhooks []application.Webhook) {
+	now := time.Now()
+	c.Lock()
+	defer c.Unlock()
+	if c.apps == nil {
+		c.apps = make(map[string]*cachedWebhooks)
+	}
+	for appID, cached := range c.apps {
+		if now.After(cached.expires) {
+			delete(c.apps, appID)
+		}
+	}
+	c.apps[appID] = &cachedWebhooks{webhooks: webhooks, expires: now.Add(WebhookCacheTTL)}
+}
+
+func (c *webhookCache) invalidate(appID string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.apps, appID)
+}
+
+func (h *handler) WithWebhooks() Handler {
+	h.webhooksEnabled = true
+	return h
+}
+
+// HandleWebhooks starts the workers that deliver messages to the webhooks of applications. Failed deliveries are
+// retried with a timer, so that unavailable webhooks do not block the workers.
+func (h *handler) HandleWebhooks() {
+	h.webhookQueue = make(chan *webhookMessage, WebhookBufferSize)
+	h.webhookRetries = make(chan *webhookDelivery, WebhookBufferSize)
+	if h.webhookClient == nil {
+		h.webhookClient = &http.Client{Timeout: WebhookTimeout}
+	}
+	for i := 0; i < WebhookWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case msg := <-h.webhookQueue:
+					h.deliverWebhookMessage(msg)
+				case delivery := <-h.webhookRetries:
+					h.deliverWebhook(delivery)
+				}
+			}
+		}()
+	}
+}
+
+// enqueueWebhookMessage adds the message to the webhook queue without blocking
+func (h *handler) enqueueWebhookMessage(msg *webhookMessage) {
+	select {
+	case h.webhookQueue <- msg:
+	default:
+		h.Ctx.WithField("AppID", msg.appID).Warn("Webhook queue full, dropping message")
+	}
+}
+
+// getWebhooks returns the (cached) webhooks of the application
+func (h *handler) getWebhooks(appID string) ([]application.Webhook, error) {
+	if webhooks, ok := h.webhookCache.get(appID); ok {
+		return webhooks, nil
+	}
+	app, err := h.applications.Get(appID)
+	if err != nil && errors.GetErrType(err) != errors.NotFound {
+		return nil, err
+	}
+	var webhooks []application.Webhook
+	if app != nil {
+		webhooks = app.Webhooks
+	}
+	h.webhookCache.set(appID, webhooks)
+	return webhooks, nil
+}
+
+func (h *handler) deliverWebhookMessage(msg *webhookMessage) {
+	ctx := h.Ctx.WithField("AppID", msg.appID)
+	webhooks, err := h.getWebhooks(msg.appID)
+	if err != nil {
+		ctx.WithError(err).Warn("Could not get webhooks")
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	var body interface{}
+	if msg.up != nil {
+		body = msg.up
+		ctx = ctx.WithField("DevID", msg.up.DevID)
+	} else {
+		body = &WebhookEvent{AppID: msg.event.AppID, DevID: msg.event.DevID, Event: msg.event.Event, Data: msg.event.Data}
+		ctx = ctx.WithFields(ttnlog.Fields{"DevID": msg.event.DevID, "Event": msg.event.Event})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		ctx.WithError(err).Warn("Could not marshal webhook message")
+		return
+	}
+
+	for _, webhook := range webhooks {
+		path := webhook.EventsPath
+		switch {
+		case msg.up != nil:
+			path = webhook.UplinkPath
+		case msg.event.Event == types.ActivationEvent:
+			path = webhook.ActivationsPath
+		}
+		if path == "" {
+			continue
+		}
+		h.deliverWebhook(&webhookDelivery{
+			ctx:     ctx.WithField("WebhookID", webhook.WebhookID),
+			webhook: webhook,
+			path:    path,
+			data:    data,
+		})
+	}
+}
+
+// deliverWebhook sends the message to the webhook. Temporary failures are retried after a backoff delay.
+func (h *handler) deliverWebhook(delivery *webhookDelivery) {
+	err := h.postWebhook(delivery.webhook, delivery.path, delivery.data)
+	if err == nil {
+		delivery.ctx.Debug("Delivered message to webhook")
+		return
+	}
+	if errors.GetErrType(err) == errors.InvalidArgument || delivery.retry >= WebhookRetries {
+		delivery.ctx.WithError(err).Warn("Could not deliver message to webhook")
+		return
+	}
+	delivery.ctx.WithError(err).Debug("Could not deliver message to webhook, retrying")
+	time.AfterFunc(WebhookBackoff.Backoff(delivery.retry), func() {
+		delivery.retry++
+		select {
+		case h.webhookRetries <- delivery:
+		default:
+			delivery.ctx.Warn("Webhook retry queue full, dropping message")
+		}
+	})
+}
+
+func (h *handler) postWebhook(webhook application.Webhook, path string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL(path), bytes.NewReader(data))
+	if err != nil {
+		return errors.NewErrInvalidArgument("Webhook URL", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, header := range webhook.Headers {
+		req.Header.Set(header.Name, header.Value)
+	}
+	res, err := h.webhookClient.Do(req)
+	if err != nil {
+		return errors.NewErrUnavailable(err.Error())
+	}
+	res.Body.Close()
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return nil
+	case res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests:
+		// Client errors will not be fixed by retrying
+		return errors.NewErrInvalidArgument("Webhook", fmt.Sprintf("returned %s", res.Status))
+	default:
+		return errors.NewErrUnavailable(fmt.Sprintf("Webhook returned %s", res.Status))
+	}
+}
+
+// WebhookHandler returns the HTTP handler for managing webhooks and receiving downlink messages from webhooks:
+//
+//	GET    /webhooks/{app_id}                   lists the webhooks of the application
+//	PUT    /webhooks/{app_id}/{webhook_id}      creates or updates a webhook
+//	DELETE /webhooks/{app_id}/{webhook_id}      deletes a webhook
+//	POST   /webhooks/{app_id}/{webhook_id}/down enqueues a downlink message (authenticated by X-Downlink-Key)
+//
+// Management requests are authenticated by a token or access key of the application in the Authorization header.
+func (h *handler) WebhookHandler() http.Handler {
+	return http.HandlerFunc(h.serveWebhookHTTP)
+}
+
+func (h *handler) serveWebhookHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/webhooks"), "/"), "/")
+	var err error
+	switch {
+	case len(parts) == 1 && parts[0] != "" && r.Method == http.MethodGet:
+		err = h.listWebhooks(w, r, parts[0])
+	case len(parts) == 2 && r.Method == http.MethodPut:
+		err = h.setWebhook(w, r, parts[0], parts[1])
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		err = h.deleteWebhook(w, r, parts[0], parts[1])
+	case len(parts) == 3 && parts[2] == "down" && r.Method == http.MethodPost:
+		err = h.webhookDownlink(w, r, parts[0], parts[1])
+	default:
+		err = errors.NewErrNotFound(r.URL.Path)
+	}
+	if err != nil {
+		writeHTTPError(w, err)
+	}
+}
+
+func (h *handler) listWebhooks(w http.ResponseWriter, r *http.Request, appID string) error {
+	if err := h.validateHTTPAuth(r, appID, rights.AppSettings); err != nil {
+		return err
+	}
+	app, err := h.applications.Get(appID)
+	if err != nil {
+		return err
+	}
+	webhooks := app.Webhooks
+	if webhooks == nil {
+		webhooks = []application.Webhook{}
+	}
+	return writeHTTPJSON(w, http.StatusOK, webhooks)
+}
+
+func (h *handler) setWebhook(w http.ResponseWriter, r *http.Request, appID, webhookID string) error {
+	if err := h.validateHTTPAuth(r, appID, rights.AppSettings); err != nil {
+		return err
+	}
+	var webhook application.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		return errors.NewErrInvalidArgument("Webhook", err.Error())
+	}
+	webhook.WebhookID = webhookID
+	if err := webhook.Validate(); err != nil {
+		return err
+	}
+	app, err := h.applications.Get(appID)
+	if err != nil {
+		return err
+	}
+	if webhook.DownlinkKey == "" {
+		if existing := app.GetWebhook(webhookID); existing != nil {
+			webhook.DownlinkKey = existing.DownlinkKey
+		} else {
+			key := make([]byte, 24)
+			random.FillBytes(key)
+			webhook.DownlinkKey = base64.RawURLEncoding.EncodeToString(key)
+		}
+	}
+	app.StartUpdate()
+	app.SetWebhook(webhook)
+	if err := h.applications.Set(app); err != nil {
+		return err
+	}
+	h.webhookCache.invalidate(appID)
+	return writeHTTPJSON(w, http.StatusOK, webhook)
+}
+
+func (h *handler) deleteWebhook(w http.ResponseWriter, r *http.Request, appID, webhookID string) error {
+	if err := h.validateHTTPAuth(r, appID, rights.AppSettings); err != nil {
+		return err
+	}
+	app, err := h.applications.Get(appID)
+	if err != nil {
+		return err
+	}
+	if app.GetWebhook(webhookID) == nil {
+		return errors.NewErrNotFound(fmt.Sprintf("Webhook %s", webhookID))
+	}
+	app.StartUpdate()
+	app.DeleteWebhook(webhookID)
+	if err := h.applications.Set(app); err != nil {
+		return err
+	}
+	h.webhookCache.invalidate(appID)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *handler) webhookDownlink(w http.ResponseWriter, r *http.Request, appID, webhookID string) error {
+	app, err := h.applications.Get(appID)
+	if err != nil {
+		return err
+	}
+	webhook := app.GetWebhook(webhookID)
+	if webhook == nil {
+		return errors.NewErrNotFound(fmt.Sprintf("Webhook %s", webhookID))
+	}
+	key := r.Header.Get("X-Downlink-Key")
+	if webhook.DownlinkKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(webhook.DownlinkKey)) != 1 {
+		return errors.NewErrPermissionDenied("Invalid downlink key")
+	}
+	var downlink types.DownlinkMessage
+	if err := json.NewDecoder(r.Body).Decode(&downlink); err != nil {
+		return errors.NewErrInvalidArgument("Downlink", err.Error())
+	}
+	if downlink.DevID == "" {
+		return errors.NewErrInvalidArgument("Downlink", "dev_id missing")
+	}
+	downlink.AppID = appID
+	if err := h.EnqueueDownlink(&downlink); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
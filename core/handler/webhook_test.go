@@ -0,0 +1,211 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package handler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	a := New(t)
+
+	var (
+		mu       sync.Mutex
+		requests = make(map[string][]string)
+		failures = 1
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/up" && failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		requests[r.URL.Path] = append(requests[r.URL.Path], r.Header.Get("X-Secret")+" "+string(body))
+	}))
+	defer server.Close()
+
+	oldBackoff := WebhookBackoff
+	WebhookBackoff.BaseDelay, WebhookBackoff.MaxDelay = time.Millisecond, time.Millisecond
+	defer func() { WebhookBackoff = oldBackoff }()
+
+	appID := "webhook-app"
+	h := &handler{
+		Component:    &component.Component{Ctx: GetLogger(t, "TestWebhookDelivery")},
+		applications: application.NewRedisApplicationStore(GetRedisClient(), "handler-test-webhook-delivery"),
+	}
+	h.HandleWebhooks()
+	h.applications.Set(&application.Application{AppID: appID, Webhooks: []application.Webhook{
+		{WebhookID: "test", BaseURL: server.URL + "/", UplinkPath: "up", ActivationsPath: "/activations"},
+	}})
+	defer h.applications.Delete(appID)
+
+	app, _ := h.applications.Get(appID)
+	webhook := app.GetWebhook("test")
+	a.So(webhook, ShouldNotBeNil)
+	webhook.Headers = []application.WebhookHeader{{Name: "X-Secret", Value: "secret"}}
+	h.webhookCache.set(appID, []application.Webhook{*webhook})
+
+	// Uplink is retried after failure
+	h.enqueueWebhookMessage(&webhookMessage{appID: appID, up: &types.UplinkMessage{AppID: appID, DevID: "dev"}})
+	h.enqueueWebhookMessage(&webhookMessage{appID: appID, event: &types.DeviceEvent{AppID: appID, DevID: "dev", Event: types.ActivationEvent}})
+	h.enqueueWebhookMessage(&webhookMessage{appID: appID, event: &types.DeviceEvent{AppID: appID, DevID: "dev", Event: types.DownlinkSentEvent}})
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	a.So(failures, ShouldEqual, 0)
+	a.So(requests["/up"], ShouldHaveLength, 1)
+	a.So(requests["/up"][0], ShouldStartWith, "secret ")
+	a.So(requests["/activations"], ShouldHaveLength, 1)
+	a.So(requests["/activations"][0], ShouldContainSubstring, `"event":"activations"`)
+	a.So(requests, ShouldHaveLength, 2) // No events path
+
+	// Client errors are not retried
+	var attempts int
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	mu.Unlock()
+	h.enqueueWebhookMessage(&webhookMessage{appID: appID, up: &types.UplinkMessage{AppID: appID, DevID: "dev"}})
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	a.So(attempts, ShouldEqual, 1)
+}
+
+func TestWebhookRetryDoesNotBlock(t *testing.T) {
+	a := New(t)
+
+	var (
+		mu        sync.Mutex
+		delivered []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/dead" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		delivered = append(delivered, r.URL.Path)
+	}))
+	defer server.Close()
+
+	oldBackoff, oldWorkers := WebhookBackoff, WebhookWorkers
+	WebhookBackoff.BaseDelay, WebhookBackoff.MaxDelay = time.Hour, time.Hour
+	WebhookWorkers = 1
+	defer func() { WebhookBackoff, WebhookWorkers = oldBackoff, oldWorkers }()
+
+	h := &handler{Component: &component.Component{Ctx: GetLogger(t, "TestWebhookRetryDoesNotBlock")}}
+	h.HandleWebhooks()
+	h.webhookCache.set("dead-app", []application.Webhook{{WebhookID: "dead", BaseURL: server.URL, UplinkPath: "/dead"}})
+	h.webhookCache.set("live-app", []application.Webhook{{WebhookID: "live", BaseURL: server.URL, UplinkPath: "/live"}})
+
+	// The unavailable webhook of one application does not delay the webhooks of other applications
+	for i := 0; i < 3; i++ {
+		h.enqueueWebhookMessage(&webhookMessage{appID: "dead-app", up: &types.UplinkMessage{AppID: "dead-app", DevID: "dev"}})
+	}
+	h.enqueueWebhookMessage(&webhookMessage{appID: "live-app", up: &types.UplinkMessage{AppID: "live-app", DevID: "dev"}})
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	a.So(delivered, ShouldResemble, []string{"/live"})
+}
+
+func TestWebhookCache(t *testing.T) {
+	a := New(t)
+
+	oldTTL := WebhookCacheTTL
+	defer func() { WebhookCacheTTL = oldTTL }()
+
+	var c webhookCache
+	_, ok := c.get("app")
+	a.So(ok, ShouldBeFalse)
+
+	c.set("app", []application.Webhook{{WebhookID: "test"}})
+	webhooks, ok := c.get("app")
+	a.So(ok, ShouldBeTrue)
+	a.So(webhooks, ShouldHaveLength, 1)
+
+	c.invalidate("app")
+	_, ok = c.get("app")
+	a.So(ok, ShouldBeFalse)
+
+	WebhookCacheTTL = -time.Second
+	c.set("app", nil)
+	_, ok = c.get("app")
+	a.So(ok, ShouldBeFalse)
+}
+
+func TestWebhookHTTP(t *testing.T) {
+	a := New(t)
+
+	appID, devID := "webhook-http-app", "dev"
+	h := &handler{
+		Component:    &component.Component{Ctx: GetLogger(t, "TestWebhookHTTP")},
+		applications: application.NewRedisApplicationStore(GetRedisClient(), "handler-test-webhook-http"),
+		devices:      device.NewRedisDeviceStore(GetRedisClient(), "handler-test-webhook-http"),
+		qEvent:       make(chan *types.DeviceEvent, 10),
+	}
+	h.applications.Set(&application.Application{AppID: appID, Webhooks: []application.Webhook{
+		{WebhookID: "test", BaseURL: "https://example.com", DownlinkKey: "key"},
+	}})
+	defer h.applications.Delete(appID)
+	h.devices.Set(&device.Device{AppID: appID, DevID: devID})
+	defer h.devices.Delete(appID, devID)
+
+	server := httptest.NewServer(h.WebhookHandler())
+	defer server.Close()
+
+	do := func(method, path, key, body string) int {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-Downlink-Key", key)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// Management requires authentication
+	a.So(do("GET", "/webhooks/"+appID, "", ""), ShouldEqual, http.StatusForbidden)
+	a.So(do("PUT", "/webhooks/"+appID+"/test", "", "{}"), ShouldEqual, http.StatusForbidden)
+	a.So(do("DELETE", "/webhooks/"+appID+"/test", "", ""), ShouldEqual, http.StatusForbidden)
+	a.So(do("GET", "/webhooks/", "", ""), ShouldEqual, http.StatusNotFound)
+
+	// Downlink requires the downlink key of the webhook
+	downlink, _ := json.Marshal(types.DownlinkMessage{DevID: devID, FPort: 1, PayloadRaw: []byte{1, 2, 3}})
+	a.So(do("POST", "/webhooks/"+appID+"/other/down", "key", string(downlink)), ShouldEqual, http.StatusNotFound)
+	a.So(do("POST", "/webhooks/"+appID+"/test/down", "", string(downlink)), ShouldEqual, http.StatusForbidden)
+	a.So(do("POST", "/webhooks/"+appID+"/test/down", "wrong", string(downlink)), ShouldEqual, http.StatusForbidden)
+	a.So(do("POST", "/webhooks/"+appID+"/test/down", "key", `{"port":1}`), ShouldEqual, http.StatusBadRequest)
+	a.So(do("POST", "/webhooks/"+appID+"/test/down", "key", string(downlink)), ShouldEqual, http.StatusAccepted)
+
+	queue, _ := h.devices.DownlinkQueue(appID, devID)
+	length, _ := queue.Length()
+	a.So(length, ShouldEqual, 1)
+}
@@ -0,0 +1,53 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package multicast contains the storage of multicast groups that is shared by the Handler and the NetworkServer
+package multicast
+
+import (
+	"fmt"
+
+	"github.com/TheThingsNetwork/ttn/core/storage"
+	"gopkg.in/redis.v5"
+)
+
+const redisGroupPrefix = "multicast"
+
+// NewRedisGroupStore creates a new Redis-based store for multicast groups of the same type as base
+func NewRedisGroupStore(client *redis.Client, prefix string, base interface{}) *RedisGroupStore {
+	store := storage.NewRedisMapStore(client, prefix+":"+redisGroupPrefix)
+	store.SetBase(base, "")
+	return &RedisGroupStore{
+		store: store,
+	}
+}
+
+// RedisGroupStore stores the multicast groups of applications in Redis.
+// - Groups are stored as a Hash
+type RedisGroupStore struct {
+	store *storage.RedisMapStore
+}
+
+func (s *RedisGroupStore) key(appID, groupID string) string {
+	return fmt.Sprintf("%s:%s", appID, groupID)
+}
+
+// List the groups of an application
+func (s *RedisGroupStore) List(appID string) ([]interface{}, error) {
+	return s.store.List(fmt.Sprintf("%s:*", appID), nil)
+}
+
+// Get a specific group
+func (s *RedisGroupStore) Get(appID, groupID string) (interface{}, error) {
+	return s.store.Get(s.key(appID, groupID))
+}
+
+// Set a group, optionally setting only the given properties
+func (s *RedisGroupStore) Set(appID, groupID string, group interface{}, properties ...string) error {
+	return s.store.Set(s.key(appID, groupID), group, properties...)
+}
+
+// Delete a group
+func (s *RedisGroupStore) Delete(appID, groupID string) error {
+	return s.store.Delete(s.key(appID, groupID))
+}
@@ -139,6 +139,8 @@ func (n *networkServer) HandleActivate(activation *pb_handler.DeviceActivationRe
 	dev.NwkSKey = *lorawan.NwkSKey
 	dev.FCntUp = 0
 	dev.FCntDown = 0
+	dev.PendingConfirmedDownlink = false
+	dev.PendingConfirmedDownlinkAt = time.Time{}
 	dev.ADR = device.ADRSettings{Band: dev.ADR.Band, Margin: dev.ADR.Margin}
 
 	if band := md.GetLoRaWAN().GetFrequencyPlan().String(); band != "" {
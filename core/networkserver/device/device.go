@@ -36,6 +36,9 @@ type Device struct {
 	Options  Options       `redis:"options"`
 	ADR      ADRSettings   `redis:"adr,include"`
 
+	PendingConfirmedDownlink   bool      `redis:"pending_confirmed_downlink"`    // Indicates whether FCntDown is in use by an unacknowledged confirmed downlink
+	PendingConfirmedDownlinkAt time.Time `redis:"pending_confirmed_downlink_at"` // Time at which the pending confirmed downlink was first sent
+
 	CreatedAt   time.Time `redis:"created_at"`
 	UpdatedAt   time.Time `redis:"updated_at"`
 	ActivatedAt time.Time `redis:"activated_at"` // Indicates whether the device was activated via OTAA method
@@ -8,11 +8,27 @@ import (
 
 	pb_broker "github.com/TheThingsNetwork/api/broker"
 	"github.com/TheThingsNetwork/api/logfields"
+	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
 	"github.com/TheThingsNetwork/api/trace"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/device"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"github.com/brocaar/lorawan"
 )
 
+// ConfirmedDownlinkRetryWindow is the time during which the FCnt of an unacknowledged confirmed downlink is reserved
+// for its retransmissions. After that, the Network Server abandons the confirmed downlink.
+var ConfirmedDownlinkRetryWindow = 24 * time.Hour
+
+// abandonConfirmedDownlink gives up on the pending confirmed downlink of the device, so that its FCnt is not reused
+func abandonConfirmedDownlink(dev *device.Device) {
+	if !dev.PendingConfirmedDownlink {
+		return
+	}
+	dev.FCntDown++
+	dev.PendingConfirmedDownlink = false
+	dev.PendingConfirmedDownlinkAt = time.Time{}
+}
+
 func (n *networkServer) HandleDownlink(message *pb_broker.DownlinkMessage) (*pb_broker.DownlinkMessage, error) {
 	var err error
 	start := time.Now()
@@ -69,8 +85,31 @@ func (n *networkServer) HandleDownlink(message *pb_broker.DownlinkMessage) (*pb_
 		return nil, err
 	}
 
+	// Confirmed downlinks are retransmitted with the same FCnt until the device acknowledges them,
+	// so FCntDown is only incremented once the ack is received (see HandleUplink). A downlink with
+	// any other FCnt (the next one, which HandleUplink puts in the template) abandons it.
+	confirmed := message.Message.GetLoRaWAN().MType == pb_lorawan.MType_CONFIRMED_DOWN
+	retransmission := dev.PendingConfirmedDownlink && uint16(lorawanDownlinkMAC.FCnt) == uint16(dev.FCntDown)
+	if dev.PendingConfirmedDownlink && (!retransmission || time.Since(dev.PendingConfirmedDownlinkAt) > ConfirmedDownlinkRetryWindow) {
+		abandonConfirmedDownlink(dev)
+	}
+	if retransmission && !confirmed {
+		return nil, errors.NewErrInvalidArgument("Downlink", "FCnt is reserved for the retransmission of a confirmed downlink")
+	}
+	if retransmission && !dev.PendingConfirmedDownlink {
+		return nil, errors.NewErrInvalidArgument("Downlink", "FCnt is no longer reserved for the retransmission of a confirmed downlink")
+	}
+
 	lorawanDownlinkMAC.FCnt = dev.FCntDown // Use full 32-bit FCnt for setting MIC
-	dev.FCntDown++                         // TODO: For confirmed downlink, FCntDown should be incremented AFTER ACK
+
+	if confirmed {
+		if !dev.PendingConfirmedDownlink {
+			dev.PendingConfirmedDownlinkAt = time.Now()
+		}
+		dev.PendingConfirmedDownlink = true
+	} else {
+		dev.FCntDown++
+	}
 
 	phyPayload := message.Message.GetLoRaWAN().PHYPayload()
 	phyPayload.SetMIC(lorawan.AES128Key(dev.NwkSKey))
@@ -5,6 +5,7 @@ package networkserver
 
 import (
 	"testing"
+	"time"
 
 	pb_broker "github.com/TheThingsNetwork/api/broker"
 	pb_protocol "github.com/TheThingsNetwork/api/protocol"
@@ -101,5 +102,85 @@ func TestHandleDownlink(t *testing.T) {
 
 	dev, _ := ns.devices.Get(appEUI, devEUI)
 	a.So(dev.FCntDown, ShouldEqual, 1)
+	a.So(dev.PendingConfirmedDownlink, ShouldBeFalse)
 
+	// Confirmed Downlink
+	phy.MHDR.MType = lorawan.ConfirmedDataDown
+	phy.MACPayload.(*lorawan.MACPayload).FHDR.FCnt = 1
+	bytes, _ = phy.MarshalBinary()
+	for i := 0; i < 2; i++ {
+		message = &pb_broker.DownlinkMessage{
+			AppEUI:         appEUI,
+			DevEUI:         devEUI,
+			Payload:        bytes,
+			DownlinkOption: downlinkOption,
+		}
+		res, err = ns.HandleDownlink(message)
+		a.So(err, ShouldBeNil)
+
+		phyPayload.UnmarshalBinary(res.Payload)
+		macPayload, _ = phyPayload.MACPayload.(*lorawan.MACPayload)
+		a.So(macPayload.FHDR.FCnt, ShouldEqual, 1) // Retransmissions use the same Frame counter
+
+		dev, _ = ns.devices.Get(appEUI, devEUI)
+		a.So(dev.FCntDown, ShouldEqual, 1)
+		a.So(dev.PendingConfirmedDownlink, ShouldBeTrue)
+	}
+
+	// Unconfirmed Downlink can not reuse the FCnt of the pending confirmed downlink
+	phy.MHDR.MType = lorawan.UnconfirmedDataDown
+	bytes, _ = phy.MarshalBinary()
+	_, err = ns.HandleDownlink(&pb_broker.DownlinkMessage{
+		AppEUI:         appEUI,
+		DevEUI:         devEUI,
+		Payload:        bytes,
+		DownlinkOption: downlinkOption,
+	})
+	a.So(err, ShouldNotBeNil)
+
+	// Downlink with the next FCnt abandons the pending confirmed downlink
+	phy.MACPayload.(*lorawan.MACPayload).FHDR.FCnt = 2
+	bytes, _ = phy.MarshalBinary()
+	res, err = ns.HandleDownlink(&pb_broker.DownlinkMessage{
+		AppEUI:         appEUI,
+		DevEUI:         devEUI,
+		Payload:        bytes,
+		DownlinkOption: downlinkOption,
+	})
+	a.So(err, ShouldBeNil)
+	phyPayload.UnmarshalBinary(res.Payload)
+	macPayload, _ = phyPayload.MACPayload.(*lorawan.MACPayload)
+	a.So(macPayload.FHDR.FCnt, ShouldEqual, 2)
+
+	dev, _ = ns.devices.Get(appEUI, devEUI)
+	a.So(dev.FCntDown, ShouldEqual, 3)
+	a.So(dev.PendingConfirmedDownlink, ShouldBeFalse)
+
+	// The FCnt of a confirmed downlink is no longer reserved after the retry window
+	phy.MHDR.MType = lorawan.ConfirmedDataDown
+	phy.MACPayload.(*lorawan.MACPayload).FHDR.FCnt = 3
+	bytes, _ = phy.MarshalBinary()
+	_, err = ns.HandleDownlink(&pb_broker.DownlinkMessage{
+		AppEUI:         appEUI,
+		DevEUI:         devEUI,
+		Payload:        bytes,
+		DownlinkOption: downlinkOption,
+	})
+	a.So(err, ShouldBeNil)
+
+	dev, _ = ns.devices.Get(appEUI, devEUI)
+	dev.PendingConfirmedDownlinkAt = time.Now().Add(-ConfirmedDownlinkRetryWindow - time.Minute)
+	ns.devices.Set(dev)
+
+	_, err = ns.HandleDownlink(&pb_broker.DownlinkMessage{
+		AppEUI:         appEUI,
+		DevEUI:         devEUI,
+		Payload:        bytes,
+		DownlinkOption: downlinkOption,
+	})
+	a.So(err, ShouldNotBeNil)
+
+	dev, _ = ns.devices.Get(appEUI, devEUI)
+	a.So(dev.FCntDown, ShouldEqual, 4)
+	a.So(dev.PendingConfirmedDownlink, ShouldBeFalse)
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/TheThingsNetwork/go-account-lib/rights"
 	"github.com/TheThingsNetwork/ttn/api/ratelimit"
 	"github.com/TheThingsNetwork/ttn/core/networkserver/device"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
 	"github.com/TheThingsNetwork/ttn/core/types"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 	gogo "github.com/gogo/protobuf/types"
@@ -217,4 +218,5 @@ func (n *networkServer) RegisterManager(s *grpc.Server) {
 	pb.RegisterNetworkServerManagerServer(s, server)
 	pb_lorawan.RegisterDeviceManagerServer(s, server)
 	pb_lorawan.RegisterDevAddrManagerServer(s, server)
+	multicast.RegisterMulticastManagerServer(s, server)
 }
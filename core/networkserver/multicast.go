@@ -0,0 +1,166 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package networkserver
+
+import (
+	"time"
+
+	"github.com/TheThingsNetwork/go-account-lib/rights"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/brocaar/lorawan"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func (n *networkServer) HandleMulticastDownlink(message *multicast.DownlinkMessage) (res *multicast.DownlinkMessage, err error) {
+	ctx := n.Ctx.WithFields(ttnlog.Fields{
+		"AppID":   message.AppID,
+		"GroupID": message.GroupID,
+		"FCnt":    message.FCnt,
+	})
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			ctx.WithError(err).Warn("Could not handle multicast downlink")
+		} else {
+			ctx.WithField("Duration", time.Now().Sub(start)).Info("Handled multicast downlink")
+		}
+	}()
+
+	n.status.downlink.Mark(1)
+
+	group, err := n.groups.Get(message.AppID, message.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var phyPayload lorawan.PHYPayload
+	if err = phyPayload.UnmarshalBinary(message.Payload); err != nil {
+		return nil, errors.NewErrInvalidArgument("Multicast Downlink", err.Error())
+	}
+	if phyPayload.MHDR.MType != lorawan.UnconfirmedDataDown {
+		return nil, errors.NewErrInvalidArgument("Multicast Downlink", "must be unconfirmed")
+	}
+	macPayload, ok := phyPayload.MACPayload.(*lorawan.MACPayload)
+	if !ok {
+		return nil, errors.NewErrInvalidArgument("Multicast Downlink", "does not contain a MAC payload")
+	}
+	if types.DevAddr(macPayload.FHDR.DevAddr) != group.McAddr {
+		return nil, errors.NewErrInvalidArgument("Multicast Downlink", "DevAddr does not match McAddr of group")
+	}
+	if uint16(macPayload.FHDR.FCnt) != uint16(message.FCnt) {
+		return nil, errors.NewErrInvalidArgument("Multicast Downlink", "FCnt does not match payload")
+	}
+	if message.FCnt < group.FCntDown {
+		return nil, errors.NewErrInvalidArgument("Multicast Downlink", "FCnt was already used")
+	}
+
+	macPayload.FHDR.FCnt = message.FCnt // Use full 32-bit FCnt for setting MIC
+	if err = phyPayload.SetMIC(lorawan.AES128Key(group.McNwkSKey)); err != nil {
+		return nil, err
+	}
+	payload, err := phyPayload.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	group.StartUpdate()
+	group.FCntDown = message.FCnt + 1
+	if err = n.groups.Set(group, "FCntDown", "UpdatedAt"); err != nil {
+		return nil, err
+	}
+
+	power := group.Power
+	if power == 0 {
+		power = multicast.DefaultPower
+	}
+
+	return &multicast.DownlinkMessage{
+		AppID:     group.AppID,
+		GroupID:   group.GroupID,
+		Payload:   payload,
+		FCnt:      message.FCnt,
+		Frequency: group.Frequency,
+		DataRate:  group.DataRate,
+		Power:     power,
+		Gateways:  group.Gateways,
+	}, nil
+}
+
+type networkServerMulticastRPC struct {
+	*networkServerRPC
+}
+
+func (s *networkServerMulticastRPC) Downlink(ctx context.Context, message *multicast.DownlinkMessage) (*multicast.DownlinkMessage, error) {
+	if err := s.ValidateContext(ctx); err != nil {
+		return nil, err
+	}
+	return s.networkServer.HandleMulticastDownlink(message)
+}
+
+func (n *networkServerManager) validateGroupClient(ctx context.Context, appID string) error {
+	claims, err := n.networkServer.Component.ValidateTTNAuthContext(ctx)
+	if err != nil {
+		return err
+	}
+	if wait, ok := n.clientRate.WaitMaxDuration(claims.Subject, 500*time.Millisecond); ok {
+		time.Sleep(wait)
+	} else {
+		return grpc.Errorf(codes.ResourceExhausted, "Rate limit for client %q reached", claims.Subject)
+	}
+	return checkAppRights(claims, appID, rights.Devices)
+}
+
+func (n *networkServerManager) GetGroup(ctx context.Context, in *multicast.GroupIdentifier) (*multicast.Group, error) {
+	if err := n.validateGroupClient(ctx, in.AppID); err != nil {
+		return nil, err
+	}
+	return n.networkServer.groups.Get(in.AppID, in.GroupID)
+}
+
+func (n *networkServerManager) SetGroup(ctx context.Context, in *multicast.Group) (*multicast.Empty, error) {
+	if err := in.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Invalid Group")
+	}
+	if err := n.validateGroupClient(ctx, in.AppID); err != nil {
+		return nil, err
+	}
+
+	group, err := n.networkServer.groups.Get(in.AppID, in.GroupID)
+	if err != nil && errors.GetErrType(err) != errors.NotFound {
+		return nil, err
+	}
+	if group == nil {
+		group = &multicast.Group{AppID: in.AppID, GroupID: in.GroupID}
+	} else {
+		group.StartUpdate()
+	}
+
+	group.McAddr = in.McAddr
+	group.McNwkSKey = in.McNwkSKey
+	group.FCntDown = in.FCntDown
+	group.Frequency = in.Frequency
+	group.DataRate = in.DataRate
+	group.Power = in.Power
+	group.Gateways = in.Gateways
+
+	if err := n.networkServer.groups.Set(group); err != nil {
+		return nil, err
+	}
+	return &multicast.Empty{}, nil
+}
+
+func (n *networkServerManager) DeleteGroup(ctx context.Context, in *multicast.GroupIdentifier) (*multicast.Empty, error) {
+	if _, err := n.GetGroup(ctx, in); err != nil {
+		return nil, err
+	}
+	if err := n.networkServer.groups.Delete(in.AppID, in.GroupID); err != nil {
+		return nil, err
+	}
+	return &multicast.Empty{}, nil
+}
@@ -0,0 +1,68 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package multicast contains the multicast groups of the NetworkServer
+package multicast
+
+import (
+	"time"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+)
+
+// DefaultPower is the transmit power (in dBm) of multicast downlinks if the group does not set it
+var DefaultPower int32 = 14
+
+// Group is a multicast group. The devices in the group share the McAddr and McNwkSKey, so that the Gateways of the
+// group can send one (Class C) downlink to all of them.
+type Group struct {
+	old *Group
+
+	AppID     string        `redis:"app_id" json:"app_id"`
+	GroupID   string        `redis:"group_id" json:"group_id"`
+	McAddr    types.DevAddr `redis:"mc_addr" json:"mc_addr"`
+	McNwkSKey types.NwkSKey `redis:"mc_nwk_s_key" json:"mc_nwk_s_key"`
+	FCntDown  uint32        `redis:"f_cnt_down" json:"f_cnt_down"`
+
+	Frequency uint64   `redis:"frequency" json:"frequency"`   // in Hz
+	DataRate  string   `redis:"data_rate" json:"data_rate"`   // for example SF12BW125
+	Power     int32    `redis:"power" json:"power,omitempty"` // in dBm, DefaultPower if zero
+	Gateways  []string `redis:"gateways" json:"gateways"`     // the IDs of the Gateways that send the downlinks
+
+	CreatedAt time.Time `redis:"created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `redis:"updated_at" json:"updated_at,omitempty"`
+}
+
+// Validate the group
+func (g *Group) Validate() error {
+	if err := api.NotEmptyAndValidID(g.AppID, "Application ID"); err != nil {
+		return err
+	}
+	if err := api.NotEmptyAndValidID(g.GroupID, "Group ID"); err != nil {
+		return err
+	}
+	if g.McAddr.IsEmpty() {
+		return errors.NewErrInvalidArgument("McAddr", "can not be empty")
+	}
+	if g.McNwkSKey.IsEmpty() {
+		return errors.NewErrInvalidArgument("McNwkSKey", "can not be empty")
+	}
+	if g.Frequency == 0 {
+		return errors.NewErrInvalidArgument("Frequency", "can not be empty")
+	}
+	if _, err := types.ParseDataRate(g.DataRate); err != nil {
+		return errors.NewErrInvalidArgument("DataRate", err.Error())
+	}
+	if len(g.Gateways) == 0 {
+		return errors.NewErrInvalidArgument("Gateways", "can not be empty")
+	}
+	return nil
+}
+
+// StartUpdate stores the state of the group
+func (g *Group) StartUpdate() {
+	old := *g
+	g.old = &old
+}
@@ -0,0 +1,120 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package multicast
+
+import (
+	"github.com/TheThingsNetwork/ttn/utils/jsoncodec"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+// GroupIdentifier identifies a multicast group
+type GroupIdentifier struct {
+	AppID   string `json:"app_id"`
+	GroupID string `json:"group_id"`
+}
+
+// Empty is the response of requests that do not return anything
+type Empty struct{}
+
+// DownlinkMessage is a multicast downlink. The Handler sends the PHYPayload with the encrypted FRMPayload and the full
+// FCnt, but without MIC. The NetworkServer sets the MIC and the transmission settings of the group.
+type DownlinkMessage struct {
+	AppID     string   `json:"app_id"`
+	GroupID   string   `json:"group_id"`
+	Payload   []byte   `json:"payload"`
+	FCnt      uint32   `json:"f_cnt"`
+	Frequency uint64   `json:"frequency,omitempty"`
+	DataRate  string   `json:"data_rate,omitempty"`
+	Power     int32    `json:"power,omitempty"`
+	Gateways  []string `json:"gateways,omitempty"`
+}
+
+// MulticastManagerServer is the server API for the MulticastManager service
+type MulticastManagerServer interface {
+	GetGroup(context.Context, *GroupIdentifier) (*Group, error)
+	SetGroup(context.Context, *Group) (*Empty, error)
+	DeleteGroup(context.Context, *GroupIdentifier) (*Empty, error)
+}
+
+// MulticastManagerClient is the client API for the MulticastManager service
+type MulticastManagerClient interface {
+	GetGroup(ctx context.Context, in *GroupIdentifier, opts ...grpc.CallOption) (*Group, error)
+	SetGroup(ctx context.Context, in *Group, opts ...grpc.CallOption) (*Empty, error)
+	DeleteGroup(ctx context.Context, in *GroupIdentifier, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type multicastManagerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewMulticastManagerClient returns a new MulticastManagerClient
+func NewMulticastManagerClient(cc *grpc.ClientConn) MulticastManagerClient {
+	return &multicastManagerClient{cc}
+}
+
+func (c *multicastManagerClient) GetGroup(ctx context.Context, in *GroupIdentifier, opts ...grpc.CallOption) (*Group, error) {
+	out := new(Group)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/lorawan.MulticastManager/GetGroup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *multicastManagerClient) SetGroup(ctx context.Context, in *Group, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/lorawan.MulticastManager/SetGroup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *multicastManagerClient) DeleteGroup(ctx context.Context, in *GroupIdentifier, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/lorawan.MulticastManager/DeleteGroup", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var multicastManagerServiceDesc = jsoncodec.ServiceDesc("lorawan.MulticastManager", (*MulticastManagerServer)(nil))
+
+// RegisterMulticastManagerServer registers the MulticastManager service
+func RegisterMulticastManagerServer(s *grpc.Server, srv MulticastManagerServer) {
+	s.RegisterService(multicastManagerServiceDesc, srv)
+}
+
+// MulticastServer is the server API for the Multicast service
+type MulticastServer interface {
+	Downlink(context.Context, *DownlinkMessage) (*DownlinkMessage, error)
+}
+
+// MulticastClient is the client API for the Multicast service
+type MulticastClient interface {
+	Downlink(ctx context.Context, in *DownlinkMessage, opts ...grpc.CallOption) (*DownlinkMessage, error)
+}
+
+type multicastClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewMulticastClient returns a new MulticastClient
+func NewMulticastClient(cc *grpc.ClientConn) MulticastClient {
+	return &multicastClient{cc}
+}
+
+func (c *multicastClient) Downlink(ctx context.Context, in *DownlinkMessage, opts ...grpc.CallOption) (*DownlinkMessage, error) {
+	out := new(DownlinkMessage)
+	if err := jsoncodec.Invoke(ctx, c.cc, "/lorawan.Multicast/Downlink", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var multicastServiceDesc = jsoncodec.ServiceDesc("lorawan.Multicast", (*MulticastServer)(nil))
+
+// RegisterMulticastServer registers the Multicast service
+func RegisterMulticastServer(s *grpc.Server, srv MulticastServer) {
+	s.RegisterService(multicastServiceDesc, srv)
+}
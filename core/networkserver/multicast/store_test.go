@@ -0,0 +1,62 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package multicast
+
+import (
+	"testing"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestGroupStore(t *testing.T) {
+	a := New(t)
+
+	s := NewRedisGroupStore(GetRedisClient(), "networkserver-test-multicast-store")
+
+	_, err := s.Get("test", "lights")
+	a.So(err, ShouldNotBeNil)
+
+	err = s.Set(&Group{
+		AppID:     "test",
+		GroupID:   "lights",
+		McAddr:    types.DevAddr{1, 2, 3, 4},
+		McNwkSKey: types.NwkSKey{1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8},
+		FCntDown:  42,
+		Frequency: 869525000,
+		DataRate:  "SF9BW125",
+		Gateways:  []string{"gtw-1", "gtw-2"},
+	})
+	a.So(err, ShouldBeNil)
+	defer s.Delete("test", "lights")
+
+	group, err := s.Get("test", "lights")
+	a.So(err, ShouldBeNil)
+	a.So(group.McAddr, ShouldEqual, types.DevAddr{1, 2, 3, 4})
+	a.So(group.FCntDown, ShouldEqual, 42)
+	a.So(group.Gateways, ShouldResemble, []string{"gtw-1", "gtw-2"})
+	a.So(group.CreatedAt.IsZero(), ShouldBeFalse)
+
+	group.StartUpdate()
+	group.FCntDown++
+	err = s.Set(group, "FCntDown")
+	a.So(err, ShouldBeNil)
+
+	group, err = s.Get("test", "lights")
+	a.So(err, ShouldBeNil)
+	a.So(group.FCntDown, ShouldEqual, 43)
+	a.So(group.DataRate, ShouldEqual, "SF9BW125")
+
+	groups, err := s.List("test")
+	a.So(err, ShouldBeNil)
+	a.So(groups, ShouldHaveLength, 1)
+
+	err = s.Delete("test", "lights")
+	a.So(err, ShouldBeNil)
+
+	groups, err = s.List("test")
+	a.So(err, ShouldBeNil)
+	a.So(groups, ShouldBeEmpty)
+}
@@ -0,0 +1,108 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package networkserver
+
+import (
+	"testing"
+
+	"github.com/TheThingsNetwork/ttn/core/component"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/TheThingsNetwork/ttn/utils/testing"
+	"github.com/brocaar/lorawan"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestHandleMulticastDownlink(t *testing.T) {
+	a := New(t)
+	ns := &networkServer{
+		Component: &component.Component{Ctx: GetLogger(t, "TestHandleMulticastDownlink")},
+		groups:    multicast.NewRedisGroupStore(GetRedisClient(), "test-handle-multicast-downlink"),
+	}
+	ns.InitStatus()
+
+	mcNwkSKey := types.NwkSKey{1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8}
+
+	buildPayload := func(devAddr types.DevAddr, fCnt uint32) []byte {
+		fPort := uint8(200)
+		phy := lorawan.PHYPayload{
+			MHDR: lorawan.MHDR{
+				MType: lorawan.UnconfirmedDataDown,
+				Major: lorawan.LoRaWANR1,
+			},
+			MACPayload: &lorawan.MACPayload{
+				FHDR: lorawan.FHDR{
+					DevAddr: lorawan.DevAddr(devAddr),
+					FCnt:    fCnt,
+				},
+				FPort:      &fPort,
+				FRMPayload: []lorawan.Payload{&lorawan.DataPayload{Bytes: []byte{0x01}}},
+			},
+		}
+		bytes, _ := phy.MarshalBinary()
+		return bytes
+	}
+
+	// Group Not Found
+	_, err := ns.HandleMulticastDownlink(&multicast.DownlinkMessage{
+		AppID:   "test",
+		GroupID: "lights",
+		Payload: buildPayload(types.DevAddr{1, 2, 3, 4}, 1),
+		FCnt:    1,
+	})
+	a.So(err, ShouldNotBeNil)
+
+	ns.groups.Set(&multicast.Group{
+		AppID:     "test",
+		GroupID:   "lights",
+		McAddr:    types.DevAddr{1, 2, 3, 4},
+		McNwkSKey: mcNwkSKey,
+		FCntDown:  65536,
+		Frequency: 869525000,
+		DataRate:  "SF9BW125",
+		Gateways:  []string{"gtw-1", "gtw-2"},
+	})
+	defer ns.groups.Delete("test", "lights")
+
+	// Wrong McAddr
+	_, err = ns.HandleMulticastDownlink(&multicast.DownlinkMessage{
+		AppID:   "test",
+		GroupID: "lights",
+		Payload: buildPayload(types.DevAddr{1, 2, 3, 5}, 65536),
+		FCnt:    65536,
+	})
+	a.So(err, ShouldNotBeNil)
+
+	// Used FCnt
+	_, err = ns.HandleMulticastDownlink(&multicast.DownlinkMessage{
+		AppID:   "test",
+		GroupID: "lights",
+		Payload: buildPayload(types.DevAddr{1, 2, 3, 4}, 1),
+		FCnt:    1,
+	})
+	a.So(err, ShouldNotBeNil)
+
+	res, err := ns.HandleMulticastDownlink(&multicast.DownlinkMessage{
+		AppID:   "test",
+		GroupID: "lights",
+		Payload: buildPayload(types.DevAddr{1, 2, 3, 4}, 65537),
+		FCnt:    65537,
+	})
+	a.So(err, ShouldBeNil)
+	a.So(res.FCnt, ShouldEqual, 65537)
+	a.So(res.Frequency, ShouldEqual, 869525000)
+	a.So(res.DataRate, ShouldEqual, "SF9BW125")
+	a.So(res.Power, ShouldEqual, multicast.DefaultPower)
+	a.So(res.Gateways, ShouldResemble, []string{"gtw-1", "gtw-2"})
+
+	var phy lorawan.PHYPayload
+	a.So(phy.UnmarshalBinary(res.Payload), ShouldBeNil)
+	phy.MACPayload.(*lorawan.MACPayload).FHDR.FCnt = 65537
+	ok, err := phy.ValidateMIC(lorawan.AES128Key(mcNwkSKey))
+	a.So(err, ShouldBeNil)
+	a.So(ok, ShouldBeTrue)
+
+	group, _ := ns.groups.Get("test", "lights")
+	a.So(group.FCntDown, ShouldEqual, 65538)
+}
@@ -9,6 +9,7 @@ import (
 	pb "github.com/TheThingsNetwork/api/networkserver"
 	"github.com/TheThingsNetwork/ttn/core/component"
 	"github.com/TheThingsNetwork/ttn/core/networkserver/device"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
 	"github.com/TheThingsNetwork/ttn/core/types"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"gopkg.in/redis.v5"
@@ -27,12 +28,14 @@ type NetworkServer interface {
 	HandleActivate(*pb_handler.DeviceActivationResponse) (*pb_handler.DeviceActivationResponse, error)
 	HandleUplink(*pb_broker.DeduplicatedUplinkMessage) (*pb_broker.DeduplicatedUplinkMessage, error)
 	HandleDownlink(*pb_broker.DownlinkMessage) (*pb_broker.DownlinkMessage, error)
+	HandleMulticastDownlink(*multicast.DownlinkMessage) (*multicast.DownlinkMessage, error)
 }
 
 // NewRedisNetworkServer creates a new Redis-backed NetworkServer
 func NewRedisNetworkServer(client *redis.Client, netID int) NetworkServer {
 	ns := &networkServer{
 		devices:  device.NewRedisDeviceStore(client, "ns"),
+		groups:   multicast.NewRedisGroupStore(client, "ns"),
 		prefixes: map[types.DevAddrPrefix][]string{},
 	}
 	ns.netID = [3]byte{byte(netID >> 16), byte(netID >> 8), byte(netID)}
@@ -42,6 +45,7 @@ func NewRedisNetworkServer(client *redis.Client, netID int) NetworkServer {
 type networkServer struct {
 	*component.Component
 	devices  device.Store
+	groups   multicast.Store
 	netID    [3]byte
 	prefixes map[types.DevAddrPrefix][]string
 	status   *status
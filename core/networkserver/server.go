@@ -7,6 +7,7 @@ import (
 	"github.com/TheThingsNetwork/api/broker"
 	"github.com/TheThingsNetwork/api/handler"
 	pb "github.com/TheThingsNetwork/api/networkserver"
+	"github.com/TheThingsNetwork/ttn/core/networkserver/multicast"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"github.com/TheThingsNetwork/ttn/utils/security"
 	"github.com/dgrijalva/jwt-go"
@@ -122,4 +123,5 @@ func (s *networkServerRPC) Downlink(ctx context.Context, message *broker.Downlin
 func (n *networkServer) RegisterRPC(s *grpc.Server) {
 	server := &networkServerRPC{n}
 	pb.RegisterNetworkServerServer(s, server)
+	multicast.RegisterMulticastServer(s, &networkServerMulticastRPC{server})
 }
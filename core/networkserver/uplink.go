@@ -58,16 +58,27 @@ func (n *networkServer) HandleUplink(message *pb_broker.DeduplicatedUplinkMessag
 	dev.FCntUp = lorawanUplinkMAC.FCnt
 	dev.LastSeen = time.Now()
 
+	// The pending confirmed downlink was acknowledged (or its retransmissions took too long), so its FCnt is used up
+	if dev.PendingConfirmedDownlink && (lorawanUplinkMAC.Ack || time.Since(dev.PendingConfirmedDownlinkAt) > ConfirmedDownlinkRetryWindow) {
+		abandonConfirmedDownlink(dev)
+	}
+
+	// While a confirmed downlink is pending, its FCnt is reserved for its retransmissions, so the template gets the next
+	fCntDown := dev.FCntDown
+	if dev.PendingConfirmedDownlink {
+		fCntDown++
+	}
+
 	// Prepare Downlink
 	message.InitResponseTemplate()
 	lorawanDownlinkMsg := message.ResponseTemplate.Message.InitLoRaWAN()
 	lorawanDownlinkMAC := lorawanDownlinkMsg.InitDownlink()
 	lorawanDownlinkMAC.FPort = lorawanUplinkMAC.FPort
 	lorawanDownlinkMAC.DevAddr = lorawanUplinkMAC.DevAddr
-	lorawanDownlinkMAC.FCnt = dev.FCntDown
+	lorawanDownlinkMAC.FCnt = fCntDown
 	conf := message.ResponseTemplate.GetDownlinkOption().GetProtocolConfiguration()
 	if lorawan := conf.GetLoRaWAN(); lorawan != nil {
-		lorawan.FCnt = dev.FCntDown
+		lorawan.FCnt = fCntDown
 	}
 
 	err = n.handleUplinkMAC(message, dev)
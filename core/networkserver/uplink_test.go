@@ -126,3 +126,88 @@ func TestHandleUplink(t *testing.T) {
 	a.So(dev.FCntUp, ShouldEqual, 1)
 	a.So(time.Now().Sub(dev.LastSeen), ShouldBeLessThan, 1*time.Second)
 }
+
+func TestHandleUplinkConfirmedDownlinkAck(t *testing.T) {
+	a := New(t)
+	ns := &networkServer{
+		Component: &component.Component{
+			Ctx: GetLogger(t, "TestHandleUplinkConfirmedDownlinkAck"),
+		},
+		devices: device.NewRedisDeviceStore(GetRedisClient(), "ns-test-handle-uplink-confirmed-downlink-ack"),
+	}
+	ns.InitStatus()
+
+	appEUI := types.AppEUI(getEUI(1, 2, 3, 4, 5, 6, 7, 8))
+	devEUI := types.DevEUI(getEUI(1, 2, 3, 4, 5, 6, 7, 8))
+	devAddr := getDevAddr(1, 2, 3, 4)
+
+	ns.devices.Set(&device.Device{
+		DevAddr:                    devAddr,
+		AppEUI:                     appEUI,
+		DevEUI:                     devEUI,
+		FCntDown:                   5,
+		PendingConfirmedDownlink:   true,
+		PendingConfirmedDownlinkAt: time.Now(),
+	})
+	defer func() {
+		ns.devices.Delete(appEUI, devEUI)
+		frames, _ := ns.devices.Frames(appEUI, devEUI)
+		frames.Clear()
+	}()
+
+	uplink := func(fCnt uint32, ack bool) *pb_broker.DeduplicatedUplinkMessage {
+		phy := lorawan.PHYPayload{
+			MHDR: lorawan.MHDR{
+				MType: lorawan.UnconfirmedDataUp,
+				Major: lorawan.LoRaWANR1,
+			},
+			MACPayload: &lorawan.MACPayload{
+				FHDR: lorawan.FHDR{
+					DevAddr: lorawan.DevAddr([4]byte{1, 2, 3, 4}),
+					FCnt:    fCnt,
+					FCtrl: lorawan.FCtrl{
+						ACK: ack,
+					},
+				},
+			},
+		}
+		bytes, _ := phy.MarshalBinary()
+		return &pb_broker.DeduplicatedUplinkMessage{
+			AppEUI:           &appEUI,
+			DevEUI:           &devEUI,
+			Payload:          bytes,
+			ResponseTemplate: &pb_broker.DownlinkMessage{DownlinkOption: &pb_broker.DownlinkOption{}},
+			GatewayMetadata: []*pb_gateway.RxMetadata{
+				&pb_gateway.RxMetadata{},
+			},
+			ProtocolMetadata: pb_protocol.RxMetadata{Protocol: &pb_protocol.RxMetadata_LoRaWAN{
+				LoRaWAN: &pb_lorawan.Metadata{
+					DataRate: "SF7BW125",
+				},
+			}},
+		}
+	}
+
+	// Uplink without ack keeps the Frame counter of the pending downlink, and offers the next one for other payloads
+	res, err := ns.HandleUplink(uplink(1, false))
+	a.So(err, ShouldBeNil)
+	dev, _ := ns.devices.Get(appEUI, devEUI)
+	a.So(dev.FCntDown, ShouldEqual, 5)
+	a.So(dev.PendingConfirmedDownlink, ShouldBeTrue)
+
+	var phyPayload lorawan.PHYPayload
+	phyPayload.UnmarshalBinary(res.ResponseTemplate.Payload)
+	macPayload, _ := phyPayload.MACPayload.(*lorawan.MACPayload)
+	a.So(macPayload.FHDR.FCnt, ShouldEqual, 6)
+
+	// Uplink with ack increments the Frame counter
+	res, err = ns.HandleUplink(uplink(2, true))
+	a.So(err, ShouldBeNil)
+	dev, _ = ns.devices.Get(appEUI, devEUI)
+	a.So(dev.FCntDown, ShouldEqual, 6)
+	a.So(dev.PendingConfirmedDownlink, ShouldBeFalse)
+
+	phyPayload.UnmarshalBinary(res.ResponseTemplate.Payload)
+	macPayload, _ = phyPayload.MACPayload.(*lorawan.MACPayload)
+	a.So(macPayload.FHDR.FCnt, ShouldEqual, 6)
+}
@@ -85,3 +85,22 @@ func (h *paginatedHandler) ServeHTTP(res http.ResponseWriter, req *http.Request)
 func WithPagination(h http.Handler) http.Handler {
 	return &paginatedHandler{h}
 }
+
+type routeHandler struct {
+	handler http.Handler
+	prefix  string
+	route   http.Handler
+}
+
+func (h *routeHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if strings.HasPrefix(req.URL.Path, h.prefix) {
+		h.route.ServeHTTP(res, req)
+		return
+	}
+	h.handler.ServeHTTP(res, req)
+}
+
+// WithRoute wraps the handler so that requests for paths that start with the prefix are served by the route
+func WithRoute(h http.Handler, prefix string, route http.Handler) http.Handler {
+	return &routeHandler{h, prefix, route}
+}
@@ -124,3 +124,22 @@ func TestPaginatedProxier(t *testing.T) {
 	a.So(hdl.req, ShouldBeNil)
 	a.So(w.Code, ShouldEqual, http.StatusBadRequest)
 }
+
+func TestRouteHandler(t *testing.T) {
+	a := New(t)
+
+	hdl := &testHandler{}
+	route := &testHandler{}
+	p := WithRoute(hdl, "/uplinks/", route)
+
+	req := httptest.NewRequest("GET", "/applications/test", bytes.NewBuffer([]byte{}))
+	p.ServeHTTP(httptest.NewRecorder(), req)
+	a.So(hdl.req, ShouldNotBeNil)
+	a.So(route.req, ShouldBeNil)
+
+	hdl.req = nil
+	req = httptest.NewRequest("GET", "/uplinks/test", bytes.NewBuffer([]byte{}))
+	p.ServeHTTP(httptest.NewRecorder(), req)
+	a.So(hdl.req, ShouldBeNil)
+	a.So(route.req, ShouldNotBeNil)
+}
@@ -158,6 +158,11 @@ func (r *router) HandleActivation(gatewayID string, activation *pb.DeviceActivat
 
 	var found bool
 	for res := range responses {
+		// A response without DownlinkOption means that the Broker already sent the join-accept
+		if res.DownlinkOption == nil {
+			found = true
+			break
+		}
 		downlink := &pb_broker.DownlinkMessage{
 			Payload:        res.Payload,
 			Message:        res.Message,
@@ -77,6 +77,12 @@ func (r *router) HandleDownlink(downlink *pb_broker.DownlinkMessage) (err error)
 	}
 
 	gateway = r.getGateway(downlink.DownlinkOption.GatewayID)
+
+	// Downlink without a slot in the schedule (Class C) is sent as soon as possible
+	if identifier == "" {
+		return gateway.HandleImmediateDownlink(downlinkMessage)
+	}
+
 	return gateway.HandleDownlink(identifier, downlinkMessage)
 }
 
@@ -69,6 +69,39 @@ func TestHandleDownlink(t *testing.T) {
 	a.So(err, ShouldBeNil)
 }
 
+func TestHandleImmediateDownlink(t *testing.T) {
+	a := New(t)
+
+	logger := GetLogger(t, "TestHandleImmediateDownlink")
+	r := &router{
+		Component: &component.Component{
+			Context: context.Background(),
+			Ctx:     logger,
+			Monitor: monitorclient.NewMonitorClient(),
+		},
+		gateways: map[string]*gateway.Gateway{},
+	}
+	r.InitStatus()
+
+	gtwID := "eui-0102030405060708"
+	downlink := &pb_broker.DownlinkMessage{
+		Payload: make([]byte, 20),
+		DownlinkOption: &pb_broker.DownlinkOption{
+			GatewayID:             gtwID,
+			ProtocolConfiguration: newReferenceDownlink().ProtocolConfiguration,
+			GatewayConfiguration:  pb_gateway.TxConfiguration{Frequency: 869525000},
+		},
+	}
+
+	// The gateway time is not known yet
+	err := r.HandleDownlink(downlink)
+	a.So(err, ShouldNotBeNil)
+
+	r.getGateway(gtwID).Schedule.Sync(0)
+	err = r.HandleDownlink(downlink)
+	a.So(err, ShouldBeNil)
+}
+
 func TestSubscribeUnsubscribeDownlink(t *testing.T) {
 	a := New(t)
 	ctrl := gomock.NewController(t)
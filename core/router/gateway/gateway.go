@@ -118,3 +118,13 @@ func (g *Gateway) HandleDownlink(identifier string, downlink *pb_router.Downlink
 	ctx.Debug("Scheduled downlink")
 	return nil
 }
+
+func (g *Gateway) HandleImmediateDownlink(downlink *pb_router.DownlinkMessage) (err error) {
+	ctx := g.Ctx.WithFields(logfields.ForMessage(downlink))
+	if err = g.Schedule.ScheduleImmediate(downlink); err != nil {
+		ctx.WithError(err).Warn("Could not schedule immediate downlink")
+		return err
+	}
+	ctx.WithField("Timestamp", downlink.GatewayConfiguration.Timestamp).Debug("Scheduled immediate downlink")
+	return nil
+}
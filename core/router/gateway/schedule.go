@@ -27,6 +27,8 @@ type Schedule interface {
 	GetOption(timestamp uint32, length uint32) (id string, score uint)
 	// Schedule a transmission on a slot
 	Schedule(id string, downlink *router_pb.DownlinkMessage) error
+	// Schedule a transmission as soon as possible, for downlink that is not a response to an uplink (Class C)
+	ScheduleImmediate(downlink *router_pb.DownlinkMessage) error
 	// Subscribe to downlink messages
 	Subscribe(subscriptionID string) <-chan *router_pb.DownlinkMessage
 	// Whether the gateway has active downlink
@@ -172,25 +174,8 @@ func (s *schedule) Schedule(id string, downlink *router_pb.DownlinkMessage) erro
 	if item, ok := s.items[id]; ok {
 		item.payload = downlink
 
-		conf := downlink.GetProtocolConfiguration()
-		if lorawan := conf.GetLoRaWAN(); lorawan != nil {
-			var time time.Duration
-			if lorawan.Modulation == pb_lorawan.Modulation_LORA {
-				// Calculate max ToA
-				time, _ = toa.ComputeLoRa(
-					uint(len(downlink.Payload)),
-					lorawan.DataRate,
-					lorawan.CodingRate,
-				)
-			}
-			if lorawan.Modulation == pb_lorawan.Modulation_FSK {
-				// Calculate max ToA
-				time, _ = toa.ComputeFSK(
-					uint(len(downlink.Payload)),
-					int(lorawan.BitRate),
-				)
-			}
-			item.length = uint32(time / 1000)
+		if length, ok := downlinkLength(downlink); ok {
+			item.length = length
 		}
 
 		if time.Now().Before(item.deadlineAt) {
@@ -230,6 +215,57 @@ func (s *schedule) Schedule(id string, downlink *router_pb.DownlinkMessage) erro
 	return errors.NewErrNotFound(id)
 }
 
+// ImmediateDelay is the time that is added to the Deadline for scheduling immediate transmissions
+var ImmediateDelay = 100 * time.Millisecond
+
+// maxImmediateSlots is the number of slots that are tried for scheduling an immediate transmission
+const maxImmediateSlots = 10
+
+// see interface
+func (s *schedule) ScheduleImmediate(downlink *router_pb.DownlinkMessage) error {
+	offset := atomic.LoadInt64(&s.offset)
+	if offset == 0 {
+		return errors.NewErrInternal("Gateway time is not synchronized")
+	}
+	length, _ := downlinkLength(downlink)
+	timestamp := uint32((time.Now().Add(Deadline+ImmediateDelay).UnixNano() - offset) / 1000)
+	for i := 0; s.getConflicts(timestamp, length) > 0; i++ {
+		if i == maxImmediateSlots {
+			return errors.NewErrInternal("No free slot for immediate transmission")
+		}
+		timestamp += length
+	}
+	id, _ := s.GetOption(timestamp, length)
+	downlink.GatewayConfiguration.Timestamp = timestamp
+	return s.Schedule(id, downlink)
+}
+
+// downlinkLength calculates the maximum time on air of the downlink (in microseconds)
+func downlinkLength(downlink *router_pb.DownlinkMessage) (length uint32, ok bool) {
+	conf := downlink.GetProtocolConfiguration()
+	lorawan := conf.GetLoRaWAN()
+	if lorawan == nil {
+		return 0, false
+	}
+	var time time.Duration
+	if lorawan.Modulation == pb_lorawan.Modulation_LORA {
+		// Calculate max ToA
+		time, _ = toa.ComputeLoRa(
+			uint(len(downlink.Payload)),
+			lorawan.DataRate,
+			lorawan.CodingRate,
+		)
+	}
+	if lorawan.Modulation == pb_lorawan.Modulation_FSK {
+		// Calculate max ToA
+		time, _ = toa.ComputeFSK(
+			uint(len(downlink.Payload)),
+			int(lorawan.BitRate),
+		)
+	}
+	return uint32(time / 1000), true
+}
+
 func (s *schedule) Stop(subscriptionID string) {
 	s.Lock()
 	defer s.Unlock()
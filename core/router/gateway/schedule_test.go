@@ -8,6 +8,8 @@ import (
 	"testing"
 	"time"
 
+	pb_protocol "github.com/TheThingsNetwork/api/protocol"
+	pb_lorawan "github.com/TheThingsNetwork/api/protocol/lorawan"
 	router_pb "github.com/TheThingsNetwork/api/router"
 	. "github.com/TheThingsNetwork/ttn/utils/testing"
 	. "github.com/smartystreets/assertions"
@@ -158,3 +160,51 @@ func TestScheduleSubscribe(t *testing.T) {
 	<-time.After(500 * time.Millisecond)
 
 }
+
+func TestScheduleImmediate(t *testing.T) {
+	a := New(t)
+	s := NewSchedule(GetLogger(t, "TestScheduleImmediate")).(*schedule)
+	Deadline = 1 * time.Millisecond // Very short deadline
+	ImmediateDelay = 10 * time.Millisecond
+
+	newDownlink := func() *router_pb.DownlinkMessage {
+		return &router_pb.DownlinkMessage{
+			Payload: make([]byte, 20),
+			ProtocolConfiguration: pb_protocol.TxConfiguration{Protocol: &pb_protocol.TxConfiguration_LoRaWAN{LoRaWAN: &pb_lorawan.TxConfiguration{
+				Modulation: pb_lorawan.Modulation_LORA,
+				DataRate:   "SF7BW125",
+				CodingRate: "4/5",
+			}}},
+		}
+	}
+
+	// Not synchronized
+	err := s.ScheduleImmediate(newDownlink())
+	a.So(err, ShouldNotBeNil)
+
+	s.Sync(0)
+
+	sub := s.Subscribe("")
+	defer s.Stop("")
+
+	downlink1 := newDownlink()
+	err = s.ScheduleImmediate(downlink1)
+	a.So(err, ShouldBeNil)
+	a.So(downlink1.GatewayConfiguration.Timestamp, ShouldBeGreaterThanOrEqualTo, 11000)
+
+	// The second downlink does not overlap with the first one
+	downlink2 := newDownlink()
+	err = s.ScheduleImmediate(downlink2)
+	a.So(err, ShouldBeNil)
+	length, _ := downlinkLength(downlink1)
+	a.So(downlink2.GatewayConfiguration.Timestamp, ShouldBeGreaterThanOrEqualTo, downlink1.GatewayConfiguration.Timestamp+length)
+
+	for _, expected := range []*router_pb.DownlinkMessage{downlink1, downlink2} {
+		select {
+		case out := <-sub:
+			a.So(out, ShouldEqual, expected)
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Did not receive immediate downlink")
+		}
+	}
+}
@@ -180,3 +180,16 @@ func (s *RedisQueueStore) Trim(key string, length int) error {
 	}
 	return err
 }
+
+// Remove the first occurrence of the value from the queue, prepending the prefix to the key if necessary. It returns
+// false if the value was not in the queue.
+func (s *RedisQueueStore) Remove(key string, value string) (bool, error) {
+	if !strings.HasPrefix(key, s.prefix) {
+		key = s.prefix + key
+	}
+	res, err := s.client.LRem(key, 1, value).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	return res > 0, err
+}
@@ -106,6 +106,33 @@ func (addr DevAddr) IsEmpty() bool {
 	return addr == empty
 }
 
+// nwkIDBits is the number of NwkID bits in a DevAddr for each NetID type
+var nwkIDBits = [8]uint{6, 6, 9, 11, 12, 13, 15, 17}
+
+// NetIDType returns the type of the NetID that this DevAddr belongs to, based on the number of leading 1 bits
+func (addr DevAddr) NetIDType() int {
+	for i := 7; i >= 0; i-- {
+		if addr[0]&(1<<uint(i)) == 0 {
+			return 7 - i
+		}
+	}
+	return -1
+}
+
+// NetID returns the NetID that this DevAddr belongs to. This returns an error for DevAddrs that do not contain a valid
+// NetID type prefix.
+func (addr DevAddr) NetID() (netID NetID, err error) {
+	netIDType := addr.NetIDType()
+	if netIDType < 0 {
+		return netID, errors.New("ttn/core: Invalid NetID type in DevAddr")
+	}
+	value := uint32(addr[0])<<24 | uint32(addr[1])<<16 | uint32(addr[2])<<8 | uint32(addr[3])
+	value <<= uint(netIDType) + 1 // strip the type prefix
+	nwkID := value >> (32 - nwkIDBits[netIDType])
+	id := uint32(netIDType)<<21 | nwkID
+	return NetID{byte(id >> 16), byte(id >> 8), byte(id)}, nil
+}
+
 // DevAddrPrefix is a DevAddr with a prefix length
 type DevAddrPrefix struct {
 	DevAddr DevAddr
@@ -94,6 +94,28 @@ func TestDevAddrMask(t *testing.T) {
 	a.So(d1.Mask(8), ShouldEqual, DevAddr{255, 0, 0, 0})
 }
 
+func TestDevAddrNetID(t *testing.T) {
+	a := New(t)
+
+	// The Things Network (type 0, NwkID 0x13)
+	netID, err := DevAddr{0x26, 0x01, 0x02, 0x03}.NetID()
+	a.So(err, ShouldBeNil)
+	a.So(netID, ShouldEqual, NetID{0x00, 0x00, 0x13})
+
+	// Type 3, NwkID 0x2AB
+	netID, err = DevAddr{0xE5, 0x56, 0x00, 0x00}.NetID()
+	a.So(err, ShouldBeNil)
+	a.So(netID, ShouldEqual, NetID{0x60, 0x02, 0xAB})
+
+	// Type 7, NwkID 0x1FFFF
+	netID, err = DevAddr{0xFE, 0xFF, 0xFF, 0x80}.NetID()
+	a.So(err, ShouldBeNil)
+	a.So(netID, ShouldEqual, NetID{0xE1, 0xFF, 0xFF})
+
+	_, err = DevAddr{0xFF, 0x00, 0x00, 0x00}.NetID()
+	a.So(err, ShouldNotBeNil)
+}
+
 func TestDevAddrWithPrefix(t *testing.T) {
 	a := New(t)
 	addr := DevAddr{0xAA, 0xAA, 0xAA, 0xAA}
@@ -3,6 +3,8 @@
 
 package types
 
+import "time"
+
 // ScheduleType can be "replace" (default), "first", "last"
 type ScheduleType string
 
@@ -15,6 +17,7 @@ const (
 
 // DownlinkMessage represents an application-layer downlink message
 type DownlinkMessage struct {
+	ID            string                 `json:"id,omitempty"` // correlation ID, set by the Handler if empty
 	AppID         string                 `json:"app_id,omitempty"`
 	DevID         string                 `json:"dev_id,omitempty"`
 	FPort         uint8                  `json:"port"`
@@ -22,4 +25,21 @@ type DownlinkMessage struct {
 	Schedule      ScheduleType           `json:"schedule,omitempty"` // allowed values: "replace" (default), "first", "last"
 	PayloadRaw    []byte                 `json:"payload_raw,omitempty"`
 	PayloadFields map[string]interface{} `json:"payload_fields,omitempty"`
+	TTL           string                 `json:"ttl,omitempty"`        // time to live in the queue, for example "1h"
+	ExpiresAt     *JSONTime              `json:"expires_at,omitempty"` // set by the Handler from the TTL if empty
+	At            *JSONTime              `json:"at,omitempty"`         // time before which the message is not added to the queue
+	Recurrence    string                 `json:"recurrence,omitempty"` // cron expression for recurring messages, for example "@daily" or "0 6 * * 1-5"
+}
+
+// Expired returns true if the downlink message expired at the given time
+func (m *DownlinkMessage) Expired(t time.Time) bool {
+	return m.ExpiresAt != nil && t.After(time.Time(*m.ExpiresAt))
+}
+
+// MulticastDownlinkMessage represents an application-layer downlink message for a multicast group
+type MulticastDownlinkMessage struct {
+	AppID      string `json:"app_id,omitempty"`
+	GroupID    string `json:"group_id,omitempty"`
+	FPort      uint8  `json:"port"`
+	PayloadRaw []byte `json:"payload_raw,omitempty"`
 }
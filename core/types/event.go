@@ -14,12 +14,16 @@ const (
 
 	DownlinkScheduledEvent EventType = "down/scheduled"
 	DownlinkSentEvent      EventType = "down/sent"
+	DownlinkFallbackEvent  EventType = "down/fallback"
 	DownlinkErrorEvent     EventType = "down/errors"
 	DownlinkAckEvent       EventType = "down/acks"
+	DownlinkNackEvent      EventType = "down/nacks"
 
 	ActivationEvent      EventType = "activations"
 	ActivationErrorEvent EventType = "activations/errors"
 
+	ClockSyncEvent EventType = "clock-sync"
+
 	CreateEvent EventType = "create"
 	UpdateEvent EventType = "update"
 	DeleteEvent EventType = "delete"
@@ -30,10 +34,12 @@ func (e EventType) Data() interface{} {
 	switch e {
 	case UplinkErrorEvent:
 		return new(ErrorEventData)
-	case DownlinkScheduledEvent, DownlinkSentEvent, DownlinkErrorEvent, DownlinkAckEvent:
+	case DownlinkScheduledEvent, DownlinkSentEvent, DownlinkFallbackEvent, DownlinkErrorEvent, DownlinkAckEvent, DownlinkNackEvent:
 		return new(DownlinkEventData)
 	case ActivationEvent, ActivationErrorEvent:
 		return new(ActivationEventData)
+	case ClockSyncEvent:
+		return new(ClockSyncEventData)
 	case CreateEvent, UpdateEvent, DeleteEvent:
 		return nil
 	}
@@ -62,6 +68,15 @@ type ActivationEventData struct {
 	Metadata Metadata `json:"metadata"`
 }
 
+// ClockSyncEventData is added to clock synchronization events
+type ClockSyncEventData struct {
+	DeviceTime     JSONTime `json:"device_time"`
+	ServerTime     JSONTime `json:"server_time"`
+	TimeCorrection int32    `json:"time_correction"` // in seconds
+	Token          uint8    `json:"token"`
+	AnswerSent     bool     `json:"answer_sent"`
+}
+
 // DownlinkEventConfigInfo contains configuration information for a downlink message, all fields are optional
 type DownlinkEventConfigInfo struct {
 	Modulation string        `json:"modulation,omitempty"`
@@ -15,5 +15,10 @@ type Metadata struct {
 	Airtime    time.Duration     `json:"airtime,omitempty"`
 	CodingRate string            `json:"coding_rate,omitempty"`
 	Gateways   []GatewayMetadata `json:"gateways,omitempty"`
+	// PayloadFormatter is the payload formatter that decoded the payload: "application", "device" or
+	// "attribute:<attribute>=<value>"
+	PayloadFormatter string `json:"payload_formatter,omitempty"`
+	// PayloadFormatterRevision is the revision of the application's payload formatter that decoded the payload
+	PayloadFormatterRevision uint64 `json:"payload_formatter_revision,omitempty"`
 	LocationMetadata
 }
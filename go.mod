@@ -25,9 +25,9 @@ replace github.com/brocaar/lorawan => github.com/ThethingsIndustries/legacy-lora
 replace github.com/robertkrimen/otto => github.com/ThethingsIndustries/otto v0.0.0-20181129100957-6ddbbb60554a
 
 require (
+	github.com/Shopify/sarama v1.19.0
 	github.com/TheThingsNetwork/api v0.0.0-20200807125557-7bae06ae0e7b
 	github.com/TheThingsNetwork/go-account-lib v0.0.0-20200324111756-39cfe6d39482
-	github.com/TheThingsNetwork/go-cayenne-lib v1.0.0
 	github.com/TheThingsNetwork/go-utils v0.0.0-20200807125606-b3493662e4bf
 	github.com/TheThingsNetwork/ttn/api v0.0.0-20200807123328-b39cc6b19c87
 	github.com/TheThingsNetwork/ttn/core/proxy v0.0.0-20200807123328-b39cc6b19c87
@@ -43,6 +43,7 @@ require (
 	github.com/bluele/gcache v0.0.0-20190518031135-bc40bd653833
 	github.com/brocaar/lorawan v0.0.0-20200726141338-ee070f85d494
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/dop251/goja v0.0.0-20211022113120-dc8c55024d06
 	github.com/fatih/color v1.9.0 // indirect
 	github.com/fatih/structs v1.1.0
 	github.com/fsnotify/fsnotify v1.4.9 // indirect
@@ -79,8 +80,9 @@ require (
 	google.golang.org/appengine v1.6.6 // indirect
 	google.golang.org/genproto v0.0.0-20200806141610-86f49bd18e98
 	google.golang.org/grpc v1.31.0
+	google.golang.org/protobuf v1.25.0
 	gopkg.in/ini.v1 v1.57.0 // indirect
 	gopkg.in/redis.v5 v5.2.9
 	gopkg.in/sourcemap.v1 v1.0.5 // indirect
-	gopkg.in/yaml.v2 v2.3.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,117 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/TheThingsNetwork/go-utils/log"
+)
+
+// Topics are the Kafka topics that are used by the Handler
+type Topics struct {
+	Uplink      string
+	Activations string
+	Events      string
+	Downlink    string
+	// DownlinkGroup is the consumer group of the Downlink topic. Handlers in the same group share the downlink
+	// messages.
+	DownlinkGroup string
+}
+
+// DefaultTopics are the default Kafka topics
+var DefaultTopics = Topics{
+	Uplink:        "ttn.uplink",
+	Activations:   "ttn.activations",
+	Events:        "ttn.events",
+	Downlink:      "ttn.downlink",
+	DownlinkGroup: "ttn-handler",
+}
+
+// Client connects to a Kafka cluster
+type Client interface {
+	Connect() error
+	Disconnect()
+	IsConnected() bool
+
+	NewPublisher(topics Topics) Publisher
+	NewSubscriber(topic, group string) Subscriber
+}
+
+// DefaultClient is the default Kafka client for The Things Network
+type DefaultClient struct {
+	ctx     log.Interface
+	brokers []string
+	config  *sarama.Config
+	mutex   sync.Mutex
+	client  sarama.Client
+}
+
+// NewClient creates a new DefaultClient
+func NewClient(ctx log.Interface, brokers ...string) Client {
+	if ctx == nil {
+		ctx = log.Get()
+	}
+	config := sarama.NewConfig()
+	config.ClientID = "ttn-handler"
+	config.Version = sarama.V0_10_2_0
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Return.Successes = true
+	config.Producer.Partitioner = sarama.NewHashPartitioner
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	config.Consumer.Return.Errors = true
+	return &DefaultClient{
+		ctx:     ctx,
+		brokers: brokers,
+		config:  config,
+	}
+}
+
+// Connect to the Kafka cluster
+func (c *DefaultClient) Connect() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.client != nil {
+		return nil
+	}
+	client, err := sarama.NewClient(c.brokers, c.config)
+	if err != nil {
+		return fmt.Errorf("Could not connect to Kafka (%s)", err)
+	}
+	c.client = client
+	c.ctx.Info("Connected to Kafka")
+	return nil
+}
+
+// Disconnect from the Kafka cluster
+func (c *DefaultClient) Disconnect() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.client == nil {
+		return
+	}
+	if err := c.client.Close(); err != nil {
+		c.ctx.Warnf("Could not close Kafka client (%s)", err)
+	}
+	c.client = nil
+	c.ctx.Debug("Disconnected from Kafka")
+}
+
+// IsConnected returns true if there is a connection to the Kafka cluster
+func (c *DefaultClient) IsConnected() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.client != nil && !c.client.Closed()
+}
+
+func (c *DefaultClient) getClient() (sarama.Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.client == nil {
+		return nil, fmt.Errorf("Not connected to Kafka")
+	}
+	return c.client, nil
+}
@@ -0,0 +1,37 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/assertions"
+)
+
+func TestConnect(t *testing.T) {
+	a := New(t)
+	broker := newMockBroker(t)
+	defer broker.Close()
+
+	c := NewClient(getLogger(t, "TestConnect"), broker.Addr())
+	a.So(c.IsConnected(), ShouldBeFalse)
+	a.So(c.Connect(), ShouldBeNil)
+	a.So(c.IsConnected(), ShouldBeTrue)
+
+	// Connecting again does nothing
+	a.So(c.Connect(), ShouldBeNil)
+
+	c.Disconnect()
+	a.So(c.IsConnected(), ShouldBeFalse)
+
+	p := c.NewPublisher(DefaultTopics)
+	a.So(p.Open(), ShouldNotBeNil)
+}
+
+func TestConnectInvalidAddress(t *testing.T) {
+	a := New(t)
+	c := NewClient(getLogger(t, "TestConnectInvalidAddress"), "localhost:1")
+	a.So(c.Connect(), ShouldNotBeNil)
+	a.So(c.IsConnected(), ShouldBeFalse)
+}
@@ -0,0 +1,125 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/types"
+)
+
+// Event is the message that is published for device and application events
+type Event struct {
+	AppID string          `json:"app_id"`
+	DevID string          `json:"dev_id,omitempty"`
+	Event types.EventType `json:"event"`
+	Data  interface{}     `json:"data,omitempty"`
+}
+
+// Publisher publishes messages to Kafka topics
+type Publisher interface {
+	Open() error
+	io.Closer
+
+	PublishUplink(dataUp types.UplinkMessage) error
+	PublishDeviceEvent(appID string, devID string, eventType types.EventType, payload interface{}) error
+	PublishAppEvent(appID string, eventType types.EventType, payload interface{}) error
+}
+
+// DefaultPublisher is the default Kafka publisher
+type DefaultPublisher struct {
+	ctx      log.Interface
+	client   *DefaultClient
+	topics   Topics
+	mutex    sync.RWMutex
+	producer sarama.SyncProducer
+}
+
+// NewPublisher returns a new publisher for the given topics
+func (c *DefaultClient) NewPublisher(topics Topics) Publisher {
+	return &DefaultPublisher{
+		ctx:    c.ctx,
+		client: c,
+		topics: topics,
+	}
+}
+
+// Open the publisher
+func (p *DefaultPublisher) Open() error {
+	client, err := p.client.getClient()
+	if err != nil {
+		return err
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		return fmt.Errorf("Could not create Kafka producer (%s)", err)
+	}
+	p.mutex.Lock()
+	p.producer = producer
+	p.mutex.Unlock()
+	return nil
+}
+
+// Close the publisher. Messages that are published after Close return an error.
+func (p *DefaultPublisher) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.producer == nil {
+		return nil
+	}
+	err := p.producer.Close()
+	p.producer = nil
+	return err
+}
+
+// DeviceKey returns the key of messages of a device
+func DeviceKey(appID, devID string) string {
+	return fmt.Sprintf("%s/%s", appID, devID)
+}
+
+func (p *DefaultPublisher) publish(topic, key string, v interface{}, timestamp time.Time) error {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.producer == nil {
+		return fmt.Errorf("Publisher is not open")
+	}
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("Unable to marshal the message payload: %s", err)
+	}
+	_, _, err = p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:     topic,
+		Key:       sarama.StringEncoder(key),
+		Value:     sarama.ByteEncoder(msg),
+		Timestamp: timestamp,
+	})
+	return err
+}
+
+// PublishUplink publishes an uplink message to the uplink topic
+func (p *DefaultPublisher) PublishUplink(dataUp types.UplinkMessage) error {
+	return p.publish(p.topics.Uplink, DeviceKey(dataUp.AppID, dataUp.DevID), dataUp, time.Time(dataUp.Metadata.Time))
+}
+
+// PublishDeviceEvent publishes a device event to the events topic, or to the activations topic for activations
+func (p *DefaultPublisher) PublishDeviceEvent(appID string, devID string, eventType types.EventType, payload interface{}) error {
+	topic := p.topics.Events
+	if eventType == types.ActivationEvent {
+		topic = p.topics.Activations
+	}
+	event := Event{AppID: appID, DevID: devID, Event: eventType, Data: payload}
+	return p.publish(topic, DeviceKey(appID, devID), event, time.Now())
+}
+
+// PublishAppEvent publishes an application event to the events topic
+func (p *DefaultPublisher) PublishAppEvent(appID string, eventType types.EventType, payload interface{}) error {
+	event := Event{AppID: appID, Event: eventType, Data: payload}
+	return p.publish(p.topics.Events, appID, event, time.Now())
+}
@@ -0,0 +1,55 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestPublish(t *testing.T) {
+	a := New(t)
+	broker := newMockBroker(t, DefaultTopics.Uplink, DefaultTopics.Activations, DefaultTopics.Events)
+	defer broker.Close()
+
+	c := NewClient(getLogger(t, "TestPublish"), broker.Addr())
+	a.So(c.Connect(), ShouldBeNil)
+	defer c.Disconnect()
+
+	p := c.NewPublisher(DefaultTopics).(*DefaultPublisher)
+	a.So(p.Open(), ShouldBeNil)
+	defer p.Close()
+	producer := &recordingProducer{SyncProducer: p.producer}
+	p.producer = producer
+
+	a.So(p.PublishUplink(types.UplinkMessage{AppID: "app", DevID: "dev", PayloadRaw: []byte{0x01, 0x02}}), ShouldBeNil)
+	a.So(p.PublishDeviceEvent("app", "dev", types.ActivationEvent, types.Activation{AppEUI: types.AppEUI{1}}), ShouldBeNil)
+	a.So(p.PublishDeviceEvent("app", "dev", types.DownlinkSentEvent, nil), ShouldBeNil)
+	a.So(p.PublishAppEvent("app", types.EventType("custom"), "data"), ShouldBeNil)
+
+	a.So(producer.msgs, ShouldHaveLength, 4)
+
+	up := producer.msgs[0]
+	a.So(up.Topic, ShouldEqual, "ttn.uplink")
+	a.So(up.Key, ShouldEqual, sarama.StringEncoder("app/dev"))
+	var uplink types.UplinkMessage
+	decode(a, up, &uplink)
+	a.So(uplink.PayloadRaw, ShouldResemble, []byte{0x01, 0x02})
+
+	activation := producer.msgs[1]
+	a.So(activation.Topic, ShouldEqual, "ttn.activations")
+	a.So(activation.Key, ShouldEqual, sarama.StringEncoder("app/dev"))
+	var event Event
+	decode(a, activation, &event)
+	a.So(event.AppID, ShouldEqual, "app")
+	a.So(event.DevID, ShouldEqual, "dev")
+	a.So(event.Event, ShouldEqual, types.ActivationEvent)
+
+	a.So(producer.msgs[2].Topic, ShouldEqual, "ttn.events")
+	a.So(producer.msgs[3].Topic, ShouldEqual, "ttn.events")
+	a.So(producer.msgs[3].Key, ShouldEqual, sarama.StringEncoder("app"))
+}
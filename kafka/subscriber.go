@@ -0,0 +1,162 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/types"
+)
+
+// DownlinkHandler is called for downlink messages. If it returns an error, the message is handled again after the
+// RetryBackoff.
+type DownlinkHandler func(subscriber Subscriber, appID string, devID string, req types.DownlinkMessage) error
+
+// RetryBackoff is the time after which a message that could not be handled is handled again
+var RetryBackoff = time.Second
+
+// Subscriber consumes messages from a Kafka topic
+type Subscriber interface {
+	Open() error
+	io.Closer
+
+	ConsumeDownlink(handler DownlinkHandler) error
+}
+
+// DefaultSubscriber is the default Kafka subscriber. It consumes the topic as a member of a consumer group, so that
+// subscribers in the same group share the partitions of the topic. The offset of a message is committed after it
+// was handled, so that a subscriber that joins the group continues where the previous one stopped.
+type DefaultSubscriber struct {
+	ctx    log.Interface
+	client *DefaultClient
+	topic  string
+	group  string
+	mutex  sync.Mutex
+	cg     sarama.ConsumerGroup
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSubscriber returns a new subscriber for the given topic and consumer group
+func (c *DefaultClient) NewSubscriber(topic, group string) Subscriber {
+	return &DefaultSubscriber{
+		ctx:    c.ctx.WithFields(log.Fields{"Topic": topic, "Group": group}),
+		client: c,
+		topic:  topic,
+		group:  group,
+	}
+}
+
+// Open the subscriber
+func (s *DefaultSubscriber) Open() error {
+	client, err := s.client.getClient()
+	if err != nil {
+		return err
+	}
+	cg, err := sarama.NewConsumerGroupFromClient(s.group, client)
+	if err != nil {
+		return fmt.Errorf("Could not create Kafka consumer group (%s)", err)
+	}
+	go func() {
+		for err := range cg.Errors() {
+			s.ctx.WithError(err).Warn("Error in Kafka consumer group")
+		}
+	}()
+	s.mutex.Lock()
+	s.cg = cg
+	s.mutex.Unlock()
+	return nil
+}
+
+// Close the subscriber. Messages that are being handled are not committed, so they are consumed again by the
+// subscriber that takes over their partition.
+func (s *DefaultSubscriber) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.cg == nil {
+		return nil
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	err := s.cg.Close()
+	s.cg = nil
+	return err
+}
+
+// ConsumeDownlink consumes downlink messages from the topic
+func (s *DefaultSubscriber) ConsumeDownlink(handler DownlinkHandler) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.cg == nil {
+		return fmt.Errorf("Subscriber is not open")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	consumer := &downlinkConsumer{subscriber: s, handler: handler}
+	s.wg.Add(1)
+	go func(cg sarama.ConsumerGroup) {
+		defer s.wg.Done()
+		for {
+			// Consume returns when the partitions of the group are rebalanced, after which the subscriber joins again
+			err := cg.Consume(ctx, []string{s.topic}, consumer)
+			if ctx.Err() != nil || err == sarama.ErrClosedConsumerGroup {
+				return
+			}
+			if err != nil {
+				s.ctx.WithError(err).Warn("Could not consume downlink")
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(RetryBackoff):
+				}
+			}
+		}
+	}(s.cg)
+	return nil
+}
+
+// downlinkConsumer implements sarama.ConsumerGroupHandler
+type downlinkConsumer struct {
+	subscriber *DefaultSubscriber
+	handler    DownlinkHandler
+}
+
+func (c *downlinkConsumer) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (c *downlinkConsumer) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (c *downlinkConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx := c.subscriber.ctx.WithField("Partition", claim.Partition())
+	for msg := range claim.Messages() {
+		dataDown := &types.DownlinkMessage{}
+		if err := json.Unmarshal(msg.Value, dataDown); err != nil {
+			ctx.Warnf("Could not unmarshal downlink (%s)", err)
+			session.MarkMessage(msg, "")
+			continue
+		}
+		for {
+			err := c.handler(c.subscriber, dataDown.AppID, dataDown.DevID, *dataDown)
+			if err == nil {
+				break
+			}
+			ctx.WithError(err).WithField("Offset", msg.Offset).Warn("Could not handle downlink, retrying")
+			select {
+			case <-session.Context().Done():
+				// The message is not marked, so it is consumed again
+				return nil
+			case <-time.After(RetryBackoff):
+			}
+		}
+		session.MarkMessage(msg, "")
+	}
+	return nil
+}
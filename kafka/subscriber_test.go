@@ -0,0 +1,76 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	. "github.com/smartystreets/assertions"
+)
+
+func TestConsumeDownlink(t *testing.T) {
+	a := New(t)
+	topic, group := DefaultTopics.Downlink, DefaultTopics.DownlinkGroup
+	broker := newMockBroker(t, topic)
+	defer broker.Close()
+	fetch := sarama.NewMockFetchResponse(t, 1).SetVersion(3).SetHighWaterMark(topic, 0, 3)
+	for i, devID := range []string{"dev0", "dev1", "dev2"} {
+		fetch.SetMessage(topic, 0, int64(i), sarama.StringEncoder(`{"app_id":"app","dev_id":"`+devID+`","payload_raw":"AQI="}`))
+	}
+	setGroupHandlers(t, broker, map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).SetVersion(1).
+			SetOffset(topic, 0, sarama.OffsetNewest, 3).
+			SetOffset(topic, 0, sarama.OffsetOldest, 0),
+		"FetchRequest": fetch,
+	}, group, topic, 1)
+
+	defer func(backoff time.Duration) { RetryBackoff = backoff }(RetryBackoff)
+	RetryBackoff = 10 * time.Millisecond
+
+	c := NewClient(getLogger(t, "TestConsumeDownlink"), broker.Addr())
+	a.So(c.Connect(), ShouldBeNil)
+	defer c.Disconnect()
+
+	s := c.NewSubscriber(topic, group)
+	a.So(s.ConsumeDownlink(nil), ShouldNotBeNil)
+	a.So(s.Open(), ShouldBeNil)
+
+	downlinks := make(chan types.DownlinkMessage, 10)
+	var failed bool
+	err := s.ConsumeDownlink(func(_ Subscriber, appID, devID string, req types.DownlinkMessage) error {
+		a.So(appID, ShouldEqual, "app")
+		if devID == "dev2" && !failed {
+			failed = true
+			return errors.New("temporary failure")
+		}
+		downlinks <- req
+		return nil
+	})
+	a.So(err, ShouldBeNil)
+
+	// The consumer group continues after the committed offset, and retries the downlink that failed
+	for _, devID := range []string{"dev1", "dev2"} {
+		select {
+		case downlink := <-downlinks:
+			a.So(downlink.DevID, ShouldEqual, devID)
+			a.So(downlink.PayloadRaw, ShouldResemble, []byte{0x01, 0x02})
+		case <-time.After(5 * time.Second):
+			t.Fatal("Did not receive downlink")
+		}
+	}
+	a.So(failed, ShouldBeTrue)
+
+	// The offset of the handled downlinks is committed when the subscriber closes
+	a.So(s.Close(), ShouldBeNil)
+	offsets := committedOffsets(broker, group, topic)
+	a.So(offsets, ShouldNotBeEmpty)
+	a.So(offsets[len(offsets)-1], ShouldEqual, 3)
+}
@@ -0,0 +1,106 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	tt "github.com/TheThingsNetwork/ttn/utils/testing"
+	. "github.com/smartystreets/assertions"
+)
+
+func getLogger(t *testing.T, tag string) ttnlog.Interface {
+	return tt.GetLogger(t, tag)
+}
+
+// newMockBroker returns an in-process Kafka broker that leads partition 0 of the given topics
+func newMockBroker(t *testing.T, topics ...string) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 1)
+	metadata := sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+	offsets := sarama.NewMockOffsetResponse(t).SetVersion(1)
+	for _, topic := range topics {
+		metadata.SetLeader(topic, 0, broker.BrokerID())
+		offsets.SetOffset(topic, 0, sarama.OffsetNewest, 0).SetOffset(topic, 0, sarama.OffsetOldest, 0)
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": metadata,
+		"ProduceRequest":  sarama.NewMockProduceResponse(t).SetVersion(2),
+		"OffsetRequest":   offsets,
+		"FetchRequest":    sarama.NewMockFetchResponse(t, 1).SetVersion(3),
+	})
+	return broker
+}
+
+// setGroupHandlers lets the broker coordinate the consumer group, assigning partition 0 of the topic to its member.
+// The offset of the group is the given committed offset.
+func setGroupHandlers(t *testing.T, broker *sarama.MockBroker, handlers map[string]sarama.MockResponse, group, topic string, committed int64) {
+	handlers["FindCoordinatorRequest"] = sarama.NewMockFindCoordinatorResponse(t).
+		SetCoordinator(sarama.CoordinatorGroup, group, broker)
+	handlers["JoinGroupRequest"] = sarama.NewMockWrapper(&sarama.JoinGroupResponse{
+		GenerationId:  1,
+		GroupProtocol: "range",
+		LeaderId:      "leader",
+		MemberId:      "member",
+	})
+	handlers["SyncGroupRequest"] = sarama.NewMockWrapper(&sarama.SyncGroupResponse{
+		MemberAssignment: memberAssignment(topic, 0),
+	})
+	handlers["HeartbeatRequest"] = sarama.NewMockWrapper(&sarama.HeartbeatResponse{})
+	handlers["LeaveGroupRequest"] = sarama.NewMockWrapper(&sarama.LeaveGroupResponse{})
+	handlers["OffsetFetchRequest"] = sarama.NewMockOffsetFetchResponse(t).
+		SetOffset(group, topic, 0, committed, "", sarama.ErrNoError)
+	handlers["OffsetCommitRequest"] = sarama.NewMockOffsetCommitResponse(t)
+	broker.SetHandlerByMap(handlers)
+}
+
+// memberAssignment encodes a ConsumerGroupMemberAssignment of the given partitions of a topic
+func memberAssignment(topic string, partitions ...int32) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, int16(0)) // Version
+	binary.Write(&buf, binary.BigEndian, int32(1)) // Number of topics
+	binary.Write(&buf, binary.BigEndian, int16(len(topic)))
+	buf.WriteString(topic)
+	binary.Write(&buf, binary.BigEndian, int32(len(partitions)))
+	for _, partition := range partitions {
+		binary.Write(&buf, binary.BigEndian, partition)
+	}
+	binary.Write(&buf, binary.BigEndian, int32(-1)) // No UserData
+	return buf.Bytes()
+}
+
+// committedOffsets returns the offsets that were committed to the broker for partition 0 of the topic
+func committedOffsets(broker *sarama.MockBroker, group, topic string) (offsets []int64) {
+	for _, rr := range broker.History() {
+		req, ok := rr.Request.(*sarama.OffsetCommitRequest)
+		if !ok || req.ConsumerGroup != group {
+			continue
+		}
+		if offset, _, err := req.Offset(topic, 0); err == nil {
+			offsets = append(offsets, offset)
+		}
+	}
+	return
+}
+
+// recordingProducer records the messages that are sent through the producer
+type recordingProducer struct {
+	sarama.SyncProducer
+	msgs []*sarama.ProducerMessage
+}
+
+func (p *recordingProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	p.msgs = append(p.msgs, msg)
+	return p.SyncProducer.SendMessage(msg)
+}
+
+func decode(a *Assertion, msg *sarama.ProducerMessage, v interface{}) {
+	value, err := msg.Value.Encode()
+	a.So(err, ShouldBeNil)
+	a.So(json.Unmarshal(value, v), ShouldBeNil)
+}
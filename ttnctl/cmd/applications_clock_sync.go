@@ -0,0 +1,76 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/clocksync"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+var applicationsClockSyncCmd = &cobra.Command{
+	Use:   "clock-sync [enable/disable]",
+	Short: "Show or change the clock synchronization of an application",
+	Long: `ttnctl applications clock-sync can be used to show, enable or disable clock synchronization.
+If enabled, the Handler answers the clock synchronization requests of the devices on port 202 instead
+of publishing them to the application, and publishes clock-sync events.`,
+	Example: `$ ttnctl applications clock-sync enable
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+  INFO Enabled clock synchronization            AppID=test
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 0, 1)
+
+		appID := util.GetAppID(ctx)
+
+		var enabled bool
+		if len(args) == 1 {
+			switch args[0] {
+			case "enable":
+				enabled = true
+			case "disable":
+				enabled = false
+			default:
+				ctx.Fatal("Expected enable or disable")
+			}
+		}
+
+		conn, manager := util.GetClockSyncManager(ctx)
+		defer conn.Close()
+
+		callCtx := ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID)))
+
+		if len(args) == 0 {
+			settings, err := manager.GetClockSync(callCtx, &clocksync.ApplicationIdentifier{AppID: appID})
+			if err != nil {
+				ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get clock synchronization")
+			}
+			ctx.WithFields(ttnlog.Fields{
+				"AppID":   appID,
+				"Enabled": settings.Enabled,
+			}).Info("Found clock synchronization")
+			return
+		}
+
+		_, err := manager.SetClockSync(callCtx, &clocksync.Settings{AppID: appID, Enabled: enabled})
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not set clock synchronization")
+		}
+
+		if enabled {
+			ctx.WithField("AppID", appID).Info("Enabled clock synchronization")
+		} else {
+			ctx.WithField("AppID", appID).Info("Disabled clock synchronization")
+		}
+	},
+}
+
+func init() {
+	applicationsCmd.AddCommand(applicationsClockSyncCmd)
+}
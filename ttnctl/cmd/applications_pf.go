@@ -5,8 +5,14 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
 
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
 	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -75,6 +81,31 @@ function Decoder(bytes, port) {
 			} else {
 				ctx.Info("No custom encoder function")
 			}
+		case "struct":
+			ctx.Info("Struct layout")
+			fmt.Println(app.Decoder)
+		case "protobuf":
+			pfConn, pfManager := util.GetPayloadFormatterManager(ctx)
+			defer pfConn.Close()
+
+			callCtx := ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID)))
+			formatter, err := pfManager.GetApplicationPayloadFormatter(callCtx, &application.ApplicationIdentifier{AppID: appID})
+			if err != nil {
+				ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get payload formatter")
+			}
+
+			ctx.Infof("Protobuf descriptor set of %d bytes", len(formatter.PayloadFormatter.ProtobufDescriptorSet))
+			ports := make([]int, 0, len(formatter.PayloadFormatter.ProtobufMessages))
+			for port := range formatter.PayloadFormatter.ProtobufMessages {
+				ports = append(ports, int(port))
+			}
+			sort.Ints(ports)
+			for _, port := range ports {
+				ctx.WithFields(ttnlog.Fields{
+					"Port":    port,
+					"Message": formatter.PayloadFormatter.ProtobufMessages[uint8(port)],
+				}).Info("Protobuf message")
+			}
 		default:
 			ctx.Infof("Payload format set to %s", app.PayloadFormat)
 		}
@@ -0,0 +1,162 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+)
+
+var applicationsPayloadFormatHistoryCmd = &cobra.Command{
+	Use:   "history [Revision]",
+	Short: "Show the revisions of the payload formatter",
+	Long: `ttnctl applications pf history shows the revisions of the payload formatter of the application.
+Every change of the payload format or payload functions results in a new revision. If a revision
+is given, the payload format and payload functions of that revision are shown.`,
+	Example: `$ ttnctl applications pf history
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Found 2 revision(s)
+
+Revision	Hash        	Author	PayloadFormat	Created             	Active
+1       	3c9a1e2f04b7	alice 	custom       	2017-09-01T12:00:00Z	
+2       	a01f7c99e5d2	bob   	custom       	2017-09-02T08:30:00Z	*
+
+$ ttnctl applications pf history 1
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Found revision                           Author=alice Hash=3c9a1e2f04b7... PayloadFormat=custom Revision=1
+  INFO Custom decoder function
+function Decoder(bytes, port) {
+  return { led: bytes[0] };
+}
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 0, 1)
+
+		appID := util.GetAppID(ctx)
+
+		var revision uint64
+		if len(args) == 1 {
+			var err error
+			revision, err = strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				ctx.WithError(err).Fatal("Invalid revision")
+			}
+		}
+
+		conn, manager := util.GetPayloadFormatterManager(ctx)
+		defer conn.Close()
+
+		callCtx := ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID)))
+
+		history, err := manager.GetPayloadFormatterHistory(callCtx, &application.ApplicationIdentifier{AppID: appID})
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get payload formatter history")
+		}
+
+		if revision != 0 {
+			for _, r := range history.Revisions {
+				if r.Revision == revision {
+					printPayloadFormatterRevision(r)
+					return
+				}
+			}
+			ctx.WithField("Revision", revision).Fatal("Revision not found")
+		}
+
+		current, err := manager.GetApplicationPayloadFormatter(callCtx, &application.ApplicationIdentifier{AppID: appID})
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get payload formatter")
+		}
+
+		ctx.Infof("Found %d revision(s)", len(history.Revisions))
+
+		table := uitable.New()
+		table.MaxColWidth = 70
+		table.AddRow("Revision", "Hash", "Author", "PayloadFormat", "Created", "Active")
+		for _, r := range history.Revisions {
+			var active string
+			if r.Revision == current.Revision {
+				active = "*"
+			}
+			var format application.PayloadFormat
+			if r.PayloadFormatter != nil {
+				format = r.PayloadFormatter.PayloadFormat
+			}
+			table.AddRow(r.Revision, shortHash(r.Hash), r.Author, format, r.CreatedAt.UTC().Format(time.RFC3339), active)
+		}
+
+		fmt.Println()
+		fmt.Println(table)
+		fmt.Println()
+	},
+}
+
+// shortHash returns the first 12 characters of a content hash
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func printPayloadFormatterRevision(r *application.PayloadFormatterRevision) {
+	formatter := r.PayloadFormatter
+	if formatter == nil {
+		formatter = &application.PayloadFormatter{}
+	}
+	ctx.WithFields(ttnlog.Fields{
+		"Revision":      r.Revision,
+		"Hash":          shortHash(r.Hash) + "...",
+		"Author":        r.Author,
+		"PayloadFormat": formatter.PayloadFormat,
+	}).Info("Found revision")
+
+	functions := []struct {
+		name string
+		code string
+	}{
+		{"decoder", formatter.CustomDecoder},
+		{"converter", formatter.CustomConverter},
+		{"validator", formatter.CustomValidator},
+		{"encoder", formatter.CustomEncoder},
+	}
+	switch formatter.PayloadFormat {
+	case application.PayloadFormatCustom:
+		for _, function := range functions {
+			if function.code == "" {
+				ctx.Infof("No custom %s function", function.name)
+				continue
+			}
+			ctx.Infof("Custom %s function", function.name)
+			fmt.Println(function.code)
+		}
+	case application.PayloadFormatStruct:
+		ctx.Info("Struct layout")
+		fmt.Println(formatter.CustomDecoder)
+	case application.PayloadFormatProtobuf:
+		ctx.Infof("Protobuf descriptor set of %d bytes", len(formatter.ProtobufDescriptorSet))
+		for port, message := range formatter.ProtobufMessages {
+			ctx.WithFields(ttnlog.Fields{
+				"Port":    port,
+				"Message": message,
+			}).Info("Protobuf message")
+		}
+	}
+}
+
+func init() {
+	applicationsPayloadFormatCmd.AddCommand(applicationsPayloadFormatHistoryCmd)
+}
@@ -0,0 +1,160 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+var applicationsPayloadFormatOverrideCmd = &cobra.Command{
+	Use:   "override [attribute:value]",
+	Short: "Show or change the payload formatter overrides for device attributes",
+	Long: `ttnctl applications pf override can be used to show, set or remove the payload formatters that
+override the payload formatter of the application for all devices with a specific attribute value.
+A payload formatter that is set on a device itself (ttnctl devices set) takes precedence.`,
+	Example: `$ ttnctl applications pf override ttn-model:sensor-v2 --payload-format custom --decoder decoder-v2.js
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Updated payload formatter override       AppID=test Attribute=ttn-model Value=sensor-v2
+
+$ ttnctl applications pf override
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Found 1 payload formatter override(s)
+  INFO Payload formatter override               Attribute=ttn-model PayloadFormat=custom Value=sensor-v2
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 0, 1)
+
+		appID := util.GetAppID(ctx)
+
+		conn, manager := util.GetPayloadFormatterManager(ctx)
+		defer conn.Close()
+
+		callCtx := ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID)))
+
+		overrides, err := manager.GetAttributePayloadFormatters(callCtx, &application.ApplicationIdentifier{AppID: appID})
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get payload formatter overrides")
+		}
+
+		if len(args) == 0 {
+			ctx.Infof("Found %d payload formatter override(s)", len(overrides.Formatters))
+			for _, override := range overrides.Formatters {
+				ctx.WithFields(ttnlog.Fields{
+					"Attribute":     override.Attribute,
+					"Value":         override.Value,
+					"PayloadFormat": override.PayloadFormat,
+				}).Info("Payload formatter override")
+			}
+			return
+		}
+
+		s := strings.SplitN(args[0], ":", 2)
+		if len(s) != 2 {
+			ctx.Fatal(fmt.Sprintf("Cannot parse attribute:value %s", args[0]))
+		}
+		attribute, value := s[0], s[1]
+
+		remove, _ := cmd.Flags().GetBool("remove")
+		formatter := payloadFormatterFromFlags(cmd)
+		if formatter == nil && !remove {
+			ctx.Fatal("Expected the payload format or payload functions of the override, or the --remove flag")
+		}
+
+		formatters := make([]application.AttributePayloadFormatter, 0, len(overrides.Formatters)+1)
+		for _, override := range overrides.Formatters {
+			if override.Attribute == attribute && override.Value == value {
+				continue
+			}
+			formatters = append(formatters, override)
+		}
+		if !remove {
+			formatters = append(formatters, application.AttributePayloadFormatter{
+				Attribute:        attribute,
+				Value:            value,
+				PayloadFormatter: *formatter,
+			})
+		}
+
+		_, err = manager.SetAttributePayloadFormatters(callCtx, &application.AttributePayloadFormatters{
+			AppID:      appID,
+			Formatters: formatters,
+		})
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not update payload formatter overrides")
+		}
+
+		ctx = ctx.WithFields(ttnlog.Fields{
+			"AppID":     appID,
+			"Attribute": attribute,
+			"Value":     value,
+		})
+		if remove {
+			ctx.Info("Removed payload formatter override")
+		} else {
+			ctx.Info("Updated payload formatter override")
+		}
+	},
+}
+
+// payloadFormatterFromFlags returns the payload formatter that is given with the payload-format, decoder, converter,
+// validator, encoder and decoder-state flags, or nil if none of these flags is set
+func payloadFormatterFromFlags(cmd *cobra.Command) *application.PayloadFormatter {
+	format, _ := cmd.Flags().GetString("payload-format")
+	formatter := &application.PayloadFormatter{PayloadFormat: application.PayloadFormat(format)}
+	functions := map[string]*string{
+		"decoder":   &formatter.CustomDecoder,
+		"converter": &formatter.CustomConverter,
+		"validator": &formatter.CustomValidator,
+		"encoder":   &formatter.CustomEncoder,
+	}
+
+	set := format != ""
+	for flag, function := range functions {
+		file, _ := cmd.Flags().GetString(flag)
+		if file == "" {
+			continue
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			ctx.WithError(err).Fatalf("Could not read %s file", flag)
+		}
+		*function = string(content)
+		set = true
+	}
+	if decoderState, _ := cmd.Flags().GetBool("decoder-state"); decoderState {
+		formatter.DecoderState = true
+		set = true
+	}
+	if !set {
+		return nil
+	}
+
+	if err := formatter.Validate(); err != nil {
+		ctx.WithError(err).Fatal("Invalid payload formatter")
+	}
+	return formatter
+}
+
+func init() {
+	applicationsPayloadFormatCmd.AddCommand(applicationsPayloadFormatOverrideCmd)
+	applicationsPayloadFormatOverrideCmd.Flags().String("payload-format", "", "Payload format of the override (custom/cayennelpp/cayennelpp-packed)")
+	applicationsPayloadFormatOverrideCmd.Flags().String("decoder", "", "File with the decoder function of the override")
+	applicationsPayloadFormatOverrideCmd.Flags().String("converter", "", "File with the converter function of the override")
+	applicationsPayloadFormatOverrideCmd.Flags().String("validator", "", "File with the validator function of the override")
+	applicationsPayloadFormatOverrideCmd.Flags().String("encoder", "", "File with the encoder function of the override")
+	applicationsPayloadFormatOverrideCmd.Flags().Bool("decoder-state", false, "Pass the per-device state to the decoder function of the override")
+	applicationsPayloadFormatOverrideCmd.Flags().Bool("remove", false, "Remove the override")
+}
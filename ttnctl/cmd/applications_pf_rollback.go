@@ -0,0 +1,61 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+var applicationsPayloadFormatRollbackCmd = &cobra.Command{
+	Use:   "rollback [Revision]",
+	Short: "Roll back the payload formatter to a previous revision",
+	Long: `ttnctl applications pf rollback sets the payload format and payload functions of the application
+to those of a previous revision. The rollback itself is recorded as a new revision.`,
+	Example: `$ ttnctl applications pf rollback 1
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Rolled back payload formatter            AppID=test From=1 Revision=3
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 1, 1)
+
+		appID := util.GetAppID(ctx)
+
+		revision, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			ctx.WithError(err).Fatal("Invalid revision")
+		}
+
+		conn, manager := util.GetPayloadFormatterManager(ctx)
+		defer conn.Close()
+
+		callCtx := ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID)))
+
+		res, err := manager.RollbackPayloadFormatter(callCtx, &application.PayloadFormatterRevisionIdentifier{
+			AppID:    appID,
+			Revision: revision,
+		})
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not roll back payload formatter")
+		}
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID":    appID,
+			"From":     revision,
+			"Revision": res.Revision,
+		}).Info("Rolled back payload formatter")
+	},
+}
+
+func init() {
+	applicationsPayloadFormatCmd.AddCommand(applicationsPayloadFormatRollbackCmd)
+}
@@ -7,19 +7,32 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/TheThingsNetwork/api/handler"
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
 	"github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
 	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"github.com/spf13/cobra"
 )
 
 var applicationsPayloadFormatSetCmd = &cobra.Command{
-	Use:   "set [decoder/converter/validator/encoder/cayennelpp] [file.js]",
+	Use:   "set [decoder/converter/validator/encoder/cayennelpp/cayennelpp-packed/protobuf/struct] [file.js/descriptor-set.pb/layout.yml]",
 	Short: "Set payload format of an application",
 	Long: `ttnctl pf set can be used to get or set the payload format and functions of an application.
-When using payload functions, you can load a file or provide them through stdin.`,
+When using payload functions, you can load a file or provide them through stdin.
+The payload functions are called as Decoder(bytes, port, metadata, state), Converter(decoded, port, metadata)
+and Validator(converted, port, metadata). The metadata is read-only and contains the app_id, dev_id,
+hardware_serial, counter, confirmed, time, frequency, data_rate, gateways (the number of gateways that
+received the uplink) and attributes (the attributes of the device). The state is only set when enabled with ttnctl applications pf state.
+With cayennelpp, every value in the payload is preceded by its channel and data type. With cayennelpp-packed,
+the channel is left out, and the channel of every value is its index in the payload.
+When using Protocol Buffers, provide a descriptor set (protoc --include_imports --descriptor_set_out) and the
+message per port. When using a binary struct, provide the field layout per port in JSON or YAML.`,
 	Example: `$ ttnctl applications pf set decoder
   INFO Discovering Handler...
   INFO Connecting with Handler...
@@ -54,6 +67,29 @@ Port: 1
   INFO Function tested successfully
 
   INFO Updated application                      AppID=test
+
+$ ttnctl applications pf set protobuf descriptor-set.pb --message 1:sensor.Measurement --message 2:sensor.Config
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Updated application                      AppID=test
+
+$ cat layout.yml
+ports:
+  1:
+    - name: temperature
+      offset: 0
+      length: 2
+      signed: true
+      scale: 0.01
+      unit: "°C"
+    - name: state
+      offset: 2
+      length: 1
+      enum: {0: idle, 1: moving}
+$ ttnctl applications pf set struct layout.yml --skip-test
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Updated application                      AppID=test
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		assertArgsLength(cmd, args, 1, 2)
@@ -72,6 +108,11 @@ Port: 1
 
 		format := args[0]
 
+		if format == "protobuf" {
+			setProtobufPayloadFormat(cmd, appID, args)
+			return
+		}
+
 		switch format {
 		case "decoder", "converter", "validator", "encoder":
 			app.PayloadFormat = "custom"
@@ -207,6 +248,34 @@ Function read from %s:
 					ctx.Fatalf("Function %s does not exist", format)
 				}
 			}
+		case "struct":
+			if len(args) != 2 {
+				ctx.Fatal("Expected the layout file")
+			}
+			content, err := ioutil.ReadFile(args[1])
+			if err != nil {
+				ctx.WithError(err).Fatal("Could not read layout file")
+			}
+			app.PayloadFormat = format
+			app.Decoder = string(content)
+
+			if skipTest, _ := cmd.Flags().GetBool("skip-test"); !skipTest {
+				payload, err := util.ReadPayload()
+				if err != nil {
+					ctx.WithError(err).Fatal("Could not parse the payload")
+				}
+
+				port, err := util.ReadPort()
+				if err != nil {
+					ctx.WithError(err).Fatal("Could not parse the port")
+				}
+
+				result, err := manager.DryUplink(payload, app, uint32(port))
+				if err != nil {
+					ctx.WithError(err).Fatal("Could not set the layout")
+				}
+				ctx.Infof("Layout tested successfully. Decoded fields: %s", result.Fields)
+			}
 		default:
 			app.PayloadFormat = format
 		}
@@ -222,8 +291,68 @@ Function read from %s:
 	},
 }
 
+// setProtobufPayloadFormat sets the Protocol Buffers payload format with the descriptor set file in the arguments and
+// the messages in the message flag. The payload functions of the application are kept.
+func setProtobufPayloadFormat(cmd *cobra.Command, appID string, args []string) {
+	if len(args) != 2 {
+		ctx.Fatal("Expected the descriptor set file")
+	}
+	descriptorSet, err := ioutil.ReadFile(args[1])
+	if err != nil {
+		ctx.WithError(err).Fatal("Could not read descriptor set file")
+	}
+
+	in, err := cmd.Flags().GetStringSlice("message")
+	if err != nil {
+		ctx.WithError(err).Fatal("Failed to read message flag")
+	}
+	messages := make(map[uint8]string, len(in))
+	for _, v := range in {
+		s := strings.SplitN(v, ":", 2)
+		if len(s) != 2 {
+			ctx.Fatalf("Cannot parse port:message %s", v)
+		}
+		port, err := strconv.ParseUint(s[0], 10, 8)
+		if err != nil {
+			ctx.WithError(err).Fatalf("Invalid port in %s", v)
+		}
+		messages[uint8(port)] = s[1]
+	}
+
+	conn, manager := util.GetPayloadFormatterManager(ctx)
+	defer conn.Close()
+
+	callCtx := ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID)))
+
+	current, err := manager.GetApplicationPayloadFormatter(callCtx, &application.ApplicationIdentifier{AppID: appID})
+	if err != nil {
+		ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get existing payload formatter")
+	}
+
+	formatter := current.PayloadFormatter
+	formatter.PayloadFormat = application.PayloadFormatProtobuf
+	formatter.ProtobufDescriptorSet = descriptorSet
+	formatter.ProtobufMessages = messages
+	if err := formatter.Validate(); err != nil {
+		ctx.WithError(err).Fatal("Invalid Protobuf payload format")
+	}
+
+	_, err = manager.SetApplicationPayloadFormatter(callCtx, &application.ApplicationPayloadFormatter{
+		AppID:            appID,
+		PayloadFormatter: formatter,
+	})
+	if err != nil {
+		ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not update application")
+	}
+
+	ctx.WithFields(log.Fields{
+		"AppID": appID,
+	}).Infof("Updated application")
+}
+
 func init() {
 	applicationsPayloadFormatSetCmd.Flags().Bool("skip-test", false, "skip payload format test")
+	applicationsPayloadFormatSetCmd.Flags().StringSlice("message", nil, "Protobuf message of a port (port:package.Message)")
 	applicationsPayloadFormatCmd.AddCommand(applicationsPayloadFormatSetCmd)
 }
 
@@ -0,0 +1,120 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+var applicationsPayloadFormatStateCmd = &cobra.Command{
+	Use:   "state [enable/disable/reset] [Device ID]",
+	Short: "Enable, disable or reset the decoder state",
+	Long: `ttnctl applications pf state enables or disables the per-device state of the custom decoder function
+of the application. With the state enabled, the decoder function gets a fourth argument: an object
+that it can read and change, and that is stored for the next uplink message of the same device.
+The JSON-encoded state of a device can not be larger than 4096 bytes; a state that becomes larger
+is reset. The state of a device is also reset when it joins, or with ttnctl applications pf state
+reset [Device ID].
+
+For payloads that are split across uplink messages, the decoder function can use the helper
+reassemble(state, key, id, index, count, bytes). It returns the payload when all count frames of
+the message with the given id are received, and null otherwise. A frame with index 0, or with a
+different id, starts a new message.`,
+	Example: `$ cat decoder.js
+function Decoder(bytes, port, metadata, state) {
+  // Delta-encoded counter
+  state.counter = (state.counter || 0) + bytes[0];
+  return { counter: state.counter };
+}
+$ ttnctl applications pf set decoder decoder.js --skip-test
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Updated application                      AppID=test
+$ ttnctl applications pf state enable
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Updated decoder state                    AppID=test DecoderState=true
+$ ttnctl applications pf state reset test-device
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Reset decoder state                      AppID=test DevID=test-device
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 1, 2)
+
+		var enable bool
+		switch args[0] {
+		case "enable":
+			enable = true
+		case "disable":
+			enable = false
+		case "reset":
+			if len(args) != 2 {
+				ctx.Fatal("Expected the Device ID of the decoder state to reset")
+			}
+		default:
+			ctx.Fatalf("Expected enable, disable or reset, got %s", args[0])
+		}
+
+		appID := util.GetAppID(ctx)
+
+		conn, manager := util.GetPayloadFormatterManager(ctx)
+		defer conn.Close()
+
+		callCtx := ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID)))
+
+		if args[0] == "reset" {
+			devID := strings.ToLower(args[1])
+			if err := api.NotEmptyAndValidID(devID, "Device ID"); err != nil {
+				ctx.Fatal(err.Error())
+			}
+			_, err := manager.ResetDecoderState(callCtx, &application.DeviceIdentifier{AppID: appID, DevID: devID})
+			if err != nil {
+				ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not reset decoder state")
+			}
+			ctx.WithFields(ttnlog.Fields{
+				"AppID": appID,
+				"DevID": devID,
+			}).Info("Reset decoder state")
+			return
+		}
+
+		current, err := manager.GetApplicationPayloadFormatter(callCtx, &application.ApplicationIdentifier{AppID: appID})
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get existing payload formatter")
+		}
+
+		formatter := current.PayloadFormatter
+		if formatter == nil || formatter.PayloadFormat != application.PayloadFormatCustom {
+			ctx.Fatal("The decoder state requires the custom payload format")
+		}
+		formatter.DecoderState = enable
+
+		_, err = manager.SetApplicationPayloadFormatter(callCtx, &application.ApplicationPayloadFormatter{
+			AppID:            appID,
+			PayloadFormatter: formatter,
+		})
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not update application")
+		}
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID":        appID,
+			"DecoderState": enable,
+		}).Info("Updated decoder state")
+	},
+}
+
+func init() {
+	applicationsPayloadFormatCmd.AddCommand(applicationsPayloadFormatStateCmd)
+}
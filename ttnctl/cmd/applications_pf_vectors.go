@@ -0,0 +1,122 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/payload"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+var applicationsPayloadFormatTestCmd = &cobra.Command{
+	Use:   "test [vectors.json]",
+	Short: "Run test vectors against the payload formatter",
+	Long: `ttnctl applications pf test runs test vectors against a payload formatter. The test vectors are read
+from the given JSON file, or are the test vectors that are stored for the application. The payload
+formatter is given with the flags, so that payload functions can be tested before they are uploaded,
+or is the payload formatter of the application.
+
+The test vectors file contains a list of test vectors. An uplink test vector decodes the hex-encoded
+payload and expects the fields. A downlink test vector encodes the fields and expects the payload:
+
+  [
+    { "name": "led on", "port": 1, "payload": "01", "fields": { "led": true } },
+    { "downlink": true, "port": 1, "payload": "01", "fields": { "led": true } }
+  ]
+
+An uplink test vector can have a "metadata" object that is passed to the payload functions, for
+example { "counter": 42, "attributes": { "model": "v2" } }.
+
+With --save, the test vectors are stored for the application. The Handler then runs them on every
+change of the payload formatter, and rejects changes that break them.`,
+	Example: `$ ttnctl applications pf test vectors.json --payload-format custom --decoder decoder.js --encoder encoder.js
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Passed                                   TestVector=led on
+  INFO Passed                                   TestVector=downlink 01 on port 1
+  INFO All 2 test vector(s) passed
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 0, 1)
+
+		appID := util.GetAppID(ctx)
+
+		conn, manager := util.GetPayloadFormatterManager(ctx)
+		defer conn.Close()
+
+		callCtx := ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID)))
+
+		var vectors []application.TestVector
+		if len(args) == 1 {
+			content, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				ctx.WithError(err).Fatal("Could not read test vectors file")
+			}
+			if err := json.Unmarshal(content, &vectors); err != nil {
+				ctx.WithError(err).Fatal("Could not parse test vectors file")
+			}
+		} else {
+			res, err := manager.GetTestVectors(callCtx, &application.ApplicationIdentifier{AppID: appID})
+			if err != nil {
+				ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get test vectors")
+			}
+			vectors = res.TestVectors
+		}
+		if len(vectors) == 0 {
+			ctx.Fatal("No test vectors")
+		}
+
+		formatter := payloadFormatterFromFlags(cmd)
+		if formatter == nil {
+			res, err := manager.GetApplicationPayloadFormatter(callCtx, &application.ApplicationIdentifier{AppID: appID})
+			if err != nil {
+				ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get payload formatter")
+			}
+			formatter = res.PayloadFormatter
+		}
+
+		var failed int
+		for i, err := range payload.RunTestVectors(appID, formatter, vectors) {
+			if err != nil {
+				ctx.WithField("TestVector", vectors[i]).WithError(err).Warn("Failed")
+				failed++
+				continue
+			}
+			ctx.WithField("TestVector", vectors[i]).Info("Passed")
+		}
+		if failed > 0 {
+			ctx.Fatalf("%d of %d test vector(s) failed", failed, len(vectors))
+		}
+		ctx.Infof("All %d test vector(s) passed", len(vectors))
+
+		if save, _ := cmd.Flags().GetBool("save"); save {
+			_, err := manager.SetTestVectors(callCtx, &application.ApplicationTestVectors{
+				AppID:       appID,
+				TestVectors: vectors,
+			})
+			if err != nil {
+				ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not save test vectors")
+			}
+			ctx.WithField("AppID", appID).Info("Saved test vectors")
+		}
+	},
+}
+
+func init() {
+	applicationsPayloadFormatCmd.AddCommand(applicationsPayloadFormatTestCmd)
+	applicationsPayloadFormatTestCmd.Flags().String("payload-format", "", "Payload format to test (custom/cayennelpp/cayennelpp-packed)")
+	applicationsPayloadFormatTestCmd.Flags().String("decoder", "", "File with the decoder function to test")
+	applicationsPayloadFormatTestCmd.Flags().String("converter", "", "File with the converter function to test")
+	applicationsPayloadFormatTestCmd.Flags().String("validator", "", "File with the validator function to test")
+	applicationsPayloadFormatTestCmd.Flags().String("encoder", "", "File with the encoder function to test")
+	applicationsPayloadFormatTestCmd.Flags().Bool("decoder-state", false, "Pass a state to the decoder function to test, which is shared by the uplink test vectors")
+	applicationsPayloadFormatTestCmd.Flags().Bool("save", false, "Store the test vectors for the application when they pass")
+}
@@ -0,0 +1,86 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/broker/usage"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+)
+
+var applicationsUsageCmd = &cobra.Command{
+	Use:   "usage [AppID]",
+	Short: "Get the traffic usage of an application",
+	Long:  `ttnctl applications usage can be used to get the daily uplinks, downlinks and airtime of an application.`,
+	Example: `$ ttnctl applications usage --days 3
+  INFO Discovering Broker...
+  INFO Connecting with Broker...
+  INFO Connected to Broker
+
+Date      	Uplinks	Downlinks	Airtime
+2017-06-05	1440   	12       	1m23.1648s
+2017-06-06	1438   	10       	1m22.9344s
+2017-06-07	712    	5        	41.0624s
+Total     	3590   	27       	3m27.1616s
+
+  INFO Got usage for 3 days                    AppID=test
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 0, 1)
+
+		var appID string
+		if len(args) == 1 {
+			appID = args[0]
+		} else {
+			appID = util.GetAppID(ctx)
+		}
+
+		days, _ := cmd.Flags().GetInt("days")
+		if days < 1 {
+			ctx.Fatal("The number of days should be at least 1")
+		}
+		to := time.Now().UTC()
+		from := to.AddDate(0, 0, 1-days)
+
+		conn, manager := util.GetUsageManager(ctx)
+		defer conn.Close()
+
+		res, err := manager.GetUsage(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			&usage.GetUsageRequest{AppID: appID, From: from.UnixNano(), To: to.UnixNano()},
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get usage")
+		}
+
+		table := uitable.New()
+		table.AddRow("Date", "Uplinks", "Downlinks", "Airtime")
+		for _, day := range res.Usage {
+			table.AddRow(day.Date, day.Uplinks, day.Downlinks, day.Airtime)
+		}
+		total := usage.Total(res.Usage)
+		table.AddRow("Total", total.Uplinks, total.Downlinks, total.Airtime)
+
+		fmt.Println()
+		fmt.Println(table)
+		fmt.Println()
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID": appID,
+		}).Infof("Got usage for %d days", len(res.Usage))
+	},
+}
+
+func init() {
+	applicationsCmd.AddCommand(applicationsUsageCmd)
+	applicationsUsageCmd.Flags().Int("days", 7, "Number of days (including today)")
+}
@@ -0,0 +1,134 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+)
+
+var devicesDownlinksCmd = &cobra.Command{
+	Use:   "downlinks [Device ID]",
+	Short: "List the downlink queue of a device",
+	Long:  `ttnctl devices downlinks can be used to list the downlink messages that are queued or scheduled for a device.`,
+	Example: `$ ttnctl devices downlinks test
+  INFO Using Application                        AppID=test
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+
+ID              	Port	Confirmed	Payload     	Expires             	At                  	Recurrence
+sl0pSuRpImmKTsdz	1   	false    	AABC        	2017-06-07T12:00:00Z
+bB0rxfNdbn2c2P8Z	2   	true     	{"led":"on"}
+config-refresh  	1   	false    	01          	                    	2017-06-08T00:00:00Z	@daily
+
+  INFO Listed 3 downlinks                       AppID=test DevID=test
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 1, 1)
+
+		devID := strings.ToLower(args[0])
+		if err := api.NotEmptyAndValidID(devID, "Device ID"); err != nil {
+			ctx.Fatal(err.Error())
+		}
+
+		appID := util.GetAppID(ctx)
+
+		conn, manager := util.GetDownlinkQueueManager(ctx)
+		defer conn.Close()
+
+		res, err := manager.ListDownlinkQueue(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			&device.DownlinkQueueRequest{AppID: appID, DevID: devID},
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get downlink queue")
+		}
+
+		table := uitable.New()
+		table.MaxColWidth = 70
+		table.AddRow("ID", "Port", "Confirmed", "Payload", "Expires", "At", "Recurrence")
+		for _, downlink := range append(res.Downlinks, res.Scheduled...) {
+			payload := fmt.Sprintf("%X", downlink.PayloadRaw)
+			if len(downlink.PayloadFields) != 0 {
+				fields, _ := json.Marshal(downlink.PayloadFields)
+				payload = string(fields)
+			}
+			var expires string
+			if downlink.ExpiresAt != nil {
+				expires = time.Time(*downlink.ExpiresAt).UTC().Format(time.RFC3339)
+			}
+			var at string
+			if downlink.At != nil {
+				at = time.Time(*downlink.At).UTC().Format(time.RFC3339)
+			}
+			table.AddRow(downlink.ID, downlink.FPort, downlink.Confirmed, crop(payload, 40), expires, at, downlink.Recurrence)
+		}
+
+		fmt.Println()
+		fmt.Println(table)
+		fmt.Println()
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID": appID,
+			"DevID": devID,
+		}).Infof("Listed %d downlinks", len(res.Downlinks)+len(res.Scheduled))
+	},
+}
+
+var devicesDeleteDownlinkCmd = &cobra.Command{
+	Use:   "delete-downlink [Device ID] [Downlink ID]",
+	Short: "Delete a downlink from the queue of a device",
+	Long:  `ttnctl devices delete-downlink can be used to delete a downlink message from the queue of a device.`,
+	Example: `$ ttnctl devices delete-downlink test sl0pSuRpImmKTsdz
+  INFO Using Application                        AppID=test
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+  INFO Deleted downlink                         AppID=test DevID=test DownlinkID=sl0pSuRpImmKTsdz
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 2, 2)
+
+		devID := strings.ToLower(args[0])
+		if err := api.NotEmptyAndValidID(devID, "Device ID"); err != nil {
+			ctx.Fatal(err.Error())
+		}
+		downlinkID := args[1]
+
+		appID := util.GetAppID(ctx)
+
+		conn, manager := util.GetDownlinkQueueManager(ctx)
+		defer conn.Close()
+
+		_, err := manager.DeleteDownlink(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			&device.DeleteDownlinkRequest{AppID: appID, DevID: devID, ID: downlinkID},
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not delete downlink")
+		}
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID":      appID,
+			"DevID":      devID,
+			"DownlinkID": downlinkID,
+		}).Info("Deleted downlink")
+	},
+}
+
+func init() {
+	devicesCmd.AddCommand(devicesDownlinksCmd)
+	devicesCmd.AddCommand(devicesDeleteDownlinkCmd)
+}
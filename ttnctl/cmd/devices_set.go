@@ -9,9 +9,13 @@ import (
 	"strings"
 
 	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
 	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
 	"github.com/TheThingsNetwork/ttn/core/types"
 	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -24,6 +28,13 @@ var devicesSetCmd = &cobra.Command{
   INFO Discovering Handler...
   INFO Connecting with Handler...
   INFO Updated device                           AppID=test DevID=test
+
+$ ttnctl devices set test --payload-format custom --decoder decoder.js
+  INFO Using Application                        AppID=test
+  INFO Discovering Handler...
+  INFO Connecting with Handler...
+  INFO Updated device                           AppID=test DevID=test
+  INFO Updated payload formatter of device      AppID=test DevID=test
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		assertArgsLength(cmd, args, 1, 1)
@@ -183,6 +194,34 @@ var devicesSetCmd = &cobra.Command{
 			"AppID": appID,
 			"DevID": devID,
 		}).Info("Updated device")
+
+		reset, _ := cmd.Flags().GetBool("reset-payload-formatter")
+		formatter := payloadFormatterFromFlags(cmd)
+		if formatter == nil && !reset {
+			return
+		}
+		if reset {
+			formatter = nil
+		}
+
+		pfConn, pfManager := util.GetPayloadFormatterManager(ctx)
+		defer pfConn.Close()
+
+		callCtx := ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID)))
+
+		_, err = pfManager.SetDevicePayloadFormatter(callCtx, &application.DevicePayloadFormatter{
+			AppID:            appID,
+			DevID:            devID,
+			PayloadFormatter: formatter,
+		})
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not update payload formatter of device")
+		}
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID": appID,
+			"DevID": devID,
+		}).Info("Updated payload formatter of device")
 	},
 }
 
@@ -214,4 +253,12 @@ func init() {
 
 	devicesSetCmd.Flags().StringSlice("attr-set", nil, "Add a device attribute (key:value)")
 	devicesSetCmd.Flags().StringSlice("attr-remove", nil, "Remove device attribute")
+
+	devicesSetCmd.Flags().String("payload-format", "", "Override the payload format of the application for this device (custom/cayennelpp/cayennelpp-packed)")
+	devicesSetCmd.Flags().String("decoder", "", "Override the decoder function of the application with the function in this file")
+	devicesSetCmd.Flags().String("converter", "", "Override the converter function of the application with the function in this file")
+	devicesSetCmd.Flags().String("validator", "", "Override the validator function of the application with the function in this file")
+	devicesSetCmd.Flags().String("encoder", "", "Override the encoder function of the application with the function in this file")
+	devicesSetCmd.Flags().Bool("decoder-state", false, "Pass the per-device state to the decoder function of the override")
+	devicesSetCmd.Flags().Bool("reset-payload-formatter", false, "Remove the payload formatter override, so that the device uses the payload formatter of the application")
 }
@@ -6,8 +6,10 @@ package cmd
 import (
 	"encoding/json"
 	"strings"
+	"time"
 
 	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/go-utils/random"
 	"github.com/TheThingsNetwork/ttn/core/types"
 	"github.com/TheThingsNetwork/ttn/ttnctl/util"
 	"github.com/spf13/cobra"
@@ -20,12 +22,17 @@ var downlinkCmd = &cobra.Command{
 	Example: `$ ttnctl downlink test aabc
   INFO Connecting to MQTT...
   INFO Connected to MQTT
-  INFO Enqueued downlink                        AppID=test DevID=test
+  INFO Enqueued downlink                        AppID=test DevID=test DownlinkID=sl0pSuRpImmKTsdz
 
 $ ttnctl downlink test --json '{"led":"on"}'
   INFO Connecting to MQTT...
   INFO Connected to MQTT
-  INFO Enqueued downlink                        AppID=test DevID=test
+  INFO Enqueued downlink                        AppID=test DevID=test DownlinkID=sl0pSuRpImmKTsdz
+
+$ ttnctl downlink test aabc --at 2017-09-01T12:00:00Z --recurrence @daily
+  INFO Connecting to MQTT...
+  INFO Connected to MQTT
+  INFO Scheduled downlink                       AppID=test DevID=test DownlinkID=sl0pSuRpImmKTsdz
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		assertArgsLength(cmd, args, 2, 2)
@@ -59,15 +66,43 @@ $ ttnctl downlink test --json '{"led":"on"}'
 			ctx.WithError(err).Fatal("Failed to read access-key flag")
 		}
 
+		ttl, err := cmd.Flags().GetDuration("ttl")
+		if err != nil {
+			ctx.WithError(err).Fatal("Failed to read ttl flag")
+		}
+
+		at, err := cmd.Flags().GetString("at")
+		if err != nil {
+			ctx.WithError(err).Fatal("Failed to read at flag")
+		}
+
+		recurrence, err := cmd.Flags().GetString("recurrence")
+		if err != nil {
+			ctx.WithError(err).Fatal("Failed to read recurrence flag")
+		}
+
 		client := util.GetMQTT(ctx, accessKey)
 		defer client.Disconnect()
 
 		message := types.DownlinkMessage{
+			ID:        random.String(16),
 			AppID:     appID,
 			DevID:     devID,
 			FPort:     uint8(fPort),
 			Confirmed: confirmed,
 		}
+		if ttl > 0 {
+			message.TTL = ttl.String()
+		}
+		if at != "" {
+			t, err := time.Parse(time.RFC3339, at)
+			if err != nil {
+				ctx.WithError(err).Fatal("Invalid time for at flag, expected RFC3339 format")
+			}
+			jsonTime := types.JSONTime(t)
+			message.At = &jsonTime
+		}
+		message.Recurrence = recurrence
 
 		if args[1] == "" {
 			ctx.Info("Invalid command")
@@ -101,7 +136,11 @@ $ ttnctl downlink test --json '{"led":"on"}'
 		if token.Error() != nil {
 			ctx.WithError(token.Error()).Fatal("Could not enqueue downlink")
 		}
-		ctx.Info("Enqueued downlink")
+		if message.At != nil || message.Recurrence != "" {
+			ctx.WithField("DownlinkID", message.ID).Info("Scheduled downlink")
+		} else {
+			ctx.WithField("DownlinkID", message.ID).Info("Enqueued downlink")
+		}
 	},
 }
 
@@ -111,4 +150,7 @@ func init() {
 	downlinkCmd.Flags().Bool("confirmed", false, "Confirmed downlink")
 	downlinkCmd.Flags().Bool("json", false, "Provide the payload as JSON")
 	downlinkCmd.Flags().String("access-key", "", "The access key to use")
+	downlinkCmd.Flags().Duration("ttl", 0, "Time after which the downlink expires if it was not sent")
+	downlinkCmd.Flags().String("at", "", "Time (RFC3339) at which the downlink is added to the queue")
+	downlinkCmd.Flags().String("recurrence", "", "Cron expression (UTC) for adding the downlink to the queue repeatedly, for example @daily")
 }
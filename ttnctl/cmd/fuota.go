@@ -0,0 +1,32 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var fuotaCmd = &cobra.Command{
+	Use:   "fuota",
+	Short: "Manage firmware updates over the air",
+	Long: `ttnctl fuota can be used to manage firmware updates over the air (FUOTA) sessions.
+The firmware is sent in fragments to a multicast group that is set up on the devices for the session.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		RootCmd.PersistentPreRun(cmd, args)
+		// The app-id key is bound to the flag of the devices command in init()
+		viper.BindPFlag("app-id", cmd.Flags().Lookup("app-id"))
+		util.GetAccount(ctx)
+		ctx.WithFields(ttnlog.Fields{
+			"AppID": util.GetAppID(ctx),
+		}).Info("Using Application")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(fuotaCmd)
+	fuotaCmd.PersistentFlags().String("app-id", "", "The app ID to use")
+}
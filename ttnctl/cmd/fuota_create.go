@@ -0,0 +1,112 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/fuota"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+var fuotaCreateCmd = &cobra.Command{
+	Use:   "create [Session ID] [Firmware File] [McAddr] [DevID]...",
+	Short: "Create a FUOTA session",
+	Long: `ttnctl fuota create can be used to send a firmware image to devices.
+The Handler sets up a multicast group and a fragmentation session on the devices with downlink messages on
+ports 200 and 201, and sends the fragments to the multicast group at the start time (Class C).
+The devices need to send uplinks before the start time to receive the setup.`,
+	Example: `$ ttnctl fuota create firmware-v2 firmware.bin 26001ADA dev-1 dev-2 --gateways gtw-1 --frequency 869525000 --data-rate SF9BW125
+  INFO Using Application                        AppID=test
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+  INFO Created FUOTA session                    AppID=test Fragments=23 SessionID=firmware-v2 StartAt=2017-09-01T13:37:00Z
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 4, 0)
+
+		sessionID := strings.ToLower(args[0])
+		if err := api.NotEmptyAndValidID(sessionID, "Session ID"); err != nil {
+			ctx.Fatal(err.Error())
+		}
+
+		appID := util.GetAppID(ctx)
+
+		firmware, err := ioutil.ReadFile(args[1])
+		if err != nil {
+			ctx.WithError(err).Fatal("Could not read firmware")
+		}
+
+		mcAddr, err := types.ParseDevAddr(args[2])
+		if err != nil {
+			ctx.Fatalf("Invalid McAddr: %s", err)
+		}
+
+		req := &fuota.SessionRequest{
+			AppID:     appID,
+			SessionID: sessionID,
+			DevIDs:    args[3:],
+			Firmware:  firmware,
+			McAddr:    mcAddr,
+		}
+		req.McGroupID, _ = cmd.Flags().GetUint8("mc-group-id")
+		req.FrequencyPlan, _ = cmd.Flags().GetString("frequency-plan")
+		req.Frequency, _ = cmd.Flags().GetUint64("frequency")
+		req.DataRate, _ = cmd.Flags().GetString("data-rate")
+		req.Power, _ = cmd.Flags().GetInt32("power")
+		req.Gateways, _ = cmd.Flags().GetStringSlice("gateways")
+		req.FragSize, _ = cmd.Flags().GetUint8("fragment-size")
+		req.Redundancy, _ = cmd.Flags().GetUint16("redundancy")
+		req.FragmentInterval, _ = cmd.Flags().GetString("fragment-interval")
+		if start, _ := cmd.Flags().GetString("start"); start != "" {
+			startAt, err := time.Parse(time.RFC3339, start)
+			if err != nil {
+				ctx.Fatalf("Invalid start time: %s", err)
+			}
+			jsonTime := types.JSONTime(startAt)
+			req.StartAt = &jsonTime
+		}
+
+		conn, manager := util.GetFUOTAManager(ctx)
+		defer conn.Close()
+
+		session, err := manager.CreateSession(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			req,
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not create FUOTA session")
+		}
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID":     appID,
+			"SessionID": sessionID,
+			"Fragments": session.TotalFragments(),
+			"StartAt":   session.StartAt.Format(time.RFC3339),
+		}).Info("Created FUOTA session")
+	},
+}
+
+func init() {
+	fuotaCmd.AddCommand(fuotaCreateCmd)
+	fuotaCreateCmd.Flags().Uint8("mc-group-id", 0, "Multicast group ID (0-3) on the devices")
+	fuotaCreateCmd.Flags().String("frequency-plan", "", "Frequency plan of the devices (guessed from the frequency if empty)")
+	fuotaCreateCmd.Flags().Uint64("frequency", 0, "Frequency (in Hz) of the fragments")
+	fuotaCreateCmd.Flags().String("data-rate", "", "Data rate of the fragments (for example SF9BW125)")
+	fuotaCreateCmd.Flags().Int32("power", 0, "Transmit power (in dBm) of the fragments (network default if zero)")
+	fuotaCreateCmd.Flags().StringSlice("gateways", []string{}, "IDs of the gateways that send the fragments")
+	fuotaCreateCmd.Flags().Uint8("fragment-size", 0, "Size of the fragments (Handler default if zero)")
+	fuotaCreateCmd.Flags().Uint16("redundancy", 0, "Number of redundancy fragments (Handler default if zero)")
+	fuotaCreateCmd.Flags().String("fragment-interval", "", "Time between fragments (for example 5s)")
+	fuotaCreateCmd.Flags().String("start", "", "Time to start sending the fragments (RFC3339)")
+}
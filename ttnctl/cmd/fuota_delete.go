@@ -0,0 +1,68 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/fuota"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+var fuotaDeleteCmd = &cobra.Command{
+	Use:   "delete [Session ID]",
+	Short: "Delete a FUOTA session",
+	Long: `ttnctl fuota delete can be used to stop and delete a FUOTA session.
+The multicast group of the session is deleted as well.`,
+	Example: `$ ttnctl fuota delete firmware-v2
+  INFO Using Application                        AppID=test
+Are you sure you want to delete FUOTA session firmware-v2 from application test?
+> yes
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+  INFO Deleted FUOTA session                    AppID=test SessionID=firmware-v2
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 1, 1)
+
+		sessionID := strings.ToLower(args[0])
+		if err := api.NotEmptyAndValidID(sessionID, "Session ID"); err != nil {
+			ctx.Fatal(err.Error())
+		}
+
+		appID := util.GetAppID(ctx)
+
+		if !confirm(fmt.Sprintf("Are you sure you want to delete FUOTA session %s from application %s?", sessionID, appID)) {
+			ctx.Info("Not doing anything")
+			return
+		}
+
+		conn, manager := util.GetFUOTAManager(ctx)
+		defer conn.Close()
+
+		_, err := manager.DeleteSession(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			&fuota.SessionIdentifier{AppID: appID, SessionID: sessionID},
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not delete FUOTA session")
+		}
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID":     appID,
+			"SessionID": sessionID,
+		}).Info("Deleted FUOTA session")
+	},
+}
+
+func init() {
+	fuotaCmd.AddCommand(fuotaDeleteCmd)
+}
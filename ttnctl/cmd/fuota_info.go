@@ -0,0 +1,91 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	"github.com/TheThingsNetwork/ttn/core/handler/fuota"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+)
+
+var fuotaInfoCmd = &cobra.Command{
+	Use:   "info [Session ID]",
+	Short: "Get the status of a FUOTA session",
+	Long:  `ttnctl fuota info can be used to get the status of a FUOTA session and the progress of its devices.`,
+	Example: `$ ttnctl fuota info firmware-v2
+  INFO Using Application                        AppID=test
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+  INFO Found FUOTA session
+
+  Application ID: test
+      Session ID: firmware-v2
+
+       Status: sent
+        Start: 2017-09-01T13:37:00Z
+    Fragments: 23/23 (20 + 3 redundancy, 48 bytes)
+
+Device ID	Setup	Received	Missing	Done 	Error
+dev-1    	true 	21      	0      	true
+dev-2    	false	0       	0      	false	Not enough memory for fragmentation session
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 1, 1)
+
+		sessionID := strings.ToLower(args[0])
+		if err := api.NotEmptyAndValidID(sessionID, "Session ID"); err != nil {
+			ctx.Fatal(err.Error())
+		}
+
+		appID := util.GetAppID(ctx)
+
+		conn, manager := util.GetFUOTAManager(ctx)
+		defer conn.Close()
+
+		session, err := manager.GetSession(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			&fuota.SessionIdentifier{AppID: appID, SessionID: sessionID},
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get FUOTA session")
+		}
+
+		ctx.Info("Found FUOTA session")
+
+		fmt.Println()
+		fmt.Printf("  Application ID: %s\n", session.AppID)
+		fmt.Printf("      Session ID: %s\n", session.SessionID)
+		fmt.Println()
+		fmt.Printf("       Status: %s\n", session.Status)
+		if session.Error != "" {
+			fmt.Printf("        Error: %s\n", session.Error)
+		}
+		fmt.Printf("        Start: %s\n", session.StartAt.Format(time.RFC3339))
+		fmt.Printf("    Fragments: %d/%d (%d + %d redundancy, %d bytes)\n", session.FragmentsSent, session.TotalFragments(), session.NbFrag, session.Redundancy, session.FragSize)
+
+		table := uitable.New()
+		table.MaxColWidth = 70
+		table.AddRow("Device ID", "Setup", "Received", "Missing", "Done", "Error")
+		for _, dev := range session.Devices {
+			table.AddRow(dev.DevID, dev.Ready(), dev.FragmentsReceived, dev.MissingFragments, dev.Done, dev.Error)
+		}
+
+		fmt.Println()
+		fmt.Println(table)
+		fmt.Println()
+	},
+}
+
+func init() {
+	fuotaCmd.AddCommand(fuotaInfoCmd)
+}
@@ -0,0 +1,75 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/fuota"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+)
+
+var fuotaListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the FUOTA sessions of an application",
+	Long:  `ttnctl fuota list can be used to list the FUOTA sessions of an application.`,
+	Example: `$ ttnctl fuota list
+  INFO Using Application                        AppID=test
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+
+Session ID 	Status 	Start               	Fragments	Devices
+firmware-v2	sending	2017-09-01T13:37:00Z	12/23    	2
+
+  INFO Listed 1 FUOTA sessions                  AppID=test
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 0, 0)
+
+		appID := util.GetAppID(ctx)
+
+		conn, manager := util.GetFUOTAManager(ctx)
+		defer conn.Close()
+
+		res, err := manager.ListSessions(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			&fuota.ApplicationIdentifier{AppID: appID},
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get FUOTA sessions")
+		}
+
+		table := uitable.New()
+		table.MaxColWidth = 70
+		table.AddRow("Session ID", "Status", "Start", "Fragments", "Devices")
+		for _, session := range res.Sessions {
+			table.AddRow(
+				session.SessionID,
+				session.Status,
+				session.StartAt.Format(time.RFC3339),
+				fmt.Sprintf("%d/%d", session.FragmentsSent, session.TotalFragments()),
+				len(session.Devices),
+			)
+		}
+
+		fmt.Println()
+		fmt.Println(table)
+		fmt.Println()
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID": appID,
+		}).Infof("Listed %d FUOTA sessions", len(res.Sessions))
+	},
+}
+
+func init() {
+	fuotaCmd.AddCommand(fuotaListCmd)
+}
@@ -0,0 +1,84 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	"github.com/TheThingsNetwork/ttn/core/handler/multicast"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+var multicastInfoCmd = &cobra.Command{
+	Use:   "info [Group ID]",
+	Short: "Get information about a multicast group",
+	Long:  `ttnctl multicast info can be used to get information about a multicast group.`,
+	Example: `$ ttnctl multicast info lights
+  INFO Using Application                        AppID=test
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+  INFO Found multicast group
+
+  Application ID: test
+        Group ID: lights
+
+       McAddr: 26001ADA
+    McNwkSKey: 3382A3066850293421ED8D392B9BF4DF
+    McAppSKey: D8DD37B4B709BA76C6FEC62CAD0CCE51
+         FCnt: 12
+    Frequency: 869525000
+     DataRate: SF9BW125
+        Power: 0
+     Gateways: gtw-1, gtw-2
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 1, 1)
+
+		groupID := strings.ToLower(args[0])
+		if err := api.NotEmptyAndValidID(groupID, "Group ID"); err != nil {
+			ctx.Fatal(err.Error())
+		}
+
+		appID := util.GetAppID(ctx)
+
+		conn, manager := util.GetMulticastManager(ctx)
+		defer conn.Close()
+
+		group, err := manager.GetGroup(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			&multicast.GroupIdentifier{AppID: appID, GroupID: groupID},
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get multicast group")
+		}
+
+		byteFormat, _ := cmd.Flags().GetString("format")
+
+		ctx.Info("Found multicast group")
+
+		fmt.Println()
+		fmt.Printf("  Application ID: %s\n", group.AppID)
+		fmt.Printf("        Group ID: %s\n", group.GroupID)
+		fmt.Println()
+		fmt.Printf("       McAddr: %s\n", formatBytes(group.McAddr, byteFormat))
+		fmt.Printf("    McNwkSKey: %s\n", formatBytes(group.McNwkSKey, byteFormat))
+		fmt.Printf("    McAppSKey: %s\n", formatBytes(group.McAppSKey, byteFormat))
+		fmt.Printf("         FCnt: %d\n", group.FCnt)
+		fmt.Printf("    Frequency: %d\n", group.Frequency)
+		fmt.Printf("     DataRate: %s\n", group.DataRate)
+		fmt.Printf("        Power: %d\n", group.Power)
+		fmt.Printf("     Gateways: %s\n", strings.Join(group.Gateways, ", "))
+	},
+}
+
+func init() {
+	multicastCmd.AddCommand(multicastInfoCmd)
+	multicastInfoCmd.Flags().String("format", "hex", "Formatting: hex/msb/lsb")
+}
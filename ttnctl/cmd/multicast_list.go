@@ -0,0 +1,68 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/multicast"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+)
+
+var multicastListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the multicast groups of an application",
+	Long:  `ttnctl multicast list can be used to list the multicast groups of an application.`,
+	Example: `$ ttnctl multicast list
+  INFO Using Application                        AppID=test
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+
+Group ID	McAddr  	FCnt
+lights  	26001ADA	12
+
+  INFO Listed 1 multicast groups                AppID=test
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 0, 0)
+
+		appID := util.GetAppID(ctx)
+
+		conn, manager := util.GetMulticastManager(ctx)
+		defer conn.Close()
+
+		res, err := manager.ListGroups(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			&multicast.ApplicationIdentifier{AppID: appID},
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not get multicast groups")
+		}
+
+		table := uitable.New()
+		table.MaxColWidth = 70
+		table.AddRow("Group ID", "McAddr", "FCnt")
+		for _, group := range res.Groups {
+			table.AddRow(group.GroupID, group.McAddr, group.FCnt)
+		}
+
+		fmt.Println()
+		fmt.Println(table)
+		fmt.Println()
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID": appID,
+		}).Infof("Listed %d multicast groups", len(res.Groups))
+	},
+}
+
+func init() {
+	multicastCmd.AddCommand(multicastListCmd)
+}
@@ -0,0 +1,119 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/go-utils/random"
+	"github.com/TheThingsNetwork/ttn/core/handler/multicast"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+var multicastRegisterCmd = &cobra.Command{
+	Use:   "register [Group ID] [McAddr] [McNwkSKey] [McAppSKey]",
+	Short: "Register a multicast group or update its settings",
+	Long: `ttnctl multicast register can be used to register a multicast group or update its settings.
+The devices in the group must be configured with the same McAddr, McNwkSKey and McAppSKey.
+Downlink messages to the group are sent by the given gateways as soon as possible (Class C).`,
+	Example: `$ ttnctl multicast register lights 26001ADA --gateways gtw-1,gtw-2 --frequency 869525000 --data-rate SF9BW125
+  INFO Using Application                        AppID=test
+  INFO Generating random McNwkSKey...
+  INFO Generating random McAppSKey...
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+  INFO Registered multicast group               AppID=test GroupID=lights McAddr=26001ADA McAppSKey=D8DD37B4B709BA76C6FEC62CAD0CCE51 McNwkSKey=3382A3066850293421ED8D392B9BF4DF
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 2, 4)
+
+		var err error
+
+		groupID := strings.ToLower(args[0])
+		if err := api.NotEmptyAndValidID(groupID, "Group ID"); err != nil {
+			ctx.Fatal(err.Error())
+		}
+
+		appID := util.GetAppID(ctx)
+
+		mcAddr, err := types.ParseDevAddr(args[1])
+		if err != nil {
+			ctx.Fatalf("Invalid McAddr: %s", err)
+		}
+
+		var mcNwkSKey types.NwkSKey
+		if len(args) > 2 {
+			mcNwkSKey, err = types.ParseNwkSKey(args[2])
+			if err != nil {
+				ctx.Fatalf("Invalid McNwkSKey: %s", err)
+			}
+		} else {
+			ctx.Info("Generating random McNwkSKey...")
+			random.FillBytes(mcNwkSKey[:])
+		}
+
+		var mcAppSKey types.AppSKey
+		if len(args) > 3 {
+			mcAppSKey, err = types.ParseAppSKey(args[3])
+			if err != nil {
+				ctx.Fatalf("Invalid McAppSKey: %s", err)
+			}
+		} else {
+			ctx.Info("Generating random McAppSKey...")
+			random.FillBytes(mcAppSKey[:])
+		}
+
+		gateways, _ := cmd.Flags().GetStringSlice("gateways")
+		frequency, _ := cmd.Flags().GetUint64("frequency")
+		dataRate, _ := cmd.Flags().GetString("data-rate")
+		power, _ := cmd.Flags().GetInt32("power")
+		fCnt, _ := cmd.Flags().GetUint32("fcnt")
+
+		conn, manager := util.GetMulticastManager(ctx)
+		defer conn.Close()
+
+		_, err = manager.SetGroup(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			&multicast.GroupSettings{
+				AppID:     appID,
+				GroupID:   groupID,
+				McAddr:    mcAddr,
+				McNwkSKey: mcNwkSKey,
+				McAppSKey: mcAppSKey,
+				FCnt:      fCnt,
+				Frequency: frequency,
+				DataRate:  dataRate,
+				Power:     power,
+				Gateways:  gateways,
+			},
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not register multicast group")
+		}
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID":     appID,
+			"GroupID":   groupID,
+			"McAddr":    mcAddr,
+			"McNwkSKey": mcNwkSKey,
+			"McAppSKey": mcAppSKey,
+		}).Info("Registered multicast group")
+	},
+}
+
+func init() {
+	multicastCmd.AddCommand(multicastRegisterCmd)
+	multicastRegisterCmd.Flags().StringSlice("gateways", []string{}, "IDs of the gateways that send the downlink messages of the group")
+	multicastRegisterCmd.Flags().Uint64("frequency", 0, "Frequency (in Hz) of the downlink messages of the group")
+	multicastRegisterCmd.Flags().String("data-rate", "", "Data rate of the downlink messages of the group (for example SF9BW125)")
+	multicastRegisterCmd.Flags().Int32("power", 0, "Transmit power (in dBm) of the downlink messages of the group (network default if zero)")
+	multicastRegisterCmd.Flags().Uint32("fcnt", 0, "Frame counter of the next downlink message of the group")
+}
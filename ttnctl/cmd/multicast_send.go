@@ -0,0 +1,73 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/TheThingsNetwork/api"
+	"github.com/TheThingsNetwork/go-account-lib/scope"
+	"github.com/TheThingsNetwork/go-utils/grpc/ttnctx"
+	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/TheThingsNetwork/ttn/ttnctl/util"
+	"github.com/TheThingsNetwork/ttn/utils/errors"
+	"github.com/spf13/cobra"
+)
+
+var multicastSendCmd = &cobra.Command{
+	Use:   "send [Group ID] [Payload]",
+	Short: "Send a downlink message to a multicast group",
+	Long: `ttnctl multicast send can be used to send a downlink message to all devices in a multicast group.
+The payload is encrypted once and sent by the gateways of the group as soon as possible.`,
+	Example: `$ ttnctl multicast send lights 01
+  INFO Using Application                        AppID=test
+  INFO Discovering Handler...                   Handler=ttn-handler-eu
+  INFO Connecting with Handler...               Handler=eu.thethings.network:1904
+  INFO Sent multicast downlink                  AppID=test FCnt=12 Gateways=gtw-1,gtw-2 GroupID=lights
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		assertArgsLength(cmd, args, 2, 2)
+
+		groupID := strings.ToLower(args[0])
+		if err := api.NotEmptyAndValidID(groupID, "Group ID"); err != nil {
+			ctx.Fatal(err.Error())
+		}
+
+		appID := util.GetAppID(ctx)
+
+		payload, err := types.ParseHEX(args[1], len(args[1])/2)
+		if err != nil {
+			ctx.WithError(err).Fatal("Invalid Payload")
+		}
+
+		fPort, err := cmd.Flags().GetInt("fport")
+		if err != nil {
+			ctx.WithError(err).Fatal("Failed to read fport flag")
+		}
+
+		conn, manager := util.GetMulticastManager(ctx)
+		defer conn.Close()
+
+		res, err := manager.SendDownlink(
+			ttnctx.OutgoingContextWithToken(util.GetContext(ctx), util.TokenForScope(ctx, scope.App(appID))),
+			&types.MulticastDownlinkMessage{AppID: appID, GroupID: groupID, FPort: uint8(fPort), PayloadRaw: payload},
+		)
+		if err != nil {
+			ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not send multicast downlink")
+		}
+
+		ctx.WithFields(ttnlog.Fields{
+			"AppID":    appID,
+			"GroupID":  groupID,
+			"FCnt":     res.FCnt,
+			"Gateways": strings.Join(res.Gateways, ","),
+		}).Info("Sent multicast downlink")
+	},
+}
+
+func init() {
+	multicastCmd.AddCommand(multicastSendCmd)
+	multicastSendCmd.Flags().Int("fport", 1, "FPort for downlink")
+}
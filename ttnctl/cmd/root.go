@@ -77,6 +77,7 @@ func init() {
 	RootCmd.PersistentFlags().StringVar(&dataDir, "data", "", "directory where ttnctl stores data (default is $HOME/.ttnctl)")
 	RootCmd.PersistentFlags().String("discovery-address", "discover.thethingsnetwork.org:1900", "The address of the Discovery server")
 	RootCmd.PersistentFlags().String("router-id", "ttn-router-eu", "The ID of the TTN Router as announced in the Discovery server")
+	RootCmd.PersistentFlags().String("broker-id", "ttn-broker-eu", "The ID of the TTN Broker as announced in the Discovery server")
 	RootCmd.PersistentFlags().String("handler-id", "ttn-handler-eu", "The ID of the TTN Handler as announced in the Discovery server")
 	RootCmd.PersistentFlags().String("mqtt-address", "eu.thethings.network:1883", "The address of the MQTT broker")
 	RootCmd.PersistentFlags().String("mqtt-username", "", "The username for the MQTT broker")
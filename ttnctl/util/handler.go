@@ -8,13 +8,17 @@ import (
 	"github.com/TheThingsNetwork/api/handler/handlerclient"
 	"github.com/TheThingsNetwork/go-account-lib/scope"
 	ttnlog "github.com/TheThingsNetwork/go-utils/log"
+	"github.com/TheThingsNetwork/ttn/core/handler/application"
+	"github.com/TheThingsNetwork/ttn/core/handler/clocksync"
+	"github.com/TheThingsNetwork/ttn/core/handler/device"
+	"github.com/TheThingsNetwork/ttn/core/handler/fuota"
+	"github.com/TheThingsNetwork/ttn/core/handler/multicast"
 	"github.com/TheThingsNetwork/ttn/utils/errors"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 )
 
-// GetHandlerManager gets a new HandlerManager for ttnctl
-func GetHandlerManager(ctx ttnlog.Interface, appID string) (*grpc.ClientConn, *handlerclient.ManagerClient) {
+func dialHandler(ctx ttnlog.Interface) *grpc.ClientConn {
 	ctx.WithField("Handler", viper.GetString("handler-id")).Info("Discovering Handler...")
 	dscConn, client := GetDiscovery(ctx)
 	defer dscConn.Close()
@@ -25,14 +29,48 @@ func GetHandlerManager(ctx ttnlog.Interface, appID string) (*grpc.ClientConn, *h
 	if err != nil {
 		ctx.WithError(errors.FromGRPCError(err)).Fatal("Could not find Handler")
 	}
-
-	token := TokenForScope(ctx, scope.App(appID))
-
 	ctx.WithField("Handler", handlerAnnouncement.NetAddress).Info("Connecting with Handler...")
 	hdlConn, err := handlerAnnouncement.Dial(nil)
 	if err != nil {
 		ctx.WithError(err).Fatal("Could not connect to Handler")
 	}
+	return hdlConn
+}
+
+// GetDownlinkQueueManager starts a management connection with the handler for managing downlink queues
+func GetDownlinkQueueManager(ctx ttnlog.Interface) (*grpc.ClientConn, device.DownlinkQueueManagerClient) {
+	hdlConn := dialHandler(ctx)
+	return hdlConn, device.NewDownlinkQueueManagerClient(hdlConn)
+}
+
+// GetMulticastManager starts a management connection with the handler for managing multicast groups
+func GetMulticastManager(ctx ttnlog.Interface) (*grpc.ClientConn, multicast.MulticastManagerClient) {
+	hdlConn := dialHandler(ctx)
+	return hdlConn, multicast.NewMulticastManagerClient(hdlConn)
+}
+
+// GetClockSyncManager starts a management connection with the handler for managing clock synchronization
+func GetClockSyncManager(ctx ttnlog.Interface) (*grpc.ClientConn, clocksync.ClockSyncManagerClient) {
+	hdlConn := dialHandler(ctx)
+	return hdlConn, clocksync.NewClockSyncManagerClient(hdlConn)
+}
+
+// GetPayloadFormatterManager starts a management connection with the handler for managing payload formatter overrides
+func GetPayloadFormatterManager(ctx ttnlog.Interface) (*grpc.ClientConn, application.PayloadFormatterManagerClient) {
+	hdlConn := dialHandler(ctx)
+	return hdlConn, application.NewPayloadFormatterManagerClient(hdlConn)
+}
+
+// GetFUOTAManager starts a management connection with the handler for managing FUOTA sessions
+func GetFUOTAManager(ctx ttnlog.Interface) (*grpc.ClientConn, fuota.FUOTAManagerClient) {
+	hdlConn := dialHandler(ctx)
+	return hdlConn, fuota.NewFUOTAManagerClient(hdlConn)
+}
+
+// GetHandlerManager gets a new HandlerManager for ttnctl
+func GetHandlerManager(ctx ttnlog.Interface, appID string) (*grpc.ClientConn, *handlerclient.ManagerClient) {
+	hdlConn := dialHandler(ctx)
+	token := TokenForScope(ctx, scope.App(appID))
 	managerClient, err := handlerclient.NewManagerClient(hdlConn, token)
 	if err != nil {
 		ctx.WithError(err).Fatal("Could not create Handler Manager")
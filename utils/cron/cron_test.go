@@ -0,0 +1,48 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package cron
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/assertions"
+)
+
+func TestCron(t *testing.T) {
+	a := New(t)
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := Parse(expr)
+		a.So(err, ShouldNotBeNil)
+	}
+
+	now := time.Date(2017, time.September, 1, 12, 30, 15, 0, time.UTC) // Friday
+
+	daily, err := Parse("@daily")
+	a.So(err, ShouldBeNil)
+	a.So(daily.Next(now), ShouldResemble, time.Date(2017, time.September, 2, 0, 0, 0, 0, time.UTC))
+
+	quarter, err := Parse("*/15 8-18 * * *")
+	a.So(err, ShouldBeNil)
+	a.So(quarter.Next(now), ShouldResemble, time.Date(2017, time.September, 1, 12, 45, 0, 0, time.UTC))
+	a.So(quarter.Next(time.Date(2017, time.September, 1, 18, 50, 0, 0, time.UTC)), ShouldResemble, time.Date(2017, time.September, 2, 8, 0, 0, 0, time.UTC))
+
+	weekdays, err := Parse("0 9 * * 1-5")
+	a.So(err, ShouldBeNil)
+	a.So(weekdays.Next(now), ShouldResemble, time.Date(2017, time.September, 4, 9, 0, 0, 0, time.UTC))
+
+	// Day of month or day of week
+	either, err := Parse("0 0 15 * 0")
+	a.So(err, ShouldBeNil)
+	a.So(either.Next(now), ShouldResemble, time.Date(2017, time.September, 3, 0, 0, 0, 0, time.UTC))
+
+	leap, err := Parse("0 0 29 2 *")
+	a.So(err, ShouldBeNil)
+	a.So(leap.Next(now), ShouldResemble, time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC))
+
+	never, err := Parse("0 0 31 2 *")
+	a.So(err, ShouldBeNil)
+	a.So(never.Next(now).IsZero(), ShouldBeTrue)
+}
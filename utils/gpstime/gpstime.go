@@ -0,0 +1,54 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package gpstime converts between UTC and GPS time, as used by the LoRaWAN application layer packages
+package gpstime
+
+import "time"
+
+// Epoch is the start of GPS time
+var Epoch = time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+// leapSeconds are the moments (in UTC) at which a leap second was inserted since the GPS epoch
+var leapSeconds = []time.Time{
+	time.Date(1981, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1982, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1983, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1985, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1988, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1991, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1992, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1993, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1994, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1996, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1997, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1999, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2006, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2009, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2012, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2015, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+}
+
+// ToGPS returns the time since the GPS epoch, including leap seconds
+func ToGPS(t time.Time) time.Duration {
+	d := t.Sub(Epoch)
+	for _, leap := range leapSeconds {
+		if !t.Before(leap) {
+			d += time.Second
+		}
+	}
+	return d
+}
+
+// FromGPS returns the UTC time for the given time since the GPS epoch
+func FromGPS(d time.Duration) time.Time {
+	t := Epoch.Add(d)
+	for i := len(leapSeconds) - 1; i >= 0; i-- {
+		if !t.Add(-time.Duration(i+1) * time.Second).Before(leapSeconds[i]) {
+			return t.Add(-time.Duration(i+1) * time.Second)
+		}
+	}
+	return t
+}
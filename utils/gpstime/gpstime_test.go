@@ -0,0 +1,27 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package gpstime
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/assertions"
+)
+
+func TestGPSTime(t *testing.T) {
+	a := New(t)
+
+	a.So(ToGPS(Epoch), ShouldEqual, 0)
+	a.So(FromGPS(0), ShouldResemble, Epoch)
+
+	beforeLeap := time.Date(2016, time.December, 31, 23, 59, 59, 0, time.UTC)
+	afterLeap := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+	a.So(ToGPS(beforeLeap)/time.Second, ShouldEqual, 1167264016)
+	a.So(ToGPS(afterLeap)/time.Second, ShouldEqual, 1167264018)
+
+	for _, utc := range []time.Time{beforeLeap, afterLeap, time.Date(2019, time.March, 14, 12, 30, 15, 0, time.UTC)} {
+		a.So(FromGPS(ToGPS(utc)), ShouldResemble, utc)
+	}
+}
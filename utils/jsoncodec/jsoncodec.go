@@ -0,0 +1,32 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+// Package jsoncodec implements a gRPC codec that exchanges messages as JSON. It is used for services with messages that
+// are not part of the TTN protos. Because protoc can not generate these services, they declare their server API as a
+// Go interface that is described with ServiceDesc, and their clients call the methods with Invoke.
+package jsoncodec
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// Name of the codec (content-subtype)
+const Name = "json"
+
+// Codec marshals and unmarshals messages as JSON
+type Codec struct{}
+
+// Marshal the message to JSON
+func (Codec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal the message from JSON
+func (Codec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// Name of the codec
+func (Codec) Name() string { return Name }
+
+func init() {
+	encoding.RegisterCodec(Codec{})
+}
@@ -0,0 +1,70 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package jsoncodec
+
+import (
+	"fmt"
+	"reflect"
+
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// ServiceDesc returns the description of the service with the given name. The handlerType is a pointer to the
+// server interface of the service. Each method of that interface takes a context and a pointer to the request, and
+// returns a pointer to the response and an error.
+func ServiceDesc(serviceName string, handlerType interface{}) *grpc.ServiceDesc {
+	server := reflect.TypeOf(handlerType).Elem()
+	desc := &grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: handlerType,
+		Streams:     []grpc.StreamDesc{},
+	}
+	for i := 0; i < server.NumMethod(); i++ {
+		method := server.Method(i)
+		if method.Type.NumIn() != 2 || method.Type.In(0) != contextType || method.Type.In(1).Kind() != reflect.Ptr ||
+			method.Type.NumOut() != 2 || method.Type.Out(1) != errorType {
+			panic(fmt.Sprintf("jsoncodec: %s.%s is not a unary method", serviceName, method.Name))
+		}
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: method.Name,
+			Handler:    unaryHandler(serviceName, method.Name, method.Type.In(1).Elem()),
+		})
+	}
+	return desc
+}
+
+func unaryHandler(serviceName, methodName string, request reflect.Type) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	call := func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+		out := reflect.ValueOf(srv).MethodByName(methodName).Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(in)})
+		if err, _ := out[1].Interface().(error); err != nil {
+			return nil, err
+		}
+		return out[0].Interface(), nil
+	}
+	fullMethod := "/" + serviceName + "/" + methodName
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := reflect.New(request).Interface()
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv, ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv, ctx, req)
+		})
+	}
+}
+
+// Invoke calls the method (such as "/handler.ClockSyncManager/GetClockSync") on the connection, exchanging the
+// messages as JSON
+func Invoke(ctx context.Context, cc *grpc.ClientConn, method string, in, out interface{}, opts ...grpc.CallOption) error {
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(Name)}, opts...)
+	return cc.Invoke(ctx, method, in, out, opts...)
+}
@@ -0,0 +1,78 @@
+// Copyright © 2017 The Things Network
+// Use of this source code is governed by the MIT license that can be found in the LICENSE file.
+
+package jsoncodec
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/assertions"
+	"golang.org/x/net/context" // See https://github.com/grpc/grpc-go/issues/711"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+type testRequest struct {
+	Name string `json:"name"`
+}
+
+type testResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+type testServer interface {
+	Greet(context.Context, *testRequest) (*testResponse, error)
+}
+
+type greeter struct{}
+
+func (greeter) Greet(ctx context.Context, in *testRequest) (*testResponse, error) {
+	if in.Name == "" {
+		return nil, errors.New("no name")
+	}
+	return &testResponse{Greeting: "Hello " + in.Name}, nil
+}
+
+func TestService(t *testing.T) {
+	a := New(t)
+
+	var intercepted []string
+	s := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		intercepted = append(intercepted, info.FullMethod)
+		return handler(ctx, req)
+	}))
+	s.RegisterService(ServiceDesc("test.Greeter", (*testServer)(nil)), greeter{})
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to connect to server: %v", err)
+	}
+	defer conn.Close()
+
+	res := new(testResponse)
+	err = Invoke(context.Background(), conn, "/test.Greeter/Greet", &testRequest{Name: "World"}, res)
+	a.So(err, ShouldBeNil)
+	a.So(res.Greeting, ShouldEqual, "Hello World")
+	a.So(intercepted, ShouldResemble, []string{"/test.Greeter/Greet"})
+
+	err = Invoke(context.Background(), conn, "/test.Greeter/Greet", &testRequest{}, res)
+	a.So(err, ShouldNotBeNil)
+	a.So(status.Convert(err).Message(), ShouldEqual, "no name")
+}
+
+func TestServiceDescInvalid(t *testing.T) {
+	a := New(t)
+
+	type invalidServer interface {
+		Greet(*testRequest) (*testResponse, error)
+	}
+	a.So(func() { ServiceDesc("test.Invalid", (*invalidServer)(nil)) }, ShouldPanic)
+}